@@ -9,9 +9,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/session"
@@ -33,6 +36,15 @@ type VSphereClient struct {
 
 	// The specialized tags client SDK imported from vmware/vic.
 	tagsClient *tags.RestClient
+
+	// The content library client. This shares the CIS REST session with
+	// tagsClient.
+	contentLibraryClient *contentlibrary.Client
+
+	// The storage policy (PBM) client. This shares the session with vimClient,
+	// and is created the first time that it is needed.
+	pbmClient   *spbm.Client
+	pbmClientMu sync.Mutex
 }
 
 // TagsClient returns the embedded REST client used for tags, after determining
@@ -66,6 +78,40 @@ func (c *VSphereClient) TagsClient() (*tags.RestClient, error) {
 	return c.tagsClient, nil
 }
 
+// ContentLibraryClient returns the client used for content library
+// operations, after determining if the connection is eligible. The checks are
+// the same as TagsClient, with the exception that the content library API
+// requires a later version of vCenter.
+func (c *VSphereClient) ContentLibraryClient() (*contentlibrary.Client, error) {
+	if err := viapi.ValidateVirtualCenter(c.vimClient); err != nil {
+		return nil, err
+	}
+	if c.contentLibraryClient == nil {
+		return nil, fmt.Errorf("content libraries require %s or higher", contentLibraryMinVersion)
+	}
+	return c.contentLibraryClient, nil
+}
+
+// PbmClient returns the client used for storage policy operations, after
+// determining if the connection is eligible. Storage policies are only
+// available on vCenter. The client is created the first time that it is
+// requested, and reused for the lifetime of the provider.
+func (c *VSphereClient) PbmClient() (*spbm.Client, error) {
+	if err := viapi.ValidateVirtualCenter(c.vimClient); err != nil {
+		return nil, err
+	}
+	c.pbmClientMu.Lock()
+	defer c.pbmClientMu.Unlock()
+	if c.pbmClient == nil {
+		pc, err := spbm.NewClient(c.vimClient)
+		if err != nil {
+			return nil, err
+		}
+		c.pbmClient = pc
+	}
+	return c.pbmClient, nil
+}
+
 // Config holds the provider configuration, and delivers a populated
 // VSphereClient based off the contained settings.
 type Config struct {
@@ -153,6 +199,9 @@ func (c *Config) Client() (*VSphereClient, error) {
 			return nil, err
 		}
 		log.Println("[DEBUG] CIS REST client configuration successful")
+		if isEligibleContentLibraryEndpoint(client.vimClient) {
+			client.contentLibraryClient = contentlibrary.NewClient(client.tagsClient, u)
+		}
 	} else {
 		// Just print a log message so that we know that tags are not available on
 		// this connection.
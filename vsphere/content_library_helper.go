@@ -0,0 +1,28 @@
+package vsphere
+
+import (
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+	"github.com/vmware/govmomi"
+)
+
+// contentLibraryMinVersion is the minimum vSphere version required for the
+// content library REST API.
+var contentLibraryMinVersion = viapi.VSphereVersion{
+	Product: "VMware vCenter Server",
+	Major:   6,
+	Minor:   5,
+	Patch:   0,
+}
+
+// isEligibleContentLibraryEndpoint is a meta-validation that is used on login
+// to see if the connected endpoint supports the content library REST API.
+func isEligibleContentLibraryEndpoint(client *govmomi.Client) bool {
+	if err := viapi.ValidateVirtualCenter(client); err != nil {
+		return false
+	}
+	clientVer := viapi.ParseVersionFromClient(client)
+	if !clientVer.ProductEqual(contentLibraryMinVersion) || clientVer.Older(contentLibraryMinVersion) {
+		return false
+	}
+	return true
+}
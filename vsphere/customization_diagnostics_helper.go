@@ -0,0 +1,273 @@
+package vsphere
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/guestoperations"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// customizationDiagnosticsKeyPrefix is the prefix of the diagnostics block in
+// the customize block of the clone sub-resource.
+const customizationDiagnosticsKeyPrefix = "clone.0.customize.0.diagnostics.0"
+
+// The files that make up a customization diagnostics bundle. Guest logs are
+// written next to these, with their file name prefixed with "guest-".
+const (
+	customizationDiagnosticsErrorFile      = "error.txt"
+	customizationDiagnosticsEventsFile     = "events.txt"
+	customizationDiagnosticsScreenshotFile = "screenshot.png"
+	customizationDiagnosticsLogFile        = "diagnostics.log"
+)
+
+// customizationGuestLogPathsLinux are the guest customization logs that are
+// collected from Linux guests.
+var customizationGuestLogPathsLinux = []string{
+	"/var/log/vmware-imc/toolsDeployPkg.log",
+}
+
+// customizationGuestLogPathsWindows are the guest customization logs that are
+// collected from Windows guests.
+var customizationGuestLogPathsWindows = []string{
+	`C:\Windows\TEMP\vmware-imc\guestcust.log`,
+	`C:\Windows\TEMP\vmware-imc\toolsDeployPkg.log`,
+}
+
+// customizationDiagnosticsBundle is a diagnostics bundle that is being written
+// to a local directory. Errors collecting individual parts of the bundle do
+// not stop the collection, and are recorded in the log of the bundle instead.
+type customizationDiagnosticsBundle struct {
+	dir string
+	log []string
+}
+
+// logf records a message in the log of the bundle.
+func (b *customizationDiagnosticsBundle) logf(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	log.Printf("[DEBUG] Customization diagnostics: %s", msg)
+	b.log = append(b.log, msg)
+}
+
+// write writes a file to the bundle directory.
+func (b *customizationDiagnosticsBundle) write(name string, data []byte) {
+	if err := ioutil.WriteFile(filepath.Join(b.dir, name), data, 0600); err != nil {
+		b.logf("could not write %s: %s", name, err)
+		return
+	}
+	b.logf("wrote %s", name)
+}
+
+// customizationDiagnosticsError returns the supplied customization error with
+// the location of a diagnostics bundle added to it. The bundle is only
+// gathered if the diagnostics block is set in the customize block of the clone
+// sub-resource, otherwise the error is returned as is.
+func customizationDiagnosticsError(d *schema.ResourceData, client *govmomi.Client, vm *object.VirtualMachine, cerr error) error {
+	if len(d.Get("clone.0.customize.0.diagnostics").([]interface{})) < 1 {
+		return cerr
+	}
+	dir, err := collectCustomizationDiagnostics(d, client, vm, cerr)
+	if err != nil {
+		return fmt.Errorf("%s\n\nAdditionally, the customization diagnostics bundle could not be written: %s", cerr, err)
+	}
+	return fmt.Errorf("%s\n\nA customization diagnostics bundle has been written to %s", cerr, dir)
+}
+
+// collectCustomizationDiagnostics gathers a diagnostics bundle for a virtual
+// machine whose customization failed or timed out, and returns the directory
+// that the bundle was written to. The bundle contains the customization
+// error, the events of the virtual machine, a screenshot of the console, and
+// the guest customization logs if VMware tools is running and guest
+// credentials are set.
+func collectCustomizationDiagnostics(d *schema.ResourceData, client *govmomi.Client, vm *object.VirtualMachine, cerr error) (string, error) {
+	log.Printf("[DEBUG] %s: Gathering customization diagnostics", resourceVSphereVirtualMachineIDString(d))
+	name := customizationDiagnosticsBundleName(d.Get("name").(string), time.Now())
+	dir := filepath.Join(d.Get(customizationDiagnosticsKeyPrefix+".directory").(string), name)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	b := &customizationDiagnosticsBundle{dir: dir}
+	b.write(customizationDiagnosticsErrorFile, []byte(cerr.Error()+"\n"))
+
+	var logLocations []string
+	events, err := selectEventsForReference(client, vm.Reference(), nil)
+	if err != nil {
+		b.logf("could not query events: %s", err)
+	} else {
+		var data []byte
+		data, logLocations = formatCustomizationDiagnosticsEvents(events)
+		b.write(customizationDiagnosticsEventsFile, data)
+	}
+
+	if data, err := downloadVirtualMachineScreenshot(client, vm); err != nil {
+		b.logf("could not take console screenshot: %s", err)
+	} else {
+		b.write(customizationDiagnosticsScreenshotFile, data)
+	}
+
+	collectCustomizationGuestLogs(d, client, vm, b, logLocations)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, customizationDiagnosticsLogFile), []byte(strings.Join(b.log, "\n")+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// collectCustomizationGuestLogs downloads the guest customization logs to the
+// bundle. Logs can only be downloaded if VMware tools is running and guest
+// credentials are set in the diagnostics block.
+func collectCustomizationGuestLogs(d *schema.ResourceData, client *govmomi.Client, vm *object.VirtualMachine, b *customizationDiagnosticsBundle, logLocations []string) {
+	username := d.Get(customizationDiagnosticsKeyPrefix + ".guest_username").(string)
+	if username == "" {
+		b.logf("skipping guest logs: guest_username is not set")
+		return
+	}
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		b.logf("skipping guest logs: could not fetch virtual machine properties: %s", err)
+		return
+	}
+	if vprops.Guest == nil || vprops.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+		b.logf("skipping guest logs: VMware tools is not running")
+		return
+	}
+	gc, err := guestoperations.NewClient(client, vm, username, d.Get(customizationDiagnosticsKeyPrefix+".guest_password").(string))
+	if err != nil {
+		b.logf("skipping guest logs: %s", err)
+		return
+	}
+	for _, p := range customizationGuestLogPaths(vprops.Guest.GuestFamily, logLocations) {
+		var data bytes.Buffer
+		if err := gc.Download(p, &data); err != nil {
+			if guestoperations.IsFileNotFoundError(err) {
+				b.logf("guest log %q not found", p)
+			} else {
+				b.logf("could not download guest log %q: %s", p, err)
+			}
+			continue
+		}
+		b.write(customizationGuestLogFileName(p), data.Bytes())
+	}
+}
+
+// downloadVirtualMachineScreenshot takes a screenshot of the console of a
+// virtual machine and downloads it from the datastore. The screenshot is
+// removed from the datastore afterwards.
+func downloadVirtualMachineScreenshot(client *govmomi.Client, vm *object.VirtualMachine) ([]byte, error) {
+	dsPath, err := virtualmachine.CreateScreenshot(vm)
+	if err != nil {
+		return nil, err
+	}
+	p := &object.DatastorePath{}
+	if ok := p.FromString(dsPath); !ok {
+		return nil, fmt.Errorf("could not read datastore path %q", dsPath)
+	}
+	ds, err := virtualMachineDatastoreByName(client, vm, p.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	r, _, err := ds.Download(ctx, p.Path, &soap.DefaultDownload)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading screenshot %q: %s", dsPath, err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading screenshot %q: %s", dsPath, err)
+	}
+
+	dc, err := getDatacenter(client, ds.DatacenterPath)
+	if err != nil {
+		return data, nil
+	}
+	task, err := object.NewFileManager(client.Client).DeleteDatastoreFile(ctx, dsPath, dc)
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	if err != nil {
+		log.Printf("[WARN] Could not remove screenshot %q: %s", dsPath, err)
+	}
+	return data, nil
+}
+
+// customizationDiagnosticsBundleName returns the name of the directory of a
+// diagnostics bundle for a virtual machine, which is the name of the virtual
+// machine and the time that the bundle was gathered at.
+func customizationDiagnosticsBundleName(name string, t time.Time) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, name)
+	return fmt.Sprintf("%s-customization-%s", name, t.UTC().Format("20060102T150405Z"))
+}
+
+// formatCustomizationDiagnosticsEvents returns the supplied events in the
+// order that they were logged in, one per line, along with the guest log
+// locations reported by customization events.
+func formatCustomizationDiagnosticsEvents(events []types.BaseEvent) ([]byte, []string) {
+	sorted := make([]types.BaseEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetEvent().Key < sorted[j].GetEvent().Key
+	})
+
+	var buf bytes.Buffer
+	var locations []string
+	for _, be := range sorted {
+		e := be.GetEvent()
+		fmt.Fprintf(&buf, "%s %s: %s", e.CreatedTime.UTC().Format(time.RFC3339), reflect.Indirect(reflect.ValueOf(be)).Type().Name(), e.FullFormattedMessage)
+		if ce, ok := be.(types.BaseCustomizationEvent); ok {
+			if loc := ce.GetCustomizationEvent().LogLocation; loc != "" {
+				fmt.Fprintf(&buf, " (log: %s)", loc)
+				locations = append(locations, loc)
+			}
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), locations
+}
+
+// customizationGuestLogPaths returns the guest logs to collect for the
+// supplied guest family. Log locations reported by customization events are
+// collected in addition to the well-known customization logs.
+func customizationGuestLogPaths(family string, logLocations []string) []string {
+	paths := customizationGuestLogPathsLinux
+	if family == string(types.VirtualMachineGuestOsFamilyWindowsGuest) {
+		paths = customizationGuestLogPathsWindows
+	}
+	var result []string
+	seen := make(map[string]struct{})
+	for _, p := range append(append([]string{}, paths...), logLocations...) {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		result = append(result, p)
+	}
+	return result
+}
+
+// customizationGuestLogFileName returns the name of the file in the bundle
+// for a guest log.
+func customizationGuestLogFileName(p string) string {
+	return "guest-" + path.Base(strings.Replace(p, `\`, "/", -1))
+}
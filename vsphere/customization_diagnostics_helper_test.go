@@ -0,0 +1,63 @@
+package vsphere
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestFormatCustomizationDiagnosticsEvents(t *testing.T) {
+	created := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	events := []types.BaseEvent{
+		&types.CustomizationUnknownFailure{
+			CustomizationFailed: types.CustomizationFailed{
+				CustomizationEvent: types.CustomizationEvent{
+					VmEvent: types.VmEvent{
+						Event: types.Event{
+							Key:                  3,
+							CreatedTime:          created.Add(2 * time.Minute),
+							FullFormattedMessage: "An error occurred while customizing VM vm1.",
+						},
+					},
+					LogLocation: "/var/log/vmware-imc/toolsDeployPkg.log",
+				},
+			},
+		},
+		&types.VmPoweredOnEvent{
+			VmEvent: types.VmEvent{
+				Event: types.Event{
+					Key:                  1,
+					CreatedTime:          created,
+					FullFormattedMessage: "vm1 on host1 is powered on",
+				},
+			},
+		},
+	}
+	expected := "2018-05-01T10:00:00Z VmPoweredOnEvent: vm1 on host1 is powered on\n" +
+		"2018-05-01T10:02:00Z CustomizationUnknownFailure: An error occurred while customizing VM vm1. (log: /var/log/vmware-imc/toolsDeployPkg.log)\n"
+
+	data, locations := formatCustomizationDiagnosticsEvents(events)
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(data))
+	}
+	if expected := []string{"/var/log/vmware-imc/toolsDeployPkg.log"}; !reflect.DeepEqual(expected, locations) {
+		t.Fatalf("expected locations %#v, got %#v", expected, locations)
+	}
+}
+
+func TestCustomizationGuestLogPaths(t *testing.T) {
+	paths := customizationGuestLogPaths("windowsGuest", []string{`C:\Windows\TEMP\vmware-imc\guestcust.log`, `C:\sysprep\setupact.log`})
+	expected := []string{
+		`C:\Windows\TEMP\vmware-imc\guestcust.log`,
+		`C:\Windows\TEMP\vmware-imc\toolsDeployPkg.log`,
+		`C:\sysprep\setupact.log`,
+	}
+	if !reflect.DeepEqual(expected, paths) {
+		t.Fatalf("expected %#v, got %#v", expected, paths)
+	}
+	if actual := customizationGuestLogFileName(paths[2]); actual != "guest-setupact.log" {
+		t.Fatalf("expected guest-setupact.log, got %s", actual)
+	}
+}
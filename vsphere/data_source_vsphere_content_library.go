@@ -0,0 +1,65 @@
+package vsphere
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceVSphereContentLibrary() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceVSphereContentLibraryRead,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the content library.",
+				Required:    true,
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The description of the content library.",
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The type of the content library. Can be one of LOCAL or SUBSCRIBED.",
+			},
+			"storage_backing": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Description: "The managed object IDs of the datastores that back the content library.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceVSphereContentLibraryRead(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	id, err := client.FindLibrary(ctx, d.Get("name").(string))
+	if err != nil {
+		return err
+	}
+	lib, err := client.GetLibrary(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(id)
+	d.Set("description", lib.Description)
+	d.Set("type", lib.Type)
+	var backings []string
+	for _, b := range lib.StorageBackings {
+		if b.DatastoreID != "" {
+			backings = append(backings, b.DatastoreID)
+		}
+	}
+	return d.Set("storage_backing", backings)
+}
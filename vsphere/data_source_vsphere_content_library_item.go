@@ -0,0 +1,58 @@
+package vsphere
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceVSphereContentLibraryItem() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceVSphereContentLibraryItemRead,
+		Schema: map[string]*schema.Schema{
+			"library_id": {
+				Type:        schema.TypeString,
+				Description: "The ID of the content library that contains the item.",
+				Required:    true,
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the item.",
+				Required:    true,
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The description of the item.",
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The type of the item, such as ovf, vm-template, or iso.",
+			},
+		},
+	}
+}
+
+func dataSourceVSphereContentLibraryItemRead(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	id, err := client.FindLibraryItem(ctx, d.Get("library_id").(string), d.Get("name").(string))
+	if err != nil {
+		return err
+	}
+	item, err := client.GetLibraryItem(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(id)
+	d.Set("description", item.Description)
+	d.Set("type", item.Type)
+	return nil
+}
@@ -0,0 +1,74 @@
+package vsphere
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceVSphereContentLibrary_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryPreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceVSphereContentLibraryConfig(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"data.vsphere_content_library.library",
+						"description",
+						"Terraform test content library",
+					),
+					resource.TestCheckResourceAttr(
+						"data.vsphere_content_library.library",
+						"type",
+						"LOCAL",
+					),
+					resource.TestCheckResourceAttrPair(
+						"data.vsphere_content_library.library", "id",
+						"vsphere_content_library.library", "id",
+					),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceVSphereContentLibraryConfig() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_content_library" "library" {
+  name            = "terraform-test-library"
+  description     = "Terraform test content library"
+  storage_backing = ["${data.vsphere_datastore.datastore.id}"]
+}
+
+data "vsphere_content_library" "library" {
+  name = "${vsphere_content_library.library.name}"
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_DATASTORE"),
+	)
+}
@@ -0,0 +1,103 @@
+package vsphere
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/hostsystem"
+)
+
+func dataSourceVSphereHostPciDevice() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceVSphereHostPciDeviceRead,
+
+		Schema: map[string]*schema.Schema{
+			"host_id": {
+				Type:        schema.TypeString,
+				Description: "The managed object ID of the host to look for PCI devices on.",
+				Required:    true,
+			},
+			"name_regex": {
+				Type:         schema.TypeString,
+				Description:  "A regular expression to filter the PCI devices against. Only devices with names that match will be included.",
+				Optional:     true,
+				ValidateFunc: validation.ValidateRegexp,
+			},
+			"vendor_id": {
+				Type:         schema.TypeString,
+				Description:  "The hexadecimal vendor ID of the PCI device, such as 10de.",
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringMatch(hostsystem.PciIDRegexp, "must be a four digit lowercase hexadecimal ID"),
+			},
+			"class_id": {
+				Type:         schema.TypeString,
+				Description:  "The hexadecimal class ID of the PCI device, such as 0300 for VGA compatible controllers.",
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringMatch(hostsystem.PciIDRegexp, "must be a four digit lowercase hexadecimal ID"),
+			},
+			"passthrough_enabled": {
+				Type:        schema.TypeBool,
+				Description: "Only include PCI devices that have passthrough enabled on the host.",
+				Optional:    true,
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the PCI device.",
+				Computed:    true,
+			},
+			"vendor_name": {
+				Type:        schema.TypeString,
+				Description: "The name of the vendor of the PCI device.",
+				Computed:    true,
+			},
+			"device_id": {
+				Type:        schema.TypeString,
+				Description: "The hexadecimal device ID of the PCI device.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceVSphereHostPciDeviceRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	hs, err := hostsystem.FromID(client, d.Get("host_id").(string))
+	if err != nil {
+		return fmt.Errorf("error fetching host: %s", err)
+	}
+	devices, enabled, err := hostsystem.PciDevices(hs)
+	if err != nil {
+		return fmt.Errorf("error fetching PCI devices: %s", err)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Id < devices[j].Id })
+
+	re, err := regexp.Compile(d.Get("name_regex").(string))
+	if err != nil {
+		return err
+	}
+	vendorID := d.Get("vendor_id").(string)
+	classID := d.Get("class_id").(string)
+	passthrough := d.Get("passthrough_enabled").(bool)
+	for _, dev := range devices {
+		switch {
+		case !re.MatchString(dev.DeviceName):
+		case vendorID != "" && hostsystem.PciIDString(dev.VendorId) != vendorID:
+		case classID != "" && hostsystem.PciIDString(dev.ClassId) != classID:
+		case passthrough && !enabled[dev.Id]:
+		default:
+			d.SetId(dev.Id)
+			d.Set("name", dev.DeviceName)
+			d.Set("vendor_name", dev.VendorName)
+			d.Set("vendor_id", hostsystem.PciIDString(dev.VendorId))
+			d.Set("device_id", hostsystem.PciIDString(dev.DeviceId))
+			d.Set("class_id", hostsystem.PciIDString(dev.ClassId))
+			return nil
+		}
+	}
+	return fmt.Errorf("no PCI devices on host %q match the supplied criteria", hs.Reference().Value)
+}
@@ -0,0 +1,87 @@
+package vsphere
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceVSphereHostPciDevice_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccDataSourceVSphereHostPciDevicePreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceVSphereHostPciDeviceConfig(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestMatchResourceAttr("data.vsphere_host_pci_device.device", "id", regexp.MustCompile("^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\\.[0-9a-f]$")),
+					resource.TestMatchResourceAttr("data.vsphere_host_pci_device.device", "vendor_id", regexp.MustCompile("^[0-9a-f]{4}$")),
+					resource.TestCheckResourceAttrSet("data.vsphere_host_pci_device.device", "name"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceVSphereHostPciDevice_noMatch(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccDataSourceVSphereHostPciDevicePreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccDataSourceVSphereHostPciDeviceConfigNoMatch(),
+				ExpectError: regexp.MustCompile("no PCI devices on host"),
+			},
+		},
+	})
+}
+
+func testAccDataSourceVSphereHostPciDevicePreCheck(t *testing.T) {
+	if os.Getenv("VSPHERE_ESXI_HOST") == "" {
+		t.Skip("set VSPHERE_ESXI_HOST to run vsphere_host_pci_device acceptance tests")
+	}
+}
+
+func testAccDataSourceVSphereHostPciDeviceConfig() string {
+	return fmt.Sprintf(`
+data "vsphere_datacenter" "datacenter" {
+  name = "%s"
+}
+
+data "vsphere_host" "esxi_host" {
+  name          = "%s"
+  datacenter_id = "${data.vsphere_datacenter.datacenter.id}"
+}
+
+data "vsphere_host_pci_device" "device" {
+  host_id = "${data.vsphere_host.esxi_host.id}"
+}
+`, os.Getenv("VSPHERE_DATACENTER"), os.Getenv("VSPHERE_ESXI_HOST"))
+}
+
+func testAccDataSourceVSphereHostPciDeviceConfigNoMatch() string {
+	return fmt.Sprintf(`
+data "vsphere_datacenter" "datacenter" {
+  name = "%s"
+}
+
+data "vsphere_host" "esxi_host" {
+  name          = "%s"
+  datacenter_id = "${data.vsphere_datacenter.datacenter.id}"
+}
+
+data "vsphere_host_pci_device" "device" {
+  host_id    = "${data.vsphere_host.esxi_host.id}"
+  name_regex = "^terraform-test-no-such-device$"
+}
+`, os.Getenv("VSPHERE_DATACENTER"), os.Getenv("VSPHERE_ESXI_HOST"))
+}
@@ -0,0 +1,39 @@
+package vsphere
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+)
+
+func dataSourceVSphereStoragePolicy() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceVSphereStoragePolicyRead,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the storage policy.",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func dataSourceVSphereStoragePolicyRead(d *schema.ResourceData, meta interface{}) error {
+	if err := viapi.ValidateVirtualCenter(meta.(*VSphereClient).vimClient); err != nil {
+		return fmt.Errorf("vsphere_storage_policy requires vCenter: %s", err)
+	}
+	client, err := meta.(*VSphereClient).PbmClient()
+	if err != nil {
+		return err
+	}
+
+	id, err := spbm.PolicyIDByName(client, d.Get("name").(string))
+	if err != nil {
+		return err
+	}
+	d.SetId(id)
+	return nil
+}
@@ -0,0 +1,48 @@
+package vsphere
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceVSphereStoragePolicy_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccDataSourceVSphereStoragePolicyPreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceVSphereStoragePolicyConfig(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestMatchResourceAttr(
+						"data.vsphere_storage_policy.policy",
+						"id",
+						regexp.MustCompile("^[a-f0-9-]+$"),
+					),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceVSphereStoragePolicyPreCheck(t *testing.T) {
+	if os.Getenv("VSPHERE_STORAGE_POLICY") == "" {
+		t.Skip("set VSPHERE_STORAGE_POLICY to run vsphere_storage_policy acceptance tests")
+	}
+}
+
+func testAccDataSourceVSphereStoragePolicyConfig() string {
+	return fmt.Sprintf(`
+data "vsphere_storage_policy" "policy" {
+  name = "%s"
+}
+`,
+		os.Getenv("VSPHERE_STORAGE_POLICY"),
+	)
+}
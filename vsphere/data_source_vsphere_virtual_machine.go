@@ -53,7 +53,7 @@ func dataSourceVSphereVirtualMachine() *schema.Resource {
 			},
 			"disks": {
 				Type:        schema.TypeList,
-				Description: "Select configuration attributes from the disks on this virtual machine, sorted by bus and unit number.",
+				Description: "Select configuration attributes from the disks on this virtual machine, sorted by controller type, bus, and unit number.",
 				Computed:    true,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
@@ -69,6 +69,10 @@ func dataSourceVSphereVirtualMachine() *schema.Resource {
 							Type:     schema.TypeBool,
 							Computed: true,
 						},
+						"controller_type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
 					},
 				},
 			},
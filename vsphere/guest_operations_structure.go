@@ -0,0 +1,74 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/guestoperations"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+	"github.com/vmware/govmomi"
+)
+
+// errGuestOperationsNotReady is returned by guestOperationsClient when it's
+// asked not to wait and the guest is not ready for guest operations.
+var errGuestOperationsNotReady = fmt.Errorf("virtual machine is not ready for guest operations")
+
+// schemaGuestOperations returns the schema shared by resources that run guest
+// operations in a virtual machine through VMware tools.
+func schemaGuestOperations() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"virtual_machine_uuid": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "The UUID of the virtual machine to run guest operations in.",
+		},
+		"guest_username": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "The username of the guest account to run guest operations as.",
+		},
+		"guest_password": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Sensitive:   true,
+			Description: "The password of the guest account to run guest operations as.",
+		},
+		"wait_for_guest_operations_timeout": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      5,
+			Description:  "The amount of time, in minutes, to wait for VMware tools in the virtual machine to be ready for guest operations.",
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+	}
+}
+
+// guestOperationsClient returns a guest operations client for the virtual
+// machine in virtual_machine_uuid. If wait is true, it waits for VMware tools
+// to be ready for guest operations first. Otherwise, errGuestOperationsNotReady
+// is returned if the guest is not ready.
+func guestOperationsClient(d *schema.ResourceData, client *govmomi.Client, wait bool) (*guestoperations.Client, error) {
+	uuid := d.Get("virtual_machine_uuid").(string)
+	vm, err := virtualmachine.FromUUID(client, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if wait {
+		if err := virtualmachine.WaitForGuestOperations(client, vm, d.Get("wait_for_guest_operations_timeout").(int)); err != nil {
+			return nil, err
+		}
+	} else {
+		props, err := virtualmachine.Properties(vm)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching VM properties: %s", err)
+		}
+		if props.Guest == nil || props.Guest.GuestOperationsReady == nil || !*props.Guest.GuestOperationsReady {
+			log.Printf("[DEBUG] Virtual machine %q is not ready for guest operations", uuid)
+			return nil, errGuestOperationsNotReady
+		}
+	}
+	return guestoperations.NewClient(client, vm, d.Get("guest_username").(string), d.Get("guest_password").(string))
+}
@@ -15,11 +15,15 @@ import (
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/clustercomputeresource"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/cryptomanager"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customizationspec"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/datastore"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/dvportgroup"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/folder"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/hostsystem"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/resourcepool"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/storagepod"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualdisk"
@@ -47,6 +51,9 @@ type testCheckVariables struct {
 	// The client for tagging operations.
 	tagsClient *tags.RestClient
 
+	// The client for content library operations.
+	contentLibraryClient *contentlibrary.Client
+
 	// The subject resource's ID.
 	resourceID string
 
@@ -70,13 +77,14 @@ func testClientVariablesForResource(s *terraform.State, addr string) (testCheckV
 	}
 
 	return testCheckVariables{
-		client:             testAccProvider.Meta().(*VSphereClient).vimClient,
-		tagsClient:         testAccProvider.Meta().(*VSphereClient).tagsClient,
-		resourceID:         rs.Primary.ID,
-		resourceAttributes: rs.Primary.Attributes,
-		esxiHost:           os.Getenv("VSPHERE_ESXI_HOST"),
-		datacenter:         os.Getenv("VSPHERE_DATACENTER"),
-		timeout:            time.Minute * 5,
+		client:               testAccProvider.Meta().(*VSphereClient).vimClient,
+		tagsClient:           testAccProvider.Meta().(*VSphereClient).tagsClient,
+		contentLibraryClient: testAccProvider.Meta().(*VSphereClient).contentLibraryClient,
+		resourceID:           rs.Primary.ID,
+		resourceAttributes:   rs.Primary.Attributes,
+		esxiHost:             os.Getenv("VSPHERE_ESXI_HOST"),
+		datacenter:           os.Getenv("VSPHERE_DATACENTER"),
+		timeout:              time.Minute * 5,
 	}, nil
 }
 
@@ -414,6 +422,60 @@ func testGetTagCategory(s *terraform.State, resourceName string) (*tags.Category
 	return category, nil
 }
 
+// testGetContentLibrary gets a content library by resource name.
+func testGetContentLibrary(s *terraform.State, resourceName string) (*contentlibrary.Library, error) {
+	tVars, err := testClientVariablesForResource(s, fmt.Sprintf("vsphere_content_library.%s", resourceName))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	return tVars.contentLibraryClient.GetLibrary(ctx, tVars.resourceID)
+}
+
+// testGetContentLibraryItem gets a content library item by resource name.
+func testGetContentLibraryItem(s *terraform.State, resourceName string) (*contentlibrary.LibraryItem, error) {
+	tVars, err := testClientVariablesForResource(s, fmt.Sprintf("vsphere_content_library_item.%s", resourceName))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	return tVars.contentLibraryClient.GetLibraryItem(ctx, tVars.resourceID)
+}
+
+// testGetVMStoragePolicy gets a storage policy by resource name.
+func testGetVMStoragePolicy(s *terraform.State, resourceName string) (*spbm.Policy, error) {
+	tVars, err := testClientVariablesForResource(s, fmt.Sprintf("vsphere_vm_storage_policy.%s", resourceName))
+	if err != nil {
+		return nil, err
+	}
+	client, err := testAccProvider.Meta().(*VSphereClient).PbmClient()
+	if err != nil {
+		return nil, err
+	}
+	return spbm.GetPolicy(client, tVars.resourceID)
+}
+
+// testGetKeyProvider gets a key provider by resource name.
+func testGetKeyProvider(s *terraform.State, resourceName string) (*types.KmipClusterInfo, error) {
+	tVars, err := testClientVariablesForResource(s, fmt.Sprintf("vsphere_key_provider.%s", resourceName))
+	if err != nil {
+		return nil, err
+	}
+	return cryptomanager.KeyProviderFromID(tVars.client, tVars.resourceID)
+}
+
+// testGetGuestOSCustomization gets the customization spec of a
+// vsphere_guest_os_customization resource.
+func testGetGuestOSCustomization(s *terraform.State, resourceName string) (*types.CustomizationSpecItem, error) {
+	tVars, err := testClientVariablesForResource(s, fmt.Sprintf("vsphere_guest_os_customization.%s", resourceName))
+	if err != nil {
+		return nil, err
+	}
+	return customizationspec.FromName(tVars.client, tVars.resourceID)
+}
+
 // testGetTag gets a tag by name.
 func testGetTag(s *terraform.State, resourceName string) (*tags.Tag, error) {
 	tVars, err := testClientVariablesForResource(s, fmt.Sprintf("vsphere_tag.%s", resourceName))
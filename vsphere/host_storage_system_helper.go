@@ -1,8 +1,6 @@
 package vsphere
 
 import (
-	"context"
-
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/hostsystem"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/object"
@@ -15,7 +13,5 @@ func hostStorageSystemFromHostSystemID(client *govmomi.Client, hsID string) (*ob
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
-	defer cancel()
-	return hs.ConfigManager().StorageSystem(ctx)
+	return hostsystem.StorageSystem(hs)
 }
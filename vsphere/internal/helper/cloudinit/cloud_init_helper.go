@@ -0,0 +1,302 @@
+package cloudinit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// The encodings that the VMware guestinfo datasource accepts in the
+// *.encoding keys.
+const (
+	EncodingBase64     = "base64"
+	EncodingGzipBase64 = "gzip+base64"
+)
+
+// The datasources that the provider can deliver cloud-init data through.
+const (
+	// DatasourceGuestInfo delivers data through guestinfo keys in the virtual
+	// machine's extra configuration, for the VMware datasource.
+	DatasourceGuestInfo = "guestinfo"
+
+	// DatasourceNoCloud delivers data on a seed ISO, for the NoCloud
+	// datasource.
+	DatasourceNoCloud = "nocloud"
+)
+
+// SeedVolumeID is the volume label that the NoCloud datasource looks for on
+// seed ISOs.
+const SeedVolumeID = "CIDATA"
+
+// The names of the files on a NoCloud seed ISO.
+const (
+	seedFileUserData      = "user-data"
+	seedFileMetaData      = "meta-data"
+	seedFileNetworkConfig = "network-config"
+)
+
+// Encode returns the guestinfo representation of data, along with its
+// encoding. Data that is already base64 or gzip+base64 encoded is passed
+// through as-is, and anything else is base64 encoded.
+func Encode(data string) (string, string) {
+	trimmed := strings.TrimSpace(data)
+	if b, err := base64.StdEncoding.DecodeString(trimmed); err == nil && trimmed != "" {
+		if isGzip(b) {
+			return trimmed, EncodingGzipBase64
+		}
+		return trimmed, EncodingBase64
+	}
+	return base64.StdEncoding.EncodeToString([]byte(data)), EncodingBase64
+}
+
+// Decode returns the raw contents of data, which can be in any of the forms
+// accepted by Encode.
+func Decode(data string) ([]byte, error) {
+	trimmed := strings.TrimSpace(data)
+	b, err := base64.StdEncoding.DecodeString(trimmed)
+	if err != nil || trimmed == "" {
+		return []byte(data), nil
+	}
+	if !isGzip(b) {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("error reading gzip data: %s", err)
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// isGzip checks b for the gzip magic number.
+func isGzip(b []byte) bool {
+	return len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b
+}
+
+// DefaultMetadata returns the metadata that is used when none is supplied.
+// Both the NoCloud and VMware datasources need metadata to be present, and the
+// instance ID is what cloud-init uses to tell if it's on a new instance.
+func DefaultMetadata(instanceID, hostname string) string {
+	return fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", instanceID, hostname)
+}
+
+// NetworkConfig returns a version 2 network configuration that enables DHCP
+// on the network interfaces with the supplied MAC addresses. The interfaces
+// are matched on their MAC address, as interface names are up to the guest.
+func NetworkConfig(macs []string) string {
+	var b bytes.Buffer
+	b.WriteString("version: 2\nethernets:\n")
+	for i, mac := range macs {
+		fmt.Fprintf(&b, "  nic%d:\n    match:\n      macaddress: %q\n    dhcp4: true\n", i, strings.ToLower(mac))
+	}
+	return b.String()
+}
+
+// MetadataWithNetworkConfig adds network configuration to metadata, which is
+// where the VMware guestinfo datasource looks for it. JSON metadata is decoded
+// and re-encoded, while YAML metadata has the keys appended to it. Metadata
+// that already has network configuration is returned as-is.
+func MetadataWithNetworkConfig(metadata, networkConfig string) (string, error) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(networkConfig))
+	if strings.HasPrefix(strings.TrimSpace(metadata), "{") {
+		m := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(metadata), &m); err != nil {
+			return "", fmt.Errorf("error parsing metadata as JSON: %s", err)
+		}
+		if _, ok := m["network"]; ok {
+			return metadata, nil
+		}
+		m["network"] = encoded
+		m["network.encoding"] = EncodingBase64
+		b, err := json.Marshal(m)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	for _, line := range strings.Split(metadata, "\n") {
+		if strings.HasPrefix(line, "network:") {
+			return metadata, nil
+		}
+	}
+	if metadata != "" && !strings.HasSuffix(metadata, "\n") {
+		metadata += "\n"
+	}
+	return fmt.Sprintf("%snetwork: %s\nnetwork.encoding: %s\n", metadata, encoded, EncodingBase64), nil
+}
+
+// SeedISO returns an ISO 9660 image suitable for the NoCloud datasource, with
+// the supplied user data, metadata, and network configuration. The network
+// configuration file is left out if networkConfig is empty.
+//
+// The image only has a root directory and no Rock Ridge or Joliet extensions,
+// which is enough for cloud-init, as Linux presents the plain ISO 9660 file
+// names in lower case.
+func SeedISO(userData, metaData, networkConfig []byte) []byte {
+	files := map[string][]byte{
+		seedFileUserData: userData,
+		seedFileMetaData: metaData,
+	}
+	if len(networkConfig) > 0 {
+		files[seedFileNetworkConfig] = networkConfig
+	}
+	return newISOImage(SeedVolumeID, files)
+}
+
+const isoSectorSize = 2048
+
+// Sector locations of the fixed structures in images produced by
+// newISOImage. The first 16 sectors are the system area, which is left
+// empty.
+const (
+	isoPrimaryVolumeDescriptorSector = 16
+	isoTerminatorSector              = 17
+	isoLPathTableSector              = 18
+	isoMPathTableSector              = 19
+	isoRootDirectorySector           = 20
+	isoFirstFileSector               = 21
+)
+
+// isoFile is a file placed in the root directory of an ISO image.
+type isoFile struct {
+	name   string
+	data   []byte
+	sector uint32
+}
+
+// newISOImage builds an ISO 9660 image with the supplied files in the root
+// directory.
+func newISOImage(volumeID string, files map[string][]byte) []byte {
+	// Directory records need to be sorted by their identifier.
+	var entries []isoFile
+	for name, data := range files {
+		entries = append(entries, isoFile{
+			name: strings.ToUpper(name) + ";1",
+			data: data,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	next := uint32(isoFirstFileSector)
+	for i := range entries {
+		entries[i].sector = next
+		next += isoSectors(len(entries[i].data))
+	}
+	total := next
+
+	img := make([]byte, int(total)*isoSectorSize)
+
+	// Root directory.
+	root := img[isoRootDirectorySector*isoSectorSize:]
+	off := 0
+	off += isoPutDirectoryRecord(root[off:], []byte{0}, isoRootDirectorySector, isoSectorSize, true)
+	off += isoPutDirectoryRecord(root[off:], []byte{1}, isoRootDirectorySector, isoSectorSize, true)
+	for _, e := range entries {
+		off += isoPutDirectoryRecord(root[off:], []byte(e.name), e.sector, uint32(len(e.data)), false)
+		copy(img[int(e.sector)*isoSectorSize:], e.data)
+	}
+
+	// Path tables. With only a root directory, each has a single record.
+	lpt := img[isoLPathTableSector*isoSectorSize:]
+	lpt[0] = 1
+	binary.LittleEndian.PutUint32(lpt[2:], isoRootDirectorySector)
+	binary.LittleEndian.PutUint16(lpt[6:], 1)
+	mpt := img[isoMPathTableSector*isoSectorSize:]
+	mpt[0] = 1
+	binary.BigEndian.PutUint32(mpt[2:], isoRootDirectorySector)
+	binary.BigEndian.PutUint16(mpt[6:], 1)
+
+	// Primary volume descriptor.
+	pvd := img[isoPrimaryVolumeDescriptorSector*isoSectorSize:]
+	pvd[0] = 1
+	copy(pvd[1:], "CD001")
+	pvd[6] = 1
+	isoPutString(pvd[8:40], "")
+	isoPutString(pvd[40:72], volumeID)
+	isoPutBothUint32(pvd[80:], total)
+	isoPutBothUint16(pvd[120:], 1)
+	isoPutBothUint16(pvd[124:], 1)
+	isoPutBothUint16(pvd[128:], isoSectorSize)
+	isoPutBothUint32(pvd[132:], 10)
+	binary.LittleEndian.PutUint32(pvd[140:], isoLPathTableSector)
+	binary.BigEndian.PutUint32(pvd[148:], isoMPathTableSector)
+	isoPutDirectoryRecord(pvd[156:], []byte{0}, isoRootDirectorySector, isoSectorSize, true)
+	isoPutString(pvd[190:813], "")
+	for _, o := range []int{813, 830, 847, 864} {
+		// Dates are left unspecified, which keeps images with the same
+		// contents identical.
+		isoPutDigits(pvd[o : o+16])
+	}
+	pvd[881] = 1
+
+	// Volume descriptor set terminator.
+	term := img[isoTerminatorSector*isoSectorSize:]
+	term[0] = 255
+	copy(term[1:], "CD001")
+	term[6] = 1
+
+	return img
+}
+
+// isoSectors returns the number of sectors needed to hold n bytes.
+func isoSectors(n int) uint32 {
+	return uint32((n + isoSectorSize - 1) / isoSectorSize)
+}
+
+// isoPutDirectoryRecord writes a directory record to b and returns its
+// length.
+func isoPutDirectoryRecord(b []byte, name []byte, sector, size uint32, dir bool) int {
+	n := 33 + len(name)
+	if n%2 != 0 {
+		n++
+	}
+	b[0] = byte(n)
+	isoPutBothUint32(b[2:], sector)
+	isoPutBothUint32(b[10:], size)
+	// Recording date: 2000-01-01 00:00:00 UTC.
+	b[18] = 100
+	b[19] = 1
+	b[20] = 1
+	if dir {
+		b[25] = 2
+	}
+	isoPutBothUint16(b[28:], 1)
+	b[32] = byte(len(name))
+	copy(b[33:], name)
+	return n
+}
+
+// isoPutBothUint32 writes v in both-endian format, which is the little endian
+// value followed by the big endian value.
+func isoPutBothUint32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b, v)
+	binary.BigEndian.PutUint32(b[4:], v)
+}
+
+// isoPutBothUint16 writes v in both-endian format.
+func isoPutBothUint16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b, v)
+	binary.BigEndian.PutUint16(b[2:], v)
+}
+
+// isoPutString writes s to b, padded with spaces.
+func isoPutString(b []byte, s string) {
+	n := copy(b, s)
+	for i := n; i < len(b); i++ {
+		b[i] = ' '
+	}
+}
+
+// isoPutDigits fills b with the ASCII digit zero, which is how unspecified
+// dates are recorded in volume descriptors.
+func isoPutDigits(b []byte) {
+	for i := range b {
+		b[i] = '0'
+	}
+}
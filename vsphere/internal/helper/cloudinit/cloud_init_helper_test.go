@@ -0,0 +1,155 @@
+package cloudinit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testUserData = "#cloud-config\npackages:\n  - nginx\n"
+
+func testGzipBase64(t *testing.T, s string) string {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	return base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+func TestEncode(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString([]byte(testUserData))
+	gz := testGzipBase64(t, testUserData)
+
+	cases := []struct {
+		Name             string
+		Data             string
+		ExpectedValue    string
+		ExpectedEncoding string
+	}{
+		{
+			Name:             "raw",
+			Data:             testUserData,
+			ExpectedValue:    b64,
+			ExpectedEncoding: EncodingBase64,
+		},
+		{
+			Name:             "base64",
+			Data:             b64,
+			ExpectedValue:    b64,
+			ExpectedEncoding: EncodingBase64,
+		},
+		{
+			Name:             "gzip+base64",
+			Data:             gz,
+			ExpectedValue:    gz,
+			ExpectedEncoding: EncodingGzipBase64,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			value, encoding := Encode(tc.Data)
+			if value != tc.ExpectedValue || encoding != tc.ExpectedEncoding {
+				t.Fatalf("expected %q (%s), got %q (%s)", tc.ExpectedValue, tc.ExpectedEncoding, value, encoding)
+			}
+			decoded, err := Decode(tc.Data)
+			if err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			if string(decoded) != testUserData {
+				t.Fatalf("expected decoded data to be %q, got %q", testUserData, string(decoded))
+			}
+		})
+	}
+}
+
+func TestMetadataWithNetworkConfig(t *testing.T) {
+	nc := NetworkConfig([]string{"00:50:56:AA:BB:CC"})
+	if !strings.Contains(nc, `macaddress: "00:50:56:aa:bb:cc"`) {
+		t.Fatalf("network config is missing interface: %s", nc)
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(nc))
+
+	cases := []struct {
+		Name     string
+		Metadata string
+		Expected string
+	}{
+		{
+			Name:     "yaml",
+			Metadata: "instance-id: foo",
+			Expected: "instance-id: foo\nnetwork: " + encoded + "\nnetwork.encoding: base64\n",
+		},
+		{
+			Name:     "json",
+			Metadata: `{"instance-id":"foo"}`,
+			Expected: `{"instance-id":"foo","network":"` + encoded + `","network.encoding":"base64"}`,
+		},
+		{
+			Name:     "existing network config",
+			Metadata: "instance-id: foo\nnetwork: bar\n",
+			Expected: "instance-id: foo\nnetwork: bar\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual, err := MetadataWithNetworkConfig(tc.Metadata, nc)
+			if err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			if actual != tc.Expected {
+				t.Fatalf("expected %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestSeedISO(t *testing.T) {
+	img := SeedISO([]byte(testUserData), []byte("instance-id: foo\n"), nil)
+	if len(img)%isoSectorSize != 0 {
+		t.Fatalf("image size %d is not a multiple of the sector size", len(img))
+	}
+
+	pvd := img[isoPrimaryVolumeDescriptorSector*isoSectorSize:]
+	if pvd[0] != 1 || string(pvd[1:6]) != "CD001" {
+		t.Fatalf("missing primary volume descriptor")
+	}
+	if id := strings.TrimSpace(string(pvd[40:72])); id != SeedVolumeID {
+		t.Fatalf("expected volume ID %q, got %q", SeedVolumeID, id)
+	}
+	if size := binary.LittleEndian.Uint32(pvd[80:]); int(size)*isoSectorSize != len(img) {
+		t.Fatalf("volume space size %d does not match image size %d", size, len(img))
+	}
+
+	// Walk the root directory and read back every file.
+	rootSector := binary.LittleEndian.Uint32(pvd[156+2:])
+	root := img[int(rootSector)*isoSectorSize : int(rootSector+1)*isoSectorSize]
+	actual := make(map[string]string)
+	for off := 0; off < len(root) && root[off] != 0; off += int(root[off]) {
+		rec := root[off:]
+		name := string(rec[33 : 33+int(rec[32])])
+		if name == "\x00" || name == "\x01" {
+			continue
+		}
+		sector := binary.LittleEndian.Uint32(rec[2:])
+		size := binary.LittleEndian.Uint32(rec[10:])
+		start := int(sector) * isoSectorSize
+		actual[name] = string(img[start : start+int(size)])
+	}
+	expected := map[string]string{
+		"META-DATA;1": "instance-id: foo\n",
+		"USER-DATA;1": testUserData,
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
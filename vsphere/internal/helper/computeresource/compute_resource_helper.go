@@ -161,6 +161,45 @@ func OSFamily(client *govmomi.Client, ref types.ManagedObjectReference, guest st
 	return b.OSFamily(ctx, guest)
 }
 
+// PciPassthroughDevices uses the compute resource's environment browser to get
+// the PCI devices that can be passed through to virtual machines on the
+// optionally supplied host.
+func PciPassthroughDevices(client *govmomi.Client, ref types.ManagedObjectReference, host *object.HostSystem) ([]types.VirtualMachinePciPassthroughInfo, error) {
+	b, err := EnvironmentBrowserFromReference(client, ref)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	return b.PciPassthroughDevices(ctx, host)
+}
+
+// ConfigOptionDescriptors uses the compute resource's environment browser to
+// get the config option descriptors, which list the virtual machine hardware
+// versions that are available in the compute resource.
+func ConfigOptionDescriptors(client *govmomi.Client, ref types.ManagedObjectReference) ([]types.VirtualMachineConfigOptionDescriptor, error) {
+	b, err := EnvironmentBrowserFromReference(client, ref)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	return b.QueryConfigOptionDescriptor(ctx)
+}
+
+// ConfigOption uses the compute resource's environment browser to get the
+// config option for the supplied descriptor key on the optionally supplied
+// host.
+func ConfigOption(client *govmomi.Client, ref types.ManagedObjectReference, key string, host *object.HostSystem) (*types.VirtualMachineConfigOption, error) {
+	b, err := EnvironmentBrowserFromReference(client, ref)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	return b.ConfigOption(ctx, key, host)
+}
+
 // EnvironmentBrowserFromReference loads an environment browser for the
 // specific compute resource reference. The reference can be either a
 // standalone host or cluster.
@@ -0,0 +1,730 @@
+package contentlibrary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vmware/vic/pkg/vsphere/tags"
+)
+
+const (
+	// sessionIDCookieName is the name of the cookie that the CIS REST API uses
+	// to track sessions.
+	sessionIDCookieName = "vmware-api-session-id"
+
+	libraryURL            = "/com/vmware/content/library"
+	localLibraryURL       = "/com/vmware/content/local-library"
+	subscribedLibraryURL  = "/com/vmware/content/subscribed-library"
+	libraryItemURL        = "/com/vmware/content/library/item"
+	libraryItemStorageURL = "/com/vmware/content/library/item/storage"
+	updateSessionURL      = "/com/vmware/content/library/item/update-session"
+	updateSessionFileURL  = "/com/vmware/content/library/item/updatesession/file"
+	ovfLibraryItemURL     = "/com/vmware/vcenter/ovf/library-item"
+	vmTemplateLibraryItem = "/vcenter/vm-template/library-items"
+)
+
+// Content library item types that can be used as a source for a virtual
+// machine.
+const (
+	ItemTypeOvf        = "ovf"
+	ItemTypeVMTemplate = "vm-template"
+)
+
+// ItemTypeIso is the type of content library items that contain ISO images,
+// which can be inserted into CDROM devices.
+const ItemTypeIso = "iso"
+
+// Content library types.
+const (
+	LibraryTypeLocal      = "LOCAL"
+	LibraryTypeSubscribed = "SUBSCRIBED"
+)
+
+// Authentication methods for published and subscribed libraries.
+const (
+	AuthenticationMethodNone  = "NONE"
+	AuthenticationMethodBasic = "BASIC"
+)
+
+// Client is a client for the content library endpoints of the CIS REST API.
+//
+// The client does not maintain a session of its own. Instead, it borrows the
+// HTTP client and session of the CIS REST client used for tags, which is
+// already authenticated and persisted by the provider. If the session has
+// expired, the tags client is used to log in again.
+type Client struct {
+	rest     *tags.RestClient
+	endpoint *url.URL
+}
+
+// NewClient returns a new content library client that shares the session of
+// the supplied CIS REST client. u should be the same URL that rest was
+// created with.
+func NewClient(rest *tags.RestClient, u *url.URL) *Client {
+	endpoint := &url.URL{}
+	*endpoint = *u
+	endpoint.Path = tags.RestPrefix
+	endpoint.User = nil
+	return &Client{
+		rest:     rest,
+		endpoint: endpoint,
+	}
+}
+
+// Error is an error returned by the CIS REST API.
+type Error struct {
+	// The HTTP status code of the response.
+	StatusCode int
+
+	// The error type, such as com.vmware.vapi.std.errors.not_found.
+	Type string
+
+	// The messages contained in the error, if any.
+	Messages []string
+}
+
+// Error implements error for Error.
+func (e *Error) Error() string {
+	msg := http.StatusText(e.StatusCode)
+	if len(e.Messages) > 0 {
+		msg = strings.Join(e.Messages, "; ")
+	}
+	if e.Type != "" {
+		return fmt.Sprintf("%s (%s)", msg, e.Type)
+	}
+	return msg
+}
+
+// IsNotFoundError returns true if the error is a not found error from the
+// CIS REST API.
+func IsNotFoundError(err error) bool {
+	if e, ok := err.(*Error); ok {
+		return e.StatusCode == http.StatusNotFound || strings.HasSuffix(e.Type, ".not_found")
+	}
+	return false
+}
+
+// newError parses the body of an error response into an Error.
+func newError(statusCode int, body []byte) error {
+	e := &Error{StatusCode: statusCode}
+	var res struct {
+		Type  string `json:"type"`
+		Value struct {
+			Messages []struct {
+				DefaultMessage string `json:"default_message"`
+			} `json:"messages"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &res); err == nil {
+		e.Type = res.Type
+		for _, m := range res.Value.Messages {
+			e.Messages = append(e.Messages, m.DefaultMessage)
+		}
+	}
+	if len(e.Messages) < 1 && len(bytes.TrimSpace(body)) > 0 {
+		e.Messages = []string{string(bytes.TrimSpace(body))}
+	}
+	return e
+}
+
+// do performs a request against the API. in is encoded to JSON as the
+// request body if it's not nil, and the "value" field of the response is
+// decoded into out if out is not nil.
+//
+// If the request fails due to an expired session, the session is renewed
+// and the request is tried again.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("error encoding request: %s", err)
+		}
+	}
+
+	resp, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		log.Printf("[DEBUG] Content library REST session expired, logging in again")
+		if err := c.rest.Login(ctx); err != nil {
+			return err
+		}
+		if resp, err = c.request(ctx, method, path, body); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %s", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return newError(resp.StatusCode, b)
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	res := struct {
+		Value interface{} `json:"value"`
+	}{
+		Value: out,
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return fmt.Errorf("error decoding response: %s", err)
+	}
+	return nil
+}
+
+// request sends a single request with the current session.
+func (c *Client) request(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	log.Printf("[DEBUG] Content library REST request: %s %s", method, path)
+	req, err := http.NewRequest(method, c.endpoint.String()+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.AddCookie(&http.Cookie{
+		Name:  sessionIDCookieName,
+		Value: c.rest.SessionID(),
+	})
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.rest.HTTP.Do(req)
+}
+
+// StorageBacking describes a datastore that backs a content library.
+type StorageBacking struct {
+	Type        string `json:"type"`
+	DatastoreID string `json:"datastore_id,omitempty"`
+}
+
+// PublishInfo describes the publication settings of a local content library.
+type PublishInfo struct {
+	Published            bool   `json:"published"`
+	AuthenticationMethod string `json:"authentication_method,omitempty"`
+	UserName             string `json:"user_name,omitempty"`
+	Password             string `json:"password,omitempty"`
+	PublishURL           string `json:"publish_url,omitempty"`
+}
+
+// SubscriptionInfo describes the subscription settings of a subscribed content
+// library.
+type SubscriptionInfo struct {
+	SubscriptionURL      string `json:"subscription_url,omitempty"`
+	AutomaticSyncEnabled bool   `json:"automatic_sync_enabled"`
+	OnDemand             bool   `json:"on_demand"`
+	AuthenticationMethod string `json:"authentication_method,omitempty"`
+	UserName             string `json:"user_name,omitempty"`
+	Password             string `json:"password,omitempty"`
+	SslThumbprint        string `json:"ssl_thumbprint,omitempty"`
+}
+
+// Library represents a content library.
+type Library struct {
+	ID               string            `json:"id,omitempty"`
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Type             string            `json:"type,omitempty"`
+	StorageBackings  []StorageBacking  `json:"storage_backings,omitempty"`
+	PublishInfo      *PublishInfo      `json:"publish_info,omitempty"`
+	SubscriptionInfo *SubscriptionInfo `json:"subscription_info,omitempty"`
+}
+
+// libraryTypeURL returns the base URL for the type-specific operations of a
+// library, which differ between local and subscribed libraries.
+func libraryTypeURL(libraryType string) string {
+	if libraryType == LibraryTypeSubscribed {
+		return subscribedLibraryURL
+	}
+	return localLibraryURL
+}
+
+// GetLibrary returns the content library with the supplied ID.
+func (c *Client) GetLibrary(ctx context.Context, id string) (*Library, error) {
+	var lib Library
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/id:%s", libraryURL, id), nil, &lib); err != nil {
+		return nil, err
+	}
+	return &lib, nil
+}
+
+// FindLibrary returns the ID of the content library with the supplied name.
+// An error is returned if no library, or more than one library, is found.
+func (c *Client) FindLibrary(ctx context.Context, name string) (string, error) {
+	req := struct {
+		Spec struct {
+			Name string `json:"name"`
+		} `json:"spec"`
+	}{}
+	req.Spec.Name = name
+	var ids []string
+	if err := c.do(ctx, http.MethodPost, libraryURL+"?~action=find", req, &ids); err != nil {
+		return "", err
+	}
+	switch {
+	case len(ids) < 1:
+		return "", fmt.Errorf("content library %q not found", name)
+	case len(ids) > 1:
+		return "", fmt.Errorf("multiple content libraries with name %q found", name)
+	}
+	return ids[0], nil
+}
+
+// CreateLibrary creates a content library and returns its ID. The library is
+// created as a local or subscribed library depending on lib.Type.
+func (c *Client) CreateLibrary(ctx context.Context, lib *Library) (string, error) {
+	log.Printf("[DEBUG] Creating %s content library %q", strings.ToLower(lib.Type), lib.Name)
+	req := struct {
+		CreateSpec *Library `json:"create_spec"`
+	}{
+		CreateSpec: lib,
+	}
+	var id string
+	if err := c.do(ctx, http.MethodPost, libraryTypeURL(lib.Type), req, &id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// UpdateLibrary updates the content library with the supplied ID. Only the
+// non-empty fields of lib are changed.
+func (c *Client) UpdateLibrary(ctx context.Context, id string, lib *Library) error {
+	log.Printf("[DEBUG] Updating content library %q", id)
+	req := struct {
+		UpdateSpec *Library `json:"update_spec"`
+	}{
+		UpdateSpec: lib,
+	}
+	return c.do(ctx, http.MethodPatch, fmt.Sprintf("%s/id:%s", libraryTypeURL(lib.Type), id), req, nil)
+}
+
+// DeleteLibrary deletes the content library with the supplied ID and type,
+// along with all of its items.
+func (c *Client) DeleteLibrary(ctx context.Context, id, libraryType string) error {
+	log.Printf("[DEBUG] Deleting content library %q", id)
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/id:%s", libraryTypeURL(libraryType), id), nil, nil)
+}
+
+// SyncLibrary forces a synchronization of the subscribed content library with
+// the supplied ID. Synchronization happens in the background.
+func (c *Client) SyncLibrary(ctx context.Context, id string) error {
+	log.Printf("[DEBUG] Synchronizing subscribed content library %q", id)
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("%s/id:%s?~action=sync", subscribedLibraryURL, id), nil, nil)
+}
+
+// LibraryItem represents a content library item.
+type LibraryItem struct {
+	ID          string `json:"id,omitempty"`
+	LibraryID   string `json:"library_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// GetLibraryItem returns the content library item with the supplied ID.
+func (c *Client) GetLibraryItem(ctx context.Context, id string) (*LibraryItem, error) {
+	var item LibraryItem
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/id:%s", libraryItemURL, id), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// FindLibraryItem returns the ID of the item with the supplied name in the
+// content library with the supplied ID. An error is returned if no item, or
+// more than one item, is found.
+func (c *Client) FindLibraryItem(ctx context.Context, libraryID, name string) (string, error) {
+	req := struct {
+		Spec struct {
+			LibraryID string `json:"library_id"`
+			Name      string `json:"name"`
+		} `json:"spec"`
+	}{}
+	req.Spec.LibraryID = libraryID
+	req.Spec.Name = name
+	var ids []string
+	if err := c.do(ctx, http.MethodPost, libraryItemURL+"?~action=find", req, &ids); err != nil {
+		return "", err
+	}
+	switch {
+	case len(ids) < 1:
+		return "", fmt.Errorf("content library item %q not found", name)
+	case len(ids) > 1:
+		return "", fmt.Errorf("multiple content library items with name %q found", name)
+	}
+	return ids[0], nil
+}
+
+// LibraryItemStorage describes the storage of a single file in a content
+// library item.
+type LibraryItemStorage struct {
+	Name           string         `json:"name"`
+	StorageBacking StorageBacking `json:"storage_backing"`
+	StorageURIs    []string       `json:"storage_uris"`
+}
+
+// ListLibraryItemStorage returns the storage of the files in the content
+// library item with the supplied ID.
+func (c *Client) ListLibraryItemStorage(ctx context.Context, id string) ([]LibraryItemStorage, error) {
+	var storage []LibraryItemStorage
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s?library_item_id=%s", libraryItemStorageURL, url.QueryEscape(id)), nil, &storage); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// CreateLibraryItem creates an empty content library item and returns its
+// ID. Files can then be added to the item with UploadLibraryItemFiles.
+func (c *Client) CreateLibraryItem(ctx context.Context, item *LibraryItem) (string, error) {
+	log.Printf("[DEBUG] Creating content library item %q in library %q", item.Name, item.LibraryID)
+	req := struct {
+		CreateSpec *LibraryItem `json:"create_spec"`
+	}{
+		CreateSpec: item,
+	}
+	var id string
+	if err := c.do(ctx, http.MethodPost, libraryItemURL, req, &id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// UpdateLibraryItem updates the name and description of the content library
+// item with the supplied ID.
+func (c *Client) UpdateLibraryItem(ctx context.Context, id string, item *LibraryItem) error {
+	log.Printf("[DEBUG] Updating content library item %q", id)
+	req := struct {
+		UpdateSpec *LibraryItem `json:"update_spec"`
+	}{
+		UpdateSpec: &LibraryItem{
+			Name:        item.Name,
+			Description: item.Description,
+		},
+	}
+	return c.do(ctx, http.MethodPatch, fmt.Sprintf("%s/id:%s", libraryItemURL, id), req, nil)
+}
+
+// DeleteLibraryItem deletes the content library item with the supplied ID.
+func (c *Client) DeleteLibraryItem(ctx context.Context, id string) error {
+	log.Printf("[DEBUG] Deleting content library item %q", id)
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/id:%s", libraryItemURL, id), nil, nil)
+}
+
+// LibraryItemFile is a file to upload to a content library item.
+type LibraryItemFile struct {
+	// The name of the file in the library item.
+	Name string
+
+	// Open returns a reader for the contents of the file, along with its
+	// size. The size may be -1 if it's not known ahead of time.
+	Open func() (io.ReadCloser, int64, error)
+}
+
+// UploadLibraryItemFiles uploads the supplied files to the content library
+// item with the supplied ID. The files are pushed through a single update
+// session, which is failed if any of the uploads fail so that the item is
+// left unchanged.
+func (c *Client) UploadLibraryItemFiles(ctx context.Context, id string, files []LibraryItemFile) error {
+	log.Printf("[DEBUG] Starting update session for content library item %q", id)
+	req := struct {
+		CreateSpec struct {
+			LibraryItemID string `json:"library_item_id"`
+		} `json:"create_spec"`
+	}{}
+	req.CreateSpec.LibraryItemID = id
+	var sessionID string
+	if err := c.do(ctx, http.MethodPost, updateSessionURL, req, &sessionID); err != nil {
+		return fmt.Errorf("error creating update session: %s", err)
+	}
+	defer func() {
+		if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/id:%s", updateSessionURL, sessionID), nil, nil); err != nil {
+			log.Printf("[WARN] Error deleting update session %q: %s", sessionID, err)
+		}
+	}()
+
+	for _, f := range files {
+		if err := c.uploadFile(ctx, sessionID, f); err != nil {
+			c.failUpdateSession(ctx, sessionID, err)
+			return fmt.Errorf("error uploading %q: %s", f.Name, err)
+		}
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/id:%s?~action=complete", updateSessionURL, sessionID), nil, nil); err != nil {
+		return fmt.Errorf("error completing update session: %s", err)
+	}
+	return c.waitForUpdateSession(ctx, sessionID)
+}
+
+// uploadFile adds a single file to an update session and pushes its
+// contents to the upload endpoint returned by the API.
+func (c *Client) uploadFile(ctx context.Context, sessionID string, f LibraryItemFile) error {
+	rc, size, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	req := struct {
+		FileSpec struct {
+			Name       string `json:"name"`
+			SourceType string `json:"source_type"`
+			Size       int64  `json:"size,omitempty"`
+		} `json:"file_spec"`
+	}{}
+	req.FileSpec.Name = f.Name
+	req.FileSpec.SourceType = "PUSH"
+	if size > 0 {
+		req.FileSpec.Size = size
+	}
+	var res struct {
+		UploadEndpoint struct {
+			URI string `json:"uri"`
+		} `json:"upload_endpoint"`
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/id:%s?~action=add", updateSessionFileURL, sessionID), req, &res); err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Uploading content library file %q (%d bytes)", f.Name, size)
+	put, err := http.NewRequest(http.MethodPut, res.UploadEndpoint.URI, rc)
+	if err != nil {
+		return err
+	}
+	put = put.WithContext(ctx)
+	put.ContentLength = size
+	put.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.rest.HTTP.Do(put)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return newError(resp.StatusCode, b)
+	}
+	return nil
+}
+
+// failUpdateSession marks an update session as failed, discarding any files
+// uploaded to it.
+func (c *Client) failUpdateSession(ctx context.Context, sessionID string, cause error) {
+	req := struct {
+		ClientErrorMessage string `json:"client_error_message"`
+	}{
+		ClientErrorMessage: cause.Error(),
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/id:%s?~action=fail", updateSessionURL, sessionID), req, nil); err != nil {
+		log.Printf("[WARN] Error failing update session %q: %s", sessionID, err)
+	}
+}
+
+// waitForUpdateSession waits for a completed update session to finish
+// processing the uploaded files.
+func (c *Client) waitForUpdateSession(ctx context.Context, sessionID string) error {
+	for {
+		var session struct {
+			State        string `json:"state"`
+			ErrorMessage struct {
+				DefaultMessage string `json:"default_message"`
+			} `json:"error_message"`
+		}
+		if err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/id:%s", updateSessionURL, sessionID), nil, &session); err != nil {
+			return err
+		}
+		switch session.State {
+		case "DONE":
+			return nil
+		case "ERROR", "CANCELED":
+			return fmt.Errorf("update session %q ended in state %s: %s", sessionID, session.State, session.ErrorMessage.DefaultMessage)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for update session %q to complete", sessionID)
+		case <-time.After(time.Second * 5):
+		}
+	}
+}
+
+// CaptureVirtualMachine creates an OVF template item in the content library
+// with the supplied ID from the virtual machine with the supplied managed
+// object ID, and returns the ID of the new item.
+func (c *Client) CaptureVirtualMachine(ctx context.Context, vmID, libraryID string, item *LibraryItem) (string, error) {
+	log.Printf("[DEBUG] Capturing virtual machine %q as content library item %q", vmID, item.Name)
+	req := struct {
+		Source struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"source"`
+		Target struct {
+			LibraryID string `json:"library_id"`
+		} `json:"target"`
+		CreateSpec struct {
+			Name        string `json:"name"`
+			Description string `json:"description,omitempty"`
+		} `json:"create_spec"`
+	}{}
+	req.Source.Type = "VirtualMachine"
+	req.Source.ID = vmID
+	req.Target.LibraryID = libraryID
+	req.CreateSpec.Name = item.Name
+	req.CreateSpec.Description = item.Description
+	var res struct {
+		Succeeded        bool               `json:"succeeded"`
+		OvfLibraryItemID string             `json:"ovf_library_item_id"`
+		Error            ovfDeploymentError `json:"error"`
+	}
+	if err := c.do(ctx, http.MethodPost, ovfLibraryItemURL+"?~action=create", req, &res); err != nil {
+		return "", err
+	}
+	if !res.Succeeded {
+		return "", fmt.Errorf("capture of virtual machine %q failed: %s", vmID, res.Error.messages())
+	}
+	return res.OvfLibraryItemID, nil
+}
+
+// DeploymentTarget describes where a virtual machine deployed from a content
+// library item is placed. All fields are managed object IDs.
+type DeploymentTarget struct {
+	ResourcePoolID string
+	FolderID       string
+	HostID         string
+	DatastoreID    string
+}
+
+// ovfDeploymentTarget is the target for an OVF library item deployment.
+type ovfDeploymentTarget struct {
+	ResourcePoolID string `json:"resource_pool_id"`
+	HostID         string `json:"host_id,omitempty"`
+	FolderID       string `json:"folder_id,omitempty"`
+}
+
+// ovfDeploymentSpec is the deployment spec for an OVF library item
+// deployment.
+type ovfDeploymentSpec struct {
+	Name               string `json:"name,omitempty"`
+	AcceptAllEULA      bool   `json:"accept_all_EULA"`
+	DefaultDatastoreID string `json:"default_datastore_id,omitempty"`
+}
+
+// ovfDeploymentResult is the result of an OVF library item deployment.
+type ovfDeploymentResult struct {
+	Succeeded  bool `json:"succeeded"`
+	ResourceID struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	} `json:"resource_id"`
+	Error ovfDeploymentError `json:"error"`
+}
+
+// ovfDeploymentError is the error section of an OVF library item operation
+// result.
+type ovfDeploymentError struct {
+	Errors []struct {
+		Error struct {
+			Messages []struct {
+				DefaultMessage string `json:"default_message"`
+			} `json:"messages"`
+		} `json:"error"`
+	} `json:"errors"`
+}
+
+// messages returns the messages of all errors, joined into a single string.
+func (e ovfDeploymentError) messages() string {
+	var msgs []string
+	for _, err := range e.Errors {
+		for _, m := range err.Error.Messages {
+			msgs = append(msgs, m.DefaultMessage)
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// DeployOvfLibraryItem deploys a virtual machine from an OVF content library
+// item, and returns the managed object ID of the new virtual machine.
+func (c *Client) DeployOvfLibraryItem(ctx context.Context, id, name string, target DeploymentTarget) (string, error) {
+	log.Printf("[DEBUG] Deploying OVF content library item %q as %q", id, name)
+	req := struct {
+		Target         ovfDeploymentTarget `json:"target"`
+		DeploymentSpec ovfDeploymentSpec   `json:"deployment_spec"`
+	}{
+		Target: ovfDeploymentTarget{
+			ResourcePoolID: target.ResourcePoolID,
+			HostID:         target.HostID,
+			FolderID:       target.FolderID,
+		},
+		DeploymentSpec: ovfDeploymentSpec{
+			Name:               name,
+			AcceptAllEULA:      true,
+			DefaultDatastoreID: target.DatastoreID,
+		},
+	}
+	var res ovfDeploymentResult
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/id:%s?~action=deploy", ovfLibraryItemURL, id), req, &res); err != nil {
+		return "", err
+	}
+	if !res.Succeeded {
+		return "", fmt.Errorf("deployment of library item %q failed: %s", id, res.Error.messages())
+	}
+	return res.ResourceID.ID, nil
+}
+
+// vmTemplateDeploySpec is the deployment spec for a VM template library item
+// deployment.
+type vmTemplateDeploySpec struct {
+	Name      string `json:"name"`
+	PoweredOn bool   `json:"powered_on"`
+	Placement struct {
+		Folder       string `json:"folder,omitempty"`
+		ResourcePool string `json:"resource_pool,omitempty"`
+		Host         string `json:"host,omitempty"`
+	} `json:"placement"`
+	VMHomeStorage *vmTemplateStorage `json:"vm_home_storage,omitempty"`
+	DiskStorage   *vmTemplateStorage `json:"disk_storage,omitempty"`
+}
+
+// vmTemplateStorage is the storage spec for a VM template library item
+// deployment.
+type vmTemplateStorage struct {
+	Datastore string `json:"datastore"`
+}
+
+// DeployVMTemplateLibraryItem deploys a virtual machine from a VM template
+// content library item, and returns the managed object ID of the new virtual
+// machine. The virtual machine is left powered off.
+func (c *Client) DeployVMTemplateLibraryItem(ctx context.Context, id, name string, target DeploymentTarget) (string, error) {
+	log.Printf("[DEBUG] Deploying VM template content library item %q as %q", id, name)
+	spec := vmTemplateDeploySpec{
+		Name: name,
+	}
+	spec.Placement.Folder = target.FolderID
+	spec.Placement.ResourcePool = target.ResourcePoolID
+	spec.Placement.Host = target.HostID
+	if target.DatastoreID != "" {
+		spec.VMHomeStorage = &vmTemplateStorage{Datastore: target.DatastoreID}
+		spec.DiskStorage = &vmTemplateStorage{Datastore: target.DatastoreID}
+	}
+	req := struct {
+		Spec vmTemplateDeploySpec `json:"spec"`
+	}{
+		Spec: spec,
+	}
+	var vmID string
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/%s?action=deploy", vmTemplateLibraryItem, id), req, &vmID); err != nil {
+		return "", err
+	}
+	return vmID, nil
+}
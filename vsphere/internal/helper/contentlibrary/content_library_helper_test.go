@@ -0,0 +1,148 @@
+package contentlibrary
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vmware/vic/pkg/vsphere/tags"
+)
+
+const testLibraryItemID = "e7cd6f1b-3b55-4c5a-a5ab-2a6ac4fa8d3b"
+
+// testClient returns a content library client pointed at the supplied test
+// server, with an existing session ID of "expired".
+func testClient(t *testing.T, ts *httptest.Server) *Client {
+	u, err := url.Parse(ts.URL + "/sdk")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	u.User = url.UserPassword("user", "pass")
+	rest := tags.NewClientWithSessionID(u, true, "", "expired")
+	return NewClient(rest, u)
+}
+
+func TestGetLibraryItem(t *testing.T) {
+	var logins int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/com/vmware/cis/session":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: sessionIDCookieName, Value: "valid", Path: "/rest"})
+			w.Write([]byte(`{"value":"valid"}`))
+		case "/rest/com/vmware/content/library/item/id:" + testLibraryItemID:
+			cookie, err := r.Cookie(sessionIDCookieName)
+			if err != nil || cookie.Value != "valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"value":{"id":"` + testLibraryItemID + `","name":"centos","type":"ovf"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type":"com.vmware.vapi.std.errors.not_found","value":{"messages":[{"default_message":"Item not found."}]}}`))
+		}
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+
+	item, err := c.GetLibraryItem(context.Background(), testLibraryItemID)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if item.Name != "centos" || item.Type != ItemTypeOvf {
+		t.Fatalf("unexpected item: %#v", item)
+	}
+	if logins != 1 {
+		t.Fatalf("expected 1 login, got %d", logins)
+	}
+
+	_, err = c.GetLibraryItem(context.Background(), "missing")
+	if !IsNotFoundError(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if err.Error() != "Item not found. (com.vmware.vapi.std.errors.not_found)" {
+		t.Fatalf("unexpected error message: %s", err)
+	}
+}
+
+func TestListLibraryItemStorage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/com/vmware/content/library/item/storage" || r.URL.Query().Get("library_item_id") != testLibraryItemID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"value":[{"name":"centos.iso","storage_backing":{"type":"DATASTORE","datastore_id":"datastore-12"},"storage_uris":["ds:///vmfs/volumes/5b2a1c10-ab22b0e4/contentlib-1a2b/` + testLibraryItemID + `/centos_1c3d.iso"]}]}`))
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+
+	storage, err := c.ListLibraryItemStorage(context.Background(), testLibraryItemID)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if len(storage) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(storage))
+	}
+	if storage[0].Name != "centos.iso" || storage[0].StorageBacking.DatastoreID != "datastore-12" || len(storage[0].StorageURIs) != 1 {
+		t.Fatalf("unexpected storage: %#v", storage[0])
+	}
+}
+
+func TestUploadLibraryItemFiles(t *testing.T) {
+	uploaded := make(map[string]string)
+	var actions []string
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/rest/com/vmware/content/library/item/update-session" && r.Method == http.MethodPost:
+			w.Write([]byte(`{"value":"session"}`))
+		case r.URL.Path == "/rest/com/vmware/content/library/item/updatesession/file/id:session":
+			b, _ := ioutil.ReadAll(r.Body)
+			name := "disk.vmdk"
+			if strings.Contains(string(b), `"appliance.ovf"`) {
+				name = "appliance.ovf"
+			}
+			w.Write([]byte(`{"value":{"upload_endpoint":{"uri":"` + ts.URL + `/upload/` + name + `"}}}`))
+		case strings.HasPrefix(r.URL.Path, "/upload/") && r.Method == http.MethodPut:
+			b, _ := ioutil.ReadAll(r.Body)
+			uploaded[strings.TrimPrefix(r.URL.Path, "/upload/")] = string(b)
+		case r.URL.Path == "/rest/com/vmware/content/library/item/update-session/id:session":
+			switch {
+			case r.Method == http.MethodGet:
+				w.Write([]byte(`{"value":{"state":"DONE"}}`))
+			case r.Method == http.MethodDelete:
+				actions = append(actions, "delete")
+			default:
+				actions = append(actions, r.URL.Query().Get("~action"))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	c := testClient(t, ts)
+
+	file := func(contents string) func() (io.ReadCloser, int64, error) {
+		return func() (io.ReadCloser, int64, error) {
+			return ioutil.NopCloser(strings.NewReader(contents)), int64(len(contents)), nil
+		}
+	}
+	err := c.UploadLibraryItemFiles(context.Background(), testLibraryItemID, []LibraryItemFile{
+		{Name: "appliance.ovf", Open: file("descriptor")},
+		{Name: "disk.vmdk", Open: file("disk data")},
+	})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if uploaded["appliance.ovf"] != "descriptor" || uploaded["disk.vmdk"] != "disk data" {
+		t.Fatalf("unexpected uploads: %#v", uploaded)
+	}
+	if strings.Join(actions, ",") != "complete,delete" {
+		t.Fatalf("unexpected session actions: %#v", actions)
+	}
+}
@@ -0,0 +1,214 @@
+package cryptomanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// reference returns the reference to the crypto manager of the connection.
+// The crypto manager is only available on vCenter 6.5 and higher.
+func reference(client *govmomi.Client) (types.ManagedObjectReference, error) {
+	if client.ServiceContent.CryptoManager == nil {
+		return types.ManagedObjectReference{}, errors.New("the crypto manager is not available on this connection - vCenter 6.5 or higher is required")
+	}
+	return *client.ServiceContent.CryptoManager, nil
+}
+
+// notFoundError is returned when a key provider cannot be found by its ID.
+type notFoundError struct {
+	id string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("key provider with ID %q not found", e.id)
+}
+
+// IsNotFoundError returns true if the error is returned because a key provider
+// could not be found.
+func IsNotFoundError(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
+
+// KeyProviders returns the standard key providers (KMS clusters) that are
+// registered with vCenter, along with the KMIP servers in each.
+func KeyProviders(client *govmomi.Client) ([]types.KmipClusterInfo, error) {
+	ref, err := reference(client)
+	if err != nil {
+		return nil, err
+	}
+	var props mo.CryptoManagerKmip
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	pc := property.DefaultCollector(client.Client)
+	if err := pc.RetrieveOne(ctx, ref, []string{"kmipServers"}, &props); err != nil {
+		return nil, err
+	}
+	return props.KmipServers, nil
+}
+
+// KeyProviderFromID returns the key provider with the supplied ID.
+func KeyProviderFromID(client *govmomi.Client, id string) (*types.KmipClusterInfo, error) {
+	log.Printf("[DEBUG] Locating key provider %q", id)
+	providers, err := KeyProviders(client)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range providers {
+		if p.ClusterId.Id == id {
+			return &p, nil
+		}
+	}
+	return nil, &notFoundError{id: id}
+}
+
+// RegisterKmipServer adds a KMIP server to the key provider in the spec. The
+// key provider is created when its first server is registered.
+func RegisterKmipServer(client *govmomi.Client, spec types.KmipServerSpec) error {
+	ref, err := reference(client)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Registering KMIP server %q with key provider %q", spec.Info.Name, spec.ClusterId.Id)
+	req := types.RegisterKmipServer{
+		This:   ref,
+		Server: spec,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	_, err = methods.RegisterKmipServer(ctx, client.Client, &req)
+	return err
+}
+
+// UpdateKmipServer updates the settings of a KMIP server that is already
+// registered with the key provider in the spec.
+func UpdateKmipServer(client *govmomi.Client, spec types.KmipServerSpec) error {
+	ref, err := reference(client)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Updating KMIP server %q in key provider %q", spec.Info.Name, spec.ClusterId.Id)
+	req := types.UpdateKmipServer{
+		This:   ref,
+		Server: spec,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	_, err = methods.UpdateKmipServer(ctx, client.Client, &req)
+	return err
+}
+
+// RemoveKmipServer removes a KMIP server from a key provider. The key
+// provider is removed along with its last server.
+func RemoveKmipServer(client *govmomi.Client, id, name string) error {
+	ref, err := reference(client)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Removing KMIP server %q from key provider %q", name, id)
+	req := types.RemoveKmipServer{
+		This:       ref,
+		ClusterId:  types.KeyProviderId{Id: id},
+		ServerName: name,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	_, err = methods.RemoveKmipServer(ctx, client.Client, &req)
+	return err
+}
+
+// MarkDefault makes the key provider with the supplied ID the default key
+// provider.
+func MarkDefault(client *govmomi.Client, id string) error {
+	ref, err := reference(client)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Marking key provider %q as default", id)
+	req := types.MarkDefault{
+		This:      ref,
+		ClusterId: types.KeyProviderId{Id: id},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	_, err = methods.MarkDefault(ctx, client.Client, &req)
+	return err
+}
+
+// GenerateKey generates a new key with the key provider with the supplied ID.
+func GenerateKey(client *govmomi.Client, id string) (*types.CryptoKeyId, error) {
+	ref, err := reference(client)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] Generating key with key provider %q", id)
+	req := types.GenerateKey{
+		This:        ref,
+		KeyProvider: &types.KeyProviderId{Id: id},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	res, err := methods.GenerateKey(ctx, client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Returnval.Success {
+		return nil, fmt.Errorf("could not generate key with key provider %q: %s", id, res.Returnval.Reason)
+	}
+	return &res.Returnval.KeyId, nil
+}
+
+// Spec returns the crypto spec that brings an object encrypted with the key
+// in current, or unencrypted if current is nil, to the key in keyID of the
+// key provider in providerID.
+//
+// An empty providerID decrypts the object. An empty keyID, or a keyID that is
+// the current key of an object that is moving to a different key provider,
+// generates a new key with the key provider. Objects that are already
+// encrypted are re-keyed with a shallow recrypt, which replaces the key
+// encryption key without re-encrypting the data of the object. nil is
+// returned if the object is already encrypted with the correct key.
+func Spec(client *govmomi.Client, current *types.CryptoKeyId, providerID, keyID string) (types.BaseCryptoSpec, error) {
+	if providerID == "" {
+		if current == nil {
+			return nil, nil
+		}
+		return &types.CryptoSpecDecrypt{}, nil
+	}
+	if current != nil {
+		var currentProviderID string
+		if current.ProviderId != nil {
+			currentProviderID = current.ProviderId.Id
+		}
+		if currentProviderID == providerID && (keyID == "" || keyID == current.KeyId) {
+			return nil, nil
+		}
+		if currentProviderID != providerID && keyID == current.KeyId {
+			keyID = ""
+		}
+	}
+
+	key := &types.CryptoKeyId{
+		KeyId:      keyID,
+		ProviderId: &types.KeyProviderId{Id: providerID},
+	}
+	if keyID == "" {
+		var err error
+		if key, err = GenerateKey(client, providerID); err != nil {
+			return nil, err
+		}
+	}
+	if current == nil {
+		return &types.CryptoSpecEncrypt{CryptoKeyId: *key}, nil
+	}
+	return &types.CryptoSpecShallowRecrypt{NewKeyId: *key}, nil
+}
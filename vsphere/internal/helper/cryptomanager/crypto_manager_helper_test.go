@@ -0,0 +1,76 @@
+package cryptomanager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestSpec(t *testing.T) {
+	current := &types.CryptoKeyId{
+		KeyId:      "key-1",
+		ProviderId: &types.KeyProviderId{Id: "kms-1"},
+	}
+	cases := []struct {
+		name       string
+		current    *types.CryptoKeyId
+		providerID string
+		keyID      string
+		expected   types.BaseCryptoSpec
+	}{
+		{
+			name: "unencrypted",
+		},
+		{
+			name:     "decrypt",
+			current:  current,
+			expected: &types.CryptoSpecDecrypt{},
+		},
+		{
+			name:       "encrypt",
+			providerID: "kms-1",
+			keyID:      "key-1",
+			expected: &types.CryptoSpecEncrypt{
+				CryptoKeyId: types.CryptoKeyId{
+					KeyId:      "key-1",
+					ProviderId: &types.KeyProviderId{Id: "kms-1"},
+				},
+			},
+		},
+		{
+			name:       "same key",
+			current:    current,
+			providerID: "kms-1",
+			keyID:      "key-1",
+		},
+		{
+			name:       "same provider, any key",
+			current:    current,
+			providerID: "kms-1",
+		},
+		{
+			name:       "re-key",
+			current:    current,
+			providerID: "kms-2",
+			keyID:      "key-2",
+			expected: &types.CryptoSpecShallowRecrypt{
+				NewKeyId: types.CryptoKeyId{
+					KeyId:      "key-2",
+					ProviderId: &types.KeyProviderId{Id: "kms-2"},
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := Spec(nil, tc.current, tc.providerID, tc.keyID)
+			if err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("expected %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package customizationspec
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vim25/xml"
+)
+
+// The types of customization specs, which match the OS family of the
+// virtual machines they can be applied to.
+const (
+	TypeLinux   = "Linux"
+	TypeWindows = "Windows"
+)
+
+// apiVersion is the newest API version of the customization spec properties
+// that are known to the types in this package. domainOU was added to
+// CustomizationIdentification in vSphere 8.0 Update 2.
+const apiVersion = "8.0.2.0"
+
+// CustomizationLinuxPrep is the Linux identity settings of a customization
+// spec, with the scriptText property that was added in vSphere 7.0.
+//
+// The property is not yet available in govmomi, so the type is defined here.
+// The name of the type must match the name of the type in the API. The type
+// is not registered with the vim25 type registry, as that would replace the
+// govmomi type for the whole provider. Customization specs are read with
+// FromName instead, which decodes them with the types in this package.
+type CustomizationLinuxPrep struct {
+	types.CustomizationLinuxPrep
+
+	ScriptText string `xml:"scriptText,omitempty"`
+}
+
+// CustomizationSysprep is the Windows identity settings of a customization
+// spec, with the identification settings below. It is defined in the same way
+// as CustomizationLinuxPrep.
+type CustomizationSysprep struct {
+	types.CustomizationIdentitySettings
+
+	GuiUnattended        types.CustomizationGuiUnattended         `xml:"guiUnattended"`
+	UserData             types.CustomizationUserData              `xml:"userData"`
+	GuiRunOnce           *types.CustomizationGuiRunOnce           `xml:"guiRunOnce,omitempty"`
+	Identification       CustomizationIdentification              `xml:"identification"`
+	LicenseFilePrintData *types.CustomizationLicenseFilePrintData `xml:"licenseFilePrintData,omitempty"`
+}
+
+// CustomizationIdentification is the domain or workgroup settings of a
+// customization spec, with the domainOU property that was added in vSphere
+// 8.0 Update 2. It is only used through CustomizationSysprep, so it is not
+// looked up by name.
+type CustomizationIdentification struct {
+	types.CustomizationIdentification
+
+	DomainOU string `xml:"domainOU,omitempty"`
+}
+
+// identityTypes are the types that take the place of the govmomi types of the
+// same name when customization specs are decoded.
+var identityTypes = map[string]reflect.Type{
+	"CustomizationLinuxPrep": reflect.TypeOf((*CustomizationLinuxPrep)(nil)).Elem(),
+	"CustomizationSysprep":   reflect.TypeOf((*CustomizationSysprep)(nil)).Elem(),
+}
+
+// typeFunc looks up the types of customization specs by name. The identity
+// types in this package are returned for the types that they replace, and the
+// govmomi types for all other types.
+func typeFunc(name string) (reflect.Type, bool) {
+	if typ, ok := identityTypes[name]; ok {
+		return typ, true
+	}
+	return types.TypeFunc()(name)
+}
+
+// VersionedClient returns a client that sends and reads customization specs
+// using the API version of the server, up to apiVersion. Properties that were
+// added after the version of the vendored API bindings are dropped by the
+// server otherwise.
+func VersionedClient(client *govmomi.Client) *govmomi.Client {
+	return viapi.VersionedClient(client, viapi.NegotiateAPIVersion(client, apiVersion))
+}
+
+// manager returns the customization spec manager of the connection, using a
+// client from VersionedClient. The customization spec manager is only
+// available on vCenter.
+func manager(client *govmomi.Client) (*object.CustomizationSpecManager, error) {
+	if client.ServiceContent.CustomizationSpecManager == nil {
+		return nil, errors.New("the customization spec manager is not available on this connection - vCenter is required")
+	}
+	return object.NewCustomizationSpecManager(VersionedClient(client).Client), nil
+}
+
+// getCustomizationSpecBody is the body of a GetCustomizationSpec call. The
+// returned spec is kept as raw XML, so that it can be decoded with typeFunc.
+type getCustomizationSpecBody struct {
+	Req    *types.GetCustomizationSpec   `xml:"urn:vim25 GetCustomizationSpec,omitempty"`
+	Res    *getCustomizationSpecResponse `xml:"urn:vim25 GetCustomizationSpecResponse,omitempty"`
+	Fault_ *soap.Fault                   `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *getCustomizationSpecBody) Fault() *soap.Fault { return b.Fault_ }
+
+type getCustomizationSpecResponse struct {
+	Returnval struct {
+		InnerXML []byte `xml:",innerxml"`
+	} `xml:"returnval"`
+}
+
+// decodeSpecItem decodes the XML of a customization spec item with the
+// identity types in this package.
+func decodeSpecItem(data []byte) (*types.CustomizationSpecItem, error) {
+	var buf bytes.Buffer
+	buf.WriteString("<returnval>")
+	buf.Write(data)
+	buf.WriteString("</returnval>")
+	dec := xml.NewDecoder(&buf)
+	dec.TypeFunc = typeFunc
+	var item types.CustomizationSpecItem
+	if err := dec.Decode(&item); err != nil {
+		return nil, fmt.Errorf("could not decode customization spec: %s", err)
+	}
+	return &item, nil
+}
+
+// notFoundError is returned when a customization spec cannot be found by its
+// name.
+type notFoundError struct {
+	name string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("customization spec %q not found", e.name)
+}
+
+// IsNotFoundError returns true if the error is returned because a
+// customization spec could not be found.
+func IsNotFoundError(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
+
+// FromName returns the customization spec stored in vCenter with the supplied
+// name.
+func FromName(client *govmomi.Client, name string) (*types.CustomizationSpecItem, error) {
+	m, err := manager(client)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] Locating customization spec %q", name)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	exists, err := m.DoesCustomizationSpecExist(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &notFoundError{name: name}
+	}
+	body := getCustomizationSpecBody{
+		Req: &types.GetCustomizationSpec{
+			This: m.Reference(),
+			Name: name,
+		},
+	}
+	if err := m.Client().RoundTrip(ctx, &body, &body); err != nil {
+		return nil, err
+	}
+	return decodeSpecItem(body.Res.Returnval.InnerXML)
+}
+
+// Create stores a new customization spec in vCenter.
+func Create(client *govmomi.Client, item types.CustomizationSpecItem) error {
+	m, err := manager(client)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Creating customization spec %q", item.Info.Name)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	return m.CreateCustomizationSpec(ctx, item)
+}
+
+// Update overwrites a customization spec that is stored in vCenter. The
+// change version of the spec is taken from the stored spec, so the update is
+// not rejected as stale.
+func Update(client *govmomi.Client, item types.CustomizationSpecItem) error {
+	m, err := manager(client)
+	if err != nil {
+		return err
+	}
+	current, err := FromName(client, item.Info.Name)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Updating customization spec %q", item.Info.Name)
+	item.Info.ChangeVersion = current.Info.ChangeVersion
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	return m.OverwriteCustomizationSpec(ctx, item)
+}
+
+// Delete removes a customization spec from vCenter.
+func Delete(client *govmomi.Client, name string) error {
+	m, err := manager(client)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Deleting customization spec %q", name)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	return m.DeleteCustomizationSpec(ctx, name)
+}
+
+// EncryptionKey returns the public key that vCenter uses to decrypt the
+// passwords in stored customization specs.
+func EncryptionKey(client *govmomi.Client) ([]byte, error) {
+	m, err := manager(client)
+	if err != nil {
+		return nil, err
+	}
+	var props mo.CustomizationSpecManager
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	pc := property.DefaultCollector(client.Client)
+	if err := pc.RetrieveOne(ctx, m.Reference(), []string{"encryptionKey"}, &props); err != nil {
+		return nil, err
+	}
+	if len(props.EncryptionKey) < 1 {
+		return nil, errors.New("vCenter did not return a customization spec encryption key")
+	}
+	return props.EncryptionKey, nil
+}
+
+// EncryptPassword encrypts a password with the supplied customization spec
+// encryption key and returns a password that can be used in a stored
+// customization spec. The key is either a DER-encoded X.509 certificate or a
+// DER-encoded public key.
+func EncryptPassword(key []byte, password string) (*types.CustomizationPassword, error) {
+	pub, err := parseEncryptionKey(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := rsa.EncryptPKCS1v15(rand.Reader, pub, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt password: %s", err)
+	}
+	return &types.CustomizationPassword{
+		Value:     base64.StdEncoding.EncodeToString(b),
+		PlainText: false,
+	}, nil
+}
+
+// parseEncryptionKey returns the RSA public key in a customization spec
+// encryption key.
+func parseEncryptionKey(key []byte) (*rsa.PublicKey, error) {
+	var pub interface{}
+	if cert, err := x509.ParseCertificate(key); err == nil {
+		pub = cert.PublicKey
+	} else if pub, err = x509.ParsePKIXPublicKey(key); err != nil {
+		return nil, fmt.Errorf("could not parse customization spec encryption key: %s", err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported customization spec encryption key type %T", pub)
+	}
+	return rsaPub, nil
+}
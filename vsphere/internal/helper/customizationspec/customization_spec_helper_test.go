@@ -0,0 +1,97 @@
+package customizationspec
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestEncryptPassword(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	key, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	password, err := EncryptPassword(key, "secret")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if password.PlainText {
+		t.Fatalf("expected encrypted password")
+	}
+	b, err := base64.StdEncoding.DecodeString(password.Value)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	actual, err := rsa.DecryptPKCS1v15(rand.Reader, priv, b)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if string(actual) != "secret" {
+		t.Fatalf("expected %q, got %q", "secret", string(actual))
+	}
+}
+
+func TestEncryptPasswordBadKey(t *testing.T) {
+	if _, err := EncryptPassword([]byte("not a key"), "secret"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestDecodeSpecItem(t *testing.T) {
+	linux := `<info><name>linux</name><description></description><type>Linux</type><changeVersion>1</changeVersion></info>` +
+		`<spec><identity xsi:type="CustomizationLinuxPrep"><hostName xsi:type="CustomizationFixedName"><name>vm1</name></hostName>` +
+		`<domain>example.com</domain><scriptText>#!/bin/sh</scriptText></identity>` +
+		`<globalIPSettings></globalIPSettings></spec>`
+	item, err := decodeSpecItem([]byte(linux))
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	prep, ok := item.Spec.Identity.(*CustomizationLinuxPrep)
+	if !ok {
+		t.Fatalf("expected *CustomizationLinuxPrep, got %T", item.Spec.Identity)
+	}
+	if prep.Domain != "example.com" || prep.ScriptText != "#!/bin/sh" {
+		t.Fatalf("bad: %#v", prep)
+	}
+	if item.Info.Name != "linux" {
+		t.Fatalf("expected name linux, got %q", item.Info.Name)
+	}
+
+	windows := `<info><name>windows</name><description></description><type>Windows</type></info>` +
+		`<spec><identity xsi:type="CustomizationSysprep"><guiUnattended><autoLogon>false</autoLogon><autoLogonCount>1</autoLogonCount><timeZone>85</timeZone></guiUnattended>` +
+		`<userData><fullName>admin</fullName><orgName>org</orgName><computerName xsi:type="CustomizationFixedName"><name>vm1</name></computerName><productId></productId></userData>` +
+		`<identification><joinDomain>example.com</joinDomain><domainAdmin>admin</domainAdmin><domainOU>OU=Servers,DC=example,DC=com</domainOU></identification></identity>` +
+		`<globalIPSettings></globalIPSettings></spec>`
+	item, err = decodeSpecItem([]byte(windows))
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	sysprep, ok := item.Spec.Identity.(*CustomizationSysprep)
+	if !ok {
+		t.Fatalf("expected *CustomizationSysprep, got %T", item.Spec.Identity)
+	}
+	if sysprep.Identification.DomainOU != "OU=Servers,DC=example,DC=com" {
+		t.Fatalf("expected domainOU to be read, got %q", sysprep.Identification.DomainOU)
+	}
+}
+
+func TestIdentityTypesNotRegistered(t *testing.T) {
+	for name := range identityTypes {
+		typ, ok := types.TypeFunc()(name)
+		if !ok {
+			t.Fatalf("expected %s to be registered", name)
+		}
+		if typ == identityTypes[name] || typ.PkgPath() != reflect.TypeOf(types.CustomizationSpec{}).PkgPath() {
+			t.Fatalf("expected the govmomi type for %s, got %s.%s", name, typ.PkgPath(), typ.Name())
+		}
+	}
+}
@@ -97,6 +97,30 @@ func (b *EnvironmentBrowser) OSFamily(ctx context.Context, guest string) (string
 	return "", fmt.Errorf("could not find guest ID %q", guest)
 }
 
+// ConfigOption returns the config option for the optionally supplied host and
+// descriptor key. The config option describes the hardware and capabilities
+// that are supported by virtual machines of the hardware version that key
+// refers to. If no key is supplied, the default config option for the
+// environment is returned.
+func (b *EnvironmentBrowser) ConfigOption(ctx context.Context, key string, host *object.HostSystem) (*types.VirtualMachineConfigOption, error) {
+	req := types.QueryConfigOption{
+		This: b.Reference(),
+		Key:  key,
+	}
+	if host != nil {
+		ref := host.Reference()
+		req.Host = &ref
+	}
+	res, err := methods.QueryConfigOption(ctx, b.Client(), &req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Returnval == nil {
+		return nil, errors.New("no config options were found for the supplied criteria")
+	}
+	return res.Returnval, nil
+}
+
 // QueryConfigOptionDescriptor returns a list the list of ConfigOption keys
 // available on the environment that this browser targets. The keys can be used
 // as query options for DefaultDevices and other functions, facilitating the
@@ -111,3 +135,31 @@ func (b *EnvironmentBrowser) QueryConfigOptionDescriptor(ctx context.Context) ([
 	}
 	return res.Returnval, nil
 }
+
+// PciPassthroughDevices returns the list of PCI devices that can be passed
+// through to virtual machines on the optionally supplied host. If no host is
+// supplied, the devices of all hosts that the environment browser targets are
+// returned.
+func (b *EnvironmentBrowser) PciPassthroughDevices(ctx context.Context, host *object.HostSystem) ([]types.VirtualMachinePciPassthroughInfo, error) {
+	req := types.QueryConfigTarget{
+		This: b.Reference(),
+	}
+	if host != nil {
+		ref := host.Reference()
+		req.Host = &ref
+	}
+	res, err := methods.QueryConfigTarget(ctx, b.Client(), &req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Returnval == nil {
+		return nil, errors.New("no config target was found for the supplied criteria")
+	}
+	var devices []types.VirtualMachinePciPassthroughInfo
+	for _, p := range res.Returnval.PciPassthrough {
+		if info, ok := p.(*types.VirtualMachinePciPassthroughInfo); ok {
+			devices = append(devices, *info)
+		}
+	}
+	return devices, nil
+}
@@ -0,0 +1,378 @@
+package guestoperations
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// processPollInterval is the interval at which a running program is checked
+// for completion.
+const processPollInterval = time.Second * 2
+
+// guestFamilyWindows is the guest family reported by VMware tools for Windows
+// guests.
+const guestFamilyWindows = "windowsGuest"
+
+// Client runs guest operations on a single virtual machine, using a set of
+// guest credentials.
+type Client struct {
+	client         *govmomi.Client
+	vm             types.ManagedObjectReference
+	auth           types.BaseGuestAuthentication
+	processManager types.ManagedObjectReference
+	fileManager    types.ManagedObjectReference
+	windows        bool
+}
+
+// Result is the outcome of a program run in the guest.
+type Result struct {
+	PID      int64
+	ExitCode int32
+	Stdout   string
+	Stderr   string
+}
+
+// NewClient returns a guest operations client for the virtual machine. The
+// guest family of the virtual machine is read here, so VMware tools needs to
+// be running.
+func NewClient(client *govmomi.Client, vm *object.VirtualMachine, username, password string) (*Client, error) {
+	if client.ServiceContent.GuestOperationsManager == nil {
+		return nil, fmt.Errorf("guest operations are not supported on this connection")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+
+	var gom mo.GuestOperationsManager
+	pc := property.DefaultCollector(client.Client)
+	if err := pc.RetrieveOne(ctx, *client.ServiceContent.GuestOperationsManager, []string{"processManager", "fileManager"}, &gom); err != nil {
+		return nil, fmt.Errorf("error fetching guest operations manager properties: %s", err)
+	}
+	if gom.ProcessManager == nil || gom.FileManager == nil {
+		return nil, fmt.Errorf("guest process and file managers are not available on this connection")
+	}
+
+	var props mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"guest.guestFamily"}, &props); err != nil {
+		return nil, fmt.Errorf("error fetching virtual machine guest properties: %s", err)
+	}
+
+	c := &Client{
+		client: client,
+		vm:     vm.Reference(),
+		auth: &types.NamePasswordAuthentication{
+			Username: username,
+			Password: password,
+		},
+		processManager: *gom.ProcessManager,
+		fileManager:    *gom.FileManager,
+	}
+	if props.Guest != nil {
+		c.windows = props.Guest.GuestFamily == guestFamilyWindows
+	}
+	return c, nil
+}
+
+// Run runs a program in the guest and waits for it to exit, or for the
+// timeout, in minutes, to expire. The standard output and error of the program
+// are captured in temporary files in the guest, which are read back and
+// removed once the program exits.
+func (c *Client) Run(path, args, workingDirectory string, env []string, timeout int) (*Result, error) {
+	stdout, err := c.createTemporaryFile("stdout")
+	if err != nil {
+		return nil, err
+	}
+	defer c.deleteFile(stdout)
+	stderr, err := c.createTemporaryFile("stderr")
+	if err != nil {
+		return nil, err
+	}
+	defer c.deleteFile(stderr)
+
+	spec := c.programSpec(path, args, stdout, stderr)
+	spec.WorkingDirectory = workingDirectory
+	spec.EnvVariables = env
+	pid, err := c.startProgram(spec)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] Started guest program %q with PID %d", path, pid)
+
+	exitCode, err := c.waitForProcess(pid, timeout)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] Guest program %q with PID %d exited with code %d", path, pid, exitCode)
+
+	result := &Result{
+		PID:      pid,
+		ExitCode: exitCode,
+	}
+	var b bytes.Buffer
+	if err := c.Download(stdout, &b); err != nil {
+		return nil, fmt.Errorf("error reading standard output: %s", err)
+	}
+	result.Stdout = b.String()
+	b.Reset()
+	if err := c.Download(stderr, &b); err != nil {
+		return nil, fmt.Errorf("error reading standard error: %s", err)
+	}
+	result.Stderr = b.String()
+	return result, nil
+}
+
+// programSpec returns a program spec that runs the program with its output
+// redirected to the supplied files. VMware tools runs programs through the
+// shell on Linux guests, so the redirection can be added to the arguments
+// directly. On Windows, the program is run through cmd.exe to the same
+// effect.
+func (c *Client) programSpec(path, args, stdout, stderr string) types.GuestProgramSpec {
+	if c.windows {
+		return types.GuestProgramSpec{
+			ProgramPath: `C:\Windows\System32\cmd.exe`,
+			Arguments:   fmt.Sprintf(`/c ""%s" %s > "%s" 2> "%s""`, path, args, stdout, stderr),
+		}
+	}
+	return types.GuestProgramSpec{
+		ProgramPath: path,
+		Arguments:   fmt.Sprintf("%s > '%s' 2> '%s'", args, stdout, stderr),
+	}
+}
+
+// startProgram starts a program in the guest and returns its PID.
+func (c *Client) startProgram(spec types.GuestProgramSpec) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	req := types.StartProgramInGuest{
+		This: c.processManager,
+		Vm:   c.vm,
+		Auth: c.auth,
+		Spec: &spec,
+	}
+	res, err := methods.StartProgramInGuest(ctx, c.client.Client, &req)
+	if err != nil {
+		return 0, fmt.Errorf("error starting guest program %q: %s", spec.ProgramPath, err)
+	}
+	return res.Returnval, nil
+}
+
+// waitForProcess polls a process in the guest until it exits, and returns its
+// exit code.
+func (c *Client) waitForProcess(pid int64, timeout int) (int32, error) {
+	if timeout < 1 {
+		timeout = 1
+	}
+	deadline := time.Now().Add(time.Minute * time.Duration(timeout))
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+		req := types.ListProcessesInGuest{
+			This: c.processManager,
+			Vm:   c.vm,
+			Auth: c.auth,
+			Pids: []int64{pid},
+		}
+		res, err := methods.ListProcessesInGuest(ctx, c.client.Client, &req)
+		cancel()
+		if err != nil {
+			return 0, fmt.Errorf("error checking guest process %d: %s", pid, err)
+		}
+		if len(res.Returnval) < 1 {
+			return 0, fmt.Errorf("guest process %d not found", pid)
+		}
+		if p := res.Returnval[0]; p.EndTime != nil {
+			return p.ExitCode, nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("timeout waiting for guest process %d to exit", pid)
+		}
+		time.Sleep(processPollInterval)
+	}
+}
+
+// createTemporaryFile creates an empty temporary file in the guest and returns
+// its path.
+func (c *Client) createTemporaryFile(suffix string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	req := types.CreateTemporaryFileInGuest{
+		This:   c.fileManager,
+		Vm:     c.vm,
+		Auth:   c.auth,
+		Prefix: "terraform-",
+		Suffix: "." + suffix,
+	}
+	res, err := methods.CreateTemporaryFileInGuest(ctx, c.client.Client, &req)
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary file in guest: %s", err)
+	}
+	return res.Returnval, nil
+}
+
+// deleteFile deletes a file in the guest. Errors are only logged, as this is
+// used to clean up temporary files.
+func (c *Client) deleteFile(path string) {
+	if err := c.DeleteFile(path); err != nil {
+		log.Printf("[DEBUG] Could not delete guest file %q: %s", path, err)
+	}
+}
+
+// DeleteFile deletes a file in the guest.
+func (c *Client) DeleteFile(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	req := types.DeleteFileInGuest{
+		This:     c.fileManager,
+		Vm:       c.vm,
+		Auth:     c.auth,
+		FilePath: path,
+	}
+	_, err := methods.DeleteFileInGuest(ctx, c.client.Client, &req)
+	return err
+}
+
+// Upload writes the size bytes read from r to a file in the guest, replacing
+// the file if it exists. The contents are streamed to the guest as they are
+// read.
+func (c *Client) Upload(path string, r io.Reader, size int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	var attrs types.BaseGuestFileAttributes = &types.GuestPosixFileAttributes{}
+	if c.windows {
+		attrs = &types.GuestWindowsFileAttributes{}
+	}
+	req := types.InitiateFileTransferToGuest{
+		This:           c.fileManager,
+		Vm:             c.vm,
+		Auth:           c.auth,
+		GuestFilePath:  path,
+		FileAttributes: attrs,
+		FileSize:       size,
+		Overwrite:      true,
+	}
+	res, err := methods.InitiateFileTransferToGuest(ctx, c.client.Client, &req)
+	if err != nil {
+		return fmt.Errorf("error initiating transfer to guest file %q: %s", path, err)
+	}
+	u, err := c.client.Client.ParseURL(res.Returnval)
+	if err != nil {
+		return err
+	}
+	p := soap.DefaultUpload
+	p.ContentLength = size
+	if err := c.client.Client.Upload(ctx, r, u, &p); err != nil {
+		return fmt.Errorf("error uploading guest file %q: %s", path, err)
+	}
+	return nil
+}
+
+// Download copies the contents of a file in the guest to w as they are
+// downloaded.
+func (c *Client) Download(path string, w io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	r, err := c.open(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// open starts the download of a file in the guest, and returns a reader for
+// its contents.
+func (c *Client) open(ctx context.Context, path string) (io.ReadCloser, error) {
+	req := types.InitiateFileTransferFromGuest{
+		This:          c.fileManager,
+		Vm:            c.vm,
+		Auth:          c.auth,
+		GuestFilePath: path,
+	}
+	res, err := methods.InitiateFileTransferFromGuest(ctx, c.client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.client.Client.ParseURL(res.Returnval.Url)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := c.client.Client.Download(ctx, u, &soap.DefaultDownload)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading guest file %q: %s", path, err)
+	}
+	return r, nil
+}
+
+// Checksum returns the SHA-256 checksum of a file in the guest. The file is
+// hashed as it's downloaded, so it's never held in memory as a whole.
+func (c *Client) Checksum(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	r, err := c.open(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	sum, err := Checksum(r)
+	if err != nil {
+		return "", fmt.Errorf("error downloading guest file %q: %s", path, err)
+	}
+	return sum, nil
+}
+
+// Stat returns the file info of a file in the guest, which holds its size
+// and modification time. This does not transfer the contents of the file.
+func (c *Client) Stat(path string) (*types.GuestFileInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	req := types.ListFilesInGuest{
+		This:     c.fileManager,
+		Vm:       c.vm,
+		Auth:     c.auth,
+		FilePath: path,
+	}
+	res, err := methods.ListFilesInGuest(ctx, c.client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range res.Returnval.Files {
+		if f.Type == string(types.GuestFileTypeFile) {
+			return &f, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a file", path)
+}
+
+// Checksum returns the hex-encoded SHA-256 checksum of the data in r.
+func Checksum(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// IsFileNotFoundError returns true if the error is a FileNotFound fault,
+// which is what guest file operations return for missing files.
+func IsFileNotFoundError(err error) bool {
+	if soap.IsSoapFault(err) {
+		if _, ok := soap.ToSoapFault(err).VimFault().(types.FileNotFound); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package guestoperations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestProgramSpec(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Windows  bool
+		Expected types.GuestProgramSpec
+	}{
+		{
+			Name: "linux",
+			Expected: types.GuestProgramSpec{
+				ProgramPath: "/bin/echo",
+				Arguments:   "foo > '/tmp/out' 2> '/tmp/err'",
+			},
+		},
+		{
+			Name:    "windows",
+			Windows: true,
+			Expected: types.GuestProgramSpec{
+				ProgramPath: `C:\Windows\System32\cmd.exe`,
+				Arguments:   `/c ""/bin/echo" foo > "/tmp/out" 2> "/tmp/err""`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := &Client{windows: tc.Windows}
+			actual := c.programSpec("/bin/echo", "foo", "/tmp/out", "/tmp/err")
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Fatalf("expected %#v, got %#v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	expected := "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c"
+	actual, err := Checksum(strings.NewReader("foo\n"))
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
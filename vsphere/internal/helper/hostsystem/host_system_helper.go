@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
 	"time"
 
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
@@ -69,6 +70,70 @@ func Properties(host *object.HostSystem) (*mo.HostSystem, error) {
 	return &props, nil
 }
 
+// StorageSystem returns the HostStorageSystem for the host.
+func StorageSystem(host *object.HostSystem) (*object.HostStorageSystem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	return host.ConfigManager().StorageSystem(ctx)
+}
+
+// ScsiDisks returns all of the SCSI disks that are visible to the host.
+func ScsiDisks(host *object.HostSystem) ([]*types.HostScsiDisk, error) {
+	ss, err := StorageSystem(host)
+	if err != nil {
+		return nil, fmt.Errorf("error loading host storage system: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	var hss mo.HostStorageSystem
+	if err := ss.Properties(ctx, ss.Reference(), []string{"storageDeviceInfo"}, &hss); err != nil {
+		return nil, fmt.Errorf("error querying storage system properties: %s", err)
+	}
+	var disks []*types.HostScsiDisk
+	if hss.StorageDeviceInfo == nil {
+		return disks, nil
+	}
+	for _, sl := range hss.StorageDeviceInfo.ScsiLun {
+		if hsd, ok := sl.(*types.HostScsiDisk); ok {
+			disks = append(disks, hsd)
+		}
+	}
+	return disks, nil
+}
+
+// PciDevices returns all of the PCI devices of the host, along with whether
+// passthrough is enabled on each device, indexed by PCI device ID.
+func PciDevices(host *object.HostSystem) ([]types.HostPciDevice, map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	var props mo.HostSystem
+	if err := host.Properties(ctx, host.Reference(), []string{"hardware.pciDevice", "config.pciPassthruInfo"}, &props); err != nil {
+		return nil, nil, err
+	}
+	enabled := make(map[string]bool)
+	if props.Config != nil {
+		for _, p := range props.Config.PciPassthruInfo {
+			info := p.GetHostPciPassthruInfo()
+			enabled[info.Id] = info.PassthruEnabled
+		}
+	}
+	if props.Hardware == nil {
+		return nil, enabled, nil
+	}
+	return props.Hardware.PciDevice, enabled, nil
+}
+
+// PciIDRegexp matches the vendor, device, and class IDs of PCI devices in the
+// format returned by PciIDString.
+var PciIDRegexp = regexp.MustCompile("^[0-9a-f]{4}$")
+
+// PciIDString returns the vendor, device, or class ID of a PCI device as the
+// four digit hexadecimal string that is commonly used to identify PCI
+// hardware, such as 10de for NVIDIA.
+func PciIDString(id int16) string {
+	return fmt.Sprintf("%04x", uint16(id))
+}
+
 // hostSystemNameFromID returns the name of a host via its its managed object
 // reference ID.
 func hostSystemNameFromID(client *govmomi.Client, id string) (string, error) {
@@ -0,0 +1,395 @@
+package ovfdeploy
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/nfc"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Source describes the location of an OVF descriptor or an OVA archive that
+// a virtual machine can be deployed from. Files referenced by an OVF
+// descriptor are expected to be found relative to the descriptor, which is
+// the same behavior that the vSphere client and ovftool exhibit.
+type Source struct {
+	// The path to the OVF descriptor or OVA archive. This is a URL when Remote
+	// is true, and a local file system path otherwise.
+	Path string
+
+	// Remote signals that Path is an HTTP or HTTPS URL.
+	Remote bool
+
+	// AllowUnverifiedSSL skips TLS certificate verification when fetching a
+	// remote source.
+	AllowUnverifiedSSL bool
+}
+
+// IsOva returns true if the source is an OVA archive, versus a plain OVF
+// descriptor.
+func (s *Source) IsOva() bool {
+	p := s.Path
+	if s.Remote {
+		if u, err := url.Parse(s.Path); err == nil {
+			p = u.Path
+		}
+	}
+	return strings.EqualFold(path.Ext(p), ".ova")
+}
+
+// readCloser combines a reader with a separate closer. It's used to return
+// the reader for a single entry in an OVA archive while still allowing the
+// underlying file or HTTP response body to be closed.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openLocation opens a file relative to the source. If name is empty, the
+// source itself is opened. The size of the stream is returned as well, which
+// may be -1 if it cannot be determined.
+func (s *Source) openLocation(name string) (io.ReadCloser, int64, error) {
+	if s.Remote {
+		u, err := url.Parse(s.Path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("cannot parse URL %q: %s", s.Path, err)
+		}
+		if name != "" {
+			ref, err := url.Parse(name)
+			if err != nil {
+				return nil, 0, fmt.Errorf("cannot parse file reference %q: %s", name, err)
+			}
+			u = u.ResolveReference(ref)
+		}
+		client := &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: s.AllowUnverifiedSSL},
+			},
+		}
+		log.Printf("[DEBUG] Fetching remote OVF file %q", u.String())
+		resp, err := client.Get(u.String())
+		if err != nil {
+			return nil, 0, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, 0, fmt.Errorf("error fetching %q: %s", u.String(), resp.Status)
+		}
+		return resp.Body, resp.ContentLength, nil
+	}
+
+	p := s.Path
+	if name != "" {
+		p = filepath.Join(filepath.Dir(s.Path), filepath.FromSlash(name))
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, fi.Size(), nil
+}
+
+// openOvaEntry scans the OVA archive for the first entry that satisfies
+// match, and returns a reader for it along with its size.
+func (s *Source) openOvaEntry(match func(string) bool) (io.ReadCloser, int64, error) {
+	f, _, err := s.openLocation("")
+	if err != nil {
+		return nil, 0, err
+	}
+	r := tar.NewReader(f)
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, 0, fmt.Errorf("error reading OVA archive %q: %s", s.Path, err)
+		}
+		if match(path.Clean(hdr.Name)) {
+			return readCloser{Reader: r, Closer: f}, hdr.Size, nil
+		}
+	}
+	f.Close()
+	return nil, 0, os.ErrNotExist
+}
+
+// walkOva reads the OVA archive once, from start to end, and calls fn for
+// each file in it in archive order, with a reader for the file and its size.
+// Walking stops at the first error returned by fn.
+//
+// Unlike Open, this only fetches a remote OVA archive once, no matter how
+// many files are read from it.
+func (s *Source) walkOva(fn func(name string, r io.Reader, size int64) error) error {
+	f, _, err := s.openLocation("")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := tar.NewReader(f)
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading OVA archive %q: %s", s.Path, err)
+		}
+		if err := fn(path.Clean(hdr.Name), r, hdr.Size); err != nil {
+			return err
+		}
+	}
+}
+
+// Open returns a reader for a file referenced by the OVF descriptor, along
+// with the size of the file.
+//
+// For OVA archives, every call reads the archive from the start until the
+// file is found, which for a remote archive means fetching it again.
+func (s *Source) Open(name string) (io.ReadCloser, int64, error) {
+	if !s.IsOva() {
+		return s.openLocation(name)
+	}
+	name = path.Clean(name)
+	rc, size, err := s.openOvaEntry(func(n string) bool { return n == name })
+	if err == os.ErrNotExist {
+		return nil, 0, fmt.Errorf("file %q not found in OVA archive %q", name, s.Path)
+	}
+	return rc, size, err
+}
+
+// Descriptor returns the contents of the OVF descriptor. For OVA archives,
+// the first .ovf file in the archive is used, as per the OVF specification.
+func (s *Source) Descriptor() (string, error) {
+	var rc io.ReadCloser
+	var err error
+	if s.IsOva() {
+		rc, _, err = s.openOvaEntry(func(n string) bool { return strings.EqualFold(path.Ext(n), ".ovf") })
+		if err == os.ErrNotExist {
+			return "", fmt.Errorf("no OVF descriptor found in OVA archive %q", s.Path)
+		}
+	} else {
+		rc, _, err = s.openLocation("")
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return "", fmt.Errorf("error reading OVF descriptor: %s", err)
+	}
+	return string(b), nil
+}
+
+// FileReferences returns the paths of the files referenced in the
+// References section of an OVF descriptor.
+func FileReferences(descriptor string) ([]string, error) {
+	var envelope struct {
+		References struct {
+			Files []struct {
+				Href string `xml:"href,attr"`
+			} `xml:"File"`
+		} `xml:"References"`
+	}
+	if err := xml.Unmarshal([]byte(descriptor), &envelope); err != nil {
+		return nil, fmt.Errorf("error parsing OVF descriptor: %s", err)
+	}
+	var files []string
+	for _, f := range envelope.References.Files {
+		files = append(files, f.Href)
+	}
+	return files, nil
+}
+
+// CreateImportSpec validates the OVF descriptor against the supplied resource
+// pool and datastore using the OvfManager and returns the resulting import
+// spec. Errors reported by the OvfManager are returned as a regular error,
+// while warnings are only logged.
+func CreateImportSpec(
+	client *govmomi.Client,
+	descriptor string,
+	pool *object.ResourcePool,
+	ds *object.Datastore,
+	params types.OvfCreateImportSpecParams,
+) (*types.OvfCreateImportSpecResult, error) {
+	if client.ServiceContent.OvfManager == nil {
+		return nil, errors.New("OVF manager is not available on this connection")
+	}
+	req := types.CreateImportSpec{
+		This:          *client.ServiceContent.OvfManager,
+		OvfDescriptor: descriptor,
+		ResourcePool:  pool.Reference(),
+		Datastore:     ds.Reference(),
+		Cisp:          params,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	res, err := methods.CreateImportSpec(ctx, client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range res.Returnval.Warning {
+		log.Printf("[WARN] OVF import spec warning for %q: %s", params.EntityName, w.LocalizedMessage)
+	}
+	if len(res.Returnval.Error) > 0 {
+		var msgs []string
+		for _, e := range res.Returnval.Error {
+			msgs = append(msgs, e.LocalizedMessage)
+		}
+		return nil, fmt.Errorf("error creating import spec: %s", strings.Join(msgs, "; "))
+	}
+	return &res.Returnval, nil
+}
+
+// Deploy imports the OVF or OVA at src as a new virtual machine. The import
+// spec is built from the descriptor with params, the virtual machine is
+// created in the supplied resource pool and folder, and all files referenced
+// in the import spec are uploaded through the resulting NFC lease. The
+// deployed virtual machine is returned.
+//
+// The timeout is in minutes and covers the entire upload process.
+func Deploy(
+	client *govmomi.Client,
+	src *Source,
+	pool *object.ResourcePool,
+	fo *object.Folder,
+	hs *object.HostSystem,
+	ds *object.Datastore,
+	params types.OvfCreateImportSpecParams,
+	timeout int,
+) (*object.VirtualMachine, error) {
+	log.Printf("[DEBUG] Deploying OVF %q as virtual machine %q", src.Path, fmt.Sprintf("%s/%s", fo.InventoryPath, params.EntityName))
+	descriptor, err := src.Descriptor()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := CreateImportSpec(client, descriptor, pool, ds, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*time.Duration(timeout))
+	defer cancel()
+	lease, err := pool.ImportVApp(ctx, spec.ImportSpec, fo, hs)
+	if err != nil {
+		return nil, fmt.Errorf("error starting OVF import: %s", err)
+	}
+	info, err := lease.Wait(ctx, spec.FileItem)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting for OVF import lease: %s", err)
+	}
+
+	if err := uploadItems(ctx, lease, info, src); err != nil {
+		// Abort the lease so that vSphere removes the partially imported
+		// virtual machine. Use a fresh context here as the upload context may
+		// have expired.
+		actx, acancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+		defer acancel()
+		if aerr := lease.Abort(actx, nil); aerr != nil {
+			log.Printf("[WARN] Error aborting OVF import lease: %s", aerr)
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.New("timeout waiting for OVF upload to complete")
+		}
+		return nil, err
+	}
+	if err := lease.Complete(ctx); err != nil {
+		return nil, fmt.Errorf("error completing OVF import lease: %s", err)
+	}
+
+	if info.Entity.Type != "VirtualMachine" {
+		return nil, fmt.Errorf("OVF import created an unsupported entity of type %q - only single virtual machine OVFs are supported", info.Entity.Type)
+	}
+	log.Printf("[DEBUG] Virtual machine %q: OVF deploy complete (MOID: %q)", fmt.Sprintf("%s/%s", fo.InventoryPath, params.EntityName), info.Entity.Value)
+	return virtualmachine.FromMOID(client, info.Entity.Value)
+}
+
+// uploadItems uploads each file in the lease from src, keeping the lease
+// alive while doing so.
+func uploadItems(ctx context.Context, lease *nfc.Lease, info *nfc.LeaseInfo, src *Source) error {
+	updater := lease.StartUpdater(ctx, info)
+	defer updater.Done()
+
+	if src.IsOva() {
+		return uploadOvaItems(ctx, lease, info.Items, src)
+	}
+	for _, item := range info.Items {
+		if err := uploadItem(ctx, lease, item, src); err != nil {
+			return fmt.Errorf("error uploading %q: %s", item.Path, err)
+		}
+	}
+	return nil
+}
+
+// uploadOvaItems uploads the file items from an OVA archive. The archive is
+// only read once, with the items uploaded in the order that they appear in
+// the archive, rather than in the order of the lease, so that a remote
+// archive is not fetched again for every item.
+func uploadOvaItems(ctx context.Context, lease *nfc.Lease, items []nfc.FileItem, src *Source) error {
+	pending := make(map[string]nfc.FileItem)
+	for _, item := range items {
+		pending[path.Clean(item.Path)] = item
+	}
+	err := src.walkOva(func(name string, r io.Reader, size int64) error {
+		item, ok := pending[name]
+		if !ok {
+			return nil
+		}
+		delete(pending, name)
+		log.Printf("[DEBUG] Uploading OVF file item %q", item.Path)
+		if err := lease.Upload(ctx, item, r, soap.Upload{ContentLength: size}); err != nil {
+			return fmt.Errorf("error uploading %q: %s", item.Path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if _, ok := pending[path.Clean(item.Path)]; ok {
+			return fmt.Errorf("file %q not found in OVA archive %q", item.Path, src.Path)
+		}
+	}
+	return nil
+}
+
+// uploadItem uploads a single file item to the lease.
+func uploadItem(ctx context.Context, lease *nfc.Lease, item nfc.FileItem, src *Source) error {
+	log.Printf("[DEBUG] Uploading OVF file item %q", item.Path)
+	rc, size, err := src.Open(item.Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	opts := soap.Upload{
+		ContentLength: size,
+	}
+	return lease.Upload(ctx, item, rc, opts)
+}
@@ -0,0 +1,218 @@
+package ovfdeploy
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testOvfDescriptor = `<?xml version="1.0" encoding="UTF-8"?><Envelope></Envelope>`
+
+const testOvfDescriptorWithReferences = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <References>
+    <File ovf:href="appliance-disk1.vmdk" ovf:id="file1" ovf:size="1024"/>
+    <File ovf:href="appliance-file1.iso" ovf:id="file2" ovf:size="2048"/>
+  </References>
+</Envelope>`
+
+// testWriteOva writes an OVA archive with the supplied files, in order, to a
+// temporary directory and returns its path.
+func testWriteOva(t *testing.T, dir string, files [][2]string) string {
+	p := filepath.Join(dir, "test.ova")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	defer f.Close()
+	w := tar.NewWriter(f)
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name: file[0],
+			Mode: 0644,
+			Size: int64(len(file[1])),
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatalf("bad: %s", err)
+		}
+		if _, err := w.Write([]byte(file[1])); err != nil {
+			t.Fatalf("bad: %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	return p
+}
+
+func TestSourceIsOva(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   Source
+		Expected bool
+	}{
+		{
+			Name:     "local ovf",
+			Source:   Source{Path: "/tmp/appliance.ovf"},
+			Expected: false,
+		},
+		{
+			Name:     "local ova",
+			Source:   Source{Path: "/tmp/appliance.OVA"},
+			Expected: true,
+		},
+		{
+			Name:     "remote ova with query string",
+			Source:   Source{Path: "https://example.com/appliance.ova?token=foo", Remote: true},
+			Expected: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if actual := tc.Source.IsOva(); actual != tc.Expected {
+				t.Fatalf("expected %t, got %t", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestSourceOva(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tf-vsphere-ovf")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := &Source{
+		Path: testWriteOva(t, dir, [][2]string{
+			{"appliance.ovf", testOvfDescriptor},
+			{"appliance.mf", "manifest"},
+			{"appliance-disk1.vmdk", "disk data"},
+		}),
+	}
+
+	descriptor, err := src.Descriptor()
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if descriptor != testOvfDescriptor {
+		t.Fatalf("expected %q, got %q", testOvfDescriptor, descriptor)
+	}
+
+	rc, size, err := src.Open("appliance-disk1.vmdk")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if string(b) != "disk data" || size != int64(len(b)) {
+		t.Fatalf("unexpected file contents %q (size %d)", string(b), size)
+	}
+
+	if _, _, err := src.Open("missing.vmdk"); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestSourceWalkOvaRemote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tf-vsphere-ovf")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := testWriteOva(t, dir, [][2]string{
+		{"appliance.ovf", testOvfDescriptor},
+		{"appliance-disk1.vmdk", "disk data"},
+		{"appliance-file1.iso", "iso data"},
+	})
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeFile(w, r, p)
+	}))
+	defer ts.Close()
+	src := &Source{Path: ts.URL + "/test.ova", Remote: true}
+
+	var actual [][2]string
+	err = src.walkOva(func(name string, r io.Reader, size int64) error {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if size != int64(len(b)) {
+			t.Fatalf("expected size %d for %q, got %d", len(b), name, size)
+		}
+		actual = append(actual, [2]string{name, string(b)})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	expected := [][2]string{
+		{"appliance.ovf", testOvfDescriptor},
+		{"appliance-disk1.vmdk", "disk data"},
+		{"appliance-file1.iso", "iso data"},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+	if requests != 1 {
+		t.Fatalf("expected the archive to be fetched once, got %d requests", requests)
+	}
+}
+
+func TestSourceOvf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tf-vsphere-ovf")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "appliance.ovf")
+	if err := ioutil.WriteFile(p, []byte(testOvfDescriptor), 0644); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "disk1.vmdk"), []byte("disk data"), 0644); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	src := &Source{Path: p}
+
+	descriptor, err := src.Descriptor()
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if descriptor != testOvfDescriptor {
+		t.Fatalf("expected %q, got %q", testOvfDescriptor, descriptor)
+	}
+
+	rc, size, err := src.Open("disk1.vmdk")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	rc.Close()
+	if size != int64(len("disk data")) {
+		t.Fatalf("expected size %d, got %d", len("disk data"), size)
+	}
+}
+
+func TestFileReferences(t *testing.T) {
+	expected := []string{"appliance-disk1.vmdk", "appliance-file1.iso"}
+	actual, err := FileReferences(testOvfDescriptorWithReferences)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
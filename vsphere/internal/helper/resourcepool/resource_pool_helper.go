@@ -126,3 +126,37 @@ func OSFamily(client *govmomi.Client, pool *object.ResourcePool, guest string) (
 	}
 	return computeresource.OSFamily(client, pprops.Owner, guest)
 }
+
+// ConfigOptionDescriptors uses the resource pool's environment browser to get
+// the virtual machine hardware versions that are available in the pool.
+func ConfigOptionDescriptors(client *govmomi.Client, pool *object.ResourcePool) ([]types.VirtualMachineConfigOptionDescriptor, error) {
+	log.Printf("[DEBUG] Looking for config option descriptors in resource pool %q", pool.Reference().Value)
+	pprops, err := Properties(pool)
+	if err != nil {
+		return nil, err
+	}
+	return computeresource.ConfigOptionDescriptors(client, pprops.Owner)
+}
+
+// ConfigOption uses the resource pool's environment browser to get the config
+// option for the supplied descriptor key on the optionally supplied host.
+func ConfigOption(client *govmomi.Client, pool *object.ResourcePool, key string, host *object.HostSystem) (*types.VirtualMachineConfigOption, error) {
+	log.Printf("[DEBUG] Looking for config option %q in resource pool %q", key, pool.Reference().Value)
+	pprops, err := Properties(pool)
+	if err != nil {
+		return nil, err
+	}
+	return computeresource.ConfigOption(client, pprops.Owner, key, host)
+}
+
+// PciPassthroughDevices uses the resource pool's environment browser to get
+// the PCI devices that can be passed through to virtual machines on the
+// optionally supplied host.
+func PciPassthroughDevices(client *govmomi.Client, pool *object.ResourcePool, host *object.HostSystem) ([]types.VirtualMachinePciPassthroughInfo, error) {
+	log.Printf("[DEBUG] Looking for PCI passthrough devices in resource pool %q", pool.Reference().Value)
+	pprops, err := Properties(pool)
+	if err != nil {
+		return nil, err
+	}
+	return computeresource.PciPassthroughDevices(client, pprops.Owner, host)
+}
@@ -0,0 +1,661 @@
+package spbm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// The SPBM (Storage Policy Based Management) API is a separate SOAP endpoint
+// on vCenter that shares its session with the vSphere API. Only the handful
+// of PBM methods and types that the provider needs are implemented here.
+const (
+	pbmPath      = "/pbm/sdk"
+	pbmNamespace = "urn:pbm"
+)
+
+// pbmServiceInstance is the reference to the PBM service instance.
+var pbmServiceInstance = types.ManagedObjectReference{
+	Type:  "PbmServiceInstance",
+	Value: "ServiceInstance",
+}
+
+// Entity types used in PbmServerObjectRef.
+const (
+	objectTypeVirtualMachine = "virtualMachine"
+	objectTypeVirtualDiskID  = "virtualDiskId"
+)
+
+type pbmProfileID struct {
+	UniqueID string `xml:"uniqueId"`
+}
+
+type pbmProfileResourceType struct {
+	ResourceType string `xml:"resourceType"`
+}
+
+type pbmServerObjectRef struct {
+	ObjectType string `xml:"objectType"`
+	Key        string `xml:"key"`
+	ServerUUID string `xml:"serverUuid,omitempty"`
+}
+
+type pbmProfile struct {
+	ProfileID   pbmProfileID              `xml:"profileId"`
+	Name        string                    `xml:"name"`
+	Description string                    `xml:"description,omitempty"`
+	Constraints *pbmCapabilityConstraints `xml:"constraints,omitempty"`
+}
+
+// pbmCapabilityConstraints is a PbmCapabilitySubProfileConstraints object,
+// which is the only kind of constraints that requirement profiles use. Type
+// carries the xsi:type of the object, as the property is polymorphic.
+type pbmCapabilityConstraints struct {
+	Type        string                    `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+	SubProfiles []pbmCapabilitySubProfile `xml:"subProfiles"`
+}
+
+type pbmCapabilitySubProfile struct {
+	Name       string                  `xml:"name"`
+	Capability []pbmCapabilityInstance `xml:"capability"`
+}
+
+type pbmCapabilityMetadataUniqueID struct {
+	Namespace string `xml:"namespace"`
+	ID        string `xml:"id"`
+}
+
+type pbmCapabilityInstance struct {
+	ID         pbmCapabilityMetadataUniqueID     `xml:"id"`
+	Constraint []pbmCapabilityConstraintInstance `xml:"constraint"`
+}
+
+type pbmCapabilityConstraintInstance struct {
+	PropertyInstance []pbmCapabilityPropertyInstance `xml:"propertyInstance"`
+}
+
+type pbmCapabilityPropertyInstance struct {
+	ID       string             `xml:"id"`
+	Operator string             `xml:"operator,omitempty"`
+	Value    pbmCapabilityValue `xml:"value"`
+}
+
+// pbmCapabilityValue is an xsd:anyType value. Scalars are kept in Value as
+// text, and PbmCapabilityDiscreteSet values are kept in Values.
+type pbmCapabilityValue struct {
+	Type   string               `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+	Values []pbmCapabilityValue `xml:"values,omitempty"`
+	Value  string               `xml:",chardata"`
+}
+
+type pbmCapabilityProfileCreateSpec struct {
+	Name         string                   `xml:"name"`
+	Description  string                   `xml:"description,omitempty"`
+	Category     string                   `xml:"category,omitempty"`
+	ResourceType pbmProfileResourceType   `xml:"resourceType"`
+	Constraints  pbmCapabilityConstraints `xml:"constraints"`
+}
+
+type pbmCapabilityProfileUpdateSpec struct {
+	Name        string                    `xml:"name,omitempty"`
+	Description string                    `xml:"description"`
+	Constraints *pbmCapabilityConstraints `xml:"constraints,omitempty"`
+}
+
+type retrieveServiceContentRequest struct {
+	This types.ManagedObjectReference `xml:"_this"`
+}
+
+type retrieveServiceContentResponse struct {
+	Returnval struct {
+		ProfileManager types.ManagedObjectReference `xml:"profileManager"`
+	} `xml:"returnval"`
+}
+
+type retrieveServiceContentBody struct {
+	Req    *retrieveServiceContentRequest  `xml:"urn:pbm PbmRetrieveServiceContent,omitempty"`
+	Res    *retrieveServiceContentResponse `xml:"urn:pbm PbmRetrieveServiceContentResponse,omitempty"`
+	Fault_ *soap.Fault                     `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *retrieveServiceContentBody) Fault() *soap.Fault { return b.Fault_ }
+
+type queryProfileRequest struct {
+	This            types.ManagedObjectReference `xml:"_this"`
+	ResourceType    pbmProfileResourceType       `xml:"resourceType"`
+	ProfileCategory string                       `xml:"profileCategory,omitempty"`
+}
+
+type queryProfileResponse struct {
+	Returnval []pbmProfileID `xml:"returnval,omitempty"`
+}
+
+type queryProfileBody struct {
+	Req    *queryProfileRequest  `xml:"urn:pbm PbmQueryProfile,omitempty"`
+	Res    *queryProfileResponse `xml:"urn:pbm PbmQueryProfileResponse,omitempty"`
+	Fault_ *soap.Fault           `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *queryProfileBody) Fault() *soap.Fault { return b.Fault_ }
+
+type retrieveContentRequest struct {
+	This       types.ManagedObjectReference `xml:"_this"`
+	ProfileIds []pbmProfileID               `xml:"profileIds"`
+}
+
+type retrieveContentResponse struct {
+	Returnval []pbmProfile `xml:"returnval"`
+}
+
+type retrieveContentBody struct {
+	Req    *retrieveContentRequest  `xml:"urn:pbm PbmRetrieveContent,omitempty"`
+	Res    *retrieveContentResponse `xml:"urn:pbm PbmRetrieveContentResponse,omitempty"`
+	Fault_ *soap.Fault              `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *retrieveContentBody) Fault() *soap.Fault { return b.Fault_ }
+
+type queryAssociatedProfileRequest struct {
+	This   types.ManagedObjectReference `xml:"_this"`
+	Entity pbmServerObjectRef           `xml:"entity"`
+}
+
+type queryAssociatedProfileResponse struct {
+	Returnval []pbmProfileID `xml:"returnval,omitempty"`
+}
+
+type queryAssociatedProfileBody struct {
+	Req    *queryAssociatedProfileRequest  `xml:"urn:pbm PbmQueryAssociatedProfile,omitempty"`
+	Res    *queryAssociatedProfileResponse `xml:"urn:pbm PbmQueryAssociatedProfileResponse,omitempty"`
+	Fault_ *soap.Fault                     `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *queryAssociatedProfileBody) Fault() *soap.Fault { return b.Fault_ }
+
+type createRequest struct {
+	This       types.ManagedObjectReference   `xml:"_this"`
+	CreateSpec pbmCapabilityProfileCreateSpec `xml:"createSpec"`
+}
+
+type createResponse struct {
+	Returnval pbmProfileID `xml:"returnval"`
+}
+
+type createBody struct {
+	Req    *createRequest  `xml:"urn:pbm PbmCreate,omitempty"`
+	Res    *createResponse `xml:"urn:pbm PbmCreateResponse,omitempty"`
+	Fault_ *soap.Fault     `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *createBody) Fault() *soap.Fault { return b.Fault_ }
+
+type updateRequest struct {
+	This       types.ManagedObjectReference   `xml:"_this"`
+	ProfileID  pbmProfileID                   `xml:"profileId"`
+	UpdateSpec pbmCapabilityProfileUpdateSpec `xml:"updateSpec"`
+}
+
+type updateResponse struct{}
+
+type updateBody struct {
+	Req    *updateRequest  `xml:"urn:pbm PbmUpdate,omitempty"`
+	Res    *updateResponse `xml:"urn:pbm PbmUpdateResponse,omitempty"`
+	Fault_ *soap.Fault     `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *updateBody) Fault() *soap.Fault { return b.Fault_ }
+
+type deleteRequest struct {
+	This      types.ManagedObjectReference `xml:"_this"`
+	ProfileID []pbmProfileID               `xml:"profileId"`
+}
+
+type deleteResponse struct {
+	Returnval []struct {
+		ProfileID pbmProfileID `xml:"profileId"`
+		Fault     *struct {
+			FaultMessage []types.LocalizableMessage `xml:"faultMessage,omitempty"`
+		} `xml:"fault,omitempty"`
+	} `xml:"returnval,omitempty"`
+}
+
+type deleteBody struct {
+	Req    *deleteRequest  `xml:"urn:pbm PbmDelete,omitempty"`
+	Res    *deleteResponse `xml:"urn:pbm PbmDeleteResponse,omitempty"`
+	Fault_ *soap.Fault     `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *deleteBody) Fault() *soap.Fault { return b.Fault_ }
+
+type queryAssociatedEntityRequest struct {
+	This    types.ManagedObjectReference `xml:"_this"`
+	Profile pbmProfileID                 `xml:"profile"`
+}
+
+type queryAssociatedEntityResponse struct {
+	Returnval []pbmServerObjectRef `xml:"returnval,omitempty"`
+}
+
+type queryAssociatedEntityBody struct {
+	Req    *queryAssociatedEntityRequest  `xml:"urn:pbm PbmQueryAssociatedEntity,omitempty"`
+	Res    *queryAssociatedEntityResponse `xml:"urn:pbm PbmQueryAssociatedEntityResponse,omitempty"`
+	Fault_ *soap.Fault                    `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *queryAssociatedEntityBody) Fault() *soap.Fault { return b.Fault_ }
+
+// Client is a SOAP client for the PBM endpoint along with the reference to
+// the profile manager. A client can be used for the lifetime of the session
+// of the vSphere client that it was created from, so it should be created
+// once and reused, rather than for every call.
+type Client struct {
+	sc             *soap.Client
+	profileManager types.ManagedObjectReference
+}
+
+// NewClient creates a PBM client off of the session of the supplied vSphere
+// client.
+func NewClient(client *govmomi.Client) (*Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	sc := client.Client.Client.NewServiceClient(pbmPath, pbmNamespace)
+	body := retrieveServiceContentBody{
+		Req: &retrieveServiceContentRequest{This: pbmServiceInstance},
+	}
+	if err := sc.RoundTrip(ctx, &body, &body); err != nil {
+		return nil, fmt.Errorf("error connecting to storage policy service: %s", err)
+	}
+	return &Client{
+		sc:             sc,
+		profileManager: body.Res.Returnval.ProfileManager,
+	}, nil
+}
+
+// PolicyIDByName locates a storage policy by its name and returns its ID.
+func PolicyIDByName(pc *Client, name string) (string, error) {
+	log.Printf("[DEBUG] Locating storage policy with name %q", name)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	qbody := queryProfileBody{
+		Req: &queryProfileRequest{
+			This:            pc.profileManager,
+			ResourceType:    pbmProfileResourceType{ResourceType: "STORAGE"},
+			ProfileCategory: "REQUIREMENT",
+		},
+	}
+	if err := pc.sc.RoundTrip(ctx, &qbody, &qbody); err != nil {
+		return "", fmt.Errorf("error querying storage policies: %s", err)
+	}
+	if len(qbody.Res.Returnval) < 1 {
+		return "", fmt.Errorf("storage policy %q not found", name)
+	}
+
+	rbody := retrieveContentBody{
+		Req: &retrieveContentRequest{
+			This:       pc.profileManager,
+			ProfileIds: qbody.Res.Returnval,
+		},
+	}
+	if err := pc.sc.RoundTrip(ctx, &rbody, &rbody); err != nil {
+		return "", fmt.Errorf("error retrieving storage policies: %s", err)
+	}
+	for _, p := range rbody.Res.Returnval {
+		if p.Name == name {
+			log.Printf("[DEBUG] Storage policy with name %q found (ID: %s)", name, p.ProfileID.UniqueID)
+			return p.ProfileID.UniqueID, nil
+		}
+	}
+	return "", fmt.Errorf("storage policy %q not found", name)
+}
+
+// queryAssociatedPolicy returns the ID of the storage policy associated with
+// the supplied entity. An empty string is returned if there is none.
+func queryAssociatedPolicy(pc *Client, entity pbmServerObjectRef) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	body := queryAssociatedProfileBody{
+		Req: &queryAssociatedProfileRequest{
+			This:   pc.profileManager,
+			Entity: entity,
+		},
+	}
+	if err := pc.sc.RoundTrip(ctx, &body, &body); err != nil {
+		return "", fmt.Errorf("error querying storage policy for %s %q: %s", entity.ObjectType, entity.Key, err)
+	}
+	if len(body.Res.Returnval) < 1 {
+		return "", nil
+	}
+	return body.Res.Returnval[0].UniqueID, nil
+}
+
+// PolicyIDByVirtualMachine returns the ID of the storage policy associated
+// with the home files of the virtual machine with the supplied managed object
+// ID.
+func PolicyIDByVirtualMachine(pc *Client, vmMOID string) (string, error) {
+	return queryAssociatedPolicy(pc, pbmServerObjectRef{
+		ObjectType: objectTypeVirtualMachine,
+		Key:        vmMOID,
+	})
+}
+
+// PolicyIDByVirtualDisk returns the ID of the storage policy associated with
+// the virtual disk with the supplied device key on the virtual machine with
+// the supplied managed object ID.
+func PolicyIDByVirtualDisk(pc *Client, vmMOID string, diskKey int) (string, error) {
+	return queryAssociatedPolicy(pc, pbmServerObjectRef{
+		ObjectType: objectTypeVirtualDiskID,
+		Key:        fmt.Sprintf("%s:%d", vmMOID, diskKey),
+	})
+}
+
+// PolicySpecByID returns the profile spec that applies the storage policy
+// with the supplied ID, for use in virtual machine config, device config and
+// relocate specs.
+func PolicySpecByID(id string) []types.BaseVirtualMachineProfileSpec {
+	return []types.BaseVirtualMachineProfileSpec{
+		&types.VirtualMachineDefinedProfileSpec{
+			ProfileId: id,
+		},
+	}
+}
+
+// Namespaces and IDs of the capabilities that the provider manages in
+// storage policy rules.
+const (
+	tagNamespace  = "http://www.vmware.com/storage/tag"
+	vsanNamespace = "VSAN"
+
+	vsanCapabilityFailuresToTolerate     = "hostFailuresToTolerate"
+	vsanCapabilityStripeWidth            = "stripeWidth"
+	vsanCapabilityObjectSpaceReservation = "proportionalCapacity"
+)
+
+// policyRuleSetName is the name of the single rule set (sub-profile) that
+// policies created by the provider have. This is the name that the vSphere
+// client gives to the first rule set of a policy.
+const policyRuleSetName = "Rule-Set 1"
+
+// TagRule is a tag-based placement rule. Datastores match the rule if they
+// are tagged with any of the tags in the category, or, if Exclude is set, if
+// they are not tagged with any of them.
+type TagRule struct {
+	Category string
+	Tags     []string
+	Exclude  bool
+}
+
+// VsanRule is a set of vSAN capability rules.
+type VsanRule struct {
+	FailuresToTolerate     int
+	StripeWidth            int
+	ObjectSpaceReservation int
+}
+
+// Policy is a storage policy requirement profile. Tag rules reference tag
+// categories and tags by name, as this is how they are stored in the policy.
+type Policy struct {
+	Name        string
+	Description string
+	TagRules    []TagRule
+	VsanRule    *VsanRule
+}
+
+// notFoundError is returned when a storage policy cannot be found by its ID.
+type notFoundError struct {
+	id string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("storage policy with ID %q not found", e.id)
+}
+
+// IsNotFoundError returns true if the error is returned because a storage
+// policy could not be found.
+func IsNotFoundError(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
+
+// constraints returns the PBM constraints for the rules in the policy. All
+// rules are placed in a single rule set, so that datastores need to satisfy
+// all of them.
+func (p *Policy) constraints() pbmCapabilityConstraints {
+	var caps []pbmCapabilityInstance
+	for _, r := range p.TagRules {
+		var values []pbmCapabilityValue
+		for _, t := range r.Tags {
+			values = append(values, pbmCapabilityValue{Type: "xsd:string", Value: t})
+		}
+		prop := pbmCapabilityPropertyInstance{
+			ID:    fmt.Sprintf("com.vmware.storage.tag.%s.property", r.Category),
+			Value: pbmCapabilityValue{Type: "PbmCapabilityDiscreteSet", Values: values},
+		}
+		if r.Exclude {
+			prop.Operator = "NOT"
+		}
+		caps = append(caps, pbmCapabilityInstance{
+			ID: pbmCapabilityMetadataUniqueID{Namespace: tagNamespace, ID: r.Category},
+			Constraint: []pbmCapabilityConstraintInstance{
+				{PropertyInstance: []pbmCapabilityPropertyInstance{prop}},
+			},
+		})
+	}
+	if r := p.VsanRule; r != nil {
+		for _, c := range []struct {
+			id    string
+			value int
+		}{
+			{vsanCapabilityFailuresToTolerate, r.FailuresToTolerate},
+			{vsanCapabilityStripeWidth, r.StripeWidth},
+			{vsanCapabilityObjectSpaceReservation, r.ObjectSpaceReservation},
+		} {
+			caps = append(caps, pbmCapabilityInstance{
+				ID: pbmCapabilityMetadataUniqueID{Namespace: vsanNamespace, ID: c.id},
+				Constraint: []pbmCapabilityConstraintInstance{
+					{
+						PropertyInstance: []pbmCapabilityPropertyInstance{
+							{
+								ID:    c.id,
+								Value: pbmCapabilityValue{Type: "xsd:int", Value: strconv.Itoa(c.value)},
+							},
+						},
+					},
+				},
+			})
+		}
+	}
+	return pbmCapabilityConstraints{
+		Type: "PbmCapabilitySubProfileConstraints",
+		SubProfiles: []pbmCapabilitySubProfile{
+			{
+				Name:       policyRuleSetName,
+				Capability: caps,
+			},
+		},
+	}
+}
+
+// readConstraints populates the rules in the policy from PBM constraints.
+// Capabilities that the provider does not manage are ignored.
+func (p *Policy) readConstraints(c *pbmCapabilityConstraints) error {
+	p.TagRules = nil
+	p.VsanRule = nil
+	if c == nil {
+		return nil
+	}
+	for _, sp := range c.SubProfiles {
+		for _, cp := range sp.Capability {
+			for _, ci := range cp.Constraint {
+				for _, prop := range ci.PropertyInstance {
+					switch cp.ID.Namespace {
+					case tagNamespace:
+						r := TagRule{
+							Category: cp.ID.ID,
+							Exclude:  prop.Operator == "NOT",
+						}
+						for _, v := range prop.Value.Values {
+							r.Tags = append(r.Tags, strings.TrimSpace(v.Value))
+						}
+						p.TagRules = append(p.TagRules, r)
+					case vsanNamespace:
+						if p.VsanRule == nil {
+							p.VsanRule = &VsanRule{}
+						}
+						v, err := strconv.Atoi(strings.TrimSpace(prop.Value.Value))
+						if err != nil {
+							return fmt.Errorf("invalid value for vSAN capability %q: %s", prop.ID, err)
+						}
+						switch prop.ID {
+						case vsanCapabilityFailuresToTolerate:
+							p.VsanRule.FailuresToTolerate = v
+						case vsanCapabilityStripeWidth:
+							p.VsanRule.StripeWidth = v
+						case vsanCapabilityObjectSpaceReservation:
+							p.VsanRule.ObjectSpaceReservation = v
+						}
+					default:
+						log.Printf("[DEBUG] Ignoring unsupported storage policy capability %s/%s", cp.ID.Namespace, cp.ID.ID)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CreatePolicy creates a storage policy requirement profile and returns its
+// ID.
+func CreatePolicy(pc *Client, policy *Policy) (string, error) {
+	log.Printf("[DEBUG] Creating storage policy %q", policy.Name)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	body := createBody{
+		Req: &createRequest{
+			This: pc.profileManager,
+			CreateSpec: pbmCapabilityProfileCreateSpec{
+				Name:         policy.Name,
+				Description:  policy.Description,
+				Category:     "REQUIREMENT",
+				ResourceType: pbmProfileResourceType{ResourceType: "STORAGE"},
+				Constraints:  policy.constraints(),
+			},
+		},
+	}
+	if err := pc.sc.RoundTrip(ctx, &body, &body); err != nil {
+		return "", err
+	}
+	log.Printf("[DEBUG] Storage policy %q created (ID: %s)", policy.Name, body.Res.Returnval.UniqueID)
+	return body.Res.Returnval.UniqueID, nil
+}
+
+// GetPolicy returns the storage policy with the supplied ID.
+func GetPolicy(pc *Client, id string) (*Policy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	body := retrieveContentBody{
+		Req: &retrieveContentRequest{
+			This:       pc.profileManager,
+			ProfileIds: []pbmProfileID{{UniqueID: id}},
+		},
+	}
+	if err := pc.sc.RoundTrip(ctx, &body, &body); err != nil {
+		// PBM reports unknown profile IDs as an invalid argument.
+		if soap.IsSoapFault(err) {
+			if _, ok := soap.ToSoapFault(err).VimFault().(types.InvalidArgument); ok {
+				return nil, &notFoundError{id: id}
+			}
+		}
+		return nil, err
+	}
+	if len(body.Res.Returnval) < 1 {
+		return nil, &notFoundError{id: id}
+	}
+	p := body.Res.Returnval[0]
+	policy := &Policy{
+		Name:        p.Name,
+		Description: p.Description,
+	}
+	if err := policy.readConstraints(p.Constraints); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdatePolicy replaces the name, description, and rules of the storage
+// policy with the supplied ID with the ones in policy.
+func UpdatePolicy(pc *Client, id string, policy *Policy) error {
+	log.Printf("[DEBUG] Updating storage policy %q (ID: %s)", policy.Name, id)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	constraints := policy.constraints()
+	body := updateBody{
+		Req: &updateRequest{
+			This:      pc.profileManager,
+			ProfileID: pbmProfileID{UniqueID: id},
+			UpdateSpec: pbmCapabilityProfileUpdateSpec{
+				Name:        policy.Name,
+				Description: policy.Description,
+				Constraints: &constraints,
+			},
+		},
+	}
+	return pc.sc.RoundTrip(ctx, &body, &body)
+}
+
+// DeletePolicy deletes the storage policy with the supplied ID.
+func DeletePolicy(pc *Client, id string) error {
+	log.Printf("[DEBUG] Deleting storage policy with ID %s", id)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	body := deleteBody{
+		Req: &deleteRequest{
+			This:      pc.profileManager,
+			ProfileID: []pbmProfileID{{UniqueID: id}},
+		},
+	}
+	if err := pc.sc.RoundTrip(ctx, &body, &body); err != nil {
+		return err
+	}
+	// Faults for individual profiles are returned in the operation outcome
+	// rather than as a SOAP fault.
+	for _, r := range body.Res.Returnval {
+		if r.Fault == nil {
+			continue
+		}
+		var msgs []string
+		for _, m := range r.Fault.FaultMessage {
+			msgs = append(msgs, m.Message)
+		}
+		return fmt.Errorf("error deleting storage policy: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// AssociatedEntities returns the keys of the entities, such as virtual
+// machines and virtual disks, that the storage policy with the supplied ID is
+// associated with.
+func AssociatedEntities(pc *Client, id string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	body := queryAssociatedEntityBody{
+		Req: &queryAssociatedEntityRequest{
+			This:    pc.profileManager,
+			Profile: pbmProfileID{UniqueID: id},
+		},
+	}
+	if err := pc.sc.RoundTrip(ctx, &body, &body); err != nil {
+		return nil, fmt.Errorf("error querying entities associated with storage policy %q: %s", id, err)
+	}
+	var keys []string
+	for _, e := range body.Res.Returnval {
+		keys = append(keys, e.Key)
+	}
+	return keys, nil
+}
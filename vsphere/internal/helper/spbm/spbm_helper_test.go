@@ -0,0 +1,54 @@
+package spbm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/xml"
+)
+
+func TestPolicyConstraints(t *testing.T) {
+	expected := &Policy{
+		TagRules: []TagRule{
+			{
+				Category: "terraform-test-category",
+				Tags:     []string{"gold", "silver"},
+			},
+			{
+				Category: "terraform-test-category-2",
+				Tags:     []string{"slow"},
+				Exclude:  true,
+			},
+		},
+		VsanRule: &VsanRule{
+			FailuresToTolerate:     1,
+			StripeWidth:            2,
+			ObjectSpaceReservation: 50,
+		},
+	}
+
+	c := expected.constraints()
+	b, err := xml.Marshal(struct {
+		XMLName     xml.Name                 `xml:"profile"`
+		Constraints pbmCapabilityConstraints `xml:"constraints"`
+	}{Constraints: c})
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if !strings.Contains(string(b), `type="PbmCapabilitySubProfileConstraints"`) {
+		t.Fatalf("constraints are missing type attribute: %s", string(b))
+	}
+
+	var profile pbmProfile
+	if err := xml.Unmarshal(b, &profile); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	actual := &Policy{}
+	if err := actual.readConstraints(profile.Constraints); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
@@ -137,6 +137,65 @@ func RenameObject(client *govmomi.Client, ref types.ManagedObjectReference, new
 	return t.Wait(tctx)
 }
 
+// VersionedClient returns a client that shares the session of the supplied
+// client, but that sends requests using the supplied vSphere API version
+// instead of the version of the vendored API bindings.
+//
+// The server drops data objects that were added in API versions newer than
+// the version of a request, from both the request and the response. Requests
+// that carry such objects, and reads that need to see them, need to be made
+// with this client. Objects that are not known to the vendored bindings decode
+// to nil entries in typed slices, so callers reading through this client need
+// to account for those.
+func VersionedClient(client *govmomi.Client, version string) *govmomi.Client {
+	sc := client.Client.Client.NewServiceClient(client.Client.URL().Path, client.Client.Namespace)
+	sc.Version = version
+	// Share the transport and cookies of the supplied client, so that the TLS
+	// settings, idle connections, and session are reused.
+	sc.Client.Transport = client.Client.Client.Client.Transport
+	sc.Client.Jar = client.Client.Client.Client.Jar
+	vc := *client.Client
+	vc.Client = sc
+	vc.RoundTripper = sc
+	return &govmomi.Client{
+		Client:         &vc,
+		SessionManager: client.SessionManager,
+	}
+}
+
+// NegotiateAPIVersion returns the API version to use for requests that carry
+// data objects of up to the supplied API version. This is the supplied
+// version, or the API version of the server if that is older.
+func NegotiateAPIVersion(client *govmomi.Client, version string) string {
+	if server := client.ServiceContent.About.ApiVersion; compareAPIVersion(server, version) < 0 {
+		return server
+	}
+	return version
+}
+
+// compareAPIVersion compares two API versions, such as 6.7.3 and 8.0.2.0,
+// component by component. It returns -1 if a is older than b, 1 if a is newer
+// than b, and 0 if they are the same.
+func compareAPIVersion(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	}
+	return 0
+}
+
 // ValidateVirtualCenter ensures that the client is connected to vCenter.
 func ValidateVirtualCenter(c *govmomi.Client) error {
 	return VimValidateVirtualCenter(c.Client)
@@ -1,9 +1,14 @@
 package viapi
 
 import (
+	"net/url"
 	"reflect"
 	"regexp"
 	"testing"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
 )
 
 // testMatchError performs regex matching for error cases.
@@ -295,3 +300,57 @@ func TestCompareVersion(t *testing.T) {
 		t.Run(tc.Name, tc.Test)
 	}
 }
+
+func TestVersionedClient(t *testing.T) {
+	u, err := url.Parse("https://vcenter.example.com/sdk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := soap.NewClient(u, true)
+	sc.Version = "6.5"
+	client := &govmomi.Client{
+		Client: &vim25.Client{
+			Client:       sc,
+			RoundTripper: sc,
+		},
+	}
+	actual := VersionedClient(client, "6.7")
+	if actual.Client.Version != "6.7" {
+		t.Fatalf("expected version 6.7, got %q", actual.Client.Version)
+	}
+	if actual.Client.RoundTripper != actual.Client.Client {
+		t.Fatal("expected requests to be sent with the versioned SOAP client")
+	}
+	if actual.Client.Client.Client.Transport != sc.Client.Transport {
+		t.Fatal("expected the transport of the supplied client to be shared")
+	}
+	if actual.Client.URL().String() != u.String() {
+		t.Fatalf("expected URL %s, got %s", u, actual.Client.URL())
+	}
+	if client.Client.Version == "6.7" {
+		t.Fatalf("expected version of the supplied client to be unchanged, got %q", client.Client.Version)
+	}
+}
+
+func TestNegotiateAPIVersion(t *testing.T) {
+	cases := []struct {
+		server   string
+		version  string
+		expected string
+	}{
+		{server: "6.5", version: "8.0.2.0", expected: "6.5"},
+		{server: "7.0.3.0", version: "8.0.2.0", expected: "7.0.3.0"},
+		{server: "8.0.2.0", version: "8.0.2.0", expected: "8.0.2.0"},
+		{server: "8.0.3.0", version: "8.0.2.0", expected: "8.0.2.0"},
+		{server: "10.0", version: "8.0.2.0", expected: "8.0.2.0"},
+	}
+	for _, tc := range cases {
+		client := &govmomi.Client{
+			Client: &vim25.Client{},
+		}
+		client.Client.ServiceContent.About.ApiVersion = tc.server
+		if actual := NegotiateAPIVersion(client, tc.version); actual != tc.expected {
+			t.Fatalf("server %s: expected %s, got %s", tc.server, tc.expected, actual)
+		}
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customizationspec"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/folder"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
@@ -17,7 +20,9 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/methods"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -202,6 +207,31 @@ func Properties(vm *object.VirtualMachine) (*mo.VirtualMachine, error) {
 	if err := vm.Properties(ctx, vm.Reference(), nil, &props); err != nil {
 		return nil, err
 	}
+	if props.Config != nil {
+		// Devices that are not known to the API bindings are read as nil when
+		// the virtual machine is read with a newer API version than the
+		// bindings, so they are dropped from the device list.
+		devices := props.Config.Hardware.Device[:0]
+		for _, device := range props.Config.Hardware.Device {
+			if device != nil {
+				devices = append(devices, device)
+			}
+		}
+		props.Config.Hardware.Device = devices
+	}
+	return &props, nil
+}
+
+// SnapshotProperties fetches the properties of a virtual machine snapshot.
+func SnapshotProperties(client *govmomi.Client, ref types.ManagedObjectReference) (*mo.VirtualMachineSnapshot, error) {
+	log.Printf("[DEBUG] Fetching properties for snapshot %q", ref.Value)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	var props mo.VirtualMachineSnapshot
+	pc := property.DefaultCollector(client.Client)
+	if err := pc.RetrieveOne(ctx, ref, nil, &props); err != nil {
+		return nil, err
+	}
 	return &props, nil
 }
 
@@ -293,6 +323,212 @@ func WaitForGuestNet(client *govmomi.Client, vm *object.VirtualMachine, routable
 	return nil
 }
 
+// WaitForGuestOperations waits for VMware tools in a virtual machine to be
+// ready for guest operations, such as running programs and transferring
+// files.
+//
+// The timeout is specified in minutes. If zero or a negative value is passed,
+// the waiter returns without error immediately.
+func WaitForGuestOperations(client *govmomi.Client, vm *object.VirtualMachine, timeout int) error {
+	if timeout < 1 {
+		log.Printf("[DEBUG] Skipping guest operations waiter for VM %q", vm.InventoryPath)
+		return nil
+	}
+	log.Printf("[DEBUG] Waiting for guest operations to be ready on VM %q (timeout = %dm)", vm.InventoryPath, timeout)
+
+	p := client.PropertyCollector()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*time.Duration(timeout))
+	defer cancel()
+
+	err := property.Wait(ctx, p, vm.Reference(), []string{"guest.guestOperationsReady"}, func(pc []types.PropertyChange) bool {
+		for _, c := range pc {
+			if c.Op != types.PropertyChangeOpAssign {
+				continue
+			}
+			if v, ok := c.Val.(bool); ok && v {
+				return true
+			}
+		}
+		return false
+	})
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.New("timeout waiting for guest operations to be ready")
+		}
+		return err
+	}
+
+	log.Printf("[DEBUG] Guest operations are now ready on VM %q", vm.InventoryPath)
+	return nil
+}
+
+// GuestWaitConditions are the guest readiness conditions that
+// WaitForGuestConditions waits for. Conditions that are not set are not
+// checked, and all conditions that are set need to be met at the same time.
+type GuestWaitConditions struct {
+	// NetworkInterfaceCIDRs maps the device key of a network interface to the
+	// networks that the interface needs to have an address in. An address in
+	// any one of the networks meets the condition for the interface.
+	NetworkInterfaceCIDRs map[int32][]*net.IPNet
+
+	// GuestInfo maps guestinfo keys to the values that they need to have.
+	GuestInfo map[string]string
+
+	// ToolsRunningStatus is the running status that VMware tools needs to
+	// report.
+	ToolsRunningStatus string
+
+	// HeartbeatStatus is the guest heartbeat status that needs to be reported.
+	HeartbeatStatus string
+}
+
+// guestWaitState is the guest state that GuestWaitConditions are checked
+// against.
+type guestWaitState struct {
+	nics               []types.GuestNicInfo
+	extraConfig        map[string]string
+	toolsRunningStatus string
+	heartbeatStatus    string
+}
+
+// properties returns the virtual machine properties that are needed to check
+// the conditions.
+func (c *GuestWaitConditions) properties() []string {
+	var props []string
+	if len(c.NetworkInterfaceCIDRs) > 0 {
+		props = append(props, "guest.net")
+	}
+	if len(c.GuestInfo) > 0 {
+		props = append(props, "config.extraConfig")
+	}
+	if c.ToolsRunningStatus != "" {
+		props = append(props, "guest.toolsRunningStatus")
+	}
+	if c.HeartbeatStatus != "" {
+		props = append(props, "guestHeartbeatStatus")
+	}
+	return props
+}
+
+// unmet returns a description of each condition that is not met by the
+// supplied guest state, sorted so that it can be used in error messages.
+func (c *GuestWaitConditions) unmet(s *guestWaitState) []string {
+	var result []string
+	for key, nets := range c.NetworkInterfaceCIDRs {
+		if !guestNicHasAddressIn(s.nics, key, nets) {
+			var cidrs []string
+			for _, n := range nets {
+				cidrs = append(cidrs, n.String())
+			}
+			result = append(result, fmt.Sprintf("network interface with key %d has no address in %s", key, strings.Join(cidrs, ", ")))
+		}
+	}
+	for k, v := range c.GuestInfo {
+		if s.extraConfig[k] != v {
+			result = append(result, fmt.Sprintf("%s is %q, waiting for %q", k, s.extraConfig[k], v))
+		}
+	}
+	if c.ToolsRunningStatus != "" && s.toolsRunningStatus != c.ToolsRunningStatus {
+		result = append(result, fmt.Sprintf("tools running status is %q, waiting for %q", s.toolsRunningStatus, c.ToolsRunningStatus))
+	}
+	if c.HeartbeatStatus != "" && s.heartbeatStatus != c.HeartbeatStatus {
+		result = append(result, fmt.Sprintf("heartbeat status is %q, waiting for %q", s.heartbeatStatus, c.HeartbeatStatus))
+	}
+	sort.Strings(result)
+	return result
+}
+
+// guestNicHasAddressIn returns true if the guest NIC for the network
+// interface with the supplied device key has an address in any of the
+// supplied networks.
+func guestNicHasAddressIn(nics []types.GuestNicInfo, key int32, nets []*net.IPNet) bool {
+	for _, n := range nics {
+		if n.DeviceConfigId != key || n.IpConfig == nil {
+			continue
+		}
+		for _, addr := range n.IpConfig.IpAddress {
+			ip := net.ParseIP(addr.IpAddress)
+			if ip == nil {
+				continue
+			}
+			for _, ipnet := range nets {
+				if ipnet.Contains(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// WaitForGuestConditions waits for a virtual machine to meet all of the
+// supplied guest readiness conditions.
+//
+// The timeout is specified in minutes. If zero or a negative value is passed,
+// the waiter returns without error immediately.
+func WaitForGuestConditions(client *govmomi.Client, vm *object.VirtualMachine, conditions GuestWaitConditions, timeout int) error {
+	props := conditions.properties()
+	if timeout < 1 || len(props) < 1 {
+		log.Printf("[DEBUG] Skipping guest condition waiter for VM %q", vm.InventoryPath)
+		return nil
+	}
+	log.Printf("[DEBUG] Waiting for guest conditions on VM %q (timeout = %dm)", vm.InventoryPath, timeout)
+
+	state := &guestWaitState{
+		extraConfig: make(map[string]string),
+	}
+	p := client.PropertyCollector()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*time.Duration(timeout))
+	defer cancel()
+
+	err := property.Wait(ctx, p, vm.Reference(), props, func(pc []types.PropertyChange) bool {
+		for _, c := range pc {
+			if c.Op != types.PropertyChangeOpAssign {
+				continue
+			}
+			switch c.Name {
+			case "guest.net":
+				if v, ok := c.Val.(types.ArrayOfGuestNicInfo); ok {
+					state.nics = v.GuestNicInfo
+				}
+			case "config.extraConfig":
+				state.extraConfig = make(map[string]string)
+				if v, ok := c.Val.(types.ArrayOfOptionValue); ok {
+					for _, bov := range v.OptionValue {
+						ov := bov.GetOptionValue()
+						if s, ok := ov.Value.(string); ok {
+							state.extraConfig[ov.Key] = s
+						}
+					}
+				}
+			case "guest.toolsRunningStatus":
+				if v, ok := c.Val.(string); ok {
+					state.toolsRunningStatus = v
+				}
+			case "guestHeartbeatStatus":
+				switch v := c.Val.(type) {
+				case types.ManagedEntityStatus:
+					state.heartbeatStatus = string(v)
+				case string:
+					state.heartbeatStatus = v
+				}
+			}
+		}
+		return len(conditions.unmet(state)) < 1
+	})
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timeout waiting for guest conditions: %s", strings.Join(conditions.unmet(state), "; "))
+		}
+		return err
+	}
+
+	log.Printf("[DEBUG] Guest conditions are now met on VM %q", vm.InventoryPath)
+	return nil
+}
+
 func skipIPAddrForWaiter(ip net.IP) bool {
 	switch {
 	case ip.IsLinkLocalMulticast():
@@ -351,13 +587,83 @@ func Clone(c *govmomi.Client, src *object.VirtualMachine, f *object.Folder, name
 	return FromMOID(c, result.Result.(types.ManagedObjectReference).Value)
 }
 
+// InstantCloneSpec is a VirtualMachineInstantCloneSpec. Instant clones were
+// added in vSphere 6.7, and the vSphere API bindings that the provider is
+// built against predate this, so the spec and the InstantClone_Task method
+// are implemented here.
+type InstantCloneSpec struct {
+	types.DynamicData
+
+	Name     string                           `xml:"name"`
+	Location types.VirtualMachineRelocateSpec `xml:"location"`
+	Config   []types.BaseOptionValue          `xml:"config,omitempty,typeattr"`
+	BiosUUID string                           `xml:"biosUuid,omitempty"`
+}
+
+// instantCloneVersion is the minimum API version that InstantClone_Task is
+// available in. Requests for the method need to be sent using this version.
+const instantCloneVersion = "6.7"
+
+type instantCloneRequest struct {
+	This types.ManagedObjectReference `xml:"_this"`
+	Spec InstantCloneSpec             `xml:"spec"`
+}
+
+type instantCloneResponse struct {
+	Returnval types.ManagedObjectReference `xml:"returnval"`
+}
+
+type instantCloneBody struct {
+	Req    *instantCloneRequest  `xml:"urn:vim25 InstantClone_Task,omitempty"`
+	Res    *instantCloneResponse `xml:"urn:vim25 InstantClone_TaskResponse,omitempty"`
+	Fault_ *soap.Fault           `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *instantCloneBody) Fault() *soap.Fault { return b.Fault_ }
+
+// InstantClone wraps the instant clone of a running or frozen virtual machine
+// and the subsequent waiting of the task. The new virtual machine shares the
+// memory and disk state of the source, and is powered on when the task
+// completes.
+func InstantClone(c *govmomi.Client, src *object.VirtualMachine, spec InstantCloneSpec, timeout int) (*object.VirtualMachine, error) {
+	log.Printf("[DEBUG] Instant cloning virtual machine %q to %q", src.InventoryPath, spec.Name)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*time.Duration(timeout))
+	defer cancel()
+	vc := viapi.VersionedClient(c, instantCloneVersion)
+	body := instantCloneBody{
+		Req: &instantCloneRequest{
+			This: src.Reference(),
+			Spec: spec,
+		},
+	}
+	if err := vc.RoundTrip(ctx, &body, &body); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.New("timeout waiting for instant clone to complete")
+		}
+		return nil, err
+	}
+	task := object.NewTask(c.Client, body.Res.Returnval)
+	result, err := task.WaitForResult(ctx, nil)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.New("timeout waiting for instant clone to complete")
+		}
+		return nil, err
+	}
+	log.Printf("[DEBUG] Virtual machine %q: instant clone complete (MOID: %q)", spec.Name, result.Result.(types.ManagedObjectReference).Value)
+	return FromMOID(c, result.Result.(types.ManagedObjectReference).Value)
+}
+
 // Customize wraps the customization of a virtual machine and the subsequent
-// waiting of the task.
-func Customize(vm *object.VirtualMachine, spec types.CustomizationSpec) error {
+// waiting of the task. The spec is sent with a client from
+// customizationspec.VersionedClient, so that settings that were added after
+// the version of the vendored API bindings are not dropped.
+func Customize(c *govmomi.Client, vm *object.VirtualMachine, spec types.CustomizationSpec) error {
 	log.Printf("[DEBUG] Sending customization spec to virtual machine %q", vm.InventoryPath)
 	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
 	defer cancel()
-	task, err := vm.Customize(ctx, spec)
+	cvm := object.NewVirtualMachine(customizationspec.VersionedClient(c).Client, vm.Reference())
+	task, err := cvm.Customize(ctx, spec)
 	if err != nil {
 		return err
 	}
@@ -366,6 +672,34 @@ func Customize(vm *object.VirtualMachine, spec types.CustomizationSpec) error {
 	return task.Wait(tctx)
 }
 
+// CreateScreenshot takes a screenshot of the console of a virtual machine and
+// returns the datastore path of the screenshot, which is stored in the
+// directory of the virtual machine.
+func CreateScreenshot(vm *object.VirtualMachine) (string, error) {
+	log.Printf("[DEBUG] Taking console screenshot of virtual machine %q", vm.InventoryPath)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	req := types.CreateScreenshot_Task{
+		This: vm.Reference(),
+	}
+	res, err := methods.CreateScreenshot_Task(ctx, vm.Client(), &req)
+	if err != nil {
+		return "", err
+	}
+	task := object.NewTask(vm.Client(), res.Returnval)
+	tctx, tcancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer tcancel()
+	result, err := task.WaitForResult(tctx, nil)
+	if err != nil {
+		return "", err
+	}
+	path, ok := result.Result.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected screenshot task result type %T", result.Result)
+	}
+	return path, nil
+}
+
 // PowerOn wraps powering on a VM and the waiting for the subsequent task.
 func PowerOn(vm *object.VirtualMachine) error {
 	log.Printf("[DEBUG] Powering on virtual machine %q", vm.InventoryPath)
@@ -394,6 +728,20 @@ func PowerOff(vm *object.VirtualMachine) error {
 	return task.Wait(tctx)
 }
 
+// Suspend wraps suspending a VM and the waiting for the subsequent task.
+func Suspend(vm *object.VirtualMachine) error {
+	log.Printf("[DEBUG] Suspending virtual machine %q", vm.InventoryPath)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	task, err := vm.Suspend(ctx)
+	if err != nil {
+		return err
+	}
+	tctx, tcancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer tcancel()
+	return task.Wait(tctx)
+}
+
 // ShutdownGuest wraps the graceful shutdown of a guest VM, and then waiting an
 // appropriate amount of time for the guest power state to go to powered off.
 // If the VM does not power off in the shutdown period specified by timeout (in
@@ -496,6 +844,119 @@ func Reconfigure(vm *object.VirtualMachine, spec types.VirtualMachineConfigSpec)
 	return task.Wait(tctx)
 }
 
+// FlagInfo is a VirtualMachineFlagInfo with the IOMMU and virtualization
+// based security flags, which were added in vSphere 6.7. The vSphere API
+// bindings that the provider is built against predate these, so the flags are
+// sent and read with ReconfigureFlags and Flags.
+//
+// The flags are not tagged with omitempty, as the XML encoder of the bindings
+// drops false values of pointers to omitempty fields. nil values are omitted
+// either way.
+type FlagInfo struct {
+	types.VirtualMachineFlagInfo
+
+	VvtdEnabled *bool `xml:"vvtdEnabled"`
+	VbsEnabled  *bool `xml:"vbsEnabled"`
+}
+
+// flagsVersion is the minimum API version that the flags in FlagInfo are
+// available in. Requests that carry the flags need to be sent using this
+// version.
+const flagsVersion = "6.7"
+
+type flagsConfigSpec struct {
+	Flags *FlagInfo `xml:"flags,omitempty"`
+}
+
+type reconfigureFlagsRequest struct {
+	This types.ManagedObjectReference `xml:"_this"`
+	Spec flagsConfigSpec              `xml:"spec"`
+}
+
+type reconfigureFlagsBody struct {
+	Req    *reconfigureFlagsRequest       `xml:"urn:vim25 ReconfigVM_Task,omitempty"`
+	Res    *types.ReconfigVM_TaskResponse `xml:"urn:vim25 ReconfigVM_TaskResponse,omitempty"`
+	Fault_ *soap.Fault                    `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *reconfigureFlagsBody) Fault() *soap.Fault { return b.Fault_ }
+
+// ReconfigureFlags wraps a Reconfigure task that only changes the flags of a
+// virtual machine, and the subsequent waiting for the task to complete.
+func ReconfigureFlags(c *govmomi.Client, vm *object.VirtualMachine, flags FlagInfo) error {
+	log.Printf("[DEBUG] Reconfiguring flags of virtual machine %q", vm.InventoryPath)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	body := reconfigureFlagsBody{
+		Req: &reconfigureFlagsRequest{
+			This: vm.Reference(),
+			Spec: flagsConfigSpec{Flags: &flags},
+		},
+	}
+	if err := viapi.VersionedClient(c, flagsVersion).RoundTrip(ctx, &body, &body); err != nil {
+		return err
+	}
+	tctx, tcancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer tcancel()
+	return object.NewTask(c.Client, body.Res.Returnval).Wait(tctx)
+}
+
+type flagsProperty struct {
+	Name string   `xml:"name"`
+	Val  FlagInfo `xml:"val"`
+}
+
+type flagsObjectContent struct {
+	PropSet []flagsProperty `xml:"propSet,omitempty"`
+}
+
+type retrieveFlagsResponse struct {
+	Returnval []flagsObjectContent `xml:"returnval,omitempty"`
+}
+
+type retrieveFlagsBody struct {
+	Req    *types.RetrieveProperties `xml:"urn:vim25 RetrieveProperties,omitempty"`
+	Res    *retrieveFlagsResponse    `xml:"urn:vim25 RetrievePropertiesResponse,omitempty"`
+	Fault_ *soap.Fault               `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+}
+
+func (b *retrieveFlagsBody) Fault() *soap.Fault { return b.Fault_ }
+
+// Flags fetches the flags of a virtual machine, including the flags that are
+// only available in FlagInfo.
+func Flags(c *govmomi.Client, vm *object.VirtualMachine) (*FlagInfo, error) {
+	log.Printf("[DEBUG] Fetching flags of virtual machine %q", vm.InventoryPath)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	body := retrieveFlagsBody{
+		Req: &types.RetrieveProperties{
+			This: c.ServiceContent.PropertyCollector,
+			SpecSet: []types.PropertyFilterSpec{
+				{
+					ObjectSet: []types.ObjectSpec{{Obj: vm.Reference()}},
+					PropSet: []types.PropertySpec{
+						{
+							Type:    vm.Reference().Type,
+							PathSet: []string{"config.flags"},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := viapi.VersionedClient(c, flagsVersion).RoundTrip(ctx, &body, &body); err != nil {
+		return nil, err
+	}
+	for _, oc := range body.Res.Returnval {
+		for _, p := range oc.PropSet {
+			if p.Name == "config.flags" {
+				return &p.Val, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("config.flags not returned for virtual machine %q", vm.InventoryPath)
+}
+
 // Relocate wraps the Relocate task and the subsequent waiting for the task to
 // complete.
 func Relocate(vm *object.VirtualMachine, spec types.VirtualMachineRelocateSpec, timeout int) error {
@@ -0,0 +1,141 @@
+package virtualmachine
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vim25/xml"
+)
+
+func TestGuestWaitConditionsUnmet(t *testing.T) {
+	_, v4net, _ := net.ParseCIDR("10.0.0.0/24")
+	_, v6net, _ := net.ParseCIDR("fd00::/64")
+	conditions := GuestWaitConditions{
+		NetworkInterfaceCIDRs: map[int32][]*net.IPNet{
+			4000: {v4net},
+			4001: {v4net, v6net},
+		},
+		GuestInfo: map[string]string{
+			"guestinfo.ready": "true",
+		},
+		ToolsRunningStatus: string(types.VirtualMachineToolsRunningStatusGuestToolsRunning),
+		HeartbeatStatus:    string(types.ManagedEntityStatusGreen),
+	}
+	nic := func(key int32, addrs ...string) types.GuestNicInfo {
+		n := types.GuestNicInfo{
+			DeviceConfigId: key,
+			IpConfig:       &types.NetIpConfigInfo{},
+		}
+		for _, addr := range addrs {
+			n.IpConfig.IpAddress = append(n.IpConfig.IpAddress, types.NetIpConfigInfoIpAddress{IpAddress: addr})
+		}
+		return n
+	}
+
+	cases := []struct {
+		name     string
+		state    guestWaitState
+		expected []string
+	}{
+		{
+			name: "nothing met",
+			state: guestWaitState{
+				nics: []types.GuestNicInfo{
+					nic(4000, "192.168.0.10"),
+				},
+				extraConfig:        map[string]string{},
+				toolsRunningStatus: string(types.VirtualMachineToolsRunningStatusGuestToolsNotRunning),
+				heartbeatStatus:    string(types.ManagedEntityStatusGray),
+			},
+			expected: []string{
+				`guestinfo.ready is "", waiting for "true"`,
+				`heartbeat status is "gray", waiting for "green"`,
+				"network interface with key 4000 has no address in 10.0.0.0/24",
+				"network interface with key 4001 has no address in 10.0.0.0/24, fd00::/64",
+				`tools running status is "guestToolsNotRunning", waiting for "guestToolsRunning"`,
+			},
+		},
+		{
+			name: "everything met",
+			state: guestWaitState{
+				nics: []types.GuestNicInfo{
+					nic(4000, "192.168.0.10", "10.0.0.10"),
+					nic(4001, "fe80::1", "fd00::10"),
+				},
+				extraConfig: map[string]string{
+					"guestinfo.ready": "true",
+				},
+				toolsRunningStatus: string(types.VirtualMachineToolsRunningStatusGuestToolsRunning),
+				heartbeatStatus:    string(types.ManagedEntityStatusGreen),
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := conditions.unmet(&tc.state); !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("expected %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFlagInfoMarshal(t *testing.T) {
+	body := reconfigureFlagsBody{
+		Req: &reconfigureFlagsRequest{
+			This: types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"},
+			Spec: flagsConfigSpec{
+				Flags: &FlagInfo{
+					VvtdEnabled: structure.BoolPtr(true),
+					VbsEnabled:  structure.BoolPtr(false),
+				},
+			},
+		},
+	}
+	data, err := xml.Marshal(body.Req)
+	if err != nil {
+		t.Fatalf("error marshaling request: %s", err)
+	}
+	expected := "<spec><flags><vvtdEnabled>true</vvtdEnabled><vbsEnabled>false</vbsEnabled></flags></spec>"
+	if !strings.Contains(string(data), expected) {
+		t.Fatalf("expected %s to contain %s", data, expected)
+	}
+}
+
+func TestFlagInfoUnmarshal(t *testing.T) {
+	data := `<RetrievePropertiesResponse xmlns="urn:vim25" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <returnval>
+    <obj type="VirtualMachine">vm-1</obj>
+    <propSet>
+      <name>config.flags</name>
+      <val xsi:type="VirtualMachineFlagInfo">
+        <enableLogging>true</enableLogging>
+        <diskUuidEnabled>true</diskUuidEnabled>
+        <vvtdEnabled>true</vvtdEnabled>
+        <vbsEnabled>true</vbsEnabled>
+      </val>
+    </propSet>
+  </returnval>
+</RetrievePropertiesResponse>`
+	var res retrieveFlagsResponse
+	if err := xml.Unmarshal([]byte(data), &res); err != nil {
+		t.Fatalf("error unmarshaling response: %s", err)
+	}
+	expected := FlagInfo{
+		VirtualMachineFlagInfo: types.VirtualMachineFlagInfo{
+			EnableLogging:   structure.BoolPtr(true),
+			DiskUuidEnabled: structure.BoolPtr(true),
+		},
+		VvtdEnabled: structure.BoolPtr(true),
+		VbsEnabled:  structure.BoolPtr(true),
+	}
+	if len(res.Returnval) != 1 || len(res.Returnval[0].PropSet) != 1 {
+		t.Fatalf("unexpected response %#v", res)
+	}
+	if actual := res.Returnval[0].PropSet[0].Val; !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,180 @@
+package virtualdevice
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// The prefixes and names of the devices that can be referenced in boot_order.
+const (
+	bootOrderDiskPrefix             = "disk:"
+	bootOrderNetworkInterfacePrefix = "network_interface:"
+	bootOrderCdrom                  = "cdrom"
+	bootOrderFloppy                 = "floppy"
+)
+
+// BootOrderDiffOperation validates the entries in boot_order against the disk,
+// network_interface, and cdrom sub-resources of the virtual machine. Disks are
+// referenced by label, and network interfaces by their index in
+// network_interface.
+func BootOrderDiffOperation(d *schema.ResourceDiff) error {
+	log.Printf("[DEBUG] BootOrderDiffOperation: Beginning diff validation")
+	labels := make(map[string]struct{})
+	for _, v := range d.Get(subresourceTypeDisk).([]interface{}) {
+		labels[v.(map[string]interface{})["label"].(string)] = struct{}{}
+	}
+	nics := len(d.Get(subresourceTypeNetworkInterface).([]interface{}))
+	cdroms := len(d.Get(subresourceTypeCdrom).([]interface{}))
+
+	seen := make(map[string]struct{})
+	for i, v := range d.Get("boot_order").([]interface{}) {
+		entry, _ := v.(string)
+		if _, ok := seen[entry]; ok {
+			return fmt.Errorf("boot_order.%d: duplicate entry %q", i, entry)
+		}
+		seen[entry] = struct{}{}
+		switch {
+		case strings.HasPrefix(entry, bootOrderDiskPrefix):
+			label := strings.TrimPrefix(entry, bootOrderDiskPrefix)
+			if _, ok := labels[label]; !ok && d.NewValueKnown(subresourceTypeDisk) {
+				return fmt.Errorf("boot_order.%d: no disk with label %q", i, label)
+			}
+		case strings.HasPrefix(entry, bootOrderNetworkInterfacePrefix):
+			n, err := strconv.Atoi(strings.TrimPrefix(entry, bootOrderNetworkInterfacePrefix))
+			if err != nil {
+				return fmt.Errorf("boot_order.%d: invalid network interface index in %q", i, entry)
+			}
+			if n >= nics && d.NewValueKnown(subresourceTypeNetworkInterface) {
+				return fmt.Errorf("boot_order.%d: no network interface at index %d", i, n)
+			}
+		case entry == bootOrderCdrom:
+			if cdroms < 1 {
+				return fmt.Errorf("boot_order.%d: cdrom requires a cdrom sub-resource", i)
+			}
+		}
+	}
+	log.Printf("[DEBUG] BootOrderDiffOperation: Diff validation complete")
+	return nil
+}
+
+// ExpandBootOrder reads boot_order into a list of bootable devices. The disks
+// and network interfaces are located in the supplied device list through
+// their sub-resources, so the device list must already contain any devices
+// that were added by the apply operations.
+//
+// An empty boot_order is read as a single base bootable device, which clears
+// the boot order of the virtual machine. An empty list can't be used for
+// this, as it's dropped from the request.
+func ExpandBootOrder(d *schema.ResourceData, l object.VirtualDeviceList) ([]types.BaseVirtualMachineBootOptionsBootableDevice, error) {
+	log.Printf("[DEBUG] ExpandBootOrder: Reading boot order")
+	var order []types.BaseVirtualMachineBootOptionsBootableDevice
+	for _, v := range d.Get("boot_order").([]interface{}) {
+		entry := v.(string)
+		switch {
+		case strings.HasPrefix(entry, bootOrderDiskPrefix):
+			label := strings.TrimPrefix(entry, bootOrderDiskPrefix)
+			device, err := findBootOrderDevice(d, l, subresourceTypeDisk, func(_ int, m map[string]interface{}) bool {
+				return m["label"].(string) == label
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot find disk with label %q: %s", label, err)
+			}
+			order = append(order, &types.VirtualMachineBootOptionsBootableDiskDevice{
+				DeviceKey: device.GetVirtualDevice().Key,
+			})
+		case strings.HasPrefix(entry, bootOrderNetworkInterfacePrefix):
+			n, _ := strconv.Atoi(strings.TrimPrefix(entry, bootOrderNetworkInterfacePrefix))
+			device, err := findBootOrderDevice(d, l, subresourceTypeNetworkInterface, func(i int, _ map[string]interface{}) bool {
+				return i == n
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot find network interface at index %d: %s", n, err)
+			}
+			order = append(order, &types.VirtualMachineBootOptionsBootableEthernetDevice{
+				DeviceKey: device.GetVirtualDevice().Key,
+			})
+		case entry == bootOrderCdrom:
+			order = append(order, &types.VirtualMachineBootOptionsBootableCdromDevice{})
+		case entry == bootOrderFloppy:
+			order = append(order, &types.VirtualMachineBootOptionsBootableFloppyDevice{})
+		}
+	}
+	if len(order) < 1 {
+		order = append(order, &types.VirtualMachineBootOptionsBootableDevice{})
+	}
+	return order, nil
+}
+
+// findBootOrderDevice locates the device of the first sub-resource of type
+// srtype that matches f, which is called with the index and data of each
+// sub-resource.
+func findBootOrderDevice(d *schema.ResourceData, l object.VirtualDeviceList, srtype string, f func(int, map[string]interface{}) bool) (types.BaseVirtualDevice, error) {
+	for i, v := range d.Get(srtype).([]interface{}) {
+		m := v.(map[string]interface{})
+		if !f(i, m) {
+			continue
+		}
+		r := &Subresource{
+			Index:  i,
+			srtype: srtype,
+			data:   m,
+			rdd:    d,
+		}
+		return r.FindVirtualDevice(l)
+	}
+	return nil, fmt.Errorf("no %s sub-resource matches", srtype)
+}
+
+// FlattenBootOrder reads the boot order of a virtual machine into boot_order.
+// Disks and network interfaces are matched to their sub-resources by device
+// key, so this must run after the disk and network interface refresh
+// operations. Devices that are not managed by a sub-resource are skipped.
+//
+// The boot order is only read if boot_order is set, which leaves the boot
+// order of virtual machines that don't manage it, such as ones cloned from
+// templates with a boot order, alone.
+func FlattenBootOrder(d *schema.ResourceData, order []types.BaseVirtualMachineBootOptionsBootableDevice) error {
+	if len(d.Get("boot_order").([]interface{})) < 1 {
+		return nil
+	}
+	log.Printf("[DEBUG] FlattenBootOrder: Reading boot order")
+	keyIndex := func(srtype string, key int32) int {
+		for i, v := range d.Get(srtype).([]interface{}) {
+			if v.(map[string]interface{})["key"].(int) == int(key) {
+				return i
+			}
+		}
+		return -1
+	}
+	var entries []interface{}
+	for _, device := range order {
+		switch device := device.(type) {
+		case *types.VirtualMachineBootOptionsBootableDiskDevice:
+			i := keyIndex(subresourceTypeDisk, device.DeviceKey)
+			if i < 0 {
+				log.Printf("[DEBUG] FlattenBootOrder: Skipping unmanaged disk with key %d", device.DeviceKey)
+				continue
+			}
+			label := d.Get(fmt.Sprintf("%s.%d.label", subresourceTypeDisk, i)).(string)
+			entries = append(entries, bootOrderDiskPrefix+label)
+		case *types.VirtualMachineBootOptionsBootableEthernetDevice:
+			i := keyIndex(subresourceTypeNetworkInterface, device.DeviceKey)
+			if i < 0 {
+				log.Printf("[DEBUG] FlattenBootOrder: Skipping unmanaged network interface with key %d", device.DeviceKey)
+				continue
+			}
+			entries = append(entries, bootOrderNetworkInterfacePrefix+strconv.Itoa(i))
+		case *types.VirtualMachineBootOptionsBootableCdromDevice:
+			entries = append(entries, bootOrderCdrom)
+		case *types.VirtualMachineBootOptionsBootableFloppyDevice:
+			entries = append(entries, bootOrderFloppy)
+		}
+	}
+	return d.Set("boot_order", entries)
+}
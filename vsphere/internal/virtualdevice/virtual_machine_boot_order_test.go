@@ -0,0 +1,81 @@
+package virtualdevice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func testBootOrderResourceData(t *testing.T, raw map[string]interface{}) *schema.ResourceData {
+	device := &schema.Resource{Schema: map[string]*schema.Schema{
+		"label": {Type: schema.TypeString, Optional: true},
+		"key":   {Type: schema.TypeInt, Optional: true},
+	}}
+	s := map[string]*schema.Schema{
+		"boot_order":                    {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+		subresourceTypeDisk:             {Type: schema.TypeList, Optional: true, Elem: device},
+		subresourceTypeNetworkInterface: {Type: schema.TypeList, Optional: true, Elem: device},
+	}
+	return schema.TestResourceDataRaw(t, s, raw)
+}
+
+func TestFlattenBootOrder(t *testing.T) {
+	order := []types.BaseVirtualMachineBootOptionsBootableDevice{
+		&types.VirtualMachineBootOptionsBootableEthernetDevice{DeviceKey: 4001},
+		&types.VirtualMachineBootOptionsBootableDiskDevice{DeviceKey: 2001},
+		&types.VirtualMachineBootOptionsBootableDiskDevice{DeviceKey: 2099},
+		&types.VirtualMachineBootOptionsBootableCdromDevice{},
+	}
+	cases := []struct {
+		name      string
+		bootOrder []interface{}
+		expected  []interface{}
+	}{
+		{
+			name:      "managed",
+			bootOrder: []interface{}{"disk:disk0"},
+			expected:  []interface{}{"network_interface:1", "disk:disk1", "cdrom"},
+		},
+		{
+			name:     "unmanaged",
+			expected: []interface{}{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := testBootOrderResourceData(t, map[string]interface{}{
+				"boot_order": tc.bootOrder,
+				subresourceTypeDisk: []interface{}{
+					map[string]interface{}{"label": "disk0", "key": 2000},
+					map[string]interface{}{"label": "disk1", "key": 2001},
+				},
+				subresourceTypeNetworkInterface: []interface{}{
+					map[string]interface{}{"key": 4000},
+					map[string]interface{}{"key": 4001},
+				},
+			})
+			if err := FlattenBootOrder(d, order); err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			if actual := d.Get("boot_order").([]interface{}); !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("expected %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExpandBootOrderEmpty(t *testing.T) {
+	d := testBootOrderResourceData(t, map[string]interface{}{})
+	order, err := ExpandBootOrder(d, nil)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	expected := []types.BaseVirtualMachineBootOptionsBootableDevice{
+		&types.VirtualMachineBootOptionsBootableDevice{},
+	}
+	if !reflect.DeepEqual(expected, order) {
+		t.Fatalf("expected %#v, got %#v", expected, order)
+	}
+}
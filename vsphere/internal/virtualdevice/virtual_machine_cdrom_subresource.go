@@ -1,14 +1,19 @@
 package virtualdevice
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"reflect"
 	"regexp"
+	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/mitchellh/copystructure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/cloudinit"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/datastore"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/object"
@@ -17,6 +22,11 @@ import (
 
 const vAppTransportIso = "iso"
 
+// cloudInitSeedIsoPattern matches the names of the NoCloud seed ISOs that are
+// inserted into client CDROM devices for cloud_init, which are named
+// "<vm directory>/_cidata-<checksum>.iso".
+var cloudInitSeedIsoPattern = regexp.MustCompile(`.*/_cidata-[0-9a-f]+\.iso$`)
+
 // CdromSubresourceSchema represents the schema for the cdrom sub-resource.
 func CdromSubresourceSchema() map[string]*schema.Schema {
 	s := map[string]*schema.Schema{
@@ -37,6 +47,12 @@ func CdromSubresourceSchema() map[string]*schema.Schema {
 			Optional:    true,
 			Description: "Indicates whether the device should be mapped to a remote client device",
 		},
+		// VirtualCdromIsoBackingInfo, from a content library
+		"content_library_item_id": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The ID of a content library item containing the ISO to insert.",
+		},
 	}
 	structure.MergeSchema(s, subresourceSchema())
 	return s
@@ -46,11 +62,15 @@ func CdromSubresourceSchema() map[string]*schema.Schema {
 // with a complex device lifecycle.
 type CdromSubresource struct {
 	*Subresource
+
+	// The content library client, used to look up ISOs in content libraries.
+	// This is nil when no CDROM device uses a content library item.
+	lc *contentlibrary.Client
 }
 
 // NewCdromSubresource returns a subresource populated with all of the necessary
 // fields.
-func NewCdromSubresource(client *govmomi.Client, rdd resourceDataDiff, d, old map[string]interface{}, idx int) *CdromSubresource {
+func NewCdromSubresource(client *govmomi.Client, lc *contentlibrary.Client, rdd resourceDataDiff, d, old map[string]interface{}, idx int) *CdromSubresource {
 	sr := &CdromSubresource{
 		Subresource: &Subresource{
 			schema:  CdromSubresourceSchema(),
@@ -60,6 +80,7 @@ func NewCdromSubresource(client *govmomi.Client, rdd resourceDataDiff, d, old ma
 			olddata: old,
 			rdd:     rdd,
 		},
+		lc: lc,
 	}
 	sr.Index = idx
 	return sr
@@ -73,12 +94,11 @@ func NewCdromSubresource(client *govmomi.Client, rdd resourceDataDiff, d, old ma
 // operation. All disk operations are carried out, with both the complete,
 // updated, VirtualDeviceList, and the complete list of changes returned as a
 // slice of BaseVirtualDeviceConfigSpec.
-func CdromApplyOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+func CdromApplyOperation(d *schema.ResourceData, c *govmomi.Client, lc *contentlibrary.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
 	log.Printf("[DEBUG] CdromApplyOperation: Beginning apply operation")
-	// While we are currently only restricting CD devices to one device, we have
-	// to actually account for the fact that someone could add multiple CD drives
-	// out of band. So this workflow is similar to the multi-device workflow that
-	// exists for network devices.
+	// CD devices are matched up by their position in the list, with their key
+	// and device address tracking the device itself. So this workflow is
+	// similar to the multi-device workflow that exists for network devices.
 	o, n := d.GetChange(subresourceTypeCdrom)
 	ods := o.([]interface{})
 	nds := n.([]interface{})
@@ -97,7 +117,7 @@ nextOld:
 				continue nextOld
 			}
 		}
-		r := NewCdromSubresource(c, d, om, nil, n)
+		r := NewCdromSubresource(c, lc, d, om, nil, n)
 		dspec, err := r.Delete(l)
 		if err != nil {
 			return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
@@ -125,7 +145,7 @@ nextOld:
 				log.Printf("[DEBUG] CdromApplyOperation: No-op resource: key %d", nm["key"].(int))
 				continue
 			}
-			r := NewCdromSubresource(c, d, nm, om, n)
+			r := NewCdromSubresource(c, lc, d, nm, om, n)
 			uspec, err := r.Update(l)
 			if err != nil {
 				return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
@@ -136,7 +156,7 @@ nextOld:
 			continue
 		}
 		// New device
-		r := NewCdromSubresource(c, d, nm, nil, n)
+		r := NewCdromSubresource(c, lc, d, nm, nil, n)
 		cspec, err := r.Create(l)
 		if err != nil {
 			return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
@@ -164,10 +184,9 @@ nextOld:
 // returned, all necessary values are just set and committed to state.
 func CdromRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) error {
 	log.Printf("[DEBUG] CdromRefreshOperation: Beginning refresh")
-	// While we are currently only restricting CD devices to one device, we have
-	// to actually account for the fact that someone could add multiple CD drives
-	// out of band. So this workflow is similar to the multi-device workflow that
-	// exists for network devices.
+	// CD devices are matched up by their position in the list, with their key
+	// and device address tracking the device itself. So this workflow is
+	// similar to the multi-device workflow that exists for network devices.
 	devices := l.Select(func(device types.BaseVirtualDevice) bool {
 		if _, ok := device.(*types.VirtualCdrom); ok {
 			return true
@@ -187,7 +206,7 @@ func CdromRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.V
 	for n, item := range curSet {
 		m := item.(map[string]interface{})
 		if m["key"].(int) < 1 {
-			r := NewCdromSubresource(c, d, m, nil, n)
+			r := NewCdromSubresource(c, nil, d, m, nil, n)
 			if err := r.Read(l); err != nil {
 				return fmt.Errorf("%s: %s", r.Addr(), err)
 			}
@@ -225,13 +244,14 @@ func CdromRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.V
 				continue
 			}
 			// We should have our device -> resource match, so read now.
-			r := NewCdromSubresource(c, d, m, nil, n)
+			r := NewCdromSubresource(c, nil, d, m, nil, n)
 			vApp, err := verifyVAppCdromIso(d, device.(*types.VirtualCdrom), l, c)
 			if err != nil {
 				return err
 			}
-			if vApp == true && r.Get("client_device") == true {
-				log.Printf("[DEBUG] CdromRefreshOperation: %s: Skipping read since CDROM is in use for vApp ISO transport", r)
+			seed := verifyCloudInitCdromIso(d, device.(*types.VirtualCdrom))
+			if (vApp == true || seed == true) && r.Get("client_device") == true {
+				log.Printf("[DEBUG] CdromRefreshOperation: %s: Skipping read since CDROM is in use for vApp or cloud-init ISO transport", r)
 				// Set the CDROM properties to match a client device so there won't be a diff.
 				r.Set("client_device", true)
 				r.Set("datastore_id", "")
@@ -266,7 +286,7 @@ func CdromRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.V
 		if err != nil {
 			return fmt.Errorf("error computing device address: %s", err)
 		}
-		r := NewCdromSubresource(c, d, m, nil, n)
+		r := NewCdromSubresource(c, nil, d, m, nil, n)
 		if err := r.Read(l); err != nil {
 			return fmt.Errorf("%s: %s", r.Addr(), err)
 		}
@@ -285,12 +305,11 @@ func CdromRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.V
 // This differs from a regular apply operation in that a configuration is
 // already present, but we don't have any existing state, which the standard
 // virtual device operations rely pretty heavily on.
-func CdromPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+func CdromPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, lc *contentlibrary.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
 	log.Printf("[DEBUG] CdromPostCloneOperation: Looking for post-clone device changes")
-	// While we are currently only restricting CD devices to one device, we have
-	// to actually account for the fact that someone could add multiple CD drives
-	// out of band. So this workflow is similar to the multi-device workflow that
-	// exists for network devices.
+	// CD devices are matched up by their position in the list, with their key
+	// and device address tracking the device itself. So this workflow is
+	// similar to the multi-device workflow that exists for network devices.
 	devices := l.Select(func(device types.BaseVirtualDevice) bool {
 		if _, ok := device.(*types.VirtualCdrom); ok {
 			return true
@@ -318,7 +337,7 @@ func CdromPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object
 		if err != nil {
 			return nil, nil, fmt.Errorf("error computing device address: %s", err)
 		}
-		r := NewCdromSubresource(c, d, m, nil, n)
+		r := NewCdromSubresource(c, lc, d, m, nil, n)
 		if err := r.Read(l); err != nil {
 			return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
 		}
@@ -337,7 +356,7 @@ func CdromPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object
 		cm := ci.(map[string]interface{})
 		if i > len(srcSet)-1 {
 			// New device
-			r := NewCdromSubresource(c, d, cm, nil, i)
+			r := NewCdromSubresource(c, lc, d, cm, nil, i)
 			cspec, err := r.Create(l)
 			if err != nil {
 				return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
@@ -360,7 +379,7 @@ func CdromPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object
 			}
 			nm.(map[string]interface{})[k] = v
 		}
-		r := NewCdromSubresource(c, d, nm.(map[string]interface{}), sm, i)
+		r := NewCdromSubresource(c, lc, d, nm.(map[string]interface{}), sm, i)
 		if !reflect.DeepEqual(sm, nm) {
 			// Update
 			cspec, err := r.Update(l)
@@ -378,7 +397,7 @@ func CdromPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object
 	if len(curSet) < len(srcSet) {
 		for i, si := range srcSet[len(curSet):] {
 			sm := si.(map[string]interface{})
-			r := NewCdromSubresource(c, d, sm, nil, i+len(curSet))
+			r := NewCdromSubresource(c, lc, d, sm, nil, i+len(curSet))
 			dspec, err := r.Delete(l)
 			if err != nil {
 				return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
@@ -401,12 +420,12 @@ func CdromPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object
 
 // CdromDiffOperation performs operations relevant to managing the
 // diff on cdrom sub-resources
-func CdromDiffOperation(d *schema.ResourceDiff, c *govmomi.Client) error {
+func CdromDiffOperation(d *schema.ResourceDiff, c *govmomi.Client, lc *contentlibrary.Client) error {
 	log.Printf("[DEBUG] CdromDiffOperation: Beginning diff validation")
 	cr := d.Get(subresourceTypeCdrom)
 	for ci, ce := range cr.([]interface{}) {
 		cm := ce.(map[string]interface{})
-		r := NewCdromSubresource(c, d, cm, nil, ci)
+		r := NewCdromSubresource(c, lc, d, cm, nil, ci)
 		if err := r.ValidateDiff(); err != nil {
 			return fmt.Errorf("%s: %s", r.Addr(), err)
 		}
@@ -422,16 +441,41 @@ func (r *CdromSubresource) ValidateDiff() error {
 	dsID := r.Get("datastore_id").(string)
 	path := r.Get("path").(string)
 	clientDevice := r.Get("client_device").(bool)
+	itemID := r.Get("content_library_item_id").(string)
 	switch {
 	case clientDevice && (dsID != "" || path != ""):
 		return fmt.Errorf("Cannot have both client_device parameter and ISO file parameters (datastore_id, path) set")
+	case itemID != "" && (clientDevice || dsID != "" || path != ""):
+		return fmt.Errorf("Cannot have content_library_item_id set with client_device or ISO file parameters (datastore_id, path)")
+	case itemID != "":
+		if err := r.validateLibraryItem(itemID); err != nil {
+			return err
+		}
 	case !clientDevice && (dsID == "" || path == ""):
-		return fmt.Errorf("Either client_device or datastore_id and path must be set")
+		return fmt.Errorf("Either client_device, content_library_item_id, or datastore_id and path must be set")
 	}
 	log.Printf("[DEBUG] %s: Config validation complete", r)
 	return nil
 }
 
+// validateLibraryItem checks that the content library item with the supplied
+// ID is an ISO item.
+func (r *CdromSubresource) validateLibraryItem(id string) error {
+	if r.lc == nil {
+		return fmt.Errorf("content_library_item_id requires a content library connection")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	item, err := r.lc.GetLibraryItem(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error fetching content library item %q: %s", id, err)
+	}
+	if item.Type != contentlibrary.ItemTypeIso {
+		return fmt.Errorf("content library item %q is of unsupported type %q - must be %s", id, item.Type, contentlibrary.ItemTypeIso)
+	}
+	return nil
+}
+
 // Create creates a vsphere_virtual_machine cdrom sub-resource.
 func (r *CdromSubresource) Create(l object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
 	log.Printf("[DEBUG] %s: Running create", r)
@@ -448,7 +492,9 @@ func (r *CdromSubresource) Create(l object.VirtualDeviceList) ([]types.BaseVirtu
 		return nil, err
 	}
 	// Map the CDROM to the correct device
-	r.mapCdrom(device, l)
+	if err := r.mapCdrom(device, l); err != nil {
+		return nil, err
+	}
 	// Done here. Save IDs, push the device to the new device list and return.
 	if err := r.SaveDevIDs(device, ctlr); err != nil {
 		return nil, err
@@ -478,11 +524,21 @@ func (r *CdromSubresource) Read(l object.VirtualDeviceList) error {
 	switch backing := device.Backing.(type) {
 	case *types.VirtualCdromRemoteAtapiBackingInfo:
 		r.Set("client_device", true)
+		r.Set("content_library_item_id", "")
 	case *types.VirtualCdromIsoBackingInfo:
 		dp := &object.DatastorePath{}
 		if ok := dp.FromString(backing.FileName); !ok {
 			return fmt.Errorf("could not read datastore path in backing %q", backing.FileName)
 		}
+		// Content library items are stored in a directory named after the item
+		// ID, so if the ISO is still in the directory of the item in state, the
+		// item is kept as the source of the ISO.
+		if id, _ := r.Get("content_library_item_id").(string); id != "" && strings.Contains(dp.Path, "/"+id+"/") {
+			r.Set("datastore_id", "")
+			r.Set("path", "")
+			break
+		}
+		r.Set("content_library_item_id", "")
 		// If a vApp ISO was inserted, it will be removed if the VM is powered off
 		// and cause backing.Datastore to be nil.
 		if backing.Datastore != nil {
@@ -500,6 +556,7 @@ func (r *CdromSubresource) Read(l object.VirtualDeviceList) error {
 		r.Set("datastore_id", "")
 		r.Set("path", "")
 		r.Set("client_device", false)
+		r.Set("content_library_item_id", "")
 	}
 	// Save the device key and address data
 	ctlr, err := findControllerForDevice(l, d)
@@ -526,7 +583,9 @@ func (r *CdromSubresource) Update(l object.VirtualDeviceList) ([]types.BaseVirtu
 	}
 
 	// Map the CDROM to the correct device
-	r.mapCdrom(device, l)
+	if err := r.mapCdrom(device, l); err != nil {
+		return nil, err
+	}
 	spec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationEdit)
 	if err != nil {
 		return nil, err
@@ -561,7 +620,19 @@ func (r *CdromSubresource) mapCdrom(device *types.VirtualCdrom, l object.Virtual
 	dsID := r.Get("datastore_id").(string)
 	path := r.Get("path").(string)
 	clientDevice := r.Get("client_device").(bool)
+	itemID := r.Get("content_library_item_id").(string)
 	switch {
+	case itemID != "":
+		// If a content library item is set, the CDROM will be mapped to the ISO
+		// in the item's storage.
+		dsPath, dsRef, err := r.libraryItemIsoPath(itemID)
+		if err != nil {
+			return err
+		}
+		device = l.InsertIso(device, dsPath)
+		device.Backing.(*types.VirtualCdromIsoBackingInfo).Datastore = dsRef
+		l.Connect(device)
+		return nil
 	case dsID != "" && path != "":
 		// If the datastore ID and path are both set, the CDROM will be mapped to a file on a datastore.
 		ds, err := datastore.FromID(r.client, dsID)
@@ -590,30 +661,148 @@ func (r *CdromSubresource) mapCdrom(device *types.VirtualCdrom, l object.Virtual
 	panic(fmt.Sprintf("%s: no CDROM types specified", r))
 }
 
+// libraryItemIsoPath returns the datastore path and datastore of the ISO in a
+// content library item.
+func (r *CdromSubresource) libraryItemIsoPath(id string) (string, *types.ManagedObjectReference, error) {
+	if r.lc == nil {
+		return "", nil, fmt.Errorf("content_library_item_id requires a content library connection")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	storage, err := r.lc.ListLibraryItemStorage(ctx, id)
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching storage of content library item %q: %s", id, err)
+	}
+	for _, f := range storage {
+		if !strings.HasSuffix(strings.ToLower(f.Name), ".iso") || len(f.StorageURIs) < 1 {
+			continue
+		}
+		ds, err := datastore.FromID(r.client, f.StorageBacking.DatastoreID)
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot find datastore for content library item %q: %s", id, err)
+		}
+		props, err := datastore.Properties(ds)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not get properties for datastore: %s", err)
+		}
+		// The storage URI is the file's location under the URL of the datastore,
+		// such as ds:///vmfs/volumes/<uuid>/contentlib-<library>/<item>/<file>.iso.
+		dsURL := strings.TrimSuffix(props.Summary.Url, "/") + "/"
+		if !strings.HasPrefix(f.StorageURIs[0], dsURL) {
+			return "", nil, fmt.Errorf("content library item %q file %q is not on datastore %q", id, f.StorageURIs[0], props.Name)
+		}
+		dsPath := &object.DatastorePath{
+			Datastore: props.Name,
+			Path:      strings.TrimPrefix(f.StorageURIs[0], dsURL),
+		}
+		ref := ds.Reference()
+		return dsPath.String(), &ref, nil
+	}
+	return "", nil, fmt.Errorf("content library item %q does not contain an ISO file", id)
+}
+
 // VerifyVAppTransport validates that all the required components are included in
 // the virtual machine configuration if vApp properties are set.
 func VerifyVAppTransport(d *schema.ResourceDiff, c *govmomi.Client) error {
 	log.Printf("[DEBUG] VAppDiffOperation: Verifying configuration meets requirements for vApp transport")
-	// Check if there is a client CDROM device configured.
-	cl := d.Get("cdrom")
-	for _, c := range cl.([]interface{}) {
-		if c.(map[string]interface{})["client_device"].(bool) == true {
-			// There is a device configured that can support vApp ISO transport if needed
-			log.Printf("[DEBUG] VAppDiffOperation: Client CDROM device exists which can support ISO transport")
-			return nil
-		}
-	}
-	// Iterate over each transport and see if ISO transport is supported.
+	// Iterate over each transport and see if ISO transport is required.
 	tm := d.Get("vapp_transport").([]interface{})
+	var required bool
 	for _, m := range tm {
 		if m.(string) == vAppTransportIso && len(tm) == 1 {
-			return fmt.Errorf("this virtual machine requires a client CDROM device to deliver vApp properties")
+			required = true
 		}
 	}
-	log.Printf("[DEBUG] VAppDiffOperation: ISO transport is not supported on this virtual machine or multiple transport options exist")
+	if !required {
+		log.Printf("[DEBUG] VAppDiffOperation: ISO transport is not supported on this virtual machine or multiple transport options exist")
+		return nil
+	}
+	// The vApp ISO needs a client CDROM device of its own if the NoCloud seed
+	// ISO for cloud_init is taking one of the client devices.
+	need := 1
+	if d.Get("cloud_init.0.datasource").(string) == cloudinit.DatasourceNoCloud {
+		need++
+	}
+	if n := clientCdromCount(d); n < need {
+		if need > 1 {
+			return fmt.Errorf("this virtual machine requires %d client CDROM devices to deliver both vApp properties and the cloud-init seed ISO (found %d)", need, n)
+		}
+		return fmt.Errorf("this virtual machine requires a client CDROM device to deliver vApp properties")
+	}
+	log.Printf("[DEBUG] VAppDiffOperation: Client CDROM device exists which can support ISO transport")
 	return nil
 }
 
+// clientCdromCount returns the number of CDROM devices in the configuration
+// that are mapped to a remote client device.
+func clientCdromCount(d *schema.ResourceDiff) int {
+	var n int
+	for _, c := range d.Get("cdrom").([]interface{}) {
+		if c.(map[string]interface{})["client_device"].(bool) == true {
+			n++
+		}
+	}
+	return n
+}
+
+// VerifyCloudInitTransport validates that there is a client CDROM device to
+// insert the NoCloud seed ISO into if cloud_init is set to use the nocloud
+// datasource.
+func VerifyCloudInitTransport(d *schema.ResourceDiff) error {
+	if d.Get("cloud_init.0.datasource").(string) != cloudinit.DatasourceNoCloud {
+		return nil
+	}
+	if clientCdromCount(d) > 0 {
+		return nil
+	}
+	return fmt.Errorf("the nocloud cloud-init datasource requires a client CDROM device to insert the seed ISO into")
+}
+
+// CloudInitSeedCdrom returns the CDROM device that the NoCloud seed ISO is
+// inserted into, along with the datastore path of the currently inserted seed
+// ISO, if any. The seed ISO goes into the first client CDROM device if it has
+// not been inserted yet.
+func CloudInitSeedCdrom(l object.VirtualDeviceList) (*types.VirtualCdrom, string) {
+	var client *types.VirtualCdrom
+	for _, device := range l.SelectByType((*types.VirtualCdrom)(nil)) {
+		cdrom := device.(*types.VirtualCdrom)
+		switch backing := cdrom.Backing.(type) {
+		case *types.VirtualCdromIsoBackingInfo:
+			if cloudInitSeedIsoPattern.MatchString(backing.FileName) {
+				return cdrom, backing.FileName
+			}
+		case *types.VirtualCdromRemoteAtapiBackingInfo:
+			if client == nil {
+				client = cdrom
+			}
+		}
+	}
+	return client, ""
+}
+
+// CloudInitSeedIsoFileName returns the file name of a NoCloud seed ISO with
+// the supplied checksum, which matches cloudInitSeedIsoPattern.
+func CloudInitSeedIsoFileName(checksum string) string {
+	return fmt.Sprintf("_cidata-%s.iso", checksum)
+}
+
+// verifyCloudInitCdromIso checks if the VirtualCdrom has the NoCloud seed ISO
+// for cloud_init inserted.
+func verifyCloudInitCdromIso(d *schema.ResourceData, device *types.VirtualCdrom) bool {
+	if d.Get("cloud_init.0.datasource").(string) != cloudinit.DatasourceNoCloud {
+		return false
+	}
+	backing, ok := device.Backing.(*types.VirtualCdromIsoBackingInfo)
+	if !ok {
+		return false
+	}
+	if !cloudInitSeedIsoPattern.MatchString(backing.FileName) {
+		return false
+	}
+	log.Printf("[DEBUG] verifyCloudInitCdromIso: CDROM has the cloud-init seed ISO inserted")
+	return true
+}
+
 // verifyVAppCdromIso takes VirtualCdrom and determines if it is needed for
 // vApp ISO transport. It does this by first checking if it has an ISO inserted
 // that matches the vApp ISO naming pattern. If it does, then the next step is
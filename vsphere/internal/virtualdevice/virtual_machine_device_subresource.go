@@ -9,7 +9,9 @@ import (
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/mitchellh/copystructure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/cryptomanager"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
@@ -19,6 +21,8 @@ const (
 	subresourceTypeDisk             = "disk"
 	subresourceTypeNetworkInterface = "network_interface"
 	subresourceTypeCdrom            = "cdrom"
+	subresourceTypeSerialPort       = "serial_port"
+	subresourceTypeParallelPort     = "parallel_port"
 )
 
 const (
@@ -30,6 +34,10 @@ const (
 	// classes.
 	SubresourceControllerTypeSATA = "sata"
 
+	// SubresourceControllerTypeNVMe is a string representation of NVMe
+	// controller classes.
+	SubresourceControllerTypeNVMe = "nvme"
+
 	// SubresourceControllerTypeSCSI is a string representation of all SCSI
 	// controller types.
 	//
@@ -52,6 +60,10 @@ const (
 	// SubresourceControllerTypePCI is a string representation of PCI controller
 	// classes.
 	SubresourceControllerTypePCI = "pci"
+
+	// SubresourceControllerTypeSIO is a string representation of the super I/O
+	// controller, which serial and parallel ports are attached to.
+	SubresourceControllerTypeSIO = "sio"
 )
 
 const (
@@ -64,6 +76,8 @@ var subresourceIDControllerTypeAllowedValues = []string{
 	SubresourceControllerTypeSCSI,
 	SubresourceControllerTypePCI,
 	SubresourceControllerTypeSATA,
+	SubresourceControllerTypeNVMe,
+	SubresourceControllerTypeSIO,
 }
 
 var sharesLevelAllowedValues = []string{
@@ -120,8 +134,12 @@ func controllerTypeToClass(c types.BaseVirtualController) (string, error) {
 		t = SubresourceControllerTypeIDE
 	case *types.VirtualAHCIController:
 		t = SubresourceControllerTypeSATA
+	case *types.VirtualNVMEController:
+		t = SubresourceControllerTypeNVMe
 	case *types.VirtualPCIController:
 		t = SubresourceControllerTypePCI
+	case *types.VirtualSIOController:
+		t = SubresourceControllerTypeSIO
 	case *types.ParaVirtualSCSIController, *types.VirtualBusLogicController,
 		*types.VirtualLsiLogicController, *types.VirtualLsiLogicSASController:
 		t = SubresourceControllerTypeSCSI
@@ -378,6 +396,10 @@ func findVirtualDeviceInListControllerSelectFunc(ct string, cb int) func(types.B
 			if _, ok := device.(*types.VirtualAHCIController); !ok {
 				return false
 			}
+		case SubresourceControllerTypeNVMe:
+			if _, ok := device.(*types.VirtualNVMEController); !ok {
+				return false
+			}
 		case SubresourceControllerTypeSCSI:
 			if _, ok := device.(types.BaseVirtualSCSIController); !ok {
 				return false
@@ -386,6 +408,10 @@ func findVirtualDeviceInListControllerSelectFunc(ct string, cb int) func(types.B
 			if _, ok := device.(*types.VirtualPCIController); !ok {
 				return false
 			}
+		case SubresourceControllerTypeSIO:
+			if _, ok := device.(*types.VirtualSIOController); !ok {
+				return false
+			}
 		}
 		vc := device.(types.BaseVirtualController).GetVirtualController()
 		if vc.BusNumber == int32(cb) {
@@ -475,9 +501,10 @@ func (r *Subresource) String() string {
 }
 
 // swapSCSIDevice swaps out the supplied controller for a new one of the
-// supplied controller type. Any connected devices are re-connected at the same
-// device units on the new device. A list of changes is returned.
-func swapSCSIDevice(l object.VirtualDeviceList, device types.BaseVirtualSCSIController, ct string) ([]types.BaseVirtualDeviceConfigSpec, error) {
+// supplied controller type and bus sharing mode. Any connected devices are
+// re-connected at the same device units on the new device. A list of changes
+// is returned.
+func swapSCSIDevice(l object.VirtualDeviceList, device types.BaseVirtualSCSIController, ct string, sharing types.VirtualSCSISharing) ([]types.BaseVirtualDeviceConfigSpec, error) {
 	log.Printf("[DEBUG] swapSCSIDevice: Swapping SCSI device for one of controller type %s: %s", ct, l.Name(device.(types.BaseVirtualDevice)))
 	var spec []types.BaseVirtualDeviceConfigSpec
 	bvd := device.(types.BaseVirtualDevice)
@@ -492,6 +519,7 @@ func swapSCSIDevice(l object.VirtualDeviceList, device types.BaseVirtualSCSICont
 		return nil, err
 	}
 	nsd.(types.BaseVirtualSCSIController).GetVirtualSCSIController().BusNumber = device.GetVirtualSCSIController().BusNumber
+	nsd.(types.BaseVirtualSCSIController).GetVirtualSCSIController().SharedBus = sharing
 	cspec, err = object.VirtualDeviceList{nsd}.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
 	if err != nil {
 		return nil, err
@@ -519,19 +547,22 @@ func swapSCSIDevice(l object.VirtualDeviceList, device types.BaseVirtualSCSICont
 }
 
 // NormalizeSCSIBus checks the SCSI controllers on the virtual machine and
-// either creates them if they don't exist, or migrates them to the specified
-// controller type. Devices are migrated to the new controller appropriately. A
-// spec slice is returned with the changes.
+// either creates them if they don't exist, or migrates them to the controller
+// type and bus sharing mode specified by scsi_type and the scsi_controller
+// sub-resources. Devices are migrated to the new controller appropriately. A
+// spec slice is returned with the changes. The bus sharing mode of controllers
+// without a scsi_controller block is left as it is.
 //
-// The first number of slots specified by count are normalized by this
-// function. Any others are left unchanged.
-func NormalizeSCSIBus(l object.VirtualDeviceList, ct string, count int) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
-	log.Printf("[DEBUG] NormalizeSCSIBus: Normalizing first %d controllers on SCSI bus to device type %s", count, ct)
+// The first number of slots specified by scsi_controller_count are normalized
+// by this function. Any others are left unchanged.
+func NormalizeSCSIBus(l object.VirtualDeviceList, d *schema.ResourceData) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	bus := expandSCSIBus(d)
+	log.Printf("[DEBUG] NormalizeSCSIBus: Normalizing first %d controllers on SCSI bus", len(bus))
 	var spec []types.BaseVirtualDeviceConfigSpec
-	ctlrs := make([]types.BaseVirtualSCSIController, count)
+	ctlrs := make([]types.BaseVirtualSCSIController, len(bus))
 	// Don't worry about doing any fancy select stuff here, just go thru the
 	// VirtualDeviceList and populate the controllers.
-	iCount := int32(count)
+	iCount := int32(len(bus))
 	for _, dev := range l {
 		if sc, ok := dev.(types.BaseVirtualSCSIController); ok {
 			if busNumber := sc.GetVirtualSCSIController().BusNumber; busNumber < iCount {
@@ -542,12 +573,17 @@ func NormalizeSCSIBus(l object.VirtualDeviceList, ct string, count int) (object.
 	log.Printf("[DEBUG] NormalizeSCSIBus: Current SCSI bus contents: %s", scsiControllerListString(ctlrs))
 	// Now iterate over the controllers
 	for n, ctlr := range ctlrs {
+		ct, sharing := bus[n].ct, bus[n].sharing
 		if ctlr == nil {
-			log.Printf("[DEBUG] NormalizeSCSIBus: Creating SCSI controller of type %s at bus number %d", ct, n)
+			if sharing == "" {
+				sharing = types.VirtualSCSISharingNoSharing
+			}
+			log.Printf("[DEBUG] NormalizeSCSIBus: Creating SCSI controller of type %s with bus sharing %s at bus number %d", ct, sharing, n)
 			nc, err := l.CreateSCSIController(ct)
 			if err != nil {
 				return nil, nil, err
 			}
+			nc.(types.BaseVirtualSCSIController).GetVirtualSCSIController().SharedBus = sharing
 			cspec, err := object.VirtualDeviceList{nc}.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
 			if err != nil {
 				return nil, nil, err
@@ -556,16 +592,30 @@ func NormalizeSCSIBus(l object.VirtualDeviceList, ct string, count int) (object.
 			l = applyDeviceChange(l, cspec)
 			continue
 		}
-		if l.Type(ctlr.(types.BaseVirtualDevice)) == ct {
+		if sharing == "" {
+			sharing = ctlr.GetVirtualSCSIController().SharedBus
+		}
+		if l.Type(ctlr.(types.BaseVirtualDevice)) != ct {
+			cspec, err := swapSCSIDevice(l, ctlr, ct, sharing)
+			if err != nil {
+				return nil, nil, err
+			}
+			spec = append(spec, cspec...)
+			l = applyDeviceChange(l, cspec)
 			continue
 		}
-		cspec, err := swapSCSIDevice(l, ctlr, ct)
-		if err != nil {
-			return nil, nil, err
+		if ctlr.GetVirtualSCSIController().SharedBus != sharing {
+			// Only the bus sharing mode has changed, which can be updated on the
+			// existing controller.
+			log.Printf("[DEBUG] NormalizeSCSIBus: Changing bus sharing on SCSI controller at bus number %d to %s", n, sharing)
+			ctlr.GetVirtualSCSIController().SharedBus = sharing
+			cspec, err := object.VirtualDeviceList{ctlr.(types.BaseVirtualDevice)}.ConfigSpec(types.VirtualDeviceConfigSpecOperationEdit)
+			if err != nil {
+				return nil, nil, err
+			}
+			spec = append(spec, cspec...)
+			l = applyDeviceChange(l, cspec)
 		}
-		spec = append(spec, cspec...)
-		l = applyDeviceChange(l, cspec)
-		continue
 	}
 	log.Printf("[DEBUG] NormalizeSCSIBus: Outgoing device list: %s", DeviceListString(l))
 	log.Printf("[DEBUG] NormalizeSCSIBus: Outgoing device config spec: %s", DeviceChangeString(spec))
@@ -576,6 +626,12 @@ func NormalizeSCSIBus(l object.VirtualDeviceList, ct string, count int) (object.
 // depending on if all controllers are one specific kind or not. Only the first
 // number of controllers specified by count are checked.
 func ReadSCSIBusState(l object.VirtualDeviceList, count int) string {
+	return readSCSIBusState(l, count, nil)
+}
+
+// readSCSIBusState works like ReadSCSIBusState, but skips the bus numbers in
+// skip. An empty string is returned if all controllers are skipped.
+func readSCSIBusState(l object.VirtualDeviceList, count int, skip map[int32]bool) string {
 	ctlrs := make([]types.BaseVirtualSCSIController, count)
 	for _, dev := range l {
 		if sc, ok := dev.(types.BaseVirtualSCSIController); ok && sc.GetVirtualSCSIController().BusNumber < int32(count) {
@@ -583,14 +639,22 @@ func ReadSCSIBusState(l object.VirtualDeviceList, count int) string {
 		}
 	}
 	log.Printf("[DEBUG] ReadSCSIBusState: SCSI controller layout for first %d controllers: %s", count, scsiControllerListString(ctlrs))
-	if ctlrs[0] == nil {
-		return subresourceControllerTypeUnknown
-	}
-	last := l.Type(ctlrs[0].(types.BaseVirtualDevice))
-	for _, ctlr := range ctlrs[1:] {
-		if ctlr == nil || l.Type(ctlr.(types.BaseVirtualDevice)) != last {
+	var last string
+	for n, ctlr := range ctlrs {
+		if skip[int32(n)] {
+			continue
+		}
+		if ctlr == nil {
+			if last == "" {
+				return subresourceControllerTypeUnknown
+			}
 			return subresourceControllerTypeMixed
 		}
+		t := l.Type(ctlr.(types.BaseVirtualDevice))
+		if last != "" && t != last {
+			return subresourceControllerTypeMixed
+		}
+		last = t
 	}
 	return last
 }
@@ -614,6 +678,30 @@ func pickSCSIController(l object.VirtualDeviceList, bus int) (types.BaseVirtualC
 	return l[0].(types.BaseVirtualController), nil
 }
 
+// pickIDEController picks the IDE controller with the lowest bus number that
+// has a free unit. Units are counted from the devices in the list rather than
+// the device list of the controller, so that devices that are pending
+// creation are accounted for.
+func pickIDEController(l object.VirtualDeviceList) types.BaseVirtualController {
+	var ctlr types.BaseVirtualController
+	for _, device := range l.SelectByType((*types.VirtualIDEController)(nil)) {
+		vc := device.(*types.VirtualIDEController)
+		if ctlr != nil && ctlr.GetVirtualController().BusNumber < vc.BusNumber {
+			continue
+		}
+		units := len(l.Select(func(d types.BaseVirtualDevice) bool {
+			return d.GetVirtualDevice().ControllerKey == vc.Key
+		}))
+		if units < diskControllerUnits[SubresourceControllerTypeIDE] {
+			ctlr = vc
+		}
+	}
+	if ctlr != nil {
+		log.Printf("[DEBUG] pickIDEController: Found IDE controller: %s", l.Name(ctlr.(types.BaseVirtualDevice)))
+	}
+	return ctlr
+}
+
 // ControllerForCreateUpdate wraps the controller selection logic to make it
 // easier to use in create or update operations. If the controller type is a
 // SCSI device, the bus number is searched as well.
@@ -623,13 +711,15 @@ func (r *Subresource) ControllerForCreateUpdate(l object.VirtualDeviceList, ct s
 	var err error
 	switch ct {
 	case SubresourceControllerTypeIDE:
-		ctlr = l.PickController(&types.VirtualIDEController{})
+		ctlr = pickIDEController(l)
 	case SubresourceControllerTypeSATA:
 		ctlr = l.PickController(&types.VirtualAHCIController{})
 	case SubresourceControllerTypeSCSI:
 		ctlr, err = pickSCSIController(l, bus)
 	case SubresourceControllerTypePCI:
 		ctlr = l.PickController(&types.VirtualPCIController{})
+	case SubresourceControllerTypeSIO:
+		ctlr = l.PickController(&types.VirtualSIOController{})
 	default:
 		return nil, fmt.Errorf("invalid controller type %T", ct)
 	}
@@ -640,9 +730,10 @@ func (r *Subresource) ControllerForCreateUpdate(l object.VirtualDeviceList, ct s
 		return nil, fmt.Errorf("could not find an available %s controller", ct)
 	}
 
-	// Assert that we are on bus 0 when we aren't looking for a SCSI controller.
-	// We currently do not support attaching devices to multiple non-SCSI buses.
-	if ctlr.GetVirtualController().BusNumber != 0 && ct != SubresourceControllerTypeSCSI {
+	// Assert that we are on bus 0 when we aren't looking for a SCSI or IDE
+	// controller. We currently do not support attaching devices to multiple
+	// buses of other controller types.
+	if ctlr.GetVirtualController().BusNumber != 0 && ct != SubresourceControllerTypeSCSI && ct != SubresourceControllerTypeIDE {
 		return nil, fmt.Errorf("there are no available slots on the primary %s controller", ct)
 	}
 	log.Printf("[DEBUG] ControllerForCreateUpdate: Found controller: %s", l.Name(ctlr.(types.BaseVirtualDevice)))
@@ -762,3 +853,77 @@ func AppendDeviceChangeSpec(
 	}
 	return spec
 }
+
+// APIVersion returns the vSphere API version to read and reconfigure virtual
+// machines with. This is the newest version that any of the devices managed
+// by this package were added in, or the version of the server if it is older.
+//
+// The version is chosen from the server rather than from the devices in the
+// configuration or state, as the devices need to be read back before they are
+// known, such as on import.
+func APIVersion(c *govmomi.Client) string {
+	return viapi.NegotiateAPIVersion(c, pciDeviceDynamicAPIVersion)
+}
+
+// EncryptionSchema represents the schema for the encryption settings of
+// virtual machines and virtual disks.
+func EncryptionSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"key_provider_id": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "The ID of the key provider to encrypt with.",
+		},
+		"key_id": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Computed:    true,
+			Description: "The ID of the key to encrypt with. A new key is generated with the key provider if this is not set.",
+		},
+	}
+}
+
+// FlattenCryptoKeyID returns the encryption settings for the supplied key.
+// The key is nil for objects that are not encrypted, in which case the
+// settings are empty.
+func FlattenCryptoKeyID(key *types.CryptoKeyId) []interface{} {
+	if key == nil {
+		return []interface{}{}
+	}
+	var providerID string
+	if key.ProviderId != nil {
+		providerID = key.ProviderId.Id
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"key_provider_id": providerID,
+			"key_id":          key.KeyId,
+		},
+	}
+}
+
+// ExpandCryptoSpec returns the crypto spec that brings an object encrypted
+// with the key in current, or unencrypted if current is nil, to the supplied
+// encryption settings. nil is returned if the object does not need to change.
+func ExpandCryptoSpec(c *govmomi.Client, current *types.CryptoKeyId, settings []interface{}) (types.BaseCryptoSpec, error) {
+	var providerID, keyID string
+	if len(settings) > 0 && settings[0] != nil {
+		m := settings[0].(map[string]interface{})
+		providerID = m["key_provider_id"].(string)
+		keyID = m["key_id"].(string)
+	}
+	return cryptomanager.Spec(c, current, providerID, keyID)
+}
+
+// expandCryptoKeyID returns the key in the supplied encryption settings, or
+// nil if the settings are empty.
+func expandCryptoKeyID(settings []interface{}) *types.CryptoKeyId {
+	if len(settings) < 1 || settings[0] == nil {
+		return nil
+	}
+	m := settings[0].(map[string]interface{})
+	return &types.CryptoKeyId{
+		KeyId:      m["key_id"].(string),
+		ProviderId: &types.KeyProviderId{Id: m["key_provider_id"].(string)},
+	}
+}
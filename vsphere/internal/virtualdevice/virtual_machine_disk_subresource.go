@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math"
 	"path"
 	"reflect"
 	"sort"
@@ -13,7 +12,11 @@ import (
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
 	"github.com/mitchellh/copystructure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/computeresource"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/datastore"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/hostsystem"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/resourcepool"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/storagepod"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
@@ -71,6 +74,44 @@ var diskSubresourceSharingAllowedValues = []string{
 	string(types.VirtualDiskSharingSharingMultiWriter),
 }
 
+var diskControllerTypeAllowedValues = []string{
+	SubresourceControllerTypeSCSI,
+	SubresourceControllerTypeSATA,
+	SubresourceControllerTypeNVMe,
+	SubresourceControllerTypeIDE,
+}
+
+var diskRDMCompatibilityModeAllowedValues = []string{
+	string(types.VirtualDiskCompatibilityModePhysicalMode),
+	string(types.VirtualDiskCompatibilityModeVirtualMode),
+}
+
+// diskControllerUnits is the number of disk unit numbers available on each
+// controller of a specific type. SCSI controllers have 16 units, one of which
+// is taken by the controller itself.
+var diskControllerUnits = map[string]int{
+	SubresourceControllerTypeSCSI: 15,
+	SubresourceControllerTypeSATA: 30,
+	SubresourceControllerTypeNVMe: 15,
+	SubresourceControllerTypeIDE:  2,
+}
+
+// diskControllerMaxCount is the maximum number of controllers of a specific
+// type that disks can be attached to. The number of SCSI controllers is
+// controlled by scsi_controller_count instead.
+var diskControllerMaxCount = map[string]int{
+	SubresourceControllerTypeSATA: 4,
+	SubresourceControllerTypeNVMe: 4,
+	SubresourceControllerTypeIDE:  2,
+}
+
+// diskUnit is a disk's unit_number on a specific controller_type. It's used to
+// check for duplicate units across the set.
+type diskUnit struct {
+	ct     string
+	number int
+}
+
 // DiskSubresourceSchema represents the schema for the disk sub-resource.
 func DiskSubresourceSchema() map[string]*schema.Schema {
 	s := map[string]*schema.Schema{
@@ -143,6 +184,19 @@ func DiskSubresourceSchema() map[string]*schema.Schema {
 			Computed:    true,
 			Description: "The UUID of the virtual disk.",
 		},
+		"storage_policy_id": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Computed:    true,
+			Description: "The ID of the storage policy to assign to the virtual disk.",
+		},
+		"encryption": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Description: "Encrypt the virtual disk with a key from a key provider. Requires encryption to be set on the virtual machine.",
+			Elem:        &schema.Resource{Schema: EncryptionSchema()},
+		},
 
 		// StorageIOAllocationInfo
 		"io_limit": {
@@ -198,8 +252,15 @@ func DiskSubresourceSchema() map[string]*schema.Schema {
 			Type:         schema.TypeInt,
 			Optional:     true,
 			Default:      0,
-			Description:  "The unique device number for this disk. This number determines where on the SCSI bus this device will be attached.",
-			ValidateFunc: validation.IntBetween(0, 59),
+			Description:  "The unique device number for this disk. This number determines where on the bus of the controller_type this device will be attached.",
+			ValidateFunc: validation.IntBetween(0, 119),
+		},
+		"controller_type": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      SubresourceControllerTypeSCSI,
+			Description:  "The type of controller to attach this disk to. Can be one of scsi, sata, nvme, or ide.",
+			ValidateFunc: validation.StringInSlice(diskControllerTypeAllowedValues, false),
 		},
 		"keep_on_remove": {
 			Type:        schema.TypeBool,
@@ -207,6 +268,33 @@ func DiskSubresourceSchema() map[string]*schema.Schema {
 			Default:     false,
 			Description: "Set to true to keep the underlying VMDK file when removing this virtual disk from configuration.",
 		},
+		"rdm": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Description: "Map a LUN to this disk as a raw device mapping, instead of creating a virtual disk.",
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"lun_name": {
+						Type:        schema.TypeString,
+						Required:    true,
+						Description: "The canonical name of the LUN to map, such as naa.60003ff44dc75adc8ba0f4f0c2c2c1b2.",
+					},
+					"compatibility_mode": {
+						Type:         schema.TypeString,
+						Optional:     true,
+						Default:      string(types.VirtualDiskCompatibilityModePhysicalMode),
+						Description:  "The compatibility mode of the raw device mapping. Can be one of physicalMode or virtualMode.",
+						ValidateFunc: validation.StringInSlice(diskRDMCompatibilityModeAllowedValues, false),
+					},
+					"lun_uuid": {
+						Type:        schema.TypeString,
+						Computed:    true,
+						Description: "The UUID of the mapped LUN.",
+					},
+				},
+			},
+		},
 		"attach": {
 			Type:          schema.TypeBool,
 			Optional:      true,
@@ -409,7 +497,7 @@ func diskApplyOperationCreateUpdate(
 // returned, all necessary values are just set and committed to state.
 func DiskRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) error {
 	log.Printf("[DEBUG] DiskRefreshOperation: Beginning refresh")
-	devices := SelectDisks(l, d.Get("scsi_controller_count").(int))
+	devices := SelectDisks(l, d.Get("scsi_controller_count").(int), diskControllerTypesInUse(d)...)
 	log.Printf("[DEBUG] DiskRefreshOperation: Disk devices located: %s", DeviceListString(devices))
 	curSet := d.Get(subresourceTypeDisk).([]interface{})
 	log.Printf("[DEBUG] DiskRefreshOperation: Current resource set from state: %s", subresourceListString(curSet))
@@ -567,7 +655,8 @@ func DiskDiffOperation(d *schema.ResourceDiff, c *govmomi.Client) error {
 	log.Printf("[DEBUG] DiskDiffOperation: Beginning collective diff validation (indexes aligned to new config)")
 	names := make(map[string]struct{})
 	attachments := make(map[string]struct{})
-	units := make(map[int]struct{})
+	units := make(map[diskUnit]struct{})
+	var hasUnitZero bool
 	if len(n.([]interface{})) < 1 {
 		return errors.New("there must be at least one disk specified")
 	}
@@ -592,17 +681,21 @@ func DiskDiffOperation(d *schema.ResourceDiff, c *govmomi.Client) error {
 			attachments[path] = struct{}{}
 		}
 
-		if _, ok := units[nm["unit_number"].(int)]; ok {
-			return fmt.Errorf("disk: duplicate unit_number %d", nm["unit_number"].(int))
+		unit := diskUnit{ct: diskControllerType(nm), number: nm["unit_number"].(int)}
+		if _, ok := units[unit]; ok {
+			return fmt.Errorf("disk: duplicate unit_number %d on %s controllers", unit.number, unit.ct)
 		}
 		names[name] = struct{}{}
-		units[nm["unit_number"].(int)] = struct{}{}
+		units[unit] = struct{}{}
+		if unit.number == 0 {
+			hasUnitZero = true
+		}
 		r := NewDiskSubresource(c, d, nm, nil, ni)
 		if err := r.DiffGeneral(); err != nil {
 			return fmt.Errorf("%s: %s", r.Addr(), err)
 		}
 	}
-	if _, ok := units[0]; !ok {
+	if !hasUnitZero {
 		return errors.New("at least one disk must have a unit_number of 0")
 	}
 
@@ -695,7 +788,7 @@ nextNew:
 // existing state.
 func DiskCloneValidateOperation(d *schema.ResourceDiff, c *govmomi.Client, l object.VirtualDeviceList, linked bool) error {
 	log.Printf("[DEBUG] DiskCloneValidateOperation: Checking existing virtual disk configuration")
-	devices := SelectDisks(l, d.Get("scsi_controller_count").(int))
+	devices := SelectDisks(l, d.Get("scsi_controller_count").(int), diskControllerTypesInUse(d)...)
 	// Sort the device list, in case it's not sorted already.
 	devSort := virtualDeviceListSorter{
 		Sort:       devices,
@@ -764,11 +857,11 @@ func DiskCloneValidateOperation(d *schema.ResourceDiff, c *govmomi.Client, l obj
 		case linked:
 			switch {
 			case sourceSize != targetSize:
-				return fmt.Errorf("%s: disk name %s must be the exact size of source when using linked_clone (expected: %d GiB)", tr.Addr(), targetName, sourceSize)
+				return fmt.Errorf("%s: disk name %s must be the exact size of source when using linked_clone or instant_clone (expected: %d GiB)", tr.Addr(), targetName, sourceSize)
 			case sourceThin != targetThin:
-				return fmt.Errorf("%s: disk name %s must have same value for thin_provisioned as source when using linked_clone (expected: %t)", tr.Addr(), targetName, sourceThin)
+				return fmt.Errorf("%s: disk name %s must have same value for thin_provisioned as source when using linked_clone or instant_clone (expected: %t)", tr.Addr(), targetName, sourceThin)
 			case sourceEager != targetEager:
-				return fmt.Errorf("%s: disk name %s must have same value for eagerly_scrub as source when using linked_clone (expected: %t)", tr.Addr(), targetName, sourceEager)
+				return fmt.Errorf("%s: disk name %s must have same value for eagerly_scrub as source when using linked_clone or instant_clone (expected: %t)", tr.Addr(), targetName, sourceEager)
 			}
 		default:
 			if sourceSize > targetSize {
@@ -776,14 +869,15 @@ func DiskCloneValidateOperation(d *schema.ResourceDiff, c *govmomi.Client, l obj
 			}
 		}
 
-		// Finally, we don't support non-SCSI (ie: SATA, IDE, NVMe) disks, so kick
-		// back an error if we see one of those.
+		// Finally, the disk needs to be on the same type of controller in
+		// configuration as it is in the source, as disks are matched up by
+		// controller type and unit number.
 		ct, _, _, err := splitDevAddr(r.DevAddr())
 		if err != nil {
 			return fmt.Errorf("%s: error parsing device address after reading disk %q: %s", tr.Addr(), targetPath, err)
 		}
-		if ct != SubresourceControllerTypeSCSI {
-			return fmt.Errorf("%s: unsupported controller type %s for disk %q. Please use a template with SCSI disks only", tr.Addr(), ct, targetPath)
+		if targetCt := diskControllerType(tr.Data()); ct != targetCt {
+			return fmt.Errorf("%s: disk name %s must have same value for controller_type as source disk %q (expected: %s, got: %s)", tr.Addr(), targetName, targetPath, ct, targetCt)
 		}
 	}
 	log.Printf("[DEBUG] DiskCloneValidateOperation: All disks in source validated successfully")
@@ -868,7 +962,7 @@ func DiskMigrateRelocateOperation(d *schema.ResourceData, c *govmomi.Client, l o
 // configurations fully in sync with what is defined.
 func DiskCloneRelocateOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) ([]types.VirtualMachineRelocateSpecDiskLocator, error) {
 	log.Printf("[DEBUG] DiskCloneRelocateOperation: Generating full disk relocate spec list")
-	devices := SelectDisks(l, d.Get("scsi_controller_count").(int))
+	devices := SelectDisks(l, d.Get("scsi_controller_count").(int), diskControllerTypesInUse(d)...)
 	log.Printf("[DEBUG] DiskCloneRelocateOperation: Disk devices located: %s", DeviceListString(devices))
 	// Sort the device list, in case it's not sorted already.
 	devSort := virtualDeviceListSorter{
@@ -929,7 +1023,7 @@ func DiskCloneRelocateOperation(d *schema.ResourceData, c *govmomi.Client, l obj
 // virtual device operations rely pretty heavily on.
 func DiskPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
 	log.Printf("[DEBUG] DiskPostCloneOperation: Looking for disk device changes post-clone")
-	devices := SelectDisks(l, d.Get("scsi_controller_count").(int))
+	devices := SelectDisks(l, d.Get("scsi_controller_count").(int), diskControllerTypesInUse(d)...)
 	log.Printf("[DEBUG] DiskPostCloneOperation: Disk devices located: %s", DeviceListString(devices))
 	// Sort the device list, in case it's not sorted already.
 	devSort := virtualDeviceListSorter{
@@ -945,6 +1039,12 @@ func DiskPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.
 	sort.Sort(virtualDiskSubresourceSorter(curSet))
 	log.Printf("[DEBUG] DiskPostCloneOperation: Resource set order after sort: %s", subresourceListString(curSet))
 
+	// Sources that are not validated ahead of time, such as OVF deploys, may
+	// come with more disks than what is in the configuration.
+	if len(devices) > len(curSet) {
+		return nil, nil, fmt.Errorf("not enough disks in configuration - you need at least %d to use this source (current: %d)", len(devices), len(curSet))
+	}
+
 	var spec []types.BaseVirtualDeviceConfigSpec
 	var updates []interface{}
 
@@ -968,6 +1068,9 @@ func DiskPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.
 		if err != nil {
 			return nil, nil, fmt.Errorf("error copying source set for disk at unit_number %d: %s", src["unit_number"].(int), err)
 		}
+		// The storage policy is not read back by the sub-resource, so clear it in
+		// the old set to ensure that any policy in configuration is applied.
+		old.(map[string]interface{})["storage_policy_id"] = ""
 		rOld := NewDiskSubresource(c, d, old.(map[string]interface{}), nil, i)
 		if err := rOld.Read(l); err != nil {
 			return nil, nil, fmt.Errorf("%s: %s", rOld.Addr(), err)
@@ -1029,10 +1132,13 @@ func DiskPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.
 // DiskImportOperation validates the disk configuration of the virtual
 // machine's VirtualDeviceList to ensure it will be imported properly, and also
 // saves device addresses into state for disks defined in config. Both the
-// imported device list is sorted by the device's unit number on the SCSI bus.
+// imported device list is sorted by controller type, and then by the device's
+// unit number on the controller's bus.
 func DiskImportOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) error {
 	log.Printf("[DEBUG] DiskImportOperation: Performing pre-read import and validation of virtual disks")
-	devices := SelectDisks(l, d.Get("scsi_controller_count").(int))
+	// Disks on all of the supported controller types are imported, and end up
+	// in state with their controller_type.
+	devices := SelectDisks(l, d.Get("scsi_controller_count").(int), diskControllerTypeAllowedValues...)
 	// Sort the device list, in case it's not sorted already.
 	devSort := virtualDeviceListSorter{
 		Sort:       devices,
@@ -1044,7 +1150,8 @@ func DiskImportOperation(d *schema.ResourceData, c *govmomi.Client, l object.Vir
 	log.Printf("[DEBUG] DiskImportOperation: Disk devices order after sort: %s", DeviceListString(devices))
 
 	// Read in the disks. We don't do anything with the results here other than
-	// validate that the disks are SCSI disks. The read operation validates the rest.
+	// validate that the disks are on supported controllers. The read operation
+	// validates the rest.
 	var curSet []interface{}
 	log.Printf("[DEBUG] DiskImportOperation: Validating disk type and saving ")
 	for i, device := range devices {
@@ -1061,16 +1168,15 @@ func DiskImportOperation(d *schema.ResourceData, c *govmomi.Client, l object.Vir
 		if err != nil {
 			return fmt.Errorf("disk.%d: error parsing device address %s: %s", i, addr, err)
 		}
-		if ct != SubresourceControllerTypeSCSI {
-			return fmt.Errorf("disk.%d: unsupported controller type %s for disk %s. The VM resource supports SCSI disks only", i, ct, addr)
-		}
 		// As one final validation, as we are no longer reading here, validate that
-		// this is a VMDK-backed virtual disk to make sure we aren't importing RDM
-		// disks or what not. The device should have already been validated as a
-		// virtual disk via SelectDisks.
-		if _, ok := device.(*types.VirtualDisk).Backing.(*types.VirtualDiskFlatVer2BackingInfo); !ok {
+		// this is a VMDK-backed virtual disk or raw device mapping to make sure we
+		// aren't importing disks with other backings. The device should have
+		// already been validated as a virtual disk via SelectDisks.
+		switch device.(*types.VirtualDisk).Backing.(type) {
+		case *types.VirtualDiskFlatVer2BackingInfo, *types.VirtualDiskRawDiskMappingVer1BackingInfo:
+		default:
 			return fmt.Errorf(
-				"disk.%d: unsupported disk type at %s (expected flat VMDK version 2, got %T)",
+				"disk.%d: unsupported disk type at %s (expected flat VMDK version 2 or raw device mapping, got %T)",
 				i,
 				addr,
 				device.(*types.VirtualDisk).Backing,
@@ -1083,6 +1189,7 @@ func DiskImportOperation(d *schema.ResourceData, c *govmomi.Client, l object.Vir
 		// the device address.
 		m["key"] = (i + 1) * -1
 		m["device_address"] = addr
+		m["controller_type"] = ct
 		// Assign a computed label. This label *needs* be the label this disk is
 		// assigned in config, or you risk service interruptions or data corruption.
 		m["label"] = fmt.Sprintf("disk%d", i)
@@ -1108,7 +1215,7 @@ func DiskImportOperation(d *schema.ResourceData, c *govmomi.Client, l object.Vir
 // order that they would be added in if a clone were to be done.
 func ReadDiskAttrsForDataSource(l object.VirtualDeviceList, count int) ([]map[string]interface{}, error) {
 	log.Printf("[DEBUG] ReadDiskAttrsForDataSource: Fetching select attributes for disks across %d SCSI controllers", count)
-	devices := SelectDisks(l, count)
+	devices := SelectDisks(l, count, diskControllerTypeAllowedValues...)
 	log.Printf("[DEBUG] ReadDiskAttrsForDataSource: Disk devices located: %s", DeviceListString(devices))
 	// Sort the device list, in case it's not sorted already.
 	devSort := virtualDeviceListSorter{
@@ -1133,9 +1240,14 @@ func ReadDiskAttrsForDataSource(l object.VirtualDeviceList, count int) ([]map[st
 		if backing.ThinProvisioned != nil {
 			thin = *backing.ThinProvisioned
 		}
+		ct, err := diskControllerClass(l, disk)
+		if err != nil {
+			return nil, fmt.Errorf("disk number %d: %s", i, err)
+		}
 		m["size"] = diskCapacityInGiB(disk)
 		m["eagerly_scrub"] = eager
 		m["thin_provisioned"] = thin
+		m["controller_type"] = ct
 		out = append(out, m)
 	}
 	log.Printf("[DEBUG] ReadDiskAttrsForDataSource: Attributes returned: %+v", out)
@@ -1153,10 +1265,11 @@ func (r *DiskSubresource) Create(l object.VirtualDeviceList) ([]types.BaseVirtua
 	}
 	// We now have the controller on which we can create our device on.
 	// Assign the disk to a controller.
-	ctlr, err := r.assignDisk(l, disk)
+	ctlr, cspec, err := r.assignDisk(l, disk)
 	if err != nil {
 		return nil, fmt.Errorf("cannot assign disk: %s", err)
 	}
+	spec = append(spec, cspec...)
 
 	if err := r.expandDiskSettings(disk); err != nil {
 		return nil, err
@@ -1177,6 +1290,16 @@ func (r *DiskSubresource) Create(l object.VirtualDeviceList) ([]types.BaseVirtua
 	if r.Get("attach").(bool) {
 		dspec[0].GetVirtualDeviceConfigSpec().FileOperation = ""
 	}
+	if policyID := r.Get("storage_policy_id").(string); policyID != "" {
+		dspec[0].GetVirtualDeviceConfigSpec().Profile = spbm.PolicySpecByID(policyID)
+	}
+	crypto, err := ExpandCryptoSpec(r.client, nil, diskEncryption(r.data))
+	if err != nil {
+		return nil, fmt.Errorf("error expanding encryption settings: %s", err)
+	}
+	if crypto != nil {
+		dspec[0].GetVirtualDeviceConfigSpec().Backing = &types.VirtualDeviceConfigSpecBackingSpec{Crypto: crypto}
+	}
 	spec = append(spec, dspec...)
 	log.Printf("[DEBUG] %s: Device config operations from create: %s", r, DeviceChangeString(spec))
 	log.Printf("[DEBUG] %s: Create finished", r)
@@ -1195,11 +1318,26 @@ func (r *DiskSubresource) Read(l object.VirtualDeviceList) error {
 	if err != nil {
 		return err
 	}
+	ct, err := controllerTypeToClass(ctlr)
+	if err != nil {
+		return err
+	}
 	r.Set("unit_number", unit)
+	r.Set("controller_type", ct)
 	if err := r.SaveDevIDs(disk, ctlr); err != nil {
 		return err
 	}
 
+	// Raw device mappings have a backing of their own.
+	if b, ok := disk.Backing.(*types.VirtualDiskRawDiskMappingVer1BackingInfo); ok {
+		if err := r.readRDMBacking(disk, b); err != nil {
+			return err
+		}
+		r.readStorageIOAllocation(disk)
+		log.Printf("[DEBUG] %s: Read finished (key and device address may have changed)", r)
+		return nil
+	}
+
 	// Fetch disk attachment state in config
 	var attach bool
 	if r.Get("attach") != nil {
@@ -1213,6 +1351,7 @@ func (r *DiskSubresource) Read(l object.VirtualDeviceList) error {
 	r.Set("uuid", b.Uuid)
 	r.Set("disk_mode", b.DiskMode)
 	r.Set("write_through", b.WriteThrough)
+	r.Set("encryption", FlattenCryptoKeyID(b.KeyId))
 
 	// Only use disk_sharing if we are on vSphere 6.0 and higher. In addition,
 	// skip if the value is unset - this prevents spurious diffs during upgrade
@@ -1240,6 +1379,13 @@ func (r *DiskSubresource) Read(l object.VirtualDeviceList) error {
 		r.Set("size", diskCapacityInGiB(disk))
 	}
 
+	r.readStorageIOAllocation(disk)
+	log.Printf("[DEBUG] %s: Read finished (key and device address may have changed)", r)
+	return nil
+}
+
+// readStorageIOAllocation reads the storage I/O settings of the disk.
+func (r *DiskSubresource) readStorageIOAllocation(disk *types.VirtualDisk) {
 	if allocation := disk.StorageIOAllocation; allocation != nil {
 		r.Set("io_limit", allocation.Limit)
 		r.Set("io_reservation", allocation.Reservation)
@@ -1248,7 +1394,56 @@ func (r *DiskSubresource) Read(l object.VirtualDeviceList) error {
 			r.Set("io_share_count", shares.Shares)
 		}
 	}
-	log.Printf("[DEBUG] %s: Read finished (key and device address may have changed)", r)
+}
+
+// readRDMBacking reads the settings of a raw device mapping backing. The LUN
+// name is kept as it is in state if the LUN has not changed, otherwise it is
+// looked up by the LUN UUID.
+func (r *DiskSubresource) readRDMBacking(disk *types.VirtualDisk, b *types.VirtualDiskRawDiskMappingVer1BackingInfo) error {
+	r.Set("uuid", b.Uuid)
+	// Physical compatibility mode passes commands through to the LUN, so the
+	// disk mode does not apply.
+	if b.CompatibilityMode != string(types.VirtualDiskCompatibilityModePhysicalMode) {
+		r.Set("disk_mode", b.DiskMode)
+	}
+	version := viapi.ParseVersionFromClient(r.client)
+	if version.Newer(viapi.VSphereVersion{Product: version.Product, Major: 6}) && b.Sharing != "" {
+		r.Set("disk_sharing", b.Sharing)
+	}
+	if b.Datastore != nil {
+		r.Set("datastore_id", b.Datastore.Value)
+	}
+	dp := &object.DatastorePath{}
+	if ok := dp.FromString(b.FileName); !ok {
+		return fmt.Errorf("could not parse path from filename: %s", b.FileName)
+	}
+	r.Set("path", dp.Path)
+	r.Set("size", diskCapacityInGiB(disk))
+
+	var name string
+	if rdm := diskRDM(r.data); rdm != nil && rdm["lun_uuid"] == b.LunUuid {
+		name = rdm["lun_name"].(string)
+	}
+	if name == "" {
+		lun, err := r.findRDMLun(func(lun *types.HostScsiDisk) bool { return lun.Uuid == b.LunUuid })
+		switch {
+		case err != nil:
+			log.Printf("[DEBUG] %s: Could not look up LUN UUID %s, using device name: %s", r, b.LunUuid, err)
+			name = path.Base(b.DeviceName)
+		case lun == nil:
+			log.Printf("[DEBUG] %s: LUN UUID %s not found, using device name", r, b.LunUuid)
+			name = path.Base(b.DeviceName)
+		default:
+			name = lun.CanonicalName
+		}
+	}
+	r.Set("rdm", []interface{}{
+		map[string]interface{}{
+			"lun_name":           name,
+			"compatibility_mode": b.CompatibilityMode,
+			"lun_uuid":           b.LunUuid,
+		},
+	})
 	return nil
 }
 
@@ -1260,13 +1455,19 @@ func (r *DiskSubresource) Update(l object.VirtualDeviceList) ([]types.BaseVirtua
 		return nil, fmt.Errorf("cannot find disk device: %s", err)
 	}
 
-	// Has the unit number changed?
-	if r.HasChange("unit_number") {
-		ctlr, err := r.assignDisk(l, disk)
+	var spec []types.BaseVirtualDeviceConfigSpec
+	// Has the unit number or controller type changed?
+	if r.HasChange("unit_number") || r.controllerTypeChanged() {
+		ctlr, cspec, err := r.assignDisk(l, disk)
 		if err != nil {
 			return nil, fmt.Errorf("cannot assign disk: %s", err)
 		}
-		r.SetRestart("unit_number")
+		spec = append(spec, cspec...)
+		if r.HasChange("unit_number") {
+			r.SetRestart("unit_number")
+		} else {
+			r.SetRestart("controller_type")
+		}
 		if err := r.SaveDevIDs(disk, ctlr); err != nil {
 			return nil, fmt.Errorf("error saving device address: %s", err)
 		}
@@ -1292,9 +1493,36 @@ func (r *DiskSubresource) Update(l object.VirtualDeviceList) ([]types.BaseVirtua
 	}
 	// Clear file operation - VirtualDeviceList currently sets this to replace, which is invalid
 	dspec[0].GetVirtualDeviceConfigSpec().FileOperation = ""
-	log.Printf("[DEBUG] %s: Device config operations from update: %s", r, DeviceChangeString(dspec))
+	if r.HasChange("storage_policy_id") {
+		if policyID := r.Get("storage_policy_id").(string); policyID != "" {
+			dspec[0].GetVirtualDeviceConfigSpec().Profile = spbm.PolicySpecByID(policyID)
+		}
+	}
+	// Encrypting, decrypting, and re-keying a disk can only be done while the
+	// virtual machine is powered off.
+	if r.HasChange("encryption") {
+		crypto, err := ExpandCryptoSpec(r.client, expandCryptoKeyID(diskEncryption(r.olddata)), diskEncryption(r.data))
+		if err != nil {
+			return nil, fmt.Errorf("error expanding encryption settings: %s", err)
+		}
+		if crypto != nil {
+			dspec[0].GetVirtualDeviceConfigSpec().Backing = &types.VirtualDeviceConfigSpecBackingSpec{Crypto: crypto}
+			r.SetRestart("encryption")
+		}
+	}
+	spec = append(spec, dspec...)
+	log.Printf("[DEBUG] %s: Device config operations from update: %s", r, DeviceChangeString(spec))
 	log.Printf("[DEBUG] %s: Update complete", r)
-	return dspec, nil
+	return spec, nil
+}
+
+// controllerTypeChanged returns true if the controller_type of the disk has
+// changed. Disks without a controller_type in state are SCSI disks.
+func (r *DiskSubresource) controllerTypeChanged() bool {
+	if r.olddata == nil {
+		return false
+	}
+	return diskControllerType(r.olddata) != diskControllerType(r.data)
 }
 
 // Delete deletes a vsphere_virtual_machine disk sub-resource.
@@ -1382,12 +1610,37 @@ func (r *DiskSubresource) DiffExisting() error {
 		}
 	}
 
+	// Carry forward the storage policy if one is not defined, as the disk keeps
+	// whatever policy it currently has in that case.
+	if r.Get("storage_policy_id").(string) == "" {
+		opolicy, _ := r.GetChange("storage_policy_id")
+		r.Set("storage_policy_id", opolicy)
+	}
+
 	// Preserve the share value if we don't have custom shares set
 	osc, _ := r.GetChange("io_share_count")
 	if r.Get("io_share_level").(string) != string(types.SharesLevelCustom) {
 		r.Set("io_share_count", osc)
 	}
 
+	// The LUN and compatibility mode of a raw device mapping cannot be changed
+	// once set. Neither can a disk be converted to or from one.
+	ordm, nrdm := diskRDM(r.olddata), diskRDM(r.data)
+	switch {
+	case ordm == nil && nrdm == nil:
+	case ordm == nil || nrdm == nil:
+		return fmt.Errorf("virtual disk %q: cannot add or remove rdm on an existing disk", name)
+	case ordm["lun_name"] != nrdm["lun_name"] || ordm["compatibility_mode"] != nrdm["compatibility_mode"]:
+		return fmt.Errorf("virtual disk %q: cannot change the LUN or compatibility mode of a raw device mapping (old: %v/%v new: %v/%v)", name, ordm["lun_name"], ordm["compatibility_mode"], nrdm["lun_name"], nrdm["compatibility_mode"])
+	default:
+		// The size of a raw device mapping is the size of its LUN, and the LUN
+		// UUID is only known after it has been looked up, so carry both of these
+		// forward.
+		osize, _ := r.GetChange("size")
+		r.Set("size", osize)
+		nrdm["lun_uuid"] = ordm["lun_uuid"]
+	}
+
 	// Ensure that the user is not attempting to shrink the disk. If we do more
 	// we might want to change the name of this method, but we want to check this
 	// here as CustomizeDiff is meant for vetoing.
@@ -1428,13 +1681,22 @@ func (r *DiskSubresource) DiffGeneral() error {
 		return err
 	}
 
-	// Enforce the maximum unit number, which is the current value of
-	// scsi_controller_count * 15 - 1.
-	ctlrCount := r.rdd.Get("scsi_controller_count").(int)
-	maxUnit := ctlrCount*15 - 1
+	// Enforce the maximum unit number. For SCSI disks, this is the current
+	// value of scsi_controller_count * 15 - 1. Other controller types have a
+	// fixed maximum number of controllers.
 	currentUnit := r.Get("unit_number").(int)
-	if currentUnit > maxUnit {
-		return fmt.Errorf("unit_number on disk %q too high (%d) - maximum value is %d with %d SCSI controller(s)", name, currentUnit, maxUnit, ctlrCount)
+	switch ct := diskControllerType(r.data); ct {
+	case SubresourceControllerTypeSCSI:
+		ctlrCount := r.rdd.Get("scsi_controller_count").(int)
+		maxUnit := ctlrCount*diskControllerUnits[ct] - 1
+		if currentUnit > maxUnit {
+			return fmt.Errorf("unit_number on disk %q too high (%d) - maximum value is %d with %d SCSI controller(s)", name, currentUnit, maxUnit, ctlrCount)
+		}
+	default:
+		maxUnit := diskControllerMaxCount[ct]*diskControllerUnits[ct] - 1
+		if currentUnit > maxUnit {
+			return fmt.Errorf("unit_number on disk %q too high (%d) - maximum value is %d for %s disks", name, currentUnit, maxUnit, ct)
+		}
 	}
 
 	if r.Get("attach").(bool) {
@@ -1448,6 +1710,15 @@ func (r *DiskSubresource) DiffGeneral() error {
 		case r.Get("keep_on_remove").(bool):
 			return fmt.Errorf("keep_on_remove for disk %q is implicit when attach is set, please remove this setting", name)
 		}
+	} else if diskRDM(r.data) != nil {
+		switch {
+		case r.Get("size").(int) > 0:
+			return fmt.Errorf("size for disk %q cannot be defined when rdm is set", name)
+		case r.Get("eagerly_scrub").(bool):
+			return fmt.Errorf("eagerly_scrub for disk %q cannot be defined when rdm is set", name)
+		case r.rdd.Get("datastore_cluster_id").(string) != "":
+			return fmt.Errorf("rdm for disk %q cannot be used when datastore_cluster_id is set", name)
+		}
 	} else {
 		// Enforce size as a required field when attach is not set
 		if r.Get("size").(int) < 1 {
@@ -1461,6 +1732,20 @@ func (r *DiskSubresource) DiffGeneral() error {
 			return fmt.Errorf("multi-writer disk_sharing is only supported on vSphere 6 and higher")
 		}
 	}
+	if r.Get("storage_policy_id").(string) != "" {
+		if err := viapi.ValidateVirtualCenter(r.client); err != nil {
+			return fmt.Errorf("storage_policy_id for disk %q requires vCenter", name)
+		}
+	}
+	// Encrypted disks can only be attached to encrypted virtual machines.
+	if len(diskEncryption(r.data)) > 0 {
+		switch {
+		case diskRDM(r.data) != nil:
+			return fmt.Errorf("encryption for disk %q cannot be used when rdm is set", name)
+		case len(r.rdd.Get("encryption").([]interface{})) < 1:
+			return fmt.Errorf("encryption for disk %q requires encryption to be set on the virtual machine", name)
+		}
+	}
 	// Prevent eagerly_scrub and thin_provisioned from both being set to true. A
 	// thin_provisioned disk cannot be eagerly scrubbed since it would then be
 	// allocating the entire disk.
@@ -1589,14 +1874,20 @@ func (r *DiskSubresource) Relocate(l object.VirtualDeviceList, clone bool) (type
 	dsref := ds.Reference()
 	relocate.Datastore = dsref
 
+	// Keep the disk on its storage policy, or move it to a new one, as part of
+	// the relocation.
+	if policyID := r.Get("storage_policy_id").(string); policyID != "" {
+		relocate.Profile = spbm.PolicySpecByID(policyID)
+	}
+
 	// Add additional backing options if we are cloning.
 	if r.rdd.Id() == "" {
 		log.Printf("[DEBUG] %s: Adding additional options to relocator for cloning", r)
 
-		backing := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+		backing := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo).GetVirtualDeviceFileBackingInfo()
 		backing.FileName = ds.Path("")
 		backing.Datastore = &dsref
-		relocate.DiskBackingInfo = backing
+		relocate.DiskBackingInfo = disk.Backing
 	}
 
 	// Done!
@@ -1620,6 +1911,19 @@ func (r *DiskSubresource) String() string {
 // used during Create and Update to set attributes to those found in
 // configuration.
 func (r *DiskSubresource) expandDiskSettings(disk *types.VirtualDisk) error {
+	// Raw device mappings only take some of the settings of virtual disks.
+	if b, ok := disk.Backing.(*types.VirtualDiskRawDiskMappingVer1BackingInfo); ok {
+		if b.CompatibilityMode != string(types.VirtualDiskCompatibilityModePhysicalMode) {
+			b.DiskMode = r.GetWithRestart("disk_mode").(string)
+		}
+		version := viapi.ParseVersionFromClient(r.client)
+		if version.Newer(viapi.VSphereVersion{Product: version.Product, Major: 6}) {
+			b.Sharing = r.GetWithRestart("disk_sharing").(string)
+		}
+		disk.StorageIOAllocation = r.expandStorageIOAllocation()
+		return nil
+	}
+
 	// Backing settings
 	b := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
 	b.DiskMode = r.GetWithRestart("disk_mode").(string)
@@ -1654,7 +1958,14 @@ func (r *DiskSubresource) expandDiskSettings(disk *types.VirtualDisk) error {
 		disk.CapacityInKB = disk.CapacityInBytes / 1024
 	}
 
-	alloc := &types.StorageIOAllocationInfo{
+	disk.StorageIOAllocation = r.expandStorageIOAllocation()
+
+	return nil
+}
+
+// expandStorageIOAllocation reads the storage I/O settings of the disk.
+func (r *DiskSubresource) expandStorageIOAllocation() *types.StorageIOAllocationInfo {
+	return &types.StorageIOAllocationInfo{
 		Limit:       structure.Int64Ptr(int64(r.Get("io_limit").(int))),
 		Reservation: structure.Int32Ptr(int32(r.Get("io_reservation").(int))),
 		Shares: &types.SharesInfo{
@@ -1662,15 +1973,16 @@ func (r *DiskSubresource) expandDiskSettings(disk *types.VirtualDisk) error {
 			Level:  types.SharesLevel(r.Get("io_share_level").(string)),
 		},
 	}
-	disk.StorageIOAllocation = alloc
-
-	return nil
 }
 
 // createDisk performs all of the logic for a base virtual disk creation.
 func (r *DiskSubresource) createDisk(l object.VirtualDeviceList) (*types.VirtualDisk, error) {
 	disk := new(types.VirtualDisk)
-	disk.Backing = new(types.VirtualDiskFlatVer2BackingInfo)
+	if diskRDM(r.data) != nil {
+		disk.Backing = new(types.VirtualDiskRawDiskMappingVer1BackingInfo)
+	} else {
+		disk.Backing = new(types.VirtualDiskFlatVer2BackingInfo)
+	}
 
 	// Only assign backing info if a datastore cluster is not specified. If one
 	// is, skip this step.
@@ -1706,35 +2018,132 @@ func (r *DiskSubresource) assignBackingInfo(disk *types.VirtualDisk) error {
 		diskName = diskPathOrName(r.data)
 	}
 
-	backing := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+	backing := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo).GetVirtualDeviceFileBackingInfo()
 	backing.FileName = ds.Path(diskName)
 	backing.Datastore = &dsref
 
+	if b, ok := disk.Backing.(*types.VirtualDiskRawDiskMappingVer1BackingInfo); ok {
+		return r.assignRDMBacking(b)
+	}
+	return nil
+}
+
+// assignRDMBacking looks up the LUN in the disk's rdm settings and assigns it
+// to the raw device mapping backing, along with the compatibility mode. The
+// mapping file is created on the datastore already in the backing.
+func (r *DiskSubresource) assignRDMBacking(b *types.VirtualDiskRawDiskMappingVer1BackingInfo) error {
+	rdm := diskRDM(r.data)
+	name := rdm["lun_name"].(string)
+	lun, err := r.findRDMLun(func(lun *types.HostScsiDisk) bool { return lun.CanonicalName == name })
+	if err != nil {
+		return fmt.Errorf("error looking up LUN %q: %s", name, err)
+	}
+	if lun == nil {
+		return fmt.Errorf("LUN %q not found", name)
+	}
+	b.DeviceName = lun.DevicePath
+	b.LunUuid = lun.Uuid
+	b.CompatibilityMode = rdm["compatibility_mode"].(string)
+	r.Set("rdm", []interface{}{
+		map[string]interface{}{
+			"lun_name":           name,
+			"compatibility_mode": b.CompatibilityMode,
+			"lun_uuid":           lun.Uuid,
+		},
+	})
 	return nil
 }
 
-// assignDisk takes a unit number and assigns it correctly to a controller on
-// the SCSI bus. An error is returned if the assigned unit number is taken.
-func (r *DiskSubresource) assignDisk(l object.VirtualDeviceList, disk *types.VirtualDisk) (types.BaseVirtualController, error) {
+// findRDMLun returns the first SCSI disk visible to the virtual machine's host
+// that matches the supplied function, or nil if there is no match. New
+// virtual machines without a host_system_id use the first host of the
+// resource pool's cluster or standalone host, as LUNs for raw device mappings
+// need to be visible to all hosts the virtual machine can run on.
+func (r *DiskSubresource) findRDMLun(match func(*types.HostScsiDisk) bool) (*types.HostScsiDisk, error) {
+	var host *object.HostSystem
+	if id, ok := r.rdd.Get("host_system_id").(string); ok && id != "" {
+		var err error
+		if host, err = hostsystem.FromID(r.client, id); err != nil {
+			return nil, err
+		}
+	} else {
+		pool, err := resourcepool.FromID(r.client, r.rdd.Get("resource_pool_id").(string))
+		if err != nil {
+			return nil, err
+		}
+		pprops, err := resourcepool.Properties(pool)
+		if err != nil {
+			return nil, err
+		}
+		cprops, err := computeresource.BasePropertiesFromReference(r.client, pprops.Owner)
+		if err != nil {
+			return nil, err
+		}
+		if len(cprops.Host) < 1 {
+			return nil, fmt.Errorf("no hosts found in resource pool %q", pool.Reference().Value)
+		}
+		host = object.NewHostSystem(r.client.Client, cprops.Host[0])
+	}
+	disks, err := hostsystem.ScsiDisks(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, disk := range disks {
+		if match(disk) {
+			return disk, nil
+		}
+	}
+	return nil, nil
+}
+
+// diskRDM returns the rdm settings for a set of disk sub-resource data, or nil
+// if the disk is not a raw device mapping.
+func diskRDM(data map[string]interface{}) map[string]interface{} {
+	if l, ok := data["rdm"].([]interface{}); ok && len(l) > 0 && l[0] != nil {
+		return l[0].(map[string]interface{})
+	}
+	return nil
+}
+
+// diskEncryption returns the encryption settings of the supplied disk data.
+// The settings are empty for disks that are not encrypted.
+func diskEncryption(data map[string]interface{}) []interface{} {
+	l, _ := data["encryption"].([]interface{})
+	return l
+}
+
+// assignDisk takes a unit number and assigns it correctly to a controller of
+// the disk's controller_type. An error is returned if the assigned unit number
+// is taken. If a SATA or NVMe controller needs to be created for the disk, the
+// config spec to add it is returned along with the controller.
+func (r *DiskSubresource) assignDisk(l object.VirtualDeviceList, disk *types.VirtualDisk) (types.BaseVirtualController, []types.BaseVirtualDeviceConfigSpec, error) {
+	ct := diskControllerType(r.Data())
 	number := r.Get("unit_number").(int)
-	// Figure out the bus number, and look up the SCSI controller that matches
-	// that. You can attach 15 disks to a SCSI controller, and we allow a maximum
-	// of 30 devices.
-	bus := number / 15
+	// Figure out the bus number, and look up the controller that matches that.
+	// The number of disks that can be attached to a controller depends on its
+	// type.
+	perCtlr := diskControllerUnits[ct]
+	bus := number / perCtlr
 	// Also determine the unit number on that controller.
-	unit := int32(math.Mod(float64(number), 15))
+	unit := int32(number % perCtlr)
 
 	// Find the controller.
-	ctlr, err := r.ControllerForCreateUpdate(l, SubresourceControllerTypeSCSI, bus)
+	ctlr, spec, err := r.diskController(l, ct, bus)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Build the unit list.
-	units := make([]bool, 16)
-	// Reserve the SCSI unit number
-	scsiUnit := ctlr.(types.BaseVirtualSCSIController).GetVirtualSCSIController().ScsiCtlrUnitNumber
-	units[scsiUnit] = true
+	units := make(map[int32]bool)
+	if sc, ok := ctlr.(types.BaseVirtualSCSIController); ok {
+		// Reserve the SCSI unit number, and if we need to, shift up the desired
+		// unit number so it's not taking the unit of the controller itself.
+		scsiUnit := sc.GetVirtualSCSIController().ScsiCtlrUnitNumber
+		units[scsiUnit] = true
+		if unit >= scsiUnit {
+			unit++
+		}
+	}
 
 	ckey := ctlr.GetVirtualController().Key
 
@@ -1746,25 +2155,54 @@ func (r *DiskSubresource) assignDisk(l object.VirtualDeviceList, disk *types.Vir
 		units[*d.UnitNumber] = true
 	}
 
-	// We now have a valid list of units. If we need to, shift up the desired
-	// unit number so it's not taking the unit of the controller itself.
-	if unit >= scsiUnit {
-		unit++
-	}
-
 	if units[unit] {
-		return nil, fmt.Errorf("unit number %d on SCSI bus %d is in use", unit, bus)
+		return nil, nil, fmt.Errorf("unit number %d on %s bus %d is in use", unit, ct, bus)
 	}
 
 	// If we made it this far, we are good to go!
-	disk.ControllerKey = ctlr.GetVirtualController().Key
+	disk.ControllerKey = ckey
 	disk.UnitNumber = &unit
-	return ctlr, nil
+	return ctlr, spec, nil
+}
+
+// diskController looks up the controller of the supplied type at the specific
+// bus number. SCSI controllers are managed by scsi_controller_count and IDE
+// controllers are always present on a virtual machine, but SATA and NVMe
+// controllers are created on demand. When this happens, the config spec to add
+// the new controller is returned as well.
+func (r *DiskSubresource) diskController(l object.VirtualDeviceList, ct string, bus int) (types.BaseVirtualController, []types.BaseVirtualDeviceConfigSpec, error) {
+	if ct == SubresourceControllerTypeSCSI {
+		ctlr, err := r.ControllerForCreateUpdate(l, ct, bus)
+		return ctlr, nil, err
+	}
+	if ctlrs := l.Select(findVirtualDeviceInListControllerSelectFunc(ct, bus)); len(ctlrs) > 0 {
+		log.Printf("[DEBUG] %s: Found %s controller at bus number %d: %s", r, ct, bus, l.Name(ctlrs[0]))
+		return ctlrs[0].(types.BaseVirtualController), nil, nil
+	}
+
+	var ctlr types.BaseVirtualController
+	switch ct {
+	case SubresourceControllerTypeSATA:
+		ctlr = &types.VirtualAHCIController{}
+	case SubresourceControllerTypeNVMe:
+		ctlr = &types.VirtualNVMEController{}
+	default:
+		return nil, nil, fmt.Errorf("could not find %s controller at bus number %d", ct, bus)
+	}
+	vc := ctlr.GetVirtualController()
+	vc.Key = l.NewKey()
+	vc.BusNumber = int32(bus)
+	log.Printf("[DEBUG] %s: Creating %s controller at bus number %d", r, ct, bus)
+	spec, err := object.VirtualDeviceList{ctlr.(types.BaseVirtualDevice)}.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctlr, spec, nil
 }
 
 // findControllerInfo determines the normalized unit number for the disk device
-// based on the SCSI controller and unit number it's connected to. The
-// controller is also returned.
+// based on the controller and unit number it's connected to. The controller is
+// also returned.
 func (r *Subresource) findControllerInfo(l object.VirtualDeviceList, disk *types.VirtualDisk) (int, types.BaseVirtualController, error) {
 	ctlr := l.FindByKey(disk.ControllerKey)
 	if ctlr == nil {
@@ -1773,16 +2211,56 @@ func (r *Subresource) findControllerInfo(l object.VirtualDeviceList, disk *types
 	if disk.UnitNumber == nil {
 		return -1, nil, fmt.Errorf("unit number on disk key %d is unset", disk.Key)
 	}
-	sc, ok := ctlr.(types.BaseVirtualSCSIController)
+	bc, ok := ctlr.(types.BaseVirtualController)
 	if !ok {
-		return -1, nil, fmt.Errorf("controller at key %d is not a SCSI controller (actual: %T)", ctlr.GetVirtualDevice().Key, ctlr)
+		return -1, nil, fmt.Errorf("device at key %d is not a controller (actual: %T)", ctlr.GetVirtualDevice().Key, ctlr)
+	}
+	ct, err := controllerTypeToClass(bc)
+	if err != nil {
+		return -1, nil, err
+	}
+	perCtlr, ok := diskControllerUnits[ct]
+	if !ok {
+		return -1, nil, fmt.Errorf("controller at key %d is not a disk controller (actual: %T)", ctlr.GetVirtualDevice().Key, ctlr)
 	}
 	unit := *disk.UnitNumber
-	if unit > sc.GetVirtualSCSIController().ScsiCtlrUnitNumber {
+	if sc, ok := ctlr.(types.BaseVirtualSCSIController); ok && unit > sc.GetVirtualSCSIController().ScsiCtlrUnitNumber {
 		unit--
 	}
-	unit = unit + 15*sc.GetVirtualSCSIController().BusNumber
-	return int(unit), ctlr.(types.BaseVirtualController), nil
+	unit = unit + int32(perCtlr)*bc.GetVirtualController().BusNumber
+	return int(unit), bc, nil
+}
+
+// diskControllerType returns the controller_type for a set of disk
+// sub-resource data. Disks in state from earlier versions of the provider do
+// not have a controller_type, and are always SCSI disks.
+func diskControllerType(data map[string]interface{}) string {
+	if ct, ok := data["controller_type"].(string); ok && ct != "" {
+		return ct
+	}
+	return SubresourceControllerTypeSCSI
+}
+
+// diskControllerClass returns the class of the controller that a disk is
+// attached to.
+func diskControllerClass(l object.VirtualDeviceList, disk *types.VirtualDisk) (string, error) {
+	ctlr, err := findControllerForDevice(l, disk)
+	if err != nil {
+		return "", err
+	}
+	return controllerTypeToClass(ctlr)
+}
+
+// diskControllerRank returns the position of a disk controller type in the
+// order that disks are sorted in. SCSI disks always sort first, so that the
+// behaviour of existing configurations does not change.
+func diskControllerRank(ct string) int {
+	for i, v := range diskControllerTypeAllowedValues {
+		if v == ct {
+			return i
+		}
+	}
+	return len(diskControllerTypeAllowedValues)
 }
 
 // diskRelocateListString pretty-prints a list of
@@ -1822,8 +2300,9 @@ func (l virtualDeviceListSorter) Len() int {
 }
 
 // Less helps implement sort.Interface for virtualDeviceListSorter. A
-// BaseVirtualDevice is "less" than another device if its controller's bus
-// number and unit number combination are earlier in the order than the other.
+// BaseVirtualDevice is "less" than another device if its controller's type,
+// bus number, and unit number combination are earlier in the order than the
+// other.
 func (l virtualDeviceListSorter) Less(i, j int) bool {
 	li := l.Sort[i]
 	lj := l.Sort[j]
@@ -1832,8 +2311,15 @@ func (l virtualDeviceListSorter) Less(i, j int) bool {
 	if liCtlr == nil || ljCtlr == nil {
 		panic(errors.New("virtualDeviceListSorter cannot be used with devices that are not assigned to a controller"))
 	}
-	if liCtlr.(types.BaseVirtualController).GetVirtualController().BusNumber < liCtlr.(types.BaseVirtualController).GetVirtualController().BusNumber {
-		return true
+	liCt, _ := controllerTypeToClass(liCtlr.(types.BaseVirtualController))
+	ljCt, _ := controllerTypeToClass(ljCtlr.(types.BaseVirtualController))
+	if diskControllerRank(liCt) != diskControllerRank(ljCt) {
+		return diskControllerRank(liCt) < diskControllerRank(ljCt)
+	}
+	liBus := liCtlr.(types.BaseVirtualController).GetVirtualController().BusNumber
+	ljBus := ljCtlr.(types.BaseVirtualController).GetVirtualController().BusNumber
+	if liBus != ljBus {
+		return liBus < ljBus
 	}
 	liUnit := li.GetVirtualDevice().UnitNumber
 	ljUnit := lj.GetVirtualDevice().UnitNumber
@@ -1848,7 +2334,8 @@ func (l virtualDeviceListSorter) Swap(i, j int) {
 	l.Sort[i], l.Sort[j] = l.Sort[j], l.Sort[i]
 }
 
-// virtualDiskSubresourceSorter sorts a list of disk sub-resources, based on unit number.
+// virtualDiskSubresourceSorter sorts a list of disk sub-resources, based on
+// controller type and unit number.
 type virtualDiskSubresourceSorter []interface{}
 
 // Len implements sort.Interface for virtualDiskSubresourceSorter.
@@ -1860,6 +2347,9 @@ func (s virtualDiskSubresourceSorter) Len() int {
 func (s virtualDiskSubresourceSorter) Less(i, j int) bool {
 	mi := s[i].(map[string]interface{})
 	mj := s[j].(map[string]interface{})
+	if ri, rj := diskControllerRank(diskControllerType(mi)), diskControllerRank(diskControllerType(mj)); ri != rj {
+		return ri < rj
+	}
 	return mi["unit_number"].(int) < mj["unit_number"].(int)
 }
 
@@ -1879,10 +2369,17 @@ func datastorePathHasBase(p, b string) bool {
 }
 
 // SelectDisks looks for disks that Terraform is supposed to manage. count is
-// the number of controllers that Terraform is managing and serves as an upper
-// limit (count - 1) of the SCSI bus number for a controller that eligible
-// disks need to be attached to.
-func SelectDisks(l object.VirtualDeviceList, count int) object.VirtualDeviceList {
+// the number of SCSI controllers that Terraform is managing and serves as an
+// upper limit (count - 1) of the SCSI bus number for a controller that
+// eligible SCSI disks need to be attached to. Disks attached to SATA, NVMe, and
+// IDE controllers are only selected if their controller type is in ctlrTypes,
+// so that disks on these controllers are not picked up as orphans on virtual
+// machines that do not manage them.
+func SelectDisks(l object.VirtualDeviceList, count int, ctlrTypes ...string) object.VirtualDeviceList {
+	selected := make(map[string]bool)
+	for _, ct := range ctlrTypes {
+		selected[ct] = true
+	}
 	devices := l.Select(func(device types.BaseVirtualDevice) bool {
 		if disk, ok := device.(*types.VirtualDisk); ok {
 			ctlr, err := findControllerForDevice(l, disk)
@@ -1890,17 +2387,63 @@ func SelectDisks(l object.VirtualDeviceList, count int) object.VirtualDeviceList
 				log.Printf("[DEBUG] DiskRefreshOperation: Error looking for controller for device %q: %s", l.Name(disk), err)
 				return false
 			}
-			if sc, ok := ctlr.(types.BaseVirtualSCSIController); ok && sc.GetVirtualSCSIController().BusNumber < int32(count) {
-				cd := sc.(types.BaseVirtualDevice)
-				log.Printf("[DEBUG] DiskRefreshOperation: Found controller %q for device %q", l.Name(cd), l.Name(disk))
-				return true
+			switch c := ctlr.(type) {
+			case types.BaseVirtualSCSIController:
+				if c.GetVirtualSCSIController().BusNumber >= int32(count) {
+					return false
+				}
+			case *types.VirtualAHCIController:
+				if !selected[SubresourceControllerTypeSATA] {
+					return false
+				}
+			case *types.VirtualNVMEController:
+				if !selected[SubresourceControllerTypeNVMe] {
+					return false
+				}
+			case *types.VirtualIDEController:
+				if !selected[SubresourceControllerTypeIDE] {
+					return false
+				}
+			default:
+				return false
 			}
+			log.Printf("[DEBUG] DiskRefreshOperation: Found controller %q for device %q", l.Name(ctlr.(types.BaseVirtualDevice)), l.Name(disk))
+			return true
 		}
 		return false
 	})
 	return devices
 }
 
+// resourceDataChange is an interface for the GetChange method that both
+// ResourceData and ResourceDiff have.
+type resourceDataChange interface {
+	GetChange(string) (interface{}, interface{})
+}
+
+// diskControllerTypesInUse returns the controller types of the disk
+// sub-resources in both the configuration and the state. These are the
+// controller types that SelectDisks selects disks on, in addition to SCSI.
+func diskControllerTypesInUse(d resourceDataChange) []string {
+	var result []string
+	seen := make(map[string]bool)
+	o, n := d.GetChange(subresourceTypeDisk)
+	for _, set := range []interface{}{o, n} {
+		l, _ := set.([]interface{})
+		for _, item := range l {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if ct := diskControllerType(m); !seen[ct] {
+				seen[ct] = true
+				result = append(result, ct)
+			}
+		}
+	}
+	return result
+}
+
 // diskLabelOrName is a helper method that returns the unique label for a disk
 // - either its label or name. An error is returned if both are defined.
 //
@@ -1995,14 +2538,13 @@ func diskUUIDMatch(device types.BaseVirtualDevice, uuid string) bool {
 	if !ok {
 		return false
 	}
-	backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
-	if !ok {
-		return false
-	}
-	if backing.Uuid != uuid {
-		return false
+	switch backing := disk.Backing.(type) {
+	case *types.VirtualDiskFlatVer2BackingInfo:
+		return backing.Uuid == uuid
+	case *types.VirtualDiskRawDiskMappingVer1BackingInfo:
+		return backing.Uuid == uuid
 	}
-	return true
+	return false
 }
 
 // diskCapacityInGiB reports the supplied disk's capacity, by first checking
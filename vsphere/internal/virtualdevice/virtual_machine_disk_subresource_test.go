@@ -1,8 +1,12 @@
 package virtualdevice
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -37,3 +41,206 @@ func TestDiskCapacityInGiB(t *testing.T) {
 		})
 	}
 }
+
+func TestFindControllerInfo(t *testing.T) {
+	devices := object.VirtualDeviceList{
+		&types.ParaVirtualSCSIController{
+			VirtualSCSIController: types.VirtualSCSIController{
+				VirtualController: types.VirtualController{
+					VirtualDevice: types.VirtualDevice{Key: 1001},
+					BusNumber:     1,
+				},
+				ScsiCtlrUnitNumber: 7,
+			},
+		},
+		&types.VirtualAHCIController{
+			VirtualSATAController: types.VirtualSATAController{
+				VirtualController: types.VirtualController{
+					VirtualDevice: types.VirtualDevice{Key: 15001},
+					BusNumber:     1,
+				},
+			},
+		},
+		&types.VirtualNVMEController{
+			VirtualController: types.VirtualController{
+				VirtualDevice: types.VirtualDevice{Key: 31000},
+				BusNumber:     0,
+			},
+		},
+		&types.VirtualIDEController{
+			VirtualController: types.VirtualController{
+				VirtualDevice: types.VirtualDevice{Key: 201},
+				BusNumber:     1,
+			},
+		},
+		&types.VirtualPCIController{
+			VirtualController: types.VirtualController{
+				VirtualDevice: types.VirtualDevice{Key: 100},
+			},
+		},
+	}
+	cases := []struct {
+		name          string
+		controllerKey int32
+		unitNumber    int32
+		expected      int
+		expectedType  string
+		expectedError bool
+	}{
+		{
+			name:          "scsi below controller unit",
+			controllerKey: 1001,
+			unitNumber:    3,
+			expected:      18,
+			expectedType:  SubresourceControllerTypeSCSI,
+		},
+		{
+			name:          "scsi above controller unit",
+			controllerKey: 1001,
+			unitNumber:    8,
+			expected:      22,
+			expectedType:  SubresourceControllerTypeSCSI,
+		},
+		{
+			name:          "sata",
+			controllerKey: 15001,
+			unitNumber:    3,
+			expected:      33,
+			expectedType:  SubresourceControllerTypeSATA,
+		},
+		{
+			name:          "nvme",
+			controllerKey: 31000,
+			unitNumber:    2,
+			expected:      2,
+			expectedType:  SubresourceControllerTypeNVMe,
+		},
+		{
+			name:          "ide",
+			controllerKey: 201,
+			unitNumber:    1,
+			expected:      3,
+			expectedType:  SubresourceControllerTypeIDE,
+		},
+		{
+			name:          "pci",
+			controllerKey: 100,
+			unitNumber:    7,
+			expectedError: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			disk := &types.VirtualDisk{
+				VirtualDevice: types.VirtualDevice{
+					Key:           2000,
+					ControllerKey: tc.controllerKey,
+					UnitNumber:    structure.Int32Ptr(tc.unitNumber),
+				},
+			}
+			r := &Subresource{}
+			unit, ctlr, err := r.findControllerInfo(devices, disk)
+			if tc.expectedError {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			if tc.expected != unit {
+				t.Fatalf("expected unit %d, got %d", tc.expected, unit)
+			}
+			ct, err := controllerTypeToClass(ctlr)
+			if err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			if tc.expectedType != ct {
+				t.Fatalf("expected controller type %s, got %s", tc.expectedType, ct)
+			}
+		})
+	}
+}
+
+func TestSelectDisksControllerTypesInUse(t *testing.T) {
+	devices := object.VirtualDeviceList{
+		&types.ParaVirtualSCSIController{
+			VirtualSCSIController: types.VirtualSCSIController{
+				VirtualController: types.VirtualController{
+					VirtualDevice: types.VirtualDevice{Key: 1000},
+					BusNumber:     0,
+				},
+				ScsiCtlrUnitNumber: 7,
+			},
+		},
+		&types.VirtualAHCIController{
+			VirtualSATAController: types.VirtualSATAController{
+				VirtualController: types.VirtualController{
+					VirtualDevice: types.VirtualDevice{Key: 15000},
+					BusNumber:     0,
+				},
+			},
+		},
+		&types.VirtualDisk{
+			VirtualDevice: types.VirtualDevice{
+				Key:           2000,
+				ControllerKey: 1000,
+				UnitNumber:    structure.Int32Ptr(0),
+			},
+		},
+		&types.VirtualDisk{
+			VirtualDevice: types.VirtualDevice{
+				Key:           16000,
+				ControllerKey: 15000,
+				UnitNumber:    structure.Int32Ptr(0),
+			},
+		},
+	}
+	s := map[string]*schema.Schema{
+		subresourceTypeDisk: {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+				"label":           {Type: schema.TypeString, Optional: true},
+				"controller_type": {Type: schema.TypeString, Optional: true},
+			}},
+		},
+	}
+	cases := []struct {
+		name     string
+		disks    []interface{}
+		expected []int32
+	}{
+		{
+			// State written by earlier versions of the provider has no
+			// controller_type. The out-of-band SATA disk must not be picked up as
+			// an orphan.
+			name: "upgraded state",
+			disks: []interface{}{
+				map[string]interface{}{"label": "disk0"},
+			},
+			expected: []int32{2000},
+		},
+		{
+			name: "sata in use",
+			disks: []interface{}{
+				map[string]interface{}{"label": "disk0"},
+				map[string]interface{}{"label": "disk1", "controller_type": SubresourceControllerTypeSATA},
+			},
+			expected: []int32{2000, 16000},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, s, map[string]interface{}{subresourceTypeDisk: tc.disks})
+			var actual []int32
+			for _, device := range SelectDisks(devices, 1, diskControllerTypesInUse(d)...) {
+				actual = append(actual, device.GetVirtualDevice().Key)
+			}
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -387,38 +387,9 @@ func NetworkInterfacePostCloneOperation(d *schema.ResourceData, c *govmomi.Clien
 	log.Printf("[DEBUG] NetworkInterfacePostCloneOperation: Network devices located: %s", DeviceListString(devices))
 	curSet := d.Get(subresourceTypeNetworkInterface).([]interface{})
 	log.Printf("[DEBUG] NetworkInterfacePostCloneOperation: Current resource set from configuration: %s", subresourceListString(curSet))
-	urange, err := nicUnitRange(devices)
+	srcSet, err := networkInterfaceSourceSet(d, c, l, devices)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error calculating network device range: %s", err)
-	}
-	srcSet := make([]interface{}, urange)
-	log.Printf("[DEBUG] NetworkInterfacePostCloneOperation: Layout from source: %d devices over a %d unit range", len(devices), urange)
-
-	// Populate the source set as if the devices were orphaned. This give us a
-	// base to diff off of.
-	log.Printf("[DEBUG] NetworkInterfacePostCloneOperation: Reading existing devices")
-	for n, device := range devices {
-		m := make(map[string]interface{})
-		vd := device.GetVirtualDevice()
-		ctlr := l.FindByKey(vd.ControllerKey)
-		if ctlr == nil {
-			return nil, nil, fmt.Errorf("could not find controller with key %d", vd.Key)
-		}
-		m["key"] = int(vd.Key)
-		var err error
-		m["device_address"], err = computeDevAddr(vd, ctlr.(types.BaseVirtualController))
-		if err != nil {
-			return nil, nil, fmt.Errorf("error computing device address: %s", err)
-		}
-		r := NewNetworkInterfaceSubresource(c, d, m, nil, n)
-		if err := r.Read(l); err != nil {
-			return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
-		}
-		_, _, idx, err := splitDevAddr(r.Get("device_address").(string))
-		if err != nil {
-			return nil, nil, fmt.Errorf("%s: error parsing device address: %s", r, err)
-		}
-		srcSet[idx-networkInterfacePciDeviceOffset] = r.Data()
+		return nil, nil, err
 	}
 
 	// Now go over our current set, kind of treating it like an apply:
@@ -495,6 +466,87 @@ func NetworkInterfacePostCloneOperation(d *schema.ResourceData, c *govmomi.Clien
 	return l, spec, nil
 }
 
+// NetworkInterfaceInstantCloneOperation returns the device change operations
+// that move the network interfaces of an instant clone source onto the
+// networks in the configuration. These are applied as part of the instant
+// clone, so that the new virtual machine never comes up on the networks of the
+// source. Interfaces are not added or removed here, that is left to
+// NetworkInterfacePostCloneOperation.
+func NetworkInterfaceInstantCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] NetworkInterfaceInstantCloneOperation: Looking for network changes for instant clone")
+	devices := l.Select(func(device types.BaseVirtualDevice) bool {
+		if _, ok := device.(types.BaseVirtualEthernetCard); ok {
+			return true
+		}
+		return false
+	})
+	srcSet, err := networkInterfaceSourceSet(d, c, l, devices)
+	if err != nil {
+		return nil, err
+	}
+	var spec []types.BaseVirtualDeviceConfigSpec
+	for i, ci := range d.Get(subresourceTypeNetworkInterface).([]interface{}) {
+		if i > len(srcSet)-1 || srcSet[i] == nil {
+			break
+		}
+		sm := srcSet[i].(map[string]interface{})
+		netID := ci.(map[string]interface{})["network_id"].(string)
+		if sm["network_id"] == netID {
+			continue
+		}
+		nc, err := copystructure.Copy(sm)
+		if err != nil {
+			return nil, fmt.Errorf("error copying source network interface state data at index %d: %s", i, err)
+		}
+		nm := nc.(map[string]interface{})
+		nm["network_id"] = netID
+		r := NewNetworkInterfaceSubresource(c, d, nm, sm, i)
+		cspec, err := r.Update(l)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", r.Addr(), err)
+		}
+		spec = append(spec, cspec...)
+	}
+	log.Printf("[DEBUG] NetworkInterfaceInstantCloneOperation: Device config operations for instant clone: %s", DeviceChangeString(spec))
+	return spec, nil
+}
+
+// networkInterfaceSourceSet reads the network interfaces of a source virtual
+// machine as if they were orphaned, indexed by their position on the PCI bus.
+// This gives the clone operations a base to diff off of.
+func networkInterfaceSourceSet(d *schema.ResourceData, c *govmomi.Client, l, devices object.VirtualDeviceList) ([]interface{}, error) {
+	urange, err := nicUnitRange(devices)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating network device range: %s", err)
+	}
+	srcSet := make([]interface{}, urange)
+	log.Printf("[DEBUG] networkInterfaceSourceSet: Layout from source: %d devices over a %d unit range", len(devices), urange)
+	for n, device := range devices {
+		m := make(map[string]interface{})
+		vd := device.GetVirtualDevice()
+		ctlr := l.FindByKey(vd.ControllerKey)
+		if ctlr == nil {
+			return nil, fmt.Errorf("could not find controller with key %d", vd.Key)
+		}
+		m["key"] = int(vd.Key)
+		var err error
+		m["device_address"], err = computeDevAddr(vd, ctlr.(types.BaseVirtualController))
+		if err != nil {
+			return nil, fmt.Errorf("error computing device address: %s", err)
+		}
+		r := NewNetworkInterfaceSubresource(c, d, m, nil, n)
+		if err := r.Read(l); err != nil {
+			return nil, fmt.Errorf("%s: %s", r.Addr(), err)
+		}
+		_, _, idx, err := splitDevAddr(r.Get("device_address").(string))
+		if err != nil {
+			return nil, fmt.Errorf("%s: error parsing device address: %s", r, err)
+		}
+		srcSet[idx-networkInterfacePciDeviceOffset] = r.Data()
+	}
+	return srcSet, nil
+}
+
 // ReadNetworkInterfaceTypes returns a list of network interface types. This is used
 // in the VM data source to discover the types of the NIC drivers on the
 // virtual machine. The list is sorted by the order that they would be added in
@@ -945,6 +997,22 @@ func (r *NetworkInterfaceSubresource) assignEthernetCard(l object.VirtualDeviceL
 	return nil
 }
 
+// NetworkInterfaceKeys returns the device keys of the network interfaces in
+// the device list, indexed by their position in the network_interface
+// sub-resource list. The position is derived from the PCI unit number of the
+// device, the same way that it is on refresh.
+func NetworkInterfaceKeys(l object.VirtualDeviceList) map[int]int32 {
+	keys := make(map[int]int32)
+	for _, device := range l.SelectByType((*types.VirtualEthernetCard)(nil)) {
+		vd := device.GetVirtualDevice()
+		if vd.UnitNumber == nil || *vd.UnitNumber < networkInterfacePciDeviceOffset {
+			continue
+		}
+		keys[int(*vd.UnitNumber-networkInterfacePciDeviceOffset)] = vd.Key
+	}
+	return keys
+}
+
 // nicUnitRange calculates a range of units given a certain VirtualDeviceList,
 // which should be network interfaces.  It's used in network interface refresh
 // logic to determine how many subresources may end up in state.
@@ -0,0 +1,139 @@
+package virtualdevice
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// The backing types that can be set on a parallel port.
+const (
+	parallelPortBackingTypeFile   = portBackingTypeFile
+	parallelPortBackingTypeDevice = portBackingTypeDevice
+)
+
+var parallelPortBackingTypeAllowedValues = []string{
+	parallelPortBackingTypeFile,
+	parallelPortBackingTypeDevice,
+}
+
+// parallelPortBackingAttributes lists the attributes that are specific to each
+// backing type. The first attribute for each backing type is required.
+var parallelPortBackingAttributes = map[string][]string{
+	parallelPortBackingTypeFile:   {"path", "datastore_id"},
+	parallelPortBackingTypeDevice: {"device_name"},
+}
+
+// ParallelPortSubresourceSchema represents the schema for the parallel_port
+// sub-resource.
+func ParallelPortSubresourceSchema() map[string]*schema.Schema {
+	return parallelPort.subresourceSchema()
+}
+
+// parallelPortSchema returns the attributes that are specific to parallel
+// ports.
+func parallelPortSchema() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"backing_type": {
+			Type:         schema.TypeString,
+			Required:     true,
+			Description:  "The backing type of the parallel port. Can be one of file or device.",
+			ValidateFunc: validation.StringInSlice(parallelPortBackingTypeAllowedValues, false),
+		},
+		// VirtualDeviceDeviceBackingInfo
+		"device_name": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The name of the host device of a device backed parallel port, such as /dev/parport0.",
+		},
+	}
+	structure.MergeSchema(s, portSchema("parallel port"))
+	return s
+}
+
+// parallelPort describes parallel port devices and their backings.
+var parallelPort = &portType{
+	srtype:    subresourceTypeParallelPort,
+	name:      "parallel port",
+	logPrefix: "ParallelPort",
+	schema:    parallelPortSchema,
+	newDevice: func() types.BaseVirtualDevice {
+		return new(types.VirtualParallelPort)
+	},
+	backingAttributes: parallelPortBackingAttributes,
+	restartKeys:       []string{"backing_type", "datastore_id", "path", "device_name"},
+	expand:            expandParallelPort,
+	flatten:           flattenParallelPort,
+}
+
+// NewParallelPortSubresource returns a parallel_port subresource populated
+// with all of the necessary fields.
+func NewParallelPortSubresource(client *govmomi.Client, rdd resourceDataDiff, d, old map[string]interface{}, idx int) *PortSubresource {
+	return newPortSubresource(parallelPort, client, rdd, d, old, idx)
+}
+
+// ParallelPortApplyOperation processes an apply operation for all parallel
+// ports in the resource. See portApplyOperation for details.
+func ParallelPortApplyOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	return portApplyOperation(parallelPort, d, c, l)
+}
+
+// ParallelPortRefreshOperation processes a refresh operation for all of the
+// parallel ports in the resource. See portRefreshOperation for details.
+func ParallelPortRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) error {
+	return portRefreshOperation(parallelPort, d, c, l)
+}
+
+// ParallelPortPostCloneOperation normalizes parallel ports on a
+// freshly-cloned virtual machine. See portPostCloneOperation for details.
+func ParallelPortPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	return portPostCloneOperation(parallelPort, d, c, l)
+}
+
+// ParallelPortDiffOperation performs operations relevant to managing the diff
+// on parallel_port sub-resources.
+func ParallelPortDiffOperation(d *schema.ResourceDiff, c *govmomi.Client) error {
+	return portDiffOperation(parallelPort, d, c)
+}
+
+// expandParallelPort sets the backing of a parallel port from the
+// sub-resource data.
+func expandParallelPort(r *PortSubresource, d types.BaseVirtualDevice) error {
+	device := d.(*types.VirtualParallelPort)
+	switch bt := r.Get("backing_type").(string); bt {
+	case parallelPortBackingTypeFile:
+		backing, err := r.expandFileBacking()
+		if err != nil {
+			return err
+		}
+		device.Backing = &types.VirtualParallelPortFileBackingInfo{
+			VirtualDeviceFileBackingInfo: backing,
+		}
+	case parallelPortBackingTypeDevice:
+		device.Backing = &types.VirtualParallelPortDeviceBackingInfo{
+			VirtualDeviceDeviceBackingInfo: r.expandDeviceBacking(),
+		}
+	default:
+		return fmt.Errorf("unsupported parallel port backing type %q", bt)
+	}
+	return nil
+}
+
+// flattenParallelPort reads the backing of a parallel port into the
+// sub-resource data.
+func flattenParallelPort(r *PortSubresource, d types.BaseVirtualDevice) error {
+	switch backing := d.(*types.VirtualParallelPort).Backing.(type) {
+	case *types.VirtualParallelPortFileBackingInfo:
+		return r.flattenFileBacking(&backing.VirtualDeviceFileBackingInfo)
+	case *types.VirtualParallelPortDeviceBackingInfo:
+		r.flattenDeviceBacking(&backing.VirtualDeviceDeviceBackingInfo)
+	default:
+		r.clearBacking(backing)
+	}
+	return nil
+}
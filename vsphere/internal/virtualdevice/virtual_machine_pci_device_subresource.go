@@ -0,0 +1,343 @@
+package virtualdevice
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/hostsystem"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/resourcepool"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// subresourceTypePCIDevice is the key for the pci_device sub-resource.
+const subresourceTypePCIDevice = "pci_device"
+
+// VirtualPCIPassthroughDynamicBackingInfo is the backing of a Dynamic
+// DirectPath I/O device, which is passed any available host PCI device that
+// matches one of the allowed devices when the virtual machine powers on.
+//
+// This type was added in vSphere 7.0 and is not yet available in govmomi, so
+// it is defined and registered with the vim25 type registry here. The name of
+// the type must match the name of the type in the API.
+type VirtualPCIPassthroughDynamicBackingInfo struct {
+	types.VirtualDeviceDeviceBackingInfo
+
+	AllowedDevice []VirtualPCIPassthroughAllowedDevice `xml:"allowedDevice,omitempty"`
+	CustomLabel   string                               `xml:"customLabel,omitempty"`
+}
+
+// VirtualPCIPassthroughAllowedDevice describes a host PCI device that can be
+// passed to a Dynamic DirectPath I/O device.
+type VirtualPCIPassthroughAllowedDevice struct {
+	types.DynamicData
+
+	VendorId    int32 `xml:"vendorId"`
+	DeviceId    int32 `xml:"deviceId"`
+	SubVendorId int32 `xml:"subVendorId,omitempty"`
+	SubDeviceId int32 `xml:"subDeviceId,omitempty"`
+	RevisionId  int16 `xml:"revisionId,omitempty"`
+}
+
+// pciDeviceDynamicAPIVersion is the API version that
+// VirtualPCIPassthroughDynamicBackingInfo was added in. Virtual machines need
+// to be managed with this version, otherwise the backing is dropped from
+// requests and responses.
+const pciDeviceDynamicAPIVersion = "7.0"
+
+func init() {
+	types.Add("VirtualPCIPassthroughDynamicBackingInfo", reflect.TypeOf((*VirtualPCIPassthroughDynamicBackingInfo)(nil)).Elem())
+	types.Add("VirtualPCIPassthroughAllowedDevice", reflect.TypeOf((*VirtualPCIPassthroughAllowedDevice)(nil)).Elem())
+}
+
+// PCIDeviceSchema represents the schema for the pci_device sub-resource.
+//
+// A PCI device either passes a specific host PCI device through to the virtual
+// machine with DirectPath I/O, or any host PCI device with a matching vendor
+// and device ID with Dynamic DirectPath I/O. Like USB controllers, PCI devices
+// are identified by their backing, and not tracked by device address.
+func PCIDeviceSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"host_device_id": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The ID of the host PCI device to pass through with DirectPath I/O, such as 0000:3b:00.0.",
+		},
+		"vendor_id": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Description:  "The hexadecimal vendor ID of the host PCI devices that can be passed through with Dynamic DirectPath I/O, such as 10de.",
+			ValidateFunc: validation.StringMatch(hostsystem.PciIDRegexp, "must be a four digit lowercase hexadecimal ID"),
+		},
+		"device_id": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Description:  "The hexadecimal device ID of the host PCI devices that can be passed through with Dynamic DirectPath I/O, such as 1eb8.",
+			ValidateFunc: validation.StringMatch(hostsystem.PciIDRegexp, "must be a four digit lowercase hexadecimal ID"),
+		},
+	}
+}
+
+// pciDeviceKey returns a string that identifies a pci_device sub-resource by
+// the host device it passes through.
+func pciDeviceKey(m map[string]interface{}) string {
+	if id := m["host_device_id"].(string); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%s:%s", m["vendor_id"].(string), m["device_id"].(string))
+}
+
+// readPCIDevice reads the backing of a PCI passthrough device into pci_device
+// sub-resource data. Devices with other backings, such as shared vGPUs, are
+// not managed by the pci_device sub-resource, in which case nil is returned.
+func readPCIDevice(device types.BaseVirtualDevice) map[string]interface{} {
+	if _, ok := device.(*types.VirtualPCIPassthrough); !ok {
+		return nil
+	}
+	switch backing := device.GetVirtualDevice().Backing.(type) {
+	case *types.VirtualPCIPassthroughDeviceBackingInfo:
+		return map[string]interface{}{
+			"host_device_id": backing.Id,
+			"vendor_id":      "",
+			"device_id":      "",
+		}
+	case *VirtualPCIPassthroughDynamicBackingInfo:
+		if len(backing.AllowedDevice) < 1 {
+			return nil
+		}
+		return map[string]interface{}{
+			"host_device_id": "",
+			"vendor_id":      hostsystem.PciIDString(int16(backing.AllowedDevice[0].VendorId)),
+			"device_id":      hostsystem.PciIDString(int16(backing.AllowedDevice[0].DeviceId)),
+		}
+	}
+	return nil
+}
+
+// pciPassthroughDevices returns the host PCI devices that can be passed
+// through to the virtual machine, on the host in host_system_id if it's set,
+// or on all of the hosts of the resource pool otherwise. If the resource pool
+// is not known yet, nil is returned.
+func pciPassthroughDevices(c *govmomi.Client, d resourceDataDiff) ([]types.VirtualMachinePciPassthroughInfo, error) {
+	poolID, _ := d.Get("resource_pool_id").(string)
+	if poolID == "" {
+		return nil, nil
+	}
+	pool, err := resourcepool.FromID(c, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	var host *object.HostSystem
+	if hsID, _ := d.Get("host_system_id").(string); hsID != "" {
+		if host, err = hostsystem.FromID(c, hsID); err != nil {
+			return nil, fmt.Errorf("could not find host system ID %q: %s", hsID, err)
+		}
+	}
+	return resourcepool.PciPassthroughDevices(c, pool, host)
+}
+
+// findPCIPassthroughDevice returns the first host PCI device in the supplied
+// list that matches the pci_device sub-resource data, or nil if there is no
+// match.
+func findPCIPassthroughDevice(devices []types.VirtualMachinePciPassthroughInfo, m map[string]interface{}) *types.VirtualMachinePciPassthroughInfo {
+	id := m["host_device_id"].(string)
+	for i, dev := range devices {
+		if id != "" {
+			if dev.PciDevice.Id == id {
+				return &devices[i]
+			}
+			continue
+		}
+		if hostsystem.PciIDString(dev.PciDevice.VendorId) == m["vendor_id"].(string) && hostsystem.PciIDString(dev.PciDevice.DeviceId) == m["device_id"].(string) {
+			return &devices[i]
+		}
+	}
+	return nil
+}
+
+// PCIDeviceDiffOperation validates the pci_device sub-resources. Each
+// sub-resource must either have host_device_id, or vendor_id and device_id
+// set. When the PCI devices change, the host PCI devices are checked against
+// the devices that can be passed through to virtual machines in the
+// environment of the resource pool or host.
+func PCIDeviceDiffOperation(d *schema.ResourceDiff, c *govmomi.Client) error {
+	log.Printf("[DEBUG] PCIDeviceDiffOperation: Beginning diff validation")
+	pds := d.Get(subresourceTypePCIDevice).([]interface{})
+	ids := make(map[string]struct{})
+	version := viapi.ParseVersionFromClient(c)
+	for i, v := range pds {
+		m := v.(map[string]interface{})
+		id := m["host_device_id"].(string)
+		dynamic := m["vendor_id"].(string) != "" || m["device_id"].(string) != ""
+		switch {
+		case id != "" && dynamic:
+			return fmt.Errorf("%s.%d: host_device_id cannot be set with vendor_id and device_id", subresourceTypePCIDevice, i)
+		case id == "" && (m["vendor_id"].(string) == "" || m["device_id"].(string) == ""):
+			return fmt.Errorf("%s.%d: either host_device_id, or vendor_id and device_id must be set", subresourceTypePCIDevice, i)
+		case dynamic && version.Older(viapi.VSphereVersion{Product: version.Product, Major: 7}):
+			return fmt.Errorf("%s.%d: vendor_id and device_id are only supported on vSphere 7.0 and higher", subresourceTypePCIDevice, i)
+		case id != "":
+			if _, ok := ids[id]; ok {
+				return fmt.Errorf("%s: duplicate host_device_id %q", subresourceTypePCIDevice, id)
+			}
+			ids[id] = struct{}{}
+		}
+	}
+	if len(pds) < 1 || !d.HasChange(subresourceTypePCIDevice) {
+		log.Printf("[DEBUG] PCIDeviceDiffOperation: Diff validation complete")
+		return nil
+	}
+	devices, err := pciPassthroughDevices(c, d)
+	if err != nil {
+		return fmt.Errorf("error fetching PCI passthrough devices: %s", err)
+	}
+	if devices == nil {
+		log.Printf("[DEBUG] PCIDeviceDiffOperation: Resource pool not known yet, skipping host device validation")
+		return nil
+	}
+	for i, v := range pds {
+		m := v.(map[string]interface{})
+		if findPCIPassthroughDevice(devices, m) == nil {
+			return fmt.Errorf("%s.%d: no host PCI device matching %q is available for passthrough", subresourceTypePCIDevice, i, pciDeviceKey(m))
+		}
+	}
+	log.Printf("[DEBUG] PCIDeviceDiffOperation: Diff validation complete")
+	return nil
+}
+
+// PCIDeviceApplyOperation adds and removes PCI passthrough devices so that the
+// supplied device list has exactly the devices in the pci_device
+// sub-resources. Like USBControllerApplyOperation, it works off of the device
+// list alone and is used for both regular apply operations and post-clone
+// operations.
+//
+// Adding or removing PCI passthrough devices requires a restart of the virtual
+// machine.
+func PCIDeviceApplyOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] PCIDeviceApplyOperation: Beginning apply operation")
+	var want []map[string]interface{}
+	for _, v := range d.Get(subresourceTypePCIDevice).([]interface{}) {
+		want = append(want, v.(map[string]interface{}))
+	}
+
+	// Match up the existing devices with the wanted devices, and remove the
+	// ones that are left over.
+	var spec []types.BaseVirtualDeviceConfigSpec
+	matched := make([]bool, len(want))
+nextDevice:
+	for _, device := range l {
+		m := readPCIDevice(device)
+		if m == nil {
+			continue
+		}
+		for i, w := range want {
+			if !matched[i] && pciDeviceKey(w) == pciDeviceKey(m) {
+				matched[i] = true
+				continue nextDevice
+			}
+		}
+		log.Printf("[DEBUG] PCIDeviceApplyOperation: Removing PCI passthrough device: %s", l.Name(device))
+		dspec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationRemove)
+		if err != nil {
+			return nil, nil, err
+		}
+		spec = append(spec, dspec...)
+	}
+	l = applyDeviceChange(l, spec)
+
+	var devices []types.VirtualMachinePciPassthroughInfo
+	for i, w := range want {
+		if matched[i] {
+			continue
+		}
+		ctlr := l.PickController(&types.VirtualPCIController{})
+		if ctlr == nil {
+			return nil, nil, fmt.Errorf("could not find an available %s controller", SubresourceControllerTypePCI)
+		}
+		if devices == nil {
+			var err error
+			if devices, err = pciPassthroughDevices(c, d); err != nil {
+				return nil, nil, fmt.Errorf("error fetching PCI passthrough devices: %s", err)
+			}
+		}
+		host := findPCIPassthroughDevice(devices, w)
+		if host == nil {
+			return nil, nil, fmt.Errorf("%s.%d: no host PCI device matching %q is available for passthrough", subresourceTypePCIDevice, i, pciDeviceKey(w))
+		}
+		device := &types.VirtualPCIPassthrough{
+			VirtualDevice: types.VirtualDevice{
+				Key:           l.NewKey(),
+				ControllerKey: ctlr.GetVirtualController().Key,
+			},
+		}
+		if w["host_device_id"].(string) != "" {
+			device.Backing = &types.VirtualPCIPassthroughDeviceBackingInfo{
+				VirtualDeviceDeviceBackingInfo: types.VirtualDeviceDeviceBackingInfo{
+					DeviceName: host.PciDevice.DeviceName,
+				},
+				Id:       host.PciDevice.Id,
+				DeviceId: hostsystem.PciIDString(host.PciDevice.DeviceId),
+				SystemId: host.SystemId,
+				VendorId: host.PciDevice.VendorId,
+			}
+		} else {
+			device.Backing = &VirtualPCIPassthroughDynamicBackingInfo{
+				AllowedDevice: []VirtualPCIPassthroughAllowedDevice{
+					{
+						VendorId: int32(uint16(host.PciDevice.VendorId)),
+						DeviceId: int32(uint16(host.PciDevice.DeviceId)),
+					},
+				},
+			}
+		}
+		log.Printf("[DEBUG] PCIDeviceApplyOperation: Adding PCI passthrough device for %q", pciDeviceKey(w))
+		cspec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
+		if err != nil {
+			return nil, nil, err
+		}
+		l = applyDeviceChange(l, cspec)
+		spec = append(spec, cspec...)
+	}
+
+	if len(spec) > 0 {
+		log.Printf("[DEBUG] PCIDeviceApplyOperation: PCI passthrough devices have changed and require a VM restart")
+		d.Set("reboot_required", true)
+	}
+	log.Printf("[DEBUG] PCIDeviceApplyOperation: Device config operations from apply: %s", DeviceChangeString(spec))
+	log.Printf("[DEBUG] PCIDeviceApplyOperation: Apply complete, returning updated spec")
+	return l, spec, nil
+}
+
+// PCIDeviceRefreshOperation reads the PCI passthrough devices of the virtual
+// machine into the pci_device sub-resources. Devices in state keep their
+// order, and any other devices are added to the end of the list.
+func PCIDeviceRefreshOperation(d *schema.ResourceData, l object.VirtualDeviceList) error {
+	log.Printf("[DEBUG] PCIDeviceRefreshOperation: Beginning refresh")
+	var have []map[string]interface{}
+	for _, device := range l {
+		if m := readPCIDevice(device); m != nil {
+			have = append(have, m)
+		}
+	}
+	var newSet []interface{}
+	for _, v := range d.Get(subresourceTypePCIDevice).([]interface{}) {
+		m := v.(map[string]interface{})
+		for i, h := range have {
+			if pciDeviceKey(h) == pciDeviceKey(m) {
+				newSet = append(newSet, h)
+				have = append(have[:i], have[i+1:]...)
+				break
+			}
+		}
+	}
+	for _, h := range have {
+		newSet = append(newSet, h)
+	}
+	log.Printf("[DEBUG] PCIDeviceRefreshOperation: Refresh complete")
+	return d.Set(subresourceTypePCIDevice, newSet)
+}
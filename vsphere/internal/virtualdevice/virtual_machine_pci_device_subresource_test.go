@@ -0,0 +1,127 @@
+package virtualdevice
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vim25/xml"
+)
+
+func TestReadPCIDevice(t *testing.T) {
+	cases := []struct {
+		name     string
+		device   types.BaseVirtualDevice
+		expected map[string]interface{}
+	}{
+		{
+			name: "directpath",
+			device: &types.VirtualPCIPassthrough{
+				VirtualDevice: types.VirtualDevice{
+					Backing: &types.VirtualPCIPassthroughDeviceBackingInfo{
+						Id: "0000:3b:00.0",
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"host_device_id": "0000:3b:00.0",
+				"vendor_id":      "",
+				"device_id":      "",
+			},
+		},
+		{
+			name: "dynamic directpath",
+			device: &types.VirtualPCIPassthrough{
+				VirtualDevice: types.VirtualDevice{
+					Backing: &VirtualPCIPassthroughDynamicBackingInfo{
+						AllowedDevice: []VirtualPCIPassthroughAllowedDevice{
+							{
+								VendorId: 0x8086,
+								DeviceId: 0x1572,
+							},
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"host_device_id": "",
+				"vendor_id":      "8086",
+				"device_id":      "1572",
+			},
+		},
+		{
+			name: "vgpu",
+			device: &types.VirtualPCIPassthrough{
+				VirtualDevice: types.VirtualDevice{
+					Backing: &types.VirtualPCIPassthroughVmiopBackingInfo{
+						Vgpu: "grid_p40-2q",
+					},
+				},
+			},
+		},
+		{
+			name:   "not passthrough",
+			device: &types.VirtualSerialPort{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := readPCIDevice(tc.device)
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("expected %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestVirtualPCIPassthroughDynamicBackingInfoXML(t *testing.T) {
+	type device struct {
+		Backing types.BaseVirtualDeviceBackingInfo `xml:"backing,typeattr"`
+	}
+	expected := &VirtualPCIPassthroughDynamicBackingInfo{
+		AllowedDevice: []VirtualPCIPassthroughAllowedDevice{
+			{
+				VendorId: 0x10de,
+				DeviceId: 0x1eb8,
+			},
+		},
+	}
+	b, err := xml.Marshal(device{Backing: expected})
+	if err != nil {
+		t.Fatalf("error marshaling backing: %s", err)
+	}
+	var actual device
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.TypeFunc = types.TypeFunc()
+	if err := dec.Decode(&actual); err != nil {
+		t.Fatalf("error unmarshaling backing: %s", err)
+	}
+	if !reflect.DeepEqual(expected, actual.Backing) {
+		t.Fatalf("expected %#v, got %#v", expected, actual.Backing)
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	cases := []struct {
+		server   string
+		expected string
+	}{
+		{server: "6.5", expected: "6.5"},
+		{server: "6.7.3", expected: "6.7.3"},
+		{server: "7.0.3.0", expected: pciDeviceDynamicAPIVersion},
+	}
+	for _, tc := range cases {
+		t.Run(tc.server, func(t *testing.T) {
+			client := &govmomi.Client{
+				Client: &vim25.Client{},
+			}
+			client.Client.ServiceContent.About.ApiVersion = tc.server
+			if actual := APIVersion(client); actual != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
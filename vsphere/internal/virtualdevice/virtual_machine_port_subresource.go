@@ -0,0 +1,660 @@
+package virtualdevice
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/mitchellh/copystructure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/datastore"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// The backing types that are shared by all port devices.
+const (
+	portBackingTypeFile   = "file"
+	portBackingTypeDevice = "device"
+)
+
+// portType describes a kind of port device on the SIO controller, such as a
+// serial or parallel port. The lifecycle of all port devices is the same, so
+// the sub-resources for each kind of port only differ in the attributes and
+// backings described here.
+type portType struct {
+	// The sub-resource type of the port, such as serial_port.
+	srtype string
+
+	// The name of the port in messages, such as serial port.
+	name string
+
+	// The prefix of the operations of the port in log messages, such as
+	// SerialPort.
+	logPrefix string
+
+	// The schema of the port sub-resource, without the common sub-resource
+	// attributes.
+	schema func() map[string]*schema.Schema
+
+	// Returns a new device of the port type.
+	newDevice func() types.BaseVirtualDevice
+
+	// The attributes that are specific to each backing type. The first
+	// attribute for each backing type is required.
+	backingAttributes map[string][]string
+
+	// The attributes that can only be changed while the virtual machine is
+	// powered off.
+	restartKeys []string
+
+	// Sets the backing and any other device specific settings of the device
+	// from the sub-resource data.
+	expand func(r *PortSubresource, device types.BaseVirtualDevice) error
+
+	// Reads the backing and any other device specific settings of the device
+	// into the sub-resource data. The backing attributes are already cleared.
+	flatten func(r *PortSubresource, device types.BaseVirtualDevice) error
+
+	// Performs any validation beyond the backing attributes. Optional.
+	validateDiff func(r *PortSubresource) error
+}
+
+// isDevice returns true if the supplied device is of the port type.
+func (t *portType) isDevice(device types.BaseVirtualDevice) bool {
+	return reflect.TypeOf(device) == reflect.TypeOf(t.newDevice())
+}
+
+// subresourceSchema returns the complete schema of the port sub-resource.
+func (t *portType) subresourceSchema() map[string]*schema.Schema {
+	s := t.schema()
+	structure.MergeSchema(s, subresourceSchema())
+	return s
+}
+
+// portSchema returns the schema for the attributes that are shared by all
+// ports, using name to describe the port.
+func portSchema(name string) map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"start_connected": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
+			Description: fmt.Sprintf("Connect the %s when the virtual machine powers on.", name),
+		},
+		// VirtualDeviceFileBackingInfo
+		"datastore_id": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: fmt.Sprintf("The datastore ID of the output file of a file backed %s.", name),
+		},
+		"path": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: fmt.Sprintf("The path to the output file of a file backed %s on the datastore.", name),
+		},
+	}
+}
+
+// PortSubresource represents a vsphere_virtual_machine port sub-resource,
+// such as serial_port or parallel_port, with a complex device lifecycle.
+type PortSubresource struct {
+	*Subresource
+
+	port *portType
+}
+
+// newPortSubresource returns a port subresource of the supplied type,
+// populated with all of the necessary fields.
+func newPortSubresource(port *portType, client *govmomi.Client, rdd resourceDataDiff, d, old map[string]interface{}, idx int) *PortSubresource {
+	sr := &PortSubresource{
+		Subresource: &Subresource{
+			schema:  port.subresourceSchema(),
+			client:  client,
+			srtype:  port.srtype,
+			data:    d,
+			olddata: old,
+			rdd:     rdd,
+		},
+		port: port,
+	}
+	sr.Index = idx
+	return sr
+}
+
+// portApplyOperation processes an apply operation for all of the ports of the
+// supplied type in the resource.
+//
+// The function takes the root resource's ResourceData, the provider
+// connection, and the device list as known to vSphere at the start of this
+// operation. All port operations are carried out, with both the complete,
+// updated, VirtualDeviceList, and the complete list of changes returned as a
+// slice of BaseVirtualDeviceConfigSpec.
+func portApplyOperation(port *portType, d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] %sApplyOperation: Beginning apply operation", port.logPrefix)
+	o, n := d.GetChange(port.srtype)
+	ods := o.([]interface{})
+	nds := n.([]interface{})
+
+	var spec []types.BaseVirtualDeviceConfigSpec
+
+	// Our old and new sets now have an accurate description of devices that may
+	// have been added, removed, or changed. Look for removed devices first.
+	log.Printf("[DEBUG] %sApplyOperation: Looking for resources to delete", port.logPrefix)
+nextOld:
+	for n, oe := range ods {
+		om := oe.(map[string]interface{})
+		for _, ne := range nds {
+			nm := ne.(map[string]interface{})
+			if om["key"] == nm["key"] {
+				continue nextOld
+			}
+		}
+		r := newPortSubresource(port, c, d, om, nil, n)
+		dspec, err := r.Delete(l)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
+		}
+		l = applyDeviceChange(l, dspec)
+		spec = append(spec, dspec...)
+	}
+
+	// Now check for creates and updates. The results of this operation are
+	// committed to state after the operation completes.
+	var updates []interface{}
+	log.Printf("[DEBUG] %sApplyOperation: Looking for resources to create or update", port.logPrefix)
+	for n, ne := range nds {
+		nm := ne.(map[string]interface{})
+		if n < len(ods) {
+			// This is an update
+			oe := ods[n]
+			om := oe.(map[string]interface{})
+			if nm["key"] != om["key"] {
+				return nil, nil, fmt.Errorf("key mismatch on %s.%d (old: %d, new: %d). This is a bug with the provider, please report it", port.srtype, n, nm["key"].(int), om["key"].(int))
+			}
+			if reflect.DeepEqual(nm, om) {
+				// no change is a no-op
+				updates = append(updates, nm)
+				log.Printf("[DEBUG] %sApplyOperation: No-op resource: key %d", port.logPrefix, nm["key"].(int))
+				continue
+			}
+			r := newPortSubresource(port, c, d, nm, om, n)
+			uspec, err := r.Update(l)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
+			}
+			l = applyDeviceChange(l, uspec)
+			spec = append(spec, uspec...)
+			updates = append(updates, r.Data())
+			continue
+		}
+		// New device
+		r := newPortSubresource(port, c, d, nm, nil, n)
+		cspec, err := r.Create(l)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
+		}
+		l = applyDeviceChange(l, cspec)
+		spec = append(spec, cspec...)
+		updates = append(updates, r.Data())
+	}
+
+	log.Printf("[DEBUG] %sApplyOperation: Post-apply final resource list: %s", port.logPrefix, subresourceListString(updates))
+	// We are now done! Return the updated device list and config spec. Save updates as well.
+	if err := d.Set(port.srtype, updates); err != nil {
+		return nil, nil, err
+	}
+	log.Printf("[DEBUG] %sApplyOperation: Device list at end of operation: %s", port.logPrefix, DeviceListString(l))
+	log.Printf("[DEBUG] %sApplyOperation: Device config operations from apply: %s", port.logPrefix, DeviceChangeString(spec))
+	log.Printf("[DEBUG] %sApplyOperation: Apply complete, returning updated spec", port.logPrefix)
+	return l, spec, nil
+}
+
+// portRefreshOperation processes a refresh operation for all of the ports of
+// the supplied type in the resource.
+//
+// This functions similar to portApplyOperation, but nothing to change is
+// returned, all necessary values are just set and committed to state. Ports
+// that are not in state are added to the end of the list, which is also how
+// ports are picked up on import.
+func portRefreshOperation(port *portType, d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) error {
+	log.Printf("[DEBUG] %sRefreshOperation: Beginning refresh", port.logPrefix)
+	devices := l.Select(port.isDevice)
+	log.Printf("[DEBUG] %sRefreshOperation: Port devices located: %s", port.logPrefix, DeviceListString(devices))
+	curSet := d.Get(port.srtype).([]interface{})
+	log.Printf("[DEBUG] %sRefreshOperation: Current resource set from state: %s", port.logPrefix, subresourceListString(curSet))
+	var newSet []interface{}
+	// First check for negative keys. These are freshly added devices that are
+	// usually coming into read post-create.
+	//
+	// If we find what we are looking for, we remove the device from the working
+	// set so that we don't try and process it in the next few passes.
+	log.Printf("[DEBUG] %sRefreshOperation: Looking for freshly-created resources to read in", port.logPrefix)
+	for n, item := range curSet {
+		m := item.(map[string]interface{})
+		if m["key"].(int) < 1 {
+			r := newPortSubresource(port, c, d, m, nil, n)
+			if err := r.Read(l); err != nil {
+				return fmt.Errorf("%s: %s", r.Addr(), err)
+			}
+			if r.Get("key").(int) < 1 {
+				// This should not have happened - if it did, our device
+				// creation/update logic failed somehow that we were not able to track.
+				return fmt.Errorf("device %d with address %s still unaccounted for after update/read", r.Get("key").(int), r.Get("device_address").(string))
+			}
+			newSet = append(newSet, r.Data())
+			for i := 0; i < len(devices); i++ {
+				device := devices[i]
+				if device.GetVirtualDevice().Key == int32(r.Get("key").(int)) {
+					devices = append(devices[:i], devices[i+1:]...)
+					i--
+				}
+			}
+		}
+	}
+	log.Printf("[DEBUG] %sRefreshOperation: Port devices after freshly-created device search: %s", port.logPrefix, DeviceListString(devices))
+	log.Printf("[DEBUG] %sRefreshOperation: Resource set to write after freshly-created device search: %s", port.logPrefix, subresourceListString(newSet))
+
+	// Go over the remaining devices, refresh via key, and then remove their
+	// entries as well.
+	log.Printf("[DEBUG] %sRefreshOperation: Looking for devices known in state", port.logPrefix)
+	for i := 0; i < len(devices); i++ {
+		device := devices[i]
+		for n, item := range curSet {
+			m := item.(map[string]interface{})
+			if m["key"].(int) < 0 {
+				// Skip any of these keys as we won't be matching any of those anyway here
+				continue
+			}
+			if device.GetVirtualDevice().Key != int32(m["key"].(int)) {
+				// Skip any device that doesn't match key as well
+				continue
+			}
+			// We should have our device -> resource match, so read now.
+			r := newPortSubresource(port, c, d, m, nil, n)
+			if err := r.Read(l); err != nil {
+				return fmt.Errorf("%s: %s", r.Addr(), err)
+			}
+			// Done reading, push this onto our new set and remove the device from
+			// the list
+			newSet = append(newSet, r.Data())
+			devices = append(devices[:i], devices[i+1:]...)
+			i--
+		}
+	}
+	log.Printf("[DEBUG] %sRefreshOperation: Resource set to write after known device search: %s", port.logPrefix, subresourceListString(newSet))
+	log.Printf("[DEBUG] %sRefreshOperation: Probable orphaned port devices: %s", port.logPrefix, DeviceListString(devices))
+
+	// Finally, any device that is still here is orphaned. They should be added
+	// as new devices.
+	for n, device := range devices {
+		r, err := readOrphanedPort(port, d, c, l, device, n)
+		if err != nil {
+			return err
+		}
+		newSet = append(newSet, r.Data())
+	}
+
+	log.Printf("[DEBUG] %sRefreshOperation: Resource set to write after adding orphaned devices: %s", port.logPrefix, subresourceListString(newSet))
+	log.Printf("[DEBUG] %sRefreshOperation: Refresh operation complete, sending new resource set", port.logPrefix)
+	return d.Set(port.srtype, newSet)
+}
+
+// portPostCloneOperation normalizes the ports of the supplied type on a
+// freshly-cloned virtual machine and outputs any necessary device change
+// operations. It also sets the state in advance of the post-create read.
+//
+// This differs from a regular apply operation in that a configuration is
+// already present, but we don't have any existing state, which the standard
+// virtual device operations rely pretty heavily on.
+func portPostCloneOperation(port *portType, d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] %sPostCloneOperation: Looking for post-clone device changes", port.logPrefix)
+	devices := l.Select(port.isDevice)
+	log.Printf("[DEBUG] %sPostCloneOperation: Port devices located: %s", port.logPrefix, DeviceListString(devices))
+	curSet := d.Get(port.srtype).([]interface{})
+	log.Printf("[DEBUG] %sPostCloneOperation: Current resource set from configuration: %s", port.logPrefix, subresourceListString(curSet))
+	var srcSet []interface{}
+
+	// Populate the source set as if the devices were orphaned. This give us a
+	// base to diff off of.
+	log.Printf("[DEBUG] %sPostCloneOperation: Reading existing devices", port.logPrefix)
+	for n, device := range devices {
+		r, err := readOrphanedPort(port, d, c, l, device, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		srcSet = append(srcSet, r.Data())
+	}
+
+	// Now go over our current set, kind of treating it like an apply:
+	//
+	// * Device past the boundaries of existing devices are created
+	// * Devices within the bounds are changed changed
+	// * Data at the source with the same data after patching config data is a
+	// no-op, but we still push the device's state
+	var spec []types.BaseVirtualDeviceConfigSpec
+	var updates []interface{}
+	for i, ci := range curSet {
+		cm := ci.(map[string]interface{})
+		if i > len(srcSet)-1 {
+			// New device
+			r := newPortSubresource(port, c, d, cm, nil, i)
+			cspec, err := r.Create(l)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
+			}
+			l = applyDeviceChange(l, cspec)
+			spec = append(spec, cspec...)
+			updates = append(updates, r.Data())
+			continue
+		}
+		sm := srcSet[i].(map[string]interface{})
+		nm, err := copystructure.Copy(sm)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error copying source %s device state data at index %d: %s", port.name, i, err)
+		}
+		for k, v := range cm {
+			// Skip key and device_address here
+			switch k {
+			case "key", "device_address":
+				continue
+			}
+			nm.(map[string]interface{})[k] = v
+		}
+		r := newPortSubresource(port, c, d, nm.(map[string]interface{}), sm, i)
+		if !reflect.DeepEqual(sm, nm) {
+			// Update
+			cspec, err := r.Update(l)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
+			}
+			l = applyDeviceChange(l, cspec)
+			spec = append(spec, cspec...)
+		}
+		updates = append(updates, r.Data())
+	}
+
+	// Any other device past the end of the ports listed in config needs to be
+	// removed.
+	if len(curSet) < len(srcSet) {
+		for i, si := range srcSet[len(curSet):] {
+			sm := si.(map[string]interface{})
+			r := newPortSubresource(port, c, d, sm, nil, i+len(curSet))
+			dspec, err := r.Delete(l)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %s", r.Addr(), err)
+			}
+			l = applyDeviceChange(l, dspec)
+			spec = append(spec, dspec...)
+		}
+	}
+
+	log.Printf("[DEBUG] %sPostCloneOperation: Post-clone final resource list: %s", port.logPrefix, subresourceListString(updates))
+	// We are now done! Return the updated device list and config spec. Save updates as well.
+	if err := d.Set(port.srtype, updates); err != nil {
+		return nil, nil, err
+	}
+	log.Printf("[DEBUG] %sPostCloneOperation: Device list at end of operation: %s", port.logPrefix, DeviceListString(l))
+	log.Printf("[DEBUG] %sPostCloneOperation: Device config operations from post-clone: %s", port.logPrefix, DeviceChangeString(spec))
+	log.Printf("[DEBUG] %sPostCloneOperation: Operation complete, returning updated spec", port.logPrefix)
+	return l, spec, nil
+}
+
+// readOrphanedPort reads a port device that is not tracked in the
+// sub-resource data into a new sub-resource at the supplied index.
+func readOrphanedPort(port *portType, d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList, device types.BaseVirtualDevice, idx int) (*PortSubresource, error) {
+	m := make(map[string]interface{})
+	vd := device.GetVirtualDevice()
+	ctlr := l.FindByKey(vd.ControllerKey)
+	if ctlr == nil {
+		return nil, fmt.Errorf("could not find controller with key %d", vd.Key)
+	}
+	m["key"] = int(vd.Key)
+	var err error
+	m["device_address"], err = computeDevAddr(vd, ctlr.(types.BaseVirtualController))
+	if err != nil {
+		return nil, fmt.Errorf("error computing device address: %s", err)
+	}
+	r := newPortSubresource(port, c, d, m, nil, idx)
+	if err := r.Read(l); err != nil {
+		return nil, fmt.Errorf("%s: %s", r.Addr(), err)
+	}
+	return r, nil
+}
+
+// portDiffOperation performs operations relevant to managing the diff on the
+// port sub-resources of the supplied type.
+func portDiffOperation(port *portType, d *schema.ResourceDiff, c *govmomi.Client) error {
+	log.Printf("[DEBUG] %sDiffOperation: Beginning diff validation", port.logPrefix)
+	for i, e := range d.Get(port.srtype).([]interface{}) {
+		r := newPortSubresource(port, c, d, e.(map[string]interface{}), nil, i)
+		if err := r.ValidateDiff(); err != nil {
+			return fmt.Errorf("%s: %s", r.Addr(), err)
+		}
+	}
+	log.Printf("[DEBUG] %sDiffOperation: Diff validation complete", port.logPrefix)
+	return nil
+}
+
+// ValidateDiff performs any complex validation of an individual port
+// sub-resource that can't be done in schema alone. This ensures that the
+// attributes of the selected backing type are set, and that attributes of
+// other backing types are not.
+func (r *PortSubresource) ValidateDiff() error {
+	log.Printf("[DEBUG] %s: Beginning %s configuration validation", r, r.port.name)
+	bt := r.Get("backing_type").(string)
+	for t, attrs := range r.port.backingAttributes {
+		for i, k := range attrs {
+			v := r.Get(k).(string)
+			switch {
+			case t == bt && i == 0 && v == "":
+				return fmt.Errorf("%s is required for %s backed %ss", k, bt, r.port.name)
+			case t != bt && v != "":
+				return fmt.Errorf("%s cannot be set for %s backed %ss", k, bt, r.port.name)
+			}
+		}
+	}
+	if r.port.validateDiff != nil {
+		if err := r.port.validateDiff(r); err != nil {
+			return err
+		}
+	}
+	log.Printf("[DEBUG] %s: Config validation complete", r)
+	return nil
+}
+
+// Create creates a vsphere_virtual_machine port sub-resource.
+func (r *PortSubresource) Create(l object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] %s: Running create", r)
+	var spec []types.BaseVirtualDeviceConfigSpec
+	ctlr, err := r.ControllerForCreateUpdate(l, SubresourceControllerTypeSIO, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// We now have the controller on which we can create our device on.
+	device := r.port.newDevice()
+	l.AssignController(device, ctlr)
+	if err := r.expand(device); err != nil {
+		return nil, err
+	}
+	// Ports cannot be added to a running virtual machine.
+	r.SetRestart("<device create>")
+	// Done here. Save IDs, push the device to the new device list and return.
+	if err := r.SaveDevIDs(device, ctlr); err != nil {
+		return nil, err
+	}
+	dspec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
+	if err != nil {
+		return nil, err
+	}
+	spec = append(spec, dspec...)
+	log.Printf("[DEBUG] %s: Device config operations from create: %s", r, DeviceChangeString(spec))
+	log.Printf("[DEBUG] %s: Create finished", r)
+	return spec, nil
+}
+
+// Read reads a vsphere_virtual_machine port sub-resource.
+func (r *PortSubresource) Read(l object.VirtualDeviceList) error {
+	log.Printf("[DEBUG] %s: Reading state", r)
+	device, err := r.findPort(l)
+	if err != nil {
+		return err
+	}
+	// Clear all backing attributes first, so that only the attributes of the
+	// current backing type are set.
+	for _, attrs := range r.port.backingAttributes {
+		for _, k := range attrs {
+			r.Set(k, "")
+		}
+	}
+	if err := r.port.flatten(r, device); err != nil {
+		return err
+	}
+	if vd := device.GetVirtualDevice(); vd.Connectable != nil {
+		r.Set("start_connected", vd.Connectable.StartConnected)
+	}
+	// Save the device key and address data
+	ctlr, err := findControllerForDevice(l, device)
+	if err != nil {
+		return err
+	}
+	if err := r.SaveDevIDs(device, ctlr); err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] %s: Read finished (key and device address may have changed)", r)
+	return nil
+}
+
+// Update updates a vsphere_virtual_machine port sub-resource.
+func (r *PortSubresource) Update(l object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] %s: Beginning update", r)
+	device, err := r.findPort(l)
+	if err != nil {
+		return nil, err
+	}
+
+	// The backing of a port can only be changed while the virtual machine is
+	// powered off.
+	for _, k := range r.port.restartKeys {
+		r.GetWithRestart(k)
+	}
+	if err := r.expand(device); err != nil {
+		return nil, err
+	}
+	spec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationEdit)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] %s: Device config operations from update: %s", r, DeviceChangeString(spec))
+	log.Printf("[DEBUG] %s: Update complete", r)
+	return spec, nil
+}
+
+// Delete deletes a vsphere_virtual_machine port sub-resource.
+func (r *PortSubresource) Delete(l object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] %s: Beginning delete", r)
+	device, err := r.findPort(l)
+	if err != nil {
+		return nil, err
+	}
+	// Ports cannot be removed from a running virtual machine.
+	r.SetRestart("<device delete>")
+	deleteSpec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationRemove)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] %s: Device config operations from update: %s", r, DeviceChangeString(deleteSpec))
+	log.Printf("[DEBUG] %s: Delete completed", r)
+	return deleteSpec, nil
+}
+
+// findPort locates the device of the port sub-resource, and checks that it is
+// of the port type.
+func (r *PortSubresource) findPort(l object.VirtualDeviceList) (types.BaseVirtualDevice, error) {
+	device, err := r.FindVirtualDevice(l)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find %s device: %s", r.port.name, err)
+	}
+	if !r.port.isDevice(device) {
+		return nil, fmt.Errorf("device at %q is not a virtual %s device", l.Name(device), r.port.name)
+	}
+	return device, nil
+}
+
+// expand sets the backing and connection settings of a port device from the
+// sub-resource data.
+func (r *PortSubresource) expand(device types.BaseVirtualDevice) error {
+	if err := r.port.expand(r, device); err != nil {
+		return err
+	}
+	vd := device.GetVirtualDevice()
+	if vd.Connectable == nil {
+		vd.Connectable = new(types.VirtualDeviceConnectInfo)
+	}
+	vd.Connectable.StartConnected = r.Get("start_connected").(bool)
+	return nil
+}
+
+// expandFileBacking returns the file backing of a file backed port, with the
+// output file in path on the datastore in datastore_id.
+func (r *PortSubresource) expandFileBacking() (types.VirtualDeviceFileBackingInfo, error) {
+	dsID := r.Get("datastore_id").(string)
+	ds, err := datastore.FromID(r.client, dsID)
+	if err != nil {
+		return types.VirtualDeviceFileBackingInfo{}, fmt.Errorf("cannot find datastore: %s", err)
+	}
+	dsProps, err := datastore.Properties(ds)
+	if err != nil {
+		return types.VirtualDeviceFileBackingInfo{}, fmt.Errorf("could not get properties for datastore: %s", err)
+	}
+	dsPath := &object.DatastorePath{
+		Datastore: dsProps.Name,
+		Path:      r.Get("path").(string),
+	}
+	dsRef := ds.Reference()
+	return types.VirtualDeviceFileBackingInfo{
+		FileName:  dsPath.String(),
+		Datastore: &dsRef,
+	}, nil
+}
+
+// flattenFileBacking reads the file backing of a file backed port into
+// datastore_id and path.
+func (r *PortSubresource) flattenFileBacking(backing *types.VirtualDeviceFileBackingInfo) error {
+	r.Set("backing_type", portBackingTypeFile)
+	dp := &object.DatastorePath{}
+	if ok := dp.FromString(backing.FileName); !ok {
+		return fmt.Errorf("could not read datastore path in backing %q", backing.FileName)
+	}
+	if backing.Datastore != nil {
+		r.Set("datastore_id", backing.Datastore.Value)
+	}
+	r.Set("path", dp.Path)
+	return nil
+}
+
+// expandDeviceBacking returns the device backing of a device backed port,
+// with the host device in device_name.
+func (r *PortSubresource) expandDeviceBacking() types.VirtualDeviceDeviceBackingInfo {
+	return types.VirtualDeviceDeviceBackingInfo{
+		DeviceName: r.Get("device_name").(string),
+	}
+}
+
+// flattenDeviceBacking reads the device backing of a device backed port into
+// device_name.
+func (r *PortSubresource) flattenDeviceBacking(backing *types.VirtualDeviceDeviceBackingInfo) {
+	r.Set("backing_type", portBackingTypeDevice)
+	r.Set("device_name", backing.DeviceName)
+}
+
+// clearBacking clears the backing type of a port with a backing that is not
+// supported, such as a ThinPrint backing, to make sure a correct diff gets
+// created.
+func (r *PortSubresource) clearBacking(backing types.BaseVirtualDeviceBackingInfo) {
+	log.Printf("[DEBUG] %s: Unknown %s backing type %T, clearing all attributes", r, r.port.name, backing)
+	r.Set("backing_type", "")
+}
@@ -0,0 +1,121 @@
+package virtualdevice
+
+import (
+	"testing"
+)
+
+func TestPortValidateDiff(t *testing.T) {
+	cases := []struct {
+		name          string
+		port          *portType
+		data          map[string]interface{}
+		expectedError bool
+	}{
+		{
+			name: "serial network",
+			port: serialPort,
+			data: map[string]interface{}{
+				"backing_type": serialPortBackingTypeNetwork,
+				"service_uri":  "telnet://:9001",
+			},
+		},
+		{
+			name: "serial network without service_uri",
+			port: serialPort,
+			data: map[string]interface{}{
+				"backing_type": serialPortBackingTypeNetwork,
+			},
+			expectedError: true,
+		},
+		{
+			name: "serial file",
+			port: serialPort,
+			data: map[string]interface{}{
+				"backing_type": serialPortBackingTypeFile,
+				"datastore_id": "datastore-123",
+				"path":         "vm/serial.log",
+			},
+		},
+		{
+			name: "serial file with pipe_name",
+			port: serialPort,
+			data: map[string]interface{}{
+				"backing_type": serialPortBackingTypeFile,
+				"datastore_id": "datastore-123",
+				"path":         "vm/serial.log",
+				"pipe_name":    `\\.\pipe\serial`,
+			},
+			expectedError: true,
+		},
+		{
+			name: "serial device with no_rx_loss",
+			port: serialPort,
+			data: map[string]interface{}{
+				"backing_type": serialPortBackingTypeDevice,
+				"device_name":  "/dev/ttyS0",
+				"no_rx_loss":   true,
+			},
+			expectedError: true,
+		},
+		{
+			name: "parallel file",
+			port: parallelPort,
+			data: map[string]interface{}{
+				"backing_type": parallelPortBackingTypeFile,
+				"datastore_id": "datastore-123",
+				"path":         "vm/parallel.log",
+			},
+		},
+		{
+			name: "parallel file without path",
+			port: parallelPort,
+			data: map[string]interface{}{
+				"backing_type": parallelPortBackingTypeFile,
+				"datastore_id": "datastore-123",
+			},
+			expectedError: true,
+		},
+		{
+			name: "parallel device",
+			port: parallelPort,
+			data: map[string]interface{}{
+				"backing_type": parallelPortBackingTypeDevice,
+				"device_name":  "/dev/parport0",
+			},
+		},
+		{
+			name: "parallel device with path",
+			port: parallelPort,
+			data: map[string]interface{}{
+				"backing_type": parallelPortBackingTypeDevice,
+				"device_name":  "/dev/parport0",
+				"path":         "vm/parallel.log",
+			},
+			expectedError: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := make(map[string]interface{})
+			for k, s := range tc.port.subresourceSchema() {
+				switch {
+				case s.Default != nil:
+					data[k] = s.Default
+				case s.Type.Zero() != nil:
+					data[k] = s.Type.Zero()
+				}
+			}
+			for k, v := range tc.data {
+				data[k] = v
+			}
+			r := newPortSubresource(tc.port, nil, nil, data, nil, 0)
+			err := r.ValidateDiff()
+			if tc.expectedError && err == nil {
+				t.Fatal("expected error, got none")
+			}
+			if !tc.expectedError && err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+		})
+	}
+}
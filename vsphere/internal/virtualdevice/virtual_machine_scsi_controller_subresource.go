@@ -0,0 +1,207 @@
+package virtualdevice
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// subresourceTypeSCSIController is the key for the scsi_controller
+// sub-resource.
+const subresourceTypeSCSIController = "scsi_controller"
+
+// SCSIBusSharingAllowedValues exports the list of bus sharing modes that can
+// be set on a SCSI controller.
+var SCSIBusSharingAllowedValues = []string{
+	string(types.VirtualSCSISharingNoSharing),
+	string(types.VirtualSCSISharingVirtualSharing),
+	string(types.VirtualSCSISharingPhysicalSharing),
+}
+
+// SCSIControllerSchema represents the schema for the scsi_controller
+// sub-resource.
+//
+// Each scsi_controller block overrides the controller type and bus sharing of
+// a single SCSI controller within the controllers managed by
+// scsi_controller_count. Controllers without a block use scsi_type, and keep
+// the bus sharing mode that they have. Controllers with bus sharing enabled
+// are always read into a block, so that bus sharing that is not in the
+// configuration shows up as a diff.
+func SCSIControllerSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"bus_number": {
+			Type:         schema.TypeInt,
+			Required:     true,
+			Description:  "The bus number of the SCSI controller. Must be lower than scsi_controller_count.",
+			ValidateFunc: validation.IntBetween(0, 3),
+		},
+		"type": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Computed:     true,
+			Description:  "The type of the SCSI controller. Can be one of lsilogic, lsilogic-sas or pvscsi. Defaults to the value of scsi_type.",
+			ValidateFunc: validation.StringInSlice(SCSIBusTypeAllowedValues, false),
+		},
+		"sharing": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      string(types.VirtualSCSISharingNoSharing),
+			Description:  "The bus sharing mode of the SCSI controller. Can be one of noSharing, virtualSharing, or physicalSharing.",
+			ValidateFunc: validation.StringInSlice(SCSIBusSharingAllowedValues, false),
+		},
+	}
+}
+
+// scsiBusConfig is the desired controller type and bus sharing mode of a
+// single SCSI controller. An empty sharing mode keeps the bus sharing mode of
+// the controller as it is.
+type scsiBusConfig struct {
+	ct      string
+	sharing types.VirtualSCSISharing
+}
+
+// expandSCSIBus returns the desired configuration of the first
+// scsi_controller_count SCSI controllers, indexed by bus number. Bus sharing is
+// only set on controllers that have a scsi_controller block, or had one that
+// has been removed, in which case bus sharing is turned off.
+func expandSCSIBus(d *schema.ResourceData) []scsiBusConfig {
+	ct := d.Get("scsi_type").(string)
+	bus := make([]scsiBusConfig, d.Get("scsi_controller_count").(int))
+	for i := range bus {
+		bus[i] = scsiBusConfig{ct: ct}
+	}
+	o, n := d.GetChange(subresourceTypeSCSIController)
+	for _, v := range o.([]interface{}) {
+		if b := v.(map[string]interface{})["bus_number"].(int); b < len(bus) {
+			bus[b].sharing = types.VirtualSCSISharingNoSharing
+		}
+	}
+	for _, v := range n.([]interface{}) {
+		m := v.(map[string]interface{})
+		b := m["bus_number"].(int)
+		if b >= len(bus) {
+			continue
+		}
+		if t := m["type"].(string); t != "" {
+			bus[b].ct = t
+		}
+		bus[b].sharing = types.VirtualSCSISharing(m["sharing"].(string))
+	}
+	return bus
+}
+
+// scsiBusSharingChanged returns true if the bus sharing mode of any
+// controller differs between two lists of scsi_controller sub-resources. A
+// controller without a block in the new list has bus sharing turned off.
+func scsiBusSharingChanged(o, n []interface{}) bool {
+	sharing := func(set []interface{}) map[int]string {
+		m := make(map[int]string)
+		for _, v := range set {
+			b := v.(map[string]interface{})
+			m[b["bus_number"].(int)] = b["sharing"].(string)
+		}
+		return m
+	}
+	oldSharing, newSharing := sharing(o), sharing(n)
+	for b := range oldSharing {
+		if _, ok := newSharing[b]; !ok {
+			newSharing[b] = string(types.VirtualSCSISharingNoSharing)
+		}
+	}
+	for b, mode := range newSharing {
+		old, ok := oldSharing[b]
+		if !ok {
+			old = string(types.VirtualSCSISharingNoSharing)
+		}
+		if old != mode {
+			return true
+		}
+	}
+	return false
+}
+
+// SCSIControllerDiffOperation validates the scsi_controller sub-resources
+// against the number of controllers in scsi_controller_count.
+func SCSIControllerDiffOperation(d *schema.ResourceDiff) error {
+	log.Printf("[DEBUG] SCSIControllerDiffOperation: Beginning diff validation")
+	count := d.Get("scsi_controller_count").(int)
+	buses := make(map[int]struct{})
+	for i, v := range d.Get(subresourceTypeSCSIController).([]interface{}) {
+		n := v.(map[string]interface{})["bus_number"].(int)
+		if n >= count {
+			return fmt.Errorf("%s.%d: bus_number %d is too high - maximum value is %d with %d SCSI controller(s)", subresourceTypeSCSIController, i, n, count-1, count)
+		}
+		if _, ok := buses[n]; ok {
+			return fmt.Errorf("%s: duplicate bus_number %d", subresourceTypeSCSIController, n)
+		}
+		buses[n] = struct{}{}
+	}
+	// Changing the bus sharing mode of a controller requires the virtual
+	// machine to be powered off.
+	if d.Id() != "" && d.HasChange(subresourceTypeSCSIController) {
+		o, n := d.GetChange(subresourceTypeSCSIController)
+		if scsiBusSharingChanged(o.([]interface{}), n.([]interface{})) {
+			log.Printf("[DEBUG] SCSIControllerDiffOperation: SCSI bus sharing has changed and requires a VM restart")
+			d.SetNew("reboot_required", true)
+		}
+	}
+	log.Printf("[DEBUG] SCSIControllerDiffOperation: Diff validation complete")
+	return nil
+}
+
+// SCSIBusRefreshOperation reads the state of the SCSI bus into scsi_type and
+// the scsi_controller sub-resources. Controllers that have a scsi_controller
+// block are read into that block. Controllers without a block that have bus
+// sharing enabled get a block, so that the bus sharing shows up as a diff
+// when it is not in the configuration. scsi_type is read from the rest of the
+// controllers managed by scsi_controller_count.
+func SCSIBusRefreshOperation(d *schema.ResourceData, l object.VirtualDeviceList) error {
+	log.Printf("[DEBUG] SCSIBusRefreshOperation: Beginning refresh")
+	count := d.Get("scsi_controller_count").(int)
+	ctlrs := make(map[int32]types.BaseVirtualDevice)
+	for _, dev := range l {
+		if sc, ok := dev.(types.BaseVirtualSCSIController); ok && sc.GetVirtualSCSIController().BusNumber < int32(count) {
+			ctlrs[sc.GetVirtualSCSIController().BusNumber] = dev
+		}
+	}
+	skip := make(map[int32]bool)
+	var newSet []interface{}
+	for _, v := range d.Get(subresourceTypeSCSIController).([]interface{}) {
+		m := v.(map[string]interface{})
+		n := int32(m["bus_number"].(int))
+		skip[n] = true
+		if dev, ok := ctlrs[n]; ok {
+			m["type"] = l.Type(dev)
+			m["sharing"] = string(dev.(types.BaseVirtualSCSIController).GetVirtualSCSIController().SharedBus)
+		}
+		newSet = append(newSet, m)
+	}
+	for n := int32(0); n < int32(count); n++ {
+		dev, ok := ctlrs[n]
+		if !ok || skip[n] {
+			continue
+		}
+		sharing := dev.(types.BaseVirtualSCSIController).GetVirtualSCSIController().SharedBus
+		if sharing == "" || sharing == types.VirtualSCSISharingNoSharing {
+			continue
+		}
+		log.Printf("[DEBUG] SCSIBusRefreshOperation: Reading SCSI controller at bus number %d with bus sharing %s", n, sharing)
+		skip[n] = true
+		newSet = append(newSet, map[string]interface{}{
+			"bus_number": int(n),
+			"type":       l.Type(dev),
+			"sharing":    string(sharing),
+		})
+	}
+	// If all of the controllers have a scsi_controller block, scsi_type has
+	// nothing to read from and is left as it is.
+	if ct := readSCSIBusState(l, count, skip); ct != "" {
+		d.Set("scsi_type", ct)
+	}
+	log.Printf("[DEBUG] SCSIBusRefreshOperation: Refresh complete")
+	return d.Set(subresourceTypeSCSIController, newSet)
+}
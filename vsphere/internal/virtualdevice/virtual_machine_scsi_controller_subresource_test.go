@@ -0,0 +1,179 @@
+package virtualdevice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestReadSCSIBusState(t *testing.T) {
+	devices := object.VirtualDeviceList{
+		&types.ParaVirtualSCSIController{
+			VirtualSCSIController: types.VirtualSCSIController{
+				VirtualController: types.VirtualController{
+					BusNumber: 0,
+				},
+			},
+		},
+		&types.VirtualLsiLogicSASController{
+			VirtualSCSIController: types.VirtualSCSIController{
+				VirtualController: types.VirtualController{
+					BusNumber: 1,
+				},
+				SharedBus: types.VirtualSCSISharingPhysicalSharing,
+			},
+		},
+		&types.ParaVirtualSCSIController{
+			VirtualSCSIController: types.VirtualSCSIController{
+				VirtualController: types.VirtualController{
+					BusNumber: 2,
+				},
+			},
+		},
+	}
+	cases := []struct {
+		name     string
+		count    int
+		skip     map[int32]bool
+		expected string
+	}{
+		{
+			name:     "single",
+			count:    1,
+			expected: SubresourceControllerTypeParaVirtual,
+		},
+		{
+			name:     "mixed",
+			count:    3,
+			expected: subresourceControllerTypeMixed,
+		},
+		{
+			name:     "mixed with override",
+			count:    3,
+			skip:     map[int32]bool{1: true},
+			expected: SubresourceControllerTypeParaVirtual,
+		},
+		{
+			name:     "missing controller",
+			count:    4,
+			skip:     map[int32]bool{1: true},
+			expected: subresourceControllerTypeMixed,
+		},
+		{
+			name:     "all overridden",
+			count:    1,
+			skip:     map[int32]bool{0: true},
+			expected: "",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := readSCSIBusState(devices, tc.count, tc.skip)
+			if tc.expected != actual {
+				t.Fatalf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func testSCSIBusResourceData(t *testing.T, raw map[string]interface{}) *schema.ResourceData {
+	s := map[string]*schema.Schema{
+		"scsi_type":                   {Type: schema.TypeString, Optional: true},
+		"scsi_controller_count":       {Type: schema.TypeInt, Optional: true},
+		subresourceTypeSCSIController: {Type: schema.TypeList, Optional: true, Elem: &schema.Resource{Schema: SCSIControllerSchema()}},
+	}
+	return schema.TestResourceDataRaw(t, s, raw)
+}
+
+func TestExpandSCSIBus(t *testing.T) {
+	d := testSCSIBusResourceData(t, map[string]interface{}{
+		"scsi_type":             SubresourceControllerTypeParaVirtual,
+		"scsi_controller_count": 2,
+		subresourceTypeSCSIController: []interface{}{
+			map[string]interface{}{
+				"bus_number": 1,
+				"type":       SubresourceControllerTypeLsiLogicSAS,
+				"sharing":    string(types.VirtualSCSISharingPhysicalSharing),
+			},
+		},
+	})
+	expected := []scsiBusConfig{
+		{ct: SubresourceControllerTypeParaVirtual},
+		{ct: SubresourceControllerTypeLsiLogicSAS, sharing: types.VirtualSCSISharingPhysicalSharing},
+	}
+	if actual := expandSCSIBus(d); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestSCSIBusRefreshOperation(t *testing.T) {
+	devices := object.VirtualDeviceList{
+		&types.ParaVirtualSCSIController{
+			VirtualSCSIController: types.VirtualSCSIController{
+				VirtualController: types.VirtualController{
+					BusNumber: 0,
+				},
+				SharedBus: types.VirtualSCSISharingNoSharing,
+			},
+		},
+		&types.VirtualLsiLogicSASController{
+			VirtualSCSIController: types.VirtualSCSIController{
+				VirtualController: types.VirtualController{
+					BusNumber: 1,
+				},
+				SharedBus: types.VirtualSCSISharingVirtualSharing,
+			},
+		},
+	}
+	d := testSCSIBusResourceData(t, map[string]interface{}{
+		"scsi_type":             SubresourceControllerTypeParaVirtual,
+		"scsi_controller_count": 2,
+	})
+	if err := SCSIBusRefreshOperation(d, devices); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{
+			"bus_number": 1,
+			"type":       SubresourceControllerTypeLsiLogicSAS,
+			"sharing":    string(types.VirtualSCSISharingVirtualSharing),
+		},
+	}
+	if actual := d.Get(subresourceTypeSCSIController).([]interface{}); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+	if actual := d.Get("scsi_type").(string); actual != SubresourceControllerTypeParaVirtual {
+		t.Fatalf("expected scsi_type %q, got %q", SubresourceControllerTypeParaVirtual, actual)
+	}
+}
+
+func TestSCSIBusSharingChanged(t *testing.T) {
+	shared := []interface{}{
+		map[string]interface{}{"bus_number": 1, "sharing": string(types.VirtualSCSISharingPhysicalSharing)},
+	}
+	unshared := []interface{}{
+		map[string]interface{}{"bus_number": 1, "sharing": string(types.VirtualSCSISharingNoSharing)},
+	}
+	cases := []struct {
+		name     string
+		o        []interface{}
+		n        []interface{}
+		expected bool
+	}{
+		{name: "unchanged", o: shared, n: shared, expected: false},
+		{name: "block removed", o: shared, n: nil, expected: true},
+		{name: "block added", o: nil, n: shared, expected: true},
+		{name: "unshared block added", o: nil, n: unshared, expected: false},
+		{name: "sharing turned off", o: shared, n: unshared, expected: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := scsiBusSharingChanged(tc.o, tc.n); actual != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
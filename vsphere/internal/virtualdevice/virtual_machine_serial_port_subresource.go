@@ -0,0 +1,231 @@
+package virtualdevice
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// The backing types that can be set on a serial port.
+const (
+	serialPortBackingTypeNetwork = "network"
+	serialPortBackingTypePipe    = "pipe"
+	serialPortBackingTypeFile    = portBackingTypeFile
+	serialPortBackingTypeDevice  = portBackingTypeDevice
+)
+
+var serialPortBackingTypeAllowedValues = []string{
+	serialPortBackingTypeNetwork,
+	serialPortBackingTypePipe,
+	serialPortBackingTypeFile,
+	serialPortBackingTypeDevice,
+}
+
+var serialPortDirectionAllowedValues = []string{
+	string(types.VirtualDeviceURIBackingOptionDirectionServer),
+	string(types.VirtualDeviceURIBackingOptionDirectionClient),
+}
+
+// serialPortBackingAttributes lists the attributes that are specific to each
+// backing type. The first attribute for each backing type is required.
+var serialPortBackingAttributes = map[string][]string{
+	serialPortBackingTypeNetwork: {"service_uri", "proxy_uri"},
+	serialPortBackingTypePipe:    {"pipe_name"},
+	serialPortBackingTypeFile:    {"path", "datastore_id"},
+	serialPortBackingTypeDevice:  {"device_name"},
+}
+
+// SerialPortSubresourceSchema represents the schema for the serial_port
+// sub-resource.
+func SerialPortSubresourceSchema() map[string]*schema.Schema {
+	return serialPort.subresourceSchema()
+}
+
+// serialPortSchema returns the attributes that are specific to serial ports.
+func serialPortSchema() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"backing_type": {
+			Type:         schema.TypeString,
+			Required:     true,
+			Description:  "The backing type of the serial port. Can be one of network, pipe, file, or device.",
+			ValidateFunc: validation.StringInSlice(serialPortBackingTypeAllowedValues, false),
+		},
+		"direction": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      string(types.VirtualDeviceURIBackingOptionDirectionServer),
+			Description:  "Whether the virtual machine acts as the server or the client end of a network or pipe backed serial port. Can be one of server or client.",
+			ValidateFunc: validation.StringInSlice(serialPortDirectionAllowedValues, false),
+		},
+		"yield_on_poll": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Allow the guest to yield the CPU when it polls the serial port.",
+		},
+		// VirtualDeviceURIBackingInfo
+		"service_uri": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The URI of a network backed serial port, such as telnet://:23 or tcp://10.0.0.1:8000.",
+		},
+		"proxy_uri": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The URI of a virtual serial port concentrator to connect a network backed serial port through.",
+		},
+		// VirtualSerialPortPipeBackingInfo
+		"pipe_name": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The name of the named pipe of a pipe backed serial port.",
+		},
+		"no_rx_loss": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Optimize a pipe backed serial port to not lose received data.",
+		},
+		// VirtualDeviceDeviceBackingInfo
+		"device_name": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The name of the host device of a device backed serial port, such as /dev/ttyS0.",
+		},
+	}
+	structure.MergeSchema(s, portSchema("serial port"))
+	return s
+}
+
+// serialPort describes serial port devices and their backings.
+var serialPort = &portType{
+	srtype:    subresourceTypeSerialPort,
+	name:      "serial port",
+	logPrefix: "SerialPort",
+	schema:    serialPortSchema,
+	newDevice: func() types.BaseVirtualDevice {
+		return new(types.VirtualSerialPort)
+	},
+	backingAttributes: serialPortBackingAttributes,
+	restartKeys:       []string{"backing_type", "direction", "yield_on_poll", "service_uri", "proxy_uri", "pipe_name", "no_rx_loss", "datastore_id", "path", "device_name"},
+	expand:            expandSerialPort,
+	flatten:           flattenSerialPort,
+	validateDiff:      validateSerialPortDiff,
+}
+
+// NewSerialPortSubresource returns a serial_port subresource populated with
+// all of the necessary fields.
+func NewSerialPortSubresource(client *govmomi.Client, rdd resourceDataDiff, d, old map[string]interface{}, idx int) *PortSubresource {
+	return newPortSubresource(serialPort, client, rdd, d, old, idx)
+}
+
+// SerialPortApplyOperation processes an apply operation for all serial ports
+// in the resource. See portApplyOperation for details.
+func SerialPortApplyOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	return portApplyOperation(serialPort, d, c, l)
+}
+
+// SerialPortRefreshOperation processes a refresh operation for all of the
+// serial ports in the resource. See portRefreshOperation for details.
+func SerialPortRefreshOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) error {
+	return portRefreshOperation(serialPort, d, c, l)
+}
+
+// SerialPortPostCloneOperation normalizes serial ports on a freshly-cloned
+// virtual machine. See portPostCloneOperation for details.
+func SerialPortPostCloneOperation(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	return portPostCloneOperation(serialPort, d, c, l)
+}
+
+// SerialPortDiffOperation performs operations relevant to managing the diff on
+// serial_port sub-resources.
+func SerialPortDiffOperation(d *schema.ResourceDiff, c *govmomi.Client) error {
+	return portDiffOperation(serialPort, d, c)
+}
+
+// validateSerialPortDiff checks that no_rx_loss is only set for pipe backed
+// serial ports.
+func validateSerialPortDiff(r *PortSubresource) error {
+	if bt := r.Get("backing_type").(string); r.Get("no_rx_loss").(bool) && bt != serialPortBackingTypePipe {
+		return fmt.Errorf("no_rx_loss cannot be set for %s backed serial ports", bt)
+	}
+	return nil
+}
+
+// expandSerialPort sets the backing and the serial port specific settings of
+// a serial port from the sub-resource data.
+func expandSerialPort(r *PortSubresource, d types.BaseVirtualDevice) error {
+	device := d.(*types.VirtualSerialPort)
+	direction := r.Get("direction").(string)
+	switch bt := r.Get("backing_type").(string); bt {
+	case serialPortBackingTypeNetwork:
+		device.Backing = &types.VirtualSerialPortURIBackingInfo{
+			VirtualDeviceURIBackingInfo: types.VirtualDeviceURIBackingInfo{
+				ServiceURI: r.Get("service_uri").(string),
+				ProxyURI:   r.Get("proxy_uri").(string),
+				Direction:  direction,
+			},
+		}
+	case serialPortBackingTypePipe:
+		device.Backing = &types.VirtualSerialPortPipeBackingInfo{
+			VirtualDevicePipeBackingInfo: types.VirtualDevicePipeBackingInfo{
+				PipeName: r.Get("pipe_name").(string),
+			},
+			Endpoint: direction,
+			NoRxLoss: structure.BoolPtr(r.Get("no_rx_loss").(bool)),
+		}
+	case serialPortBackingTypeFile:
+		backing, err := r.expandFileBacking()
+		if err != nil {
+			return err
+		}
+		device.Backing = &types.VirtualSerialPortFileBackingInfo{
+			VirtualDeviceFileBackingInfo: backing,
+		}
+	case serialPortBackingTypeDevice:
+		device.Backing = &types.VirtualSerialPortDeviceBackingInfo{
+			VirtualDeviceDeviceBackingInfo: r.expandDeviceBacking(),
+		}
+	default:
+		return fmt.Errorf("unsupported serial port backing type %q", bt)
+	}
+	device.YieldOnPoll = r.Get("yield_on_poll").(bool)
+	return nil
+}
+
+// flattenSerialPort reads the backing and the serial port specific settings
+// of a serial port into the sub-resource data.
+func flattenSerialPort(r *PortSubresource, d types.BaseVirtualDevice) error {
+	device := d.(*types.VirtualSerialPort)
+	r.Set("no_rx_loss", false)
+	if v, _ := r.Get("direction").(string); v == "" {
+		r.Set("direction", string(types.VirtualDeviceURIBackingOptionDirectionServer))
+	}
+	switch backing := device.Backing.(type) {
+	case *types.VirtualSerialPortURIBackingInfo:
+		r.Set("backing_type", serialPortBackingTypeNetwork)
+		r.Set("service_uri", backing.ServiceURI)
+		r.Set("proxy_uri", backing.ProxyURI)
+		r.Set("direction", backing.Direction)
+	case *types.VirtualSerialPortPipeBackingInfo:
+		r.Set("backing_type", serialPortBackingTypePipe)
+		r.Set("pipe_name", backing.PipeName)
+		r.Set("direction", backing.Endpoint)
+		r.Set("no_rx_loss", backing.NoRxLoss != nil && *backing.NoRxLoss)
+	case *types.VirtualSerialPortFileBackingInfo:
+		if err := r.flattenFileBacking(&backing.VirtualDeviceFileBackingInfo); err != nil {
+			return err
+		}
+	case *types.VirtualSerialPortDeviceBackingInfo:
+		r.flattenDeviceBacking(&backing.VirtualDeviceDeviceBackingInfo)
+	default:
+		r.clearBacking(backing)
+	}
+	r.Set("yield_on_poll", device.YieldOnPoll)
+	return nil
+}
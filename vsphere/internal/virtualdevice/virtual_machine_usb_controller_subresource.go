@@ -0,0 +1,182 @@
+package virtualdevice
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// subresourceTypeUSBController is the key for the usb_controller
+// sub-resource.
+const subresourceTypeUSBController = "usb_controller"
+
+// The USB controller types that can be added to a virtual machine. A virtual
+// machine can have at most one controller of each type.
+const (
+	usbControllerTypeEHCI = "ehci"
+	usbControllerTypeXHCI = "xhci"
+)
+
+var usbControllerTypeAllowedValues = []string{
+	usbControllerTypeEHCI,
+	usbControllerTypeXHCI,
+}
+
+// USBControllerSchema represents the schema for the usb_controller
+// sub-resource.
+//
+// USB controllers are not tracked by device address like other devices, as a
+// virtual machine can only have one controller of each type. The type of the
+// controller identifies it instead.
+func USBControllerSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"type": {
+			Type:         schema.TypeString,
+			Required:     true,
+			Description:  "The type of the USB controller. Can be one of ehci (USB 2.0) or xhci (USB 3.x).",
+			ValidateFunc: validation.StringInSlice(usbControllerTypeAllowedValues, false),
+		},
+	}
+}
+
+// usbControllerType returns the usb_controller type of the supplied device, or
+// an empty string if the device is not a USB controller.
+func usbControllerType(device types.BaseVirtualDevice) string {
+	switch device.(type) {
+	case *types.VirtualUSBController:
+		return usbControllerTypeEHCI
+	case *types.VirtualUSBXHCIController:
+		return usbControllerTypeXHCI
+	}
+	return ""
+}
+
+// newUSBController returns a new USB controller of the supplied type, attached
+// to the supplied PCI controller.
+func newUSBController(l object.VirtualDeviceList, ct string, ctlr types.BaseVirtualController) types.BaseVirtualDevice {
+	vc := types.VirtualController{
+		VirtualDevice: types.VirtualDevice{
+			Key:           l.NewKey(),
+			ControllerKey: ctlr.GetVirtualController().Key,
+		},
+	}
+	if ct == usbControllerTypeXHCI {
+		return &types.VirtualUSBXHCIController{
+			VirtualController:  vc,
+			AutoConnectDevices: structure.BoolPtr(true),
+		}
+	}
+	return &types.VirtualUSBController{
+		VirtualController:  vc,
+		AutoConnectDevices: structure.BoolPtr(true),
+		EhciEnabled:        structure.BoolPtr(true),
+	}
+}
+
+// USBControllerDiffOperation validates the usb_controller sub-resources.
+func USBControllerDiffOperation(d *schema.ResourceDiff) error {
+	log.Printf("[DEBUG] USBControllerDiffOperation: Beginning diff validation")
+	seen := make(map[string]struct{})
+	for _, v := range d.Get(subresourceTypeUSBController).([]interface{}) {
+		ct := v.(map[string]interface{})["type"].(string)
+		if _, ok := seen[ct]; ok {
+			return fmt.Errorf("%s: duplicate type %q - only one USB controller of each type is supported", subresourceTypeUSBController, ct)
+		}
+		seen[ct] = struct{}{}
+	}
+	log.Printf("[DEBUG] USBControllerDiffOperation: Diff validation complete")
+	return nil
+}
+
+// USBControllerApplyOperation adds and removes USB controllers so that the
+// supplied device list has exactly the controllers in the usb_controller
+// sub-resources. As the operation works off of the device list alone, it is
+// used for both regular apply operations and post-clone operations.
+//
+// Adding or removing USB controllers requires a restart of the virtual
+// machine.
+func USBControllerApplyOperation(d *schema.ResourceData, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] USBControllerApplyOperation: Beginning apply operation")
+	want := make(map[string]bool)
+	for _, v := range d.Get(subresourceTypeUSBController).([]interface{}) {
+		want[v.(map[string]interface{})["type"].(string)] = true
+	}
+
+	var spec []types.BaseVirtualDeviceConfigSpec
+	have := make(map[string]bool)
+	for _, device := range l {
+		ct := usbControllerType(device)
+		if ct == "" {
+			continue
+		}
+		if want[ct] && !have[ct] {
+			have[ct] = true
+			continue
+		}
+		log.Printf("[DEBUG] USBControllerApplyOperation: Removing USB controller: %s", l.Name(device))
+		dspec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationRemove)
+		if err != nil {
+			return nil, nil, err
+		}
+		spec = append(spec, dspec...)
+	}
+	l = applyDeviceChange(l, spec)
+	for _, ct := range usbControllerTypeAllowedValues {
+		if !want[ct] || have[ct] {
+			continue
+		}
+		ctlr := l.PickController(&types.VirtualPCIController{})
+		if ctlr == nil {
+			return nil, nil, fmt.Errorf("could not find an available %s controller", SubresourceControllerTypePCI)
+		}
+		device := newUSBController(l, ct, ctlr)
+		log.Printf("[DEBUG] USBControllerApplyOperation: Adding %s USB controller", ct)
+		cspec, err := object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
+		if err != nil {
+			return nil, nil, err
+		}
+		l = applyDeviceChange(l, cspec)
+		spec = append(spec, cspec...)
+	}
+
+	if len(spec) > 0 {
+		log.Printf("[DEBUG] USBControllerApplyOperation: USB controllers have changed and require a VM restart")
+		d.Set("reboot_required", true)
+	}
+	log.Printf("[DEBUG] USBControllerApplyOperation: Device config operations from apply: %s", DeviceChangeString(spec))
+	log.Printf("[DEBUG] USBControllerApplyOperation: Apply complete, returning updated spec")
+	return l, spec, nil
+}
+
+// USBControllerRefreshOperation reads the USB controllers of the virtual
+// machine into the usb_controller sub-resources. Controllers in state keep
+// their order, and any other controllers are added to the end of the list.
+func USBControllerRefreshOperation(d *schema.ResourceData, l object.VirtualDeviceList) error {
+	log.Printf("[DEBUG] USBControllerRefreshOperation: Beginning refresh")
+	have := make(map[string]bool)
+	for _, device := range l {
+		if ct := usbControllerType(device); ct != "" {
+			have[ct] = true
+		}
+	}
+	var newSet []interface{}
+	for _, v := range d.Get(subresourceTypeUSBController).([]interface{}) {
+		ct := v.(map[string]interface{})["type"].(string)
+		if have[ct] {
+			newSet = append(newSet, map[string]interface{}{"type": ct})
+			delete(have, ct)
+		}
+	}
+	for _, ct := range usbControllerTypeAllowedValues {
+		if have[ct] {
+			newSet = append(newSet, map[string]interface{}{"type": ct})
+		}
+	}
+	log.Printf("[DEBUG] USBControllerRefreshOperation: Refresh complete")
+	return d.Set(subresourceTypeUSBController, newSet)
+}
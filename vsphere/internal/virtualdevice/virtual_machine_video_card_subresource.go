@@ -0,0 +1,205 @@
+package virtualdevice
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// subresourceTypeVideoCard is the key for the video_card sub-resource.
+const subresourceTypeVideoCard = "video_card"
+
+var videoCardUse3dRendererAllowedValues = []string{
+	string(types.VirtualMachineVideoCardUse3dRendererAutomatic),
+	string(types.VirtualMachineVideoCardUse3dRendererSoftware),
+	string(types.VirtualMachineVideoCardUse3dRendererHardware),
+}
+
+// VideoCardSchema represents the schema for the video_card sub-resource.
+//
+// Every virtual machine has exactly one video card, so the sub-resource
+// configures the existing video card rather than adding or removing one.
+// Settings that are not configured keep the value that the video card
+// already has, such as the defaults of the guest OS or the settings of the
+// template that the virtual machine was cloned from.
+func VideoCardSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"video_ram_size_kb": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Computed:     true,
+			Description:  "The amount of video memory, in KB. Ignored when auto_detect is enabled.",
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+		"num_displays": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Computed:     true,
+			Description:  "The number of displays. Ignored when auto_detect is enabled.",
+			ValidateFunc: validation.IntBetween(1, 10),
+		},
+		"auto_detect": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Computed:    true,
+			Description: "Let the guest OS determine the number of displays and the amount of video memory.",
+		},
+		"enable_3d": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Computed:    true,
+			Description: "Enable 3D acceleration.",
+		},
+		"use_3d_renderer": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Computed:     true,
+			Description:  "The 3D renderer to use when enable_3d is set. Can be one of automatic, software, or hardware.",
+			ValidateFunc: validation.StringInSlice(videoCardUse3dRendererAllowedValues, false),
+		},
+		"graphics_memory_size_kb": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Computed:     true,
+			Description:  "The amount of graphics memory for 3D acceleration, in KB.",
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+	}
+}
+
+// VideoCardApplyOperation configures the video card of the virtual machine
+// with the settings in the video_card sub-resource. A video card is added if
+// the device list does not have one. As the operation works off of the device
+// list alone, it is used for both regular apply operations and post-clone
+// operations.
+//
+// Changing the video card requires a restart of the virtual machine.
+func VideoCardApplyOperation(d *schema.ResourceData, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] VideoCardApplyOperation: Beginning apply operation")
+	vcs := d.Get(subresourceTypeVideoCard).([]interface{})
+	if len(vcs) < 1 || vcs[0] == nil {
+		log.Printf("[DEBUG] VideoCardApplyOperation: No video card settings, skipping")
+		return l, nil, nil
+	}
+	m := videoCardConfiguredData(d, vcs[0].(map[string]interface{}))
+
+	var current *types.VirtualMachineVideoCard
+	op := types.VirtualDeviceConfigSpecOperationEdit
+	if devices := l.SelectByType((*types.VirtualMachineVideoCard)(nil)); len(devices) > 0 {
+		current = devices[0].(*types.VirtualMachineVideoCard)
+	} else {
+		ctlr := l.PickController(&types.VirtualPCIController{})
+		if ctlr == nil {
+			return nil, nil, fmt.Errorf("could not find an available %s controller", SubresourceControllerTypePCI)
+		}
+		current = &types.VirtualMachineVideoCard{
+			VirtualDevice: types.VirtualDevice{
+				Key:           l.NewKey(),
+				ControllerKey: ctlr.GetVirtualController().Key,
+			},
+		}
+		op = types.VirtualDeviceConfigSpecOperationAdd
+	}
+	device := expandVideoCard(m, current)
+
+	var spec []types.BaseVirtualDeviceConfigSpec
+	if op == types.VirtualDeviceConfigSpecOperationAdd || !reflect.DeepEqual(flattenVideoCard(current), flattenVideoCard(device)) {
+		log.Printf("[DEBUG] VideoCardApplyOperation: Configuring video card: %s", l.Name(device))
+		dspec, err := object.VirtualDeviceList{device}.ConfigSpec(op)
+		if err != nil {
+			return nil, nil, err
+		}
+		spec = append(spec, dspec...)
+		l = applyDeviceChange(l, spec)
+		log.Printf("[DEBUG] VideoCardApplyOperation: Video card has changed and requires a VM restart")
+		d.Set("reboot_required", true)
+	}
+	log.Printf("[DEBUG] VideoCardApplyOperation: Device config operations from apply: %s", DeviceChangeString(spec))
+	log.Printf("[DEBUG] VideoCardApplyOperation: Apply complete, returning updated spec")
+	return l, spec, nil
+}
+
+// VideoCardRefreshOperation reads the video card of the virtual machine into
+// the video_card sub-resource.
+func VideoCardRefreshOperation(d *schema.ResourceData, l object.VirtualDeviceList) error {
+	log.Printf("[DEBUG] VideoCardRefreshOperation: Beginning refresh")
+	var vcs []interface{}
+	if devices := l.SelectByType((*types.VirtualMachineVideoCard)(nil)); len(devices) > 0 {
+		vcs = append(vcs, flattenVideoCard(devices[0].(*types.VirtualMachineVideoCard)))
+	}
+	log.Printf("[DEBUG] VideoCardRefreshOperation: Refresh complete")
+	return d.Set(subresourceTypeVideoCard, vcs)
+}
+
+// videoCardBoolKeys are the boolean settings of the video_card sub-resource.
+// Their zero value can't be told apart from an unset value in the
+// sub-resource data.
+var videoCardBoolKeys = []string{"auto_detect", "enable_3d"}
+
+// videoCardConfiguredData returns a copy of the video_card sub-resource data
+// without the boolean settings that are neither configured nor in state, so
+// that expandVideoCard leaves them at their current value.
+func videoCardConfiguredData(d *schema.ResourceData, m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, v := range m {
+		result[k] = v
+	}
+	for _, k := range videoCardBoolKeys {
+		if _, ok := d.GetOkExists(fmt.Sprintf("%s.0.%s", subresourceTypeVideoCard, k)); !ok {
+			delete(result, k)
+		}
+	}
+	return result
+}
+
+// expandVideoCard returns a copy of the supplied video card with the settings
+// in the video_card sub-resource data applied. Settings that are not set, and
+// boolean settings that are missing from the data, keep their current value.
+func expandVideoCard(m map[string]interface{}, current *types.VirtualMachineVideoCard) *types.VirtualMachineVideoCard {
+	device := *current
+	if v := m["video_ram_size_kb"].(int); v > 0 {
+		device.VideoRamSizeInKB = int64(v)
+	}
+	if v := m["num_displays"].(int); v > 0 {
+		device.NumDisplays = int32(v)
+	}
+	if v, ok := m["auto_detect"].(bool); ok {
+		device.UseAutoDetect = structure.BoolPtr(v)
+	}
+	if v, ok := m["enable_3d"].(bool); ok {
+		device.Enable3DSupport = structure.BoolPtr(v)
+	}
+	if v := m["use_3d_renderer"].(string); v != "" {
+		device.Use3dRenderer = v
+	}
+	if v := m["graphics_memory_size_kb"].(int); v > 0 {
+		device.GraphicsMemorySizeInKB = int64(v)
+	}
+	return &device
+}
+
+// flattenVideoCard returns the video_card sub-resource data for the supplied
+// video card.
+func flattenVideoCard(device *types.VirtualMachineVideoCard) map[string]interface{} {
+	var autoDetect, enable3D bool
+	if device.UseAutoDetect != nil {
+		autoDetect = *device.UseAutoDetect
+	}
+	if device.Enable3DSupport != nil {
+		enable3D = *device.Enable3DSupport
+	}
+	return map[string]interface{}{
+		"video_ram_size_kb":       int(device.VideoRamSizeInKB),
+		"num_displays":            int(device.NumDisplays),
+		"auto_detect":             autoDetect,
+		"enable_3d":               enable3D,
+		"use_3d_renderer":         device.Use3dRenderer,
+		"graphics_memory_size_kb": int(device.GraphicsMemorySizeInKB),
+	}
+}
@@ -0,0 +1,113 @@
+package virtualdevice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestExpandVideoCard(t *testing.T) {
+	current := &types.VirtualMachineVideoCard{
+		VirtualDevice:          types.VirtualDevice{Key: 500},
+		VideoRamSizeInKB:       4096,
+		NumDisplays:            1,
+		UseAutoDetect:          structure.BoolPtr(false),
+		Enable3DSupport:        structure.BoolPtr(false),
+		Use3dRenderer:          string(types.VirtualMachineVideoCardUse3dRendererAutomatic),
+		GraphicsMemorySizeInKB: 262144,
+	}
+	cases := []struct {
+		name     string
+		data     map[string]interface{}
+		current  *types.VirtualMachineVideoCard
+		unset    []string
+		expected map[string]interface{}
+	}{
+		{
+			name: "unset settings keep their value",
+			data: map[string]interface{}{
+				"num_displays": 2,
+			},
+			expected: map[string]interface{}{
+				"video_ram_size_kb":       4096,
+				"num_displays":            2,
+				"auto_detect":             false,
+				"enable_3d":               false,
+				"use_3d_renderer":         "automatic",
+				"graphics_memory_size_kb": 262144,
+			},
+		},
+		{
+			name: "3d acceleration",
+			data: map[string]interface{}{
+				"video_ram_size_kb":       16384,
+				"enable_3d":               true,
+				"use_3d_renderer":         "hardware",
+				"graphics_memory_size_kb": 524288,
+			},
+			expected: map[string]interface{}{
+				"video_ram_size_kb":       16384,
+				"num_displays":            1,
+				"auto_detect":             false,
+				"enable_3d":               true,
+				"use_3d_renderer":         "hardware",
+				"graphics_memory_size_kb": 524288,
+			},
+		},
+		{
+			name: "unconfigured booleans keep their value",
+			data: map[string]interface{}{
+				"video_ram_size_kb": 8192,
+			},
+			current: &types.VirtualMachineVideoCard{
+				VideoRamSizeInKB: 4096,
+				NumDisplays:      1,
+				UseAutoDetect:    structure.BoolPtr(true),
+				Enable3DSupport:  structure.BoolPtr(true),
+			},
+			unset: videoCardBoolKeys,
+			expected: map[string]interface{}{
+				"video_ram_size_kb":       8192,
+				"num_displays":            1,
+				"auto_detect":             true,
+				"enable_3d":               true,
+				"use_3d_renderer":         "",
+				"graphics_memory_size_kb": 0,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := make(map[string]interface{})
+			for k, s := range VideoCardSchema() {
+				switch s.Type.String() {
+				case "TypeInt":
+					data[k] = 0
+				case "TypeBool":
+					data[k] = false
+				default:
+					data[k] = ""
+				}
+			}
+			for k, v := range tc.data {
+				data[k] = v
+			}
+			for _, k := range tc.unset {
+				delete(data, k)
+			}
+			c := current
+			if tc.current != nil {
+				c = tc.current
+			}
+			device := expandVideoCard(data, c)
+			if device.Key != c.Key {
+				t.Fatalf("expected key %d, got %d", c.Key, device.Key)
+			}
+			if actual := flattenVideoCard(device); !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("expected %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package virtualdevice
+
+import (
+	"log"
+	"reflect"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// VirtualTPM is a virtual Trusted Platform Module 2.0 device.
+//
+// This type was added in vSphere 6.7 and is not yet available in govmomi, so
+// it is defined and registered with the vim25 type registry here. The name of
+// the type must match the name of the type in the API.
+type VirtualTPM struct {
+	types.VirtualDevice
+
+	EndorsementKeyCertificateSigningRequest [][]byte `xml:"endorsementKeyCertificateSigningRequest,omitempty"`
+	EndorsementKeyCertificate               [][]byte `xml:"endorsementKeyCertificate,omitempty"`
+}
+
+func init() {
+	types.Add("VirtualTPM", reflect.TypeOf((*VirtualTPM)(nil)).Elem())
+}
+
+// VTPMApplyOperation adds or removes the virtual TPM of the virtual machine,
+// depending on the value of vtpm. As the operation works off of the device
+// list alone, it is used for both regular apply operations and post-clone
+// operations.
+//
+// Adding or removing a virtual TPM requires a restart of the virtual machine.
+func VTPMApplyOperation(d *schema.ResourceData, l object.VirtualDeviceList) (object.VirtualDeviceList, []types.BaseVirtualDeviceConfigSpec, error) {
+	log.Printf("[DEBUG] VTPMApplyOperation: Beginning apply operation")
+	devices := l.SelectByType((*VirtualTPM)(nil))
+	var spec []types.BaseVirtualDeviceConfigSpec
+	var err error
+	switch want := d.Get("vtpm").(bool); {
+	case want && len(devices) < 1:
+		log.Printf("[DEBUG] VTPMApplyOperation: Adding virtual TPM")
+		device := &VirtualTPM{
+			VirtualDevice: types.VirtualDevice{
+				Key: l.NewKey(),
+			},
+		}
+		spec, err = object.VirtualDeviceList{device}.ConfigSpec(types.VirtualDeviceConfigSpecOperationAdd)
+	case !want && len(devices) > 0:
+		log.Printf("[DEBUG] VTPMApplyOperation: Removing virtual TPM")
+		spec, err = devices.ConfigSpec(types.VirtualDeviceConfigSpecOperationRemove)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	l = applyDeviceChange(l, spec)
+
+	if len(spec) > 0 {
+		log.Printf("[DEBUG] VTPMApplyOperation: Virtual TPM has changed and requires a VM restart")
+		d.Set("reboot_required", true)
+	}
+	log.Printf("[DEBUG] VTPMApplyOperation: Device config operations from apply: %s", DeviceChangeString(spec))
+	log.Printf("[DEBUG] VTPMApplyOperation: Apply complete, returning updated spec")
+	return l, spec, nil
+}
+
+// VTPMRefreshOperation sets vtpm if the virtual machine has a virtual TPM.
+func VTPMRefreshOperation(d *schema.ResourceData, l object.VirtualDeviceList) error {
+	log.Printf("[DEBUG] VTPMRefreshOperation: Beginning refresh")
+	devices := l.SelectByType((*VirtualTPM)(nil))
+	log.Printf("[DEBUG] VTPMRefreshOperation: Refresh complete")
+	return d.Set("vtpm", len(devices) > 0)
+}
@@ -1,14 +1,22 @@
 package vmworkflow
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customizationspec"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/datastore"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/hostsystem"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/provider"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/resourcepool"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/virtualdevice"
 	"github.com/vmware/govmomi"
@@ -25,14 +33,40 @@ import (
 func VirtualMachineCloneSchema() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"template_uuid": {
-			Type:        schema.TypeString,
-			Required:    true,
-			Description: "The UUID of the source virtual machine or template.",
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"clone.0.content_library_item_id"},
+			Description:   "The UUID of the source virtual machine or template.",
+		},
+		"content_library_item_id": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"clone.0.template_uuid"},
+			Description:   "The ID of a content library item to deploy the virtual machine from. The item must be an OVF template or a VM template.",
 		},
 		"linked_clone": {
-			Type:        schema.TypeBool,
-			Optional:    true,
-			Description: "Whether or not to create a linked clone when cloning. When this option is used, the source VM must have a single snapshot associated with it.",
+			Type:          schema.TypeBool,
+			Optional:      true,
+			ConflictsWith: []string{"clone.0.instant_clone"},
+			Description:   "Whether or not to create a linked clone when cloning. When this option is used, the source VM must have a single snapshot associated with it, unless snapshot_name or snapshot_id is set.",
+		},
+		"snapshot_name": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"clone.0.snapshot_id", "clone.0.content_library_item_id", "clone.0.instant_clone"},
+			Description:   "The name of the snapshot of the source VM to clone from. The name must be unique within the snapshot tree of the source.",
+		},
+		"snapshot_id": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"clone.0.snapshot_name", "clone.0.content_library_item_id", "clone.0.instant_clone"},
+			Description:   "The managed object ID of the snapshot of the source VM to clone from.",
+		},
+		"instant_clone": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			ConflictsWith: []string{"clone.0.linked_clone", "clone.0.content_library_item_id", "clone.0.customize", "clone.0.snapshot_name", "clone.0.snapshot_id"},
+			Description:   "Whether or not to create an instant clone when cloning. When this option is used, the source VM must be powered on or frozen, and the new virtual machine starts from its running state instead of booting.",
 		},
 		"timeout": {
 			Type:         schema.TypeInt,
@@ -57,8 +91,14 @@ func VirtualMachineCloneSchema() map[string]*schema.Schema {
 // the new VM configuration line up with the configuration in the existing
 // template, and checking to make sure that the VM has a single snapshot we can
 // use in the even that linked clones are enabled.
+//
+// The snapshot that the clone will be made from, if any, is resolved here and
+// saved to clone_snapshot_id.
 func ValidateVirtualMachineClone(d *schema.ResourceDiff, c *govmomi.Client) error {
 	tUUID := d.Get("clone.0.template_uuid").(string)
+	if tUUID == "" {
+		return errors.New("one of template_uuid or content_library_item_id must be set in clone")
+	}
 	log.Printf("[DEBUG] ValidateVirtualMachineClone: Validating fitness of source VM/template %s", tUUID)
 	vm, err := virtualmachine.FromUUID(c, tUUID)
 	if err != nil {
@@ -68,8 +108,15 @@ func ValidateVirtualMachineClone(d *schema.ResourceDiff, c *govmomi.Client) erro
 	if err != nil {
 		return fmt.Errorf("error fetching virtual machine or template properties: %s", err)
 	}
-	// The virtual machine needs to be powered off to be suitable for cloning.
-	if vprops.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOff {
+	// The virtual machine needs to be powered off to be suitable for cloning,
+	// unless we are doing an instant clone, in which case it needs to be
+	// running. Frozen virtual machines report as powered on.
+	instant := d.Get("clone.0.instant_clone").(bool)
+	if instant {
+		if err := validateInstantClone(d, c, vprops); err != nil {
+			return err
+		}
+	} else if vprops.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOff {
 		return fmt.Errorf("virtual machine %s must be powered off to be used as a source for cloning", tUUID)
 	}
 	// Check to see if our guest IDs match.
@@ -78,38 +125,47 @@ func ValidateVirtualMachineClone(d *schema.ResourceDiff, c *govmomi.Client) erro
 	if eGuestID != aGuestID {
 		return fmt.Errorf("invalid guest ID %q for clone. Please set it to %q", aGuestID, eGuestID)
 	}
-	// If linked clone is enabled, check to see if we have a snapshot. There need
-	// to be a single snapshot on the template for it to be eligible.
+	// Resolve the snapshot to clone from. If linked clone is enabled and no
+	// snapshot has been pinned, there need to be a single snapshot on the
+	// template for it to be eligible.
 	linked := d.Get("clone.0.linked_clone").(bool)
-	if linked {
-		log.Printf("[DEBUG] ValidateVirtualMachineClone: Checking snapshots on %s for linked clone eligibility", tUUID)
-		if err := validateCloneSnapshots(vprops); err != nil {
-			return err
-		}
+	log.Printf("[DEBUG] ValidateVirtualMachineClone: Checking snapshots on %s", tUUID)
+	snapshot, err := resolveCloneSnapshot(
+		vprops,
+		d.Get("clone.0.snapshot_name").(string),
+		d.Get("clone.0.snapshot_id").(string),
+		linked,
+	)
+	if err != nil {
+		return err
+	}
+	var snapshotID string
+	if snapshot != nil {
+		snapshotID = snapshot.Value
 	}
+	d.SetNew("clone_snapshot_id", snapshotID)
 	// Check to make sure the disks for this VM/template line up with the disks
 	// in the configuration. This is in the virtual device package, so pass off
 	// to that now.
-	l := object.VirtualDeviceList(vprops.Config.Hardware.Device)
-	if err := virtualdevice.DiskCloneValidateOperation(d, c, l, linked); err != nil {
+	l, err := cloneSourceDevices(c, vprops, snapshot)
+	if err != nil {
 		return err
 	}
-
-	// If a customization spec was defined, we need to check some items in it as well.
-	if len(d.Get("clone.0.customize").([]interface{})) > 0 {
-		poolID := d.Get("resource_pool_id").(string)
-		pool, err := resourcepool.FromID(c, poolID)
-		if err != nil {
-			return fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
-		}
-		family, err := resourcepool.OSFamily(c, pool, d.Get("guest_id").(string))
-		if err != nil {
-			return fmt.Errorf("cannot find OS family for guest ID %q: %s", d.Get("guest_id").(string), err)
-		}
-		if err := ValidateCustomizationSpec(d, family); err != nil {
+	// Instant clones share the disks of the source through child disks, the
+	// same way linked clones do, so the same restrictions apply.
+	if err := virtualdevice.DiskCloneValidateOperation(d, c, l, linked || instant); err != nil {
+		return err
+	}
+	if instant {
+		if err := validateInstantCloneConfig(d, vprops, l); err != nil {
 			return err
 		}
 	}
+
+	// If a customization spec was defined, we need to check some items in it as well.
+	if err := ValidateCloneCustomization(d, c); err != nil {
+		return err
+	}
 	vconfig := vprops.Config.VAppConfig
 	if vconfig != nil {
 		// We need to set the vApp transport types here so that it is available
@@ -122,6 +178,254 @@ func ValidateVirtualMachineClone(d *schema.ResourceDiff, c *govmomi.Client) erro
 	return nil
 }
 
+// ValidateVirtualMachineLibraryClone does pre-creation validation of a
+// virtual machine that is being cloned from a content library item. As the
+// virtual machine hardware is not known until the item is deployed, only the
+// item type and the parts of the configuration that do not depend on the
+// source can be checked here.
+func ValidateVirtualMachineLibraryClone(d *schema.ResourceDiff, c *govmomi.Client, lc *contentlibrary.Client) error {
+	id := d.Get("clone.0.content_library_item_id").(string)
+	log.Printf("[DEBUG] ValidateVirtualMachineLibraryClone: Validating fitness of content library item %s", id)
+	ctx, cancel := context.WithTimeout(context.Background(), provider.DefaultAPITimeout)
+	defer cancel()
+	item, err := lc.GetLibraryItem(ctx, id)
+	if err != nil {
+		return fmt.Errorf("cannot locate content library item %q: %s", id, err)
+	}
+	switch item.Type {
+	case contentlibrary.ItemTypeOvf, contentlibrary.ItemTypeVMTemplate:
+	default:
+		return fmt.Errorf("content library item %q is of unsupported type %q - must be one of %s or %s", id, item.Type, contentlibrary.ItemTypeOvf, contentlibrary.ItemTypeVMTemplate)
+	}
+	if d.Get("clone.0.linked_clone").(bool) {
+		return errors.New("linked_clone is not supported when cloning from a content library item")
+	}
+	if _, ok := d.GetOk("datastore_cluster_id"); ok {
+		return errors.New("datastore_cluster_id is not supported when cloning from a content library item, use datastore_id instead")
+	}
+	if err := ValidateCloneCustomization(d, c); err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] ValidateVirtualMachineLibraryClone: Content library item %s is a suitable source for cloning", id)
+	return nil
+}
+
+// validateInstantClone checks that the source virtual machine and the
+// configuration are suitable for an instant clone.
+func validateInstantClone(d *schema.ResourceDiff, c *govmomi.Client, props *mo.VirtualMachine) error {
+	version := viapi.ParseVersionFromClient(c)
+	if version.Older(viapi.VSphereVersion{Product: version.Product, Major: 6, Minor: 7}) {
+		return fmt.Errorf("instant_clone is only supported on vSphere 6.7 and higher")
+	}
+	if props.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+		return fmt.Errorf("virtual machine %s must be powered on or frozen to be used as a source for instant cloning", props.Config.Uuid)
+	}
+	if _, ok := d.GetOk("datastore_cluster_id"); ok {
+		return errors.New("datastore_cluster_id is not supported with instant_clone, use datastore_id instead")
+	}
+	return nil
+}
+
+// instantCloneSetting is a setting of an instant clone that has to match the
+// setting of the source virtual machine.
+type instantCloneSetting struct {
+	key    string
+	source interface{}
+}
+
+// validateInstantCloneConfig checks that the configuration only differs from
+// the source virtual machine in settings that can be changed while the virtual
+// machine is running. Instant clones are already running when the rest of the
+// configuration is applied, and a failed reconfiguration would destroy the new
+// virtual machine.
+func validateInstantCloneConfig(d *schema.ResourceDiff, props *mo.VirtualMachine, l object.VirtualDeviceList) error {
+	hw := props.Config.Hardware
+	cpuHotAdd := props.Config.CpuHotAddEnabled != nil && *props.Config.CpuHotAddEnabled
+	cpuHotRemove := props.Config.CpuHotRemoveEnabled != nil && *props.Config.CpuHotRemoveEnabled
+	memoryHotAdd := props.Config.MemoryHotAddEnabled != nil && *props.Config.MemoryHotAddEnabled
+	switch cpus := int32(d.Get("num_cpus").(int)); {
+	case cpus > hw.NumCPU && !cpuHotAdd:
+		return fmt.Errorf("num_cpus cannot be increased from %d to %d on an instant clone, as CPU hot add is not enabled on the source virtual machine", hw.NumCPU, cpus)
+	case cpus < hw.NumCPU && !cpuHotRemove:
+		return fmt.Errorf("num_cpus cannot be decreased from %d to %d on an instant clone, as CPU hot remove is not enabled on the source virtual machine", hw.NumCPU, cpus)
+	}
+	switch memory := int32(d.Get("memory").(int)); {
+	case memory > hw.MemoryMB && !memoryHotAdd:
+		return fmt.Errorf("memory cannot be increased from %d to %d on an instant clone, as memory hot add is not enabled on the source virtual machine", hw.MemoryMB, memory)
+	case memory < hw.MemoryMB:
+		return fmt.Errorf("memory cannot be decreased from %d to %d on an instant clone", hw.MemoryMB, memory)
+	}
+	coresPerSocket := int(hw.NumCoresPerSocket)
+	if coresPerSocket < 1 {
+		coresPerSocket = 1
+	}
+	settings := []instantCloneSetting{
+		{key: "num_cores_per_socket", source: coresPerSocket},
+		{key: "firmware", source: props.Config.Firmware},
+		{key: "cpu_hot_add_enabled", source: cpuHotAdd},
+		{key: "cpu_hot_remove_enabled", source: cpuHotRemove},
+		{key: "memory_hot_add_enabled", source: memoryHotAdd},
+	}
+	// The type of the first SCSI controller is only compared if the source has
+	// one, as SCSI controllers can be added to a running virtual machine.
+	if scsiType := virtualdevice.ReadSCSIBusState(l, 1); scsiType != "unknown" {
+		settings = append(settings, instantCloneSetting{key: "scsi_type", source: scsiType})
+	}
+	for _, setting := range settings {
+		if v := d.Get(setting.key); v != setting.source {
+			return fmt.Errorf("%s cannot be changed from %v to %v on an instant clone, as the virtual machine is running when it is reconfigured", setting.key, setting.source, v)
+		}
+	}
+	return nil
+}
+
+// ValidateCloneCustomization validates the customization spec for a clone,
+// if one has been defined.
+func ValidateCloneCustomization(d *schema.ResourceDiff, c *govmomi.Client) error {
+	if len(d.Get("clone.0.customize").([]interface{})) < 1 {
+		return nil
+	}
+	poolID := d.Get("resource_pool_id").(string)
+	pool, err := resourcepool.FromID(c, poolID)
+	if err != nil {
+		return fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	family, err := resourcepool.OSFamily(c, pool, d.Get("guest_id").(string))
+	if err != nil {
+		return fmt.Errorf("cannot find OS family for guest ID %q: %s", d.Get("guest_id").(string), err)
+	}
+	// The stored spec may be created in the same apply, in which case it's
+	// only checked on creation of the virtual machine.
+	if name := d.Get("clone.0.customize.0.spec_name").(string); name != "" {
+		item, err := customizationspec.FromName(c, name)
+		switch {
+		case customizationspec.IsNotFoundError(err):
+		case err != nil:
+			return fmt.Errorf("cannot locate customization spec %q: %s", name, err)
+		case customizationSpecFamily(item.Info.Type) != family:
+			return fmt.Errorf("customization spec %q is a %s spec and cannot be used for guest ID %q", name, item.Info.Type, d.Get("guest_id").(string))
+		}
+	}
+	return ValidateCustomizationSpec(d, c, family)
+}
+
+// customizationSpecFamily returns the guest OS family for the type of a
+// stored customization spec.
+func customizationSpecFamily(specType string) string {
+	switch specType {
+	case customizationspec.TypeLinux:
+		return string(types.VirtualMachineGuestOsFamilyLinuxGuest)
+	case customizationspec.TypeWindows:
+		return string(types.VirtualMachineGuestOsFamilyWindowsGuest)
+	}
+	return ""
+}
+
+// DiffCloneSnapshot resolves the snapshot that an existing virtual machine
+// would be cloned from today, and updates clone_snapshot_id if it differs from
+// the snapshot the virtual machine was originally cloned from. This does not
+// force a new resource, but makes changes to the snapshot tree of the source,
+// such as a new current snapshot or a snapshot being replaced, show up in the
+// plan.
+//
+// Failures to look up the source are not errors, as the source of a clone is
+// free to be removed once the clone is complete.
+func DiffCloneSnapshot(d *schema.ResourceDiff, c *govmomi.Client) error {
+	tUUID := d.Get("clone.0.template_uuid").(string)
+	name := d.Get("clone.0.snapshot_name").(string)
+	id := d.Get("clone.0.snapshot_id").(string)
+	linked := d.Get("clone.0.linked_clone").(bool)
+	if tUUID == "" || (name == "" && id == "" && !linked) {
+		return nil
+	}
+	vm, err := virtualmachine.FromUUID(c, tUUID)
+	if err != nil {
+		log.Printf("[DEBUG] DiffCloneSnapshot: Cannot locate source VM/template %s, skipping: %s", tUUID, err)
+		return nil
+	}
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return fmt.Errorf("error fetching virtual machine or template properties: %s", err)
+	}
+	var snapshotID string
+	snapshot, err := resolveCloneSnapshot(vprops, name, id, linked)
+	if err != nil {
+		log.Printf("[DEBUG] DiffCloneSnapshot: Snapshot no longer resolves on %s: %s", tUUID, err)
+	} else {
+		snapshotID = snapshot.Value
+	}
+	if old := d.Get("clone_snapshot_id").(string); old != snapshotID {
+		log.Printf("[DEBUG] DiffCloneSnapshot: Clone snapshot has changed from %q to %q", old, snapshotID)
+		return d.SetNew("clone_snapshot_id", snapshotID)
+	}
+	return nil
+}
+
+// resolveCloneSnapshot returns the snapshot that a virtual machine should be
+// cloned from. A snapshot pinned by name or ID takes precedence, otherwise the
+// current snapshot is used for linked clones, after checking that there is no
+// ambiguity in selecting it. nil is returned if the clone should be made from
+// the current state of the source.
+func resolveCloneSnapshot(props *mo.VirtualMachine, name, id string, linked bool) (*types.ManagedObjectReference, error) {
+	switch {
+	case name != "" || id != "":
+		return findCloneSnapshot(props, name, id)
+	case linked:
+		if err := validateCloneSnapshots(props); err != nil {
+			return nil, err
+		}
+		return props.Snapshot.CurrentSnapshot, nil
+	}
+	return nil, nil
+}
+
+// findCloneSnapshot searches the snapshot tree of a virtual machine for a
+// snapshot by either name or managed object ID. Names need to be unique in
+// the tree to be used.
+func findCloneSnapshot(props *mo.VirtualMachine, name, id string) (*types.ManagedObjectReference, error) {
+	if props.Snapshot == nil {
+		return nil, fmt.Errorf("virtual machine or template %s has no snapshots", props.Config.Uuid)
+	}
+	var found []types.ManagedObjectReference
+	var walk func([]types.VirtualMachineSnapshotTree)
+	walk = func(trees []types.VirtualMachineSnapshotTree) {
+		for _, tree := range trees {
+			if (id != "" && tree.Snapshot.Value == id) || (name != "" && tree.Name == name) {
+				found = append(found, tree.Snapshot)
+			}
+			walk(tree.ChildSnapshotList)
+		}
+	}
+	walk(props.Snapshot.RootSnapshotList)
+
+	desc := fmt.Sprintf("ID %q", id)
+	if name != "" {
+		desc = fmt.Sprintf("name %q", name)
+	}
+	switch {
+	case len(found) < 1:
+		return nil, fmt.Errorf("virtual machine or template %s has no snapshot with %s", props.Config.Uuid, desc)
+	case len(found) > 1:
+		return nil, fmt.Errorf("virtual machine or template %s has %d snapshots with %s, use snapshot_id instead", props.Config.Uuid, len(found), desc)
+	}
+	return &found[0], nil
+}
+
+// cloneSourceDevices returns the device list that a clone will be made from.
+// This is the device list of the source, unless the clone is made from a
+// snapshot, in which case it is the device list of the snapshot, as the
+// hardware of the source may have changed since the snapshot was taken.
+func cloneSourceDevices(c *govmomi.Client, props *mo.VirtualMachine, snapshot *types.ManagedObjectReference) (object.VirtualDeviceList, error) {
+	if snapshot == nil {
+		return object.VirtualDeviceList(props.Config.Hardware.Device), nil
+	}
+	sprops, err := virtualmachine.SnapshotProperties(c, *snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching properties of snapshot %q: %s", snapshot.Value, err)
+	}
+	return object.VirtualDeviceList(sprops.Config.Hardware.Device), nil
+}
+
 // validateCloneSnapshots checks a VM to make sure it has a single snapshot
 // with no children, to make sure there is no ambiguity when selecting a
 // snapshot for linked clones.
@@ -174,18 +478,27 @@ func ExpandVirtualMachineCloneSpec(d *schema.ResourceData, c *govmomi.Client) (t
 	if err != nil {
 		return spec, nil, fmt.Errorf("error fetching virtual machine or template properties: %s", err)
 	}
-	// If we are creating a linked clone, grab the current snapshot of the
-	// source, and populate the appropriate field. This should have already been
-	// validated, but just in case, validate it again here.
+	// Grab the snapshot to clone from, if any. This is either a pinned
+	// snapshot, or the current snapshot of the source if we are creating a
+	// linked clone. This should have already been validated, but just in case,
+	// validate it again here.
+	log.Printf("[DEBUG] ExpandVirtualMachineCloneSpec: Fetching snapshot for VM/template UUID %s", tUUID)
+	snapshot, err := resolveCloneSnapshot(
+		vprops,
+		d.Get("clone.0.snapshot_name").(string),
+		d.Get("clone.0.snapshot_id").(string),
+		d.Get("clone.0.linked_clone").(bool),
+	)
+	if err != nil {
+		return spec, nil, err
+	}
+	if snapshot != nil {
+		spec.Snapshot = snapshot
+		log.Printf("[DEBUG] ExpandVirtualMachineCloneSpec: Snapshot for clone: %s", snapshot.Value)
+	}
 	if d.Get("clone.0.linked_clone").(bool) {
 		log.Printf("[DEBUG] ExpandVirtualMachineCloneSpec: Clone type is a linked clone")
-		log.Printf("[DEBUG] ExpandVirtualMachineCloneSpec: Fetching snapshot for VM/template UUID %s", tUUID)
-		if err := validateCloneSnapshots(vprops); err != nil {
-			return spec, nil, err
-		}
-		spec.Snapshot = vprops.Snapshot.CurrentSnapshot
 		spec.Location.DiskMoveType = string(types.VirtualMachineRelocateDiskMoveOptionsCreateNewChildDiskBacking)
-		log.Printf("[DEBUG] ExpandVirtualMachineCloneSpec: Snapshot for clone: %s", vprops.Snapshot.CurrentSnapshot.Value)
 	}
 
 	// Set the target host system and resource pool.
@@ -214,12 +527,134 @@ func ExpandVirtualMachineCloneSpec(d *schema.ResourceData, c *govmomi.Client) (t
 	}
 
 	// Grab the relocate spec for the disks.
-	l := object.VirtualDeviceList(vprops.Config.Hardware.Device)
+	l, err := cloneSourceDevices(c, vprops, snapshot)
+	if err != nil {
+		return spec, nil, err
+	}
 	relocators, err := virtualdevice.DiskCloneRelocateOperation(d, c, l)
 	if err != nil {
 		return spec, nil, err
 	}
 	spec.Location.Disk = relocators
+	if policyID := d.Get("storage_policy_id").(string); policyID != "" {
+		spec.Location.Profile = spbm.PolicySpecByID(policyID)
+	}
 	log.Printf("[DEBUG] ExpandVirtualMachineCloneSpec: Clone spec prep complete")
 	return spec, vm, nil
 }
+
+// ExpandLibraryDeploymentTarget returns the placement for a virtual machine
+// that is being cloned from a content library item. fo is the folder that the
+// virtual machine will be placed in.
+func ExpandLibraryDeploymentTarget(d *schema.ResourceData, c *govmomi.Client, fo *object.Folder) (contentlibrary.DeploymentTarget, error) {
+	log.Printf("[DEBUG] ExpandLibraryDeploymentTarget: Preparing deployment target for VM")
+	target := contentlibrary.DeploymentTarget{
+		FolderID:    fo.Reference().Value,
+		DatastoreID: d.Get("datastore_id").(string),
+	}
+	poolID := d.Get("resource_pool_id").(string)
+	pool, err := resourcepool.FromID(c, poolID)
+	if err != nil {
+		return target, fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	var hs *object.HostSystem
+	if v, ok := d.GetOk("host_system_id"); ok {
+		hsID := v.(string)
+		var err error
+		if hs, err = hostsystem.FromID(c, hsID); err != nil {
+			return target, fmt.Errorf("error locating host system at ID %q: %s", hsID, err)
+		}
+		target.HostID = hsID
+	}
+	// Validate that the host is part of the resource pool before proceeding
+	if err := resourcepool.ValidateHost(c, pool, hs); err != nil {
+		return target, err
+	}
+	target.ResourcePoolID = pool.Reference().Value
+	log.Printf("[DEBUG] ExpandLibraryDeploymentTarget: Deployment target prep complete")
+	return target, nil
+}
+
+// ExpandVirtualMachineInstantCloneSpec creates an instant clone spec for an
+// existing, running virtual machine. fo is the folder that the virtual machine
+// will be placed in.
+//
+// The spec contains the target location of the new virtual machine, any
+// network changes that need to be made to the interfaces inherited from the
+// source, and the extra_config for the new virtual machine. As an instant
+// clone starts running right away, this is the only point where per-clone
+// guestinfo can be supplied before the guest is live.
+func ExpandVirtualMachineInstantCloneSpec(d *schema.ResourceData, c *govmomi.Client, fo *object.Folder) (virtualmachine.InstantCloneSpec, *object.VirtualMachine, error) {
+	spec := virtualmachine.InstantCloneSpec{
+		Name: d.Get("name").(string),
+	}
+	log.Printf("[DEBUG] ExpandVirtualMachineInstantCloneSpec: Preparing instant clone spec for VM")
+
+	foRef := fo.Reference()
+	spec.Location.Folder = &foRef
+	if dsID, ok := d.GetOk("datastore_id"); ok {
+		ds, err := datastore.FromID(c, dsID.(string))
+		if err != nil {
+			return spec, nil, fmt.Errorf("error locating datastore for VM: %s", err)
+		}
+		spec.Location.Datastore = types.NewReference(ds.Reference())
+	}
+
+	tUUID := d.Get("clone.0.template_uuid").(string)
+	log.Printf("[DEBUG] ExpandVirtualMachineInstantCloneSpec: Instant cloning from UUID: %s", tUUID)
+	vm, err := virtualmachine.FromUUID(c, tUUID)
+	if err != nil {
+		return spec, nil, fmt.Errorf("cannot locate virtual machine with UUID %q: %s", tUUID, err)
+	}
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return spec, nil, fmt.Errorf("error fetching virtual machine properties: %s", err)
+	}
+
+	// Set the target host system and resource pool.
+	poolID := d.Get("resource_pool_id").(string)
+	pool, err := resourcepool.FromID(c, poolID)
+	if err != nil {
+		return spec, nil, fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	var hs *object.HostSystem
+	if v, ok := d.GetOk("host_system_id"); ok {
+		hsID := v.(string)
+		var err error
+		if hs, err = hostsystem.FromID(c, hsID); err != nil {
+			return spec, nil, fmt.Errorf("error locating host system at ID %q: %s", hsID, err)
+		}
+	}
+	if err := resourcepool.ValidateHost(c, pool, hs); err != nil {
+		return spec, nil, err
+	}
+	poolRef := pool.Reference()
+	spec.Location.Pool = &poolRef
+	if hs != nil {
+		hsRef := hs.Reference()
+		spec.Location.Host = &hsRef
+	}
+
+	// Move the network interfaces of the source onto the configured networks.
+	l := object.VirtualDeviceList(vprops.Config.Hardware.Device)
+	spec.Location.DeviceChange, err = virtualdevice.NetworkInterfaceInstantCloneOperation(d, c, l)
+	if err != nil {
+		return spec, nil, err
+	}
+
+	// Sort the extra_config keys so that the spec is stable.
+	ec := d.Get("extra_config").(map[string]interface{})
+	keys := make([]string, 0, len(ec))
+	for k := range ec {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		spec.Config = append(spec.Config, &types.OptionValue{
+			Key:   k,
+			Value: ec[k],
+		})
+	}
+	log.Printf("[DEBUG] ExpandVirtualMachineInstantCloneSpec: Instant clone spec prep complete")
+	return spec, vm, nil
+}
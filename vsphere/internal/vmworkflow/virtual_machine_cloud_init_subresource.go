@@ -0,0 +1,150 @@
+package vmworkflow
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/cloudinit"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+var cloudInitDatasourceAllowedValues = []string{
+	cloudinit.DatasourceGuestInfo,
+	cloudinit.DatasourceNoCloud,
+}
+
+// The guestinfo keys read by the VMware datasource.
+const (
+	cloudInitGuestInfoUserData         = "guestinfo.userdata"
+	cloudInitGuestInfoUserDataEncoding = "guestinfo.userdata.encoding"
+	cloudInitGuestInfoMetadata         = "guestinfo.metadata"
+	cloudInitGuestInfoMetadataEncoding = "guestinfo.metadata.encoding"
+)
+
+// CloudInitGuestInfoKeys is the list of extraConfig keys managed by the
+// cloud_init sub-resource.
+var CloudInitGuestInfoKeys = []string{
+	cloudInitGuestInfoUserData,
+	cloudInitGuestInfoUserDataEncoding,
+	cloudInitGuestInfoMetadata,
+	cloudInitGuestInfoMetadataEncoding,
+}
+
+// VirtualMachineCloudInitSchema represents the schema for the VM cloud-init
+// sub-resource.
+//
+// This sub-resource delivers user data, metadata, and network configuration
+// to cloud-init in the guest, either through guestinfo or a NoCloud seed
+// ISO.
+func VirtualMachineCloudInitSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"user_data": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The cloud-init user data. Can be raw, base64 encoded, or gzip compressed and base64 encoded.",
+		},
+		"metadata": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The cloud-init metadata. Can be raw, base64 encoded, or gzip compressed and base64 encoded. Defaults to metadata with the instance ID and hostname of the virtual machine.",
+		},
+		"network_config": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"cloud_init.0.generate_network_config"},
+			Description:   "The cloud-init network configuration. Can be raw, base64 encoded, or gzip compressed and base64 encoded.",
+		},
+		"generate_network_config": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			ConflictsWith: []string{"cloud_init.0.network_config"},
+			Description:   "Generate a network configuration that enables DHCP on each network_interface, matched by MAC address.",
+		},
+		"datasource": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      cloudinit.DatasourceGuestInfo,
+			Description:  "The datasource to deliver data to cloud-init with. Can be one of guestinfo or nocloud.",
+			ValidateFunc: validation.StringInSlice(cloudInitDatasourceAllowedValues, false),
+		},
+	}
+}
+
+// CloudInitData is the decoded data for the cloud_init sub-resource.
+type CloudInitData struct {
+	UserData      string
+	Metadata      string
+	NetworkConfig string
+}
+
+// ExpandCloudInitData reads the cloud_init sub-resource. Metadata defaults to
+// the instance ID and hostname supplied, and network configuration is
+// generated from the MAC addresses supplied when requested.
+func ExpandCloudInitData(d *schema.ResourceData, instanceID, hostname string, macs []string) (*CloudInitData, error) {
+	data := &CloudInitData{
+		UserData: d.Get("cloud_init.0.user_data").(string),
+		Metadata: d.Get("cloud_init.0.metadata").(string),
+	}
+	if data.Metadata == "" {
+		data.Metadata = cloudinit.DefaultMetadata(instanceID, hostname)
+	}
+	switch {
+	case d.Get("cloud_init.0.generate_network_config").(bool):
+		data.NetworkConfig = cloudinit.NetworkConfig(macs)
+	case d.Get("cloud_init.0.network_config").(string) != "":
+		b, err := cloudinit.Decode(d.Get("cloud_init.0.network_config").(string))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding network_config: %s", err)
+		}
+		data.NetworkConfig = string(b)
+	}
+	return data, nil
+}
+
+// GuestInfo returns the guestinfo extraConfig options for the data. If the
+// data is nil, the options clear all guestinfo keys set by cloud_init.
+func (c *CloudInitData) GuestInfo() ([]types.BaseOptionValue, error) {
+	values := make(map[string]string)
+	if c != nil {
+		metadata := c.Metadata
+		if c.NetworkConfig != "" {
+			// The VMware datasource reads network configuration from the
+			// metadata, which needs to be decoded for this.
+			b, err := cloudinit.Decode(metadata)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding metadata: %s", err)
+			}
+			if metadata, err = cloudinit.MetadataWithNetworkConfig(string(b), c.NetworkConfig); err != nil {
+				return nil, err
+			}
+		}
+		values[cloudInitGuestInfoMetadata], values[cloudInitGuestInfoMetadataEncoding] = cloudinit.Encode(metadata)
+		if c.UserData != "" {
+			values[cloudInitGuestInfoUserData], values[cloudInitGuestInfoUserDataEncoding] = cloudinit.Encode(c.UserData)
+		}
+	}
+	var opts []types.BaseOptionValue
+	for _, k := range CloudInitGuestInfoKeys {
+		opts = append(opts, &types.OptionValue{
+			Key:   k,
+			Value: values[k],
+		})
+	}
+	log.Printf("[DEBUG] Cloud-init guestinfo keys: %d set out of %d", len(values), len(opts))
+	return opts, nil
+}
+
+// SeedISO returns a NoCloud seed ISO for the data.
+func (c *CloudInitData) SeedISO() ([]byte, error) {
+	userData, err := cloudinit.Decode(c.UserData)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding user_data: %s", err)
+	}
+	metadata, err := cloudinit.Decode(c.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding metadata: %s", err)
+	}
+	return cloudinit.SeedISO(userData, metadata, []byte(c.NetworkConfig)), nil
+}
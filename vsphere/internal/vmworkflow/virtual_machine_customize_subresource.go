@@ -1,28 +1,53 @@
 package vmworkflow
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"text/template"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customizationspec"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
-const (
-	cKeyPrefix        = "clone.0.customize.0"
-	cLinuxKeyPrefix   = "clone.0.customize.0.linux_options.0"
-	cWindowsKeyPrefix = "clone.0.customize.0.windows_options.0"
-	cNetifKeyPrefix   = "clone.0.customize.0.network_interface"
+// The minimum versions of vCenter for script_text and domain_ou.
+var (
+	customizationScriptTextMinVersion = viapi.VSphereVersion{Major: 7}
+	customizationDomainOUMinVersion   = viapi.VSphereVersion{Major: 8, Patch: 2}
 )
 
+// cKeyPrefix is the prefix of the customize block of the clone sub-resource
+// of virtual machines.
+const cKeyPrefix = "clone.0.customize.0"
+
+// The customization spec settings are shared by the customize block of
+// virtual machines and the vsphere_guest_os_customization resource, so the
+// schema, expanders, and flatteners work off of the prefix of the block the
+// settings are in.
+
+// linuxKeyPrefix returns the prefix of the linux_options block in the
+// customization settings at prefix.
+func linuxKeyPrefix(prefix string) string {
+	return prefix + ".linux_options.0"
+}
+
+// windowsKeyPrefix returns the prefix of the windows_options block in the
+// customization settings at prefix.
+func windowsKeyPrefix(prefix string) string {
+	return prefix + ".windows_options.0"
+}
+
 // netifKey renders a specific network_interface key for a specific resource
 // index.
-func netifKey(key string, n int) string {
-	return fmt.Sprintf("%s.%d.%s", cNetifKeyPrefix, n, key)
+func netifKey(prefix string, key string, n int) string {
+	return fmt.Sprintf("%s.network_interface.%d.%s", prefix, n, key)
 }
 
 // matchGateway take an IP, mask, and gateway, and checks to see if the gateway
@@ -51,8 +76,9 @@ func v4CIDRMaskToDotted(mask int) string {
 	return fmt.Sprintf("%d.%d.%d.%d", a, b, c, d)
 }
 
-// VirtualMachineCustomizeSchema returns the schema for VM customization.
-func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
+// CustomizationSpecSchema returns the schema for the settings of a
+// customization spec, for settings at the supplied prefix.
+func CustomizationSpecSchema(prefix string) map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		// CustomizationGlobalIPSettings
 		"dns_server_list": {
@@ -73,7 +99,7 @@ func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
 			Type:          schema.TypeList,
 			Optional:      true,
 			MaxItems:      1,
-			ConflictsWith: []string{cKeyPrefix + "." + "windows_options", cKeyPrefix + "." + "windows_sysprep_text"},
+			ConflictsWith: []string{prefix + "." + "windows_options", prefix + "." + "windows_sysprep_text"},
 			Description:   "A list of configuration options specific to Linux virtual machines.",
 			Elem: &schema.Resource{Schema: map[string]*schema.Schema{
 				"domain": {
@@ -101,6 +127,11 @@ func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
 						"must be similar to America/Los_Angeles or other Linux/Unix TZ format",
 					),
 				},
+				"script_text": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "A script to run before and after the guest OS customization. The script is called with precustomization before, and postcustomization after the customization. Requires vCenter 7.0 or higher.",
+				},
 			}},
 		},
 
@@ -109,7 +140,7 @@ func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
 			Type:          schema.TypeList,
 			Optional:      true,
 			MaxItems:      1,
-			ConflictsWith: []string{cKeyPrefix + "." + "linux_options", cKeyPrefix + "." + "windows_sysprep_text"},
+			ConflictsWith: []string{prefix + "." + "linux_options", prefix + "." + "windows_sysprep_text"},
 			Description:   "A list of configuration options specific to Windows virtual machines.",
 			Elem: &schema.Resource{Schema: map[string]*schema.Schema{
 				// CustomizationGuiRunOnce
@@ -148,26 +179,32 @@ func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
 				"domain_admin_user": {
 					Type:          schema.TypeString,
 					Optional:      true,
-					ConflictsWith: []string{cWindowsKeyPrefix + "." + "workgroup"},
+					ConflictsWith: []string{windowsKeyPrefix(prefix) + "." + "workgroup"},
 					Description:   "The user account of the domain administrator used to join this virtual machine to the domain.",
 				},
 				"domain_admin_password": {
 					Type:          schema.TypeString,
 					Optional:      true,
 					Sensitive:     true,
-					ConflictsWith: []string{cWindowsKeyPrefix + "." + "workgroup"},
+					ConflictsWith: []string{windowsKeyPrefix(prefix) + "." + "workgroup"},
 					Description:   "The password of the domain administrator used to join this virtual machine to the domain.",
 				},
 				"join_domain": {
 					Type:          schema.TypeString,
 					Optional:      true,
-					ConflictsWith: []string{cWindowsKeyPrefix + "." + "workgroup"},
+					ConflictsWith: []string{windowsKeyPrefix(prefix) + "." + "workgroup"},
 					Description:   "The domain that the virtual machine should join.",
 				},
+				"domain_ou": {
+					Type:          schema.TypeString,
+					Optional:      true,
+					ConflictsWith: []string{windowsKeyPrefix(prefix) + "." + "workgroup"},
+					Description:   "The organizational unit to place the computer account of the virtual machine in when joining the domain, such as OU=Servers,DC=example,DC=internal. Requires vCenter 8.0 Update 2 or higher.",
+				},
 				"workgroup": {
 					Type:          schema.TypeString,
 					Optional:      true,
-					ConflictsWith: []string{cWindowsKeyPrefix + "." + "join_domain"},
+					ConflictsWith: []string{windowsKeyPrefix(prefix) + "." + "join_domain", windowsKeyPrefix(prefix) + "." + "domain_ou"},
 					Description:   "The workgroup for this virtual machine if not joining a domain.",
 				},
 
@@ -201,7 +238,7 @@ func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
 		"windows_sysprep_text": {
 			Type:          schema.TypeString,
 			Optional:      true,
-			ConflictsWith: []string{cKeyPrefix + "." + "linux_options", cKeyPrefix + "." + "windows_options"},
+			ConflictsWith: []string{prefix + "." + "linux_options", prefix + "." + "windows_options"},
 			Description:   "Use this option to specify a windows sysprep file directly.",
 		},
 
@@ -256,44 +293,88 @@ func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
 			Optional:    true,
 			Description: "The IPv6 default gateway when using network_interface customization on the virtual machine. This address must be local to a static IPv4 address configured in an interface sub-resource.",
 		},
-		"timeout": {
-			Type:        schema.TypeInt,
-			Optional:    true,
-			Default:     10,
-			Description: "The amount of time, in minutes, to wait for guest OS customization to complete before returning with an error. Setting this value to 0 or a negative value skips the waiter.",
-		},
 	}
 }
 
+// VirtualMachineCustomizeSchema returns the schema for VM customization.
+func VirtualMachineCustomizeSchema() map[string]*schema.Schema {
+	s := CustomizationSpecSchema(cKeyPrefix)
+	s["spec_name"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "The name of a customization spec stored in vCenter to use as the base of the customization. The settings in this block override the settings of the stored spec.",
+	}
+	s["timeout"] = &schema.Schema{
+		Type:        schema.TypeInt,
+		Optional:    true,
+		Default:     10,
+		Description: "The amount of time, in minutes, to wait for guest OS customization to complete before returning with an error. Setting this value to 0 or a negative value skips the waiter.",
+	}
+	s["reapply_on_change"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Customize the existing virtual machine again when the customization settings change, instead of re-creating it. The virtual machine is powered off for the customization.",
+	}
+	s["diagnostics"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: "Gather a diagnostics bundle when guest OS customization fails or times out.",
+		Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"directory": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The local directory to write diagnostics bundles to. Each bundle is written to a new subdirectory.",
+			},
+			"guest_username": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The username of a guest account used to download the customization logs from the guest. Guest logs are not collected when this is not set.",
+			},
+			"guest_password": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "The password of the guest account used to download the customization logs from the guest.",
+			},
+		}},
+	}
+	return s
+}
+
 // expandCustomizationGlobalIPSettings reads certain ResourceData keys and
 // returns a CustomizationGlobalIPSettings.
-func expandCustomizationGlobalIPSettings(d *schema.ResourceData) types.CustomizationGlobalIPSettings {
+func expandCustomizationGlobalIPSettings(d *schema.ResourceData, prefix string) types.CustomizationGlobalIPSettings {
 	obj := types.CustomizationGlobalIPSettings{
-		DnsSuffixList: structure.SliceInterfacesToStrings(d.Get(cKeyPrefix + "." + "dns_suffix_list").([]interface{})),
-		DnsServerList: structure.SliceInterfacesToStrings(d.Get(cKeyPrefix + "." + "dns_server_list").([]interface{})),
+		DnsSuffixList: structure.SliceInterfacesToStrings(d.Get(prefix + "." + "dns_suffix_list").([]interface{})),
+		DnsServerList: structure.SliceInterfacesToStrings(d.Get(prefix + "." + "dns_server_list").([]interface{})),
 	}
 	return obj
 }
 
 // expandCustomizationLinuxPrep reads certain ResourceData keys and
 // returns a CustomizationLinuxPrep.
-func expandCustomizationLinuxPrep(d *schema.ResourceData) *types.CustomizationLinuxPrep {
-	obj := &types.CustomizationLinuxPrep{
-		HostName: &types.CustomizationFixedName{
-			Name: d.Get(cLinuxKeyPrefix + "." + "host_name").(string),
+func expandCustomizationLinuxPrep(d *schema.ResourceData, prefix string) *customizationspec.CustomizationLinuxPrep {
+	obj := &customizationspec.CustomizationLinuxPrep{
+		CustomizationLinuxPrep: types.CustomizationLinuxPrep{
+			HostName: &types.CustomizationFixedName{
+				Name: d.Get(linuxKeyPrefix(prefix) + "." + "host_name").(string),
+			},
+			Domain:     d.Get(linuxKeyPrefix(prefix) + "." + "domain").(string),
+			TimeZone:   d.Get(linuxKeyPrefix(prefix) + "." + "time_zone").(string),
+			HwClockUTC: structure.GetBoolPtr(d, linuxKeyPrefix(prefix)+"."+"hw_clock_utc"),
 		},
-		Domain:     d.Get(cLinuxKeyPrefix + "." + "domain").(string),
-		TimeZone:   d.Get(cLinuxKeyPrefix + "." + "time_zone").(string),
-		HwClockUTC: structure.GetBoolPtr(d, cLinuxKeyPrefix+"."+"hw_clock_utc"),
+		ScriptText: d.Get(linuxKeyPrefix(prefix) + "." + "script_text").(string),
 	}
 	return obj
 }
 
 // expandCustomizationGuiRunOnce reads certain ResourceData keys and
 // returns a CustomizationGuiRunOnce.
-func expandCustomizationGuiRunOnce(d *schema.ResourceData) *types.CustomizationGuiRunOnce {
+func expandCustomizationGuiRunOnce(d *schema.ResourceData, prefix string) *types.CustomizationGuiRunOnce {
 	obj := &types.CustomizationGuiRunOnce{
-		CommandList: structure.SliceInterfacesToStrings(d.Get(cWindowsKeyPrefix + "." + "run_once_command_list").([]interface{})),
+		CommandList: structure.SliceInterfacesToStrings(d.Get(windowsKeyPrefix(prefix) + "." + "run_once_command_list").([]interface{})),
 	}
 	if len(obj.CommandList) < 1 {
 		return nil
@@ -303,13 +384,13 @@ func expandCustomizationGuiRunOnce(d *schema.ResourceData) *types.CustomizationG
 
 // expandCustomizationGuiUnattended reads certain ResourceData keys and
 // returns a CustomizationGuiUnattended.
-func expandCustomizationGuiUnattended(d *schema.ResourceData) types.CustomizationGuiUnattended {
+func expandCustomizationGuiUnattended(d *schema.ResourceData, prefix string) types.CustomizationGuiUnattended {
 	obj := types.CustomizationGuiUnattended{
-		TimeZone:       int32(d.Get(cWindowsKeyPrefix + "." + "time_zone").(int)),
-		AutoLogon:      d.Get(cWindowsKeyPrefix + "." + "auto_logon").(bool),
-		AutoLogonCount: int32(d.Get(cWindowsKeyPrefix + "." + "auto_logon_count").(int)),
+		TimeZone:       int32(d.Get(windowsKeyPrefix(prefix) + "." + "time_zone").(int)),
+		AutoLogon:      d.Get(windowsKeyPrefix(prefix) + "." + "auto_logon").(bool),
+		AutoLogonCount: int32(d.Get(windowsKeyPrefix(prefix) + "." + "auto_logon_count").(int)),
 	}
-	if v, ok := d.GetOk(cWindowsKeyPrefix + "." + "admin_password"); ok {
+	if v, ok := d.GetOk(windowsKeyPrefix(prefix) + "." + "admin_password"); ok {
 		obj.Password = &types.CustomizationPassword{
 			Value:     v.(string),
 			PlainText: true,
@@ -321,13 +402,16 @@ func expandCustomizationGuiUnattended(d *schema.ResourceData) types.Customizatio
 
 // expandCustomizationIdentification reads certain ResourceData keys and
 // returns a CustomizationIdentification.
-func expandCustomizationIdentification(d *schema.ResourceData) types.CustomizationIdentification {
-	obj := types.CustomizationIdentification{
-		JoinWorkgroup: d.Get(cWindowsKeyPrefix + "." + "workgroup").(string),
-		JoinDomain:    d.Get(cWindowsKeyPrefix + "." + "join_domain").(string),
-		DomainAdmin:   d.Get(cWindowsKeyPrefix + "." + "domain_admin_user").(string),
+func expandCustomizationIdentification(d *schema.ResourceData, prefix string) customizationspec.CustomizationIdentification {
+	obj := customizationspec.CustomizationIdentification{
+		CustomizationIdentification: types.CustomizationIdentification{
+			JoinWorkgroup: d.Get(windowsKeyPrefix(prefix) + "." + "workgroup").(string),
+			JoinDomain:    d.Get(windowsKeyPrefix(prefix) + "." + "join_domain").(string),
+			DomainAdmin:   d.Get(windowsKeyPrefix(prefix) + "." + "domain_admin_user").(string),
+		},
+		DomainOU: d.Get(windowsKeyPrefix(prefix) + "." + "domain_ou").(string),
 	}
-	if v, ok := d.GetOk(cWindowsKeyPrefix + "." + "domain_admin_password"); ok {
+	if v, ok := d.GetOk(windowsKeyPrefix(prefix) + "." + "domain_admin_password"); ok {
 		obj.DomainAdminPassword = &types.CustomizationPassword{
 			Value:     v.(string),
 			PlainText: true,
@@ -338,35 +422,35 @@ func expandCustomizationIdentification(d *schema.ResourceData) types.Customizati
 
 // expandCustomizationUserData reads certain ResourceData keys and
 // returns a CustomizationUserData.
-func expandCustomizationUserData(d *schema.ResourceData) types.CustomizationUserData {
+func expandCustomizationUserData(d *schema.ResourceData, prefix string) types.CustomizationUserData {
 	obj := types.CustomizationUserData{
-		FullName: d.Get(cWindowsKeyPrefix + "." + "full_name").(string),
-		OrgName:  d.Get(cWindowsKeyPrefix + "." + "organization_name").(string),
+		FullName: d.Get(windowsKeyPrefix(prefix) + "." + "full_name").(string),
+		OrgName:  d.Get(windowsKeyPrefix(prefix) + "." + "organization_name").(string),
 		ComputerName: &types.CustomizationFixedName{
-			Name: d.Get(cWindowsKeyPrefix + "." + "computer_name").(string),
+			Name: d.Get(windowsKeyPrefix(prefix) + "." + "computer_name").(string),
 		},
-		ProductId: d.Get(cWindowsKeyPrefix + "." + "product_key").(string),
+		ProductId: d.Get(windowsKeyPrefix(prefix) + "." + "product_key").(string),
 	}
 	return obj
 }
 
 // expandCustomizationSysprep reads certain ResourceData keys and
 // returns a CustomizationSysprep.
-func expandCustomizationSysprep(d *schema.ResourceData) *types.CustomizationSysprep {
-	obj := &types.CustomizationSysprep{
-		GuiUnattended:  expandCustomizationGuiUnattended(d),
-		UserData:       expandCustomizationUserData(d),
-		GuiRunOnce:     expandCustomizationGuiRunOnce(d),
-		Identification: expandCustomizationIdentification(d),
+func expandCustomizationSysprep(d *schema.ResourceData, prefix string) *customizationspec.CustomizationSysprep {
+	obj := &customizationspec.CustomizationSysprep{
+		GuiUnattended:  expandCustomizationGuiUnattended(d, prefix),
+		UserData:       expandCustomizationUserData(d, prefix),
+		GuiRunOnce:     expandCustomizationGuiRunOnce(d, prefix),
+		Identification: expandCustomizationIdentification(d, prefix),
 	}
 	return obj
 }
 
 // expandCustomizationSysprepText reads certain ResourceData keys and
 // returns a CustomizationSysprepText.
-func expandCustomizationSysprepText(d *schema.ResourceData) *types.CustomizationSysprepText {
+func expandCustomizationSysprepText(d *schema.ResourceData, prefix string) *types.CustomizationSysprepText {
 	obj := &types.CustomizationSysprepText{
-		Value: d.Get(cKeyPrefix + "." + "windows_sysprep_text").(string),
+		Value: d.Get(prefix + "." + "windows_sysprep_text").(string),
 	}
 	return obj
 }
@@ -377,17 +461,17 @@ func expandCustomizationSysprepText(d *schema.ResourceData) *types.Customization
 // Only one of the three types of identity settings can be specified: Linux
 // settings (from linux_options), Windows settings (from windows_options), and
 // the raw Windows sysprep file (via windows_sysprep_text).
-func expandBaseCustomizationIdentitySettings(d *schema.ResourceData, family string) types.BaseCustomizationIdentitySettings {
+func expandBaseCustomizationIdentitySettings(d *schema.ResourceData, prefix string, family string) types.BaseCustomizationIdentitySettings {
 	var obj types.BaseCustomizationIdentitySettings
-	_, windowsExists := d.GetOkExists(cKeyPrefix + "." + "windows_options")
-	_, sysprepExists := d.GetOkExists(cKeyPrefix + "." + "windows_sysprep_text")
+	_, windowsExists := d.GetOkExists(prefix + "." + "windows_options")
+	_, sysprepExists := d.GetOkExists(prefix + "." + "windows_sysprep_text")
 	switch {
 	case family == string(types.VirtualMachineGuestOsFamilyLinuxGuest):
-		obj = expandCustomizationLinuxPrep(d)
+		obj = expandCustomizationLinuxPrep(d, prefix)
 	case family == string(types.VirtualMachineGuestOsFamilyWindowsGuest) && windowsExists:
-		obj = expandCustomizationSysprep(d)
+		obj = expandCustomizationSysprep(d, prefix)
 	case family == string(types.VirtualMachineGuestOsFamilyWindowsGuest) && sysprepExists:
-		obj = expandCustomizationSysprepText(d)
+		obj = expandCustomizationSysprepText(d, prefix)
 	default:
 		obj = &types.CustomizationIdentitySettings{}
 	}
@@ -396,15 +480,15 @@ func expandBaseCustomizationIdentitySettings(d *schema.ResourceData, family stri
 
 // expandCustomizationIPSettingsIPV6AddressSpec reads certain ResourceData keys and
 // returns a CustomizationIPSettingsIpV6AddressSpec.
-func expandCustomizationIPSettingsIPV6AddressSpec(d *schema.ResourceData, n int, gwAdd bool) (*types.CustomizationIPSettingsIpV6AddressSpec, bool) {
-	v, ok := d.GetOk(netifKey("ipv6_address", n))
+func expandCustomizationIPSettingsIPV6AddressSpec(d *schema.ResourceData, prefix string, n int, gwAdd bool) (*types.CustomizationIPSettingsIpV6AddressSpec, bool) {
+	v, ok := d.GetOk(netifKey(prefix, "ipv6_address", n))
 	var gwFound bool
 	if !ok {
 		return nil, gwFound
 	}
 	addr := v.(string)
-	mask := d.Get(netifKey("ipv6_netmask", n)).(int)
-	gw, gwOk := d.Get(cKeyPrefix + "." + "ipv6_gateway").(string)
+	mask := d.Get(netifKey(prefix, "ipv6_netmask", n)).(int)
+	gw, gwOk := d.Get(prefix + "." + "ipv6_gateway").(string)
 	obj := &types.CustomizationIPSettingsIpV6AddressSpec{
 		Ip: []types.BaseCustomizationIpV6Generator{
 			&types.CustomizationFixedIpV6{
@@ -422,11 +506,11 @@ func expandCustomizationIPSettingsIPV6AddressSpec(d *schema.ResourceData, n int,
 
 // expandCustomizationIPSettings reads certain ResourceData keys and
 // returns a CustomizationIPSettings.
-func expandCustomizationIPSettings(d *schema.ResourceData, n int, v4gwAdd, v6gwAdd bool) (types.CustomizationIPSettings, bool, bool) {
+func expandCustomizationIPSettings(d *schema.ResourceData, prefix string, n int, v4gwAdd, v6gwAdd bool) (types.CustomizationIPSettings, bool, bool) {
 	var v4gwFound, v6gwFound bool
-	v4addr, v4addrOk := d.GetOk(netifKey("ipv4_address", n))
-	v4mask := d.Get(netifKey("ipv4_netmask", n)).(int)
-	v4gw, v4gwOk := d.Get(cKeyPrefix + "." + "ipv4_gateway").(string)
+	v4addr, v4addrOk := d.GetOk(netifKey(prefix, "ipv4_address", n))
+	v4mask := d.Get(netifKey(prefix, "ipv4_netmask", n)).(int)
+	v4gw, v4gwOk := d.Get(prefix + "." + "ipv4_gateway").(string)
 	var obj types.CustomizationIPSettings
 	switch {
 	case v4addrOk:
@@ -442,16 +526,16 @@ func expandCustomizationIPSettings(d *schema.ResourceData, n int, v4gwAdd, v6gwA
 	default:
 		obj.Ip = &types.CustomizationDhcpIpGenerator{}
 	}
-	obj.DnsServerList = structure.SliceInterfacesToStrings(d.Get(netifKey("dns_server_list", n)).([]interface{}))
-	obj.DnsDomain = d.Get(netifKey("dns_domain", n)).(string)
-	obj.IpV6Spec, v6gwFound = expandCustomizationIPSettingsIPV6AddressSpec(d, n, v6gwAdd)
+	obj.DnsServerList = structure.SliceInterfacesToStrings(d.Get(netifKey(prefix, "dns_server_list", n)).([]interface{}))
+	obj.DnsDomain = d.Get(netifKey(prefix, "dns_domain", n)).(string)
+	obj.IpV6Spec, v6gwFound = expandCustomizationIPSettingsIPV6AddressSpec(d, prefix, n, v6gwAdd)
 	return obj, v4gwFound, v6gwFound
 }
 
 // expandSliceOfCustomizationAdapterMapping reads certain ResourceData keys and
 // returns a CustomizationAdapterMapping slice.
-func expandSliceOfCustomizationAdapterMapping(d *schema.ResourceData) []types.CustomizationAdapterMapping {
-	s := d.Get(cKeyPrefix + "." + "network_interface").([]interface{})
+func expandSliceOfCustomizationAdapterMapping(d *schema.ResourceData, prefix string) []types.CustomizationAdapterMapping {
+	s := d.Get(prefix + "." + "network_interface").([]interface{})
 	if len(s) < 1 {
 		return nil
 	}
@@ -459,7 +543,7 @@ func expandSliceOfCustomizationAdapterMapping(d *schema.ResourceData) []types.Cu
 	var v4gwFound, v6gwFound bool
 	for i := range s {
 		var adapter types.CustomizationIPSettings
-		adapter, v4gwFound, v6gwFound = expandCustomizationIPSettings(d, i, !v4gwFound, !v6gwFound)
+		adapter, v4gwFound, v6gwFound = expandCustomizationIPSettings(d, prefix, i, !v4gwFound, !v6gwFound)
 		obj := types.CustomizationAdapterMapping{
 			Adapter: adapter,
 		}
@@ -468,29 +552,392 @@ func expandSliceOfCustomizationAdapterMapping(d *schema.ResourceData) []types.Cu
 	return result
 }
 
+// expandCustomizationSpec reads the customization settings at the supplied
+// prefix and returns a CustomizationSpec.
+func expandCustomizationSpec(d *schema.ResourceData, prefix string, family string) types.CustomizationSpec {
+	obj := types.CustomizationSpec{
+		Identity:         expandBaseCustomizationIdentitySettings(d, prefix, family),
+		GlobalIPSettings: expandCustomizationGlobalIPSettings(d, prefix),
+		NicSettingMap:    expandSliceOfCustomizationAdapterMapping(d, prefix),
+	}
+	return obj
+}
+
 // ExpandCustomizationSpec reads certain ResourceData keys and
 // returns a CustomizationSpec.
 func ExpandCustomizationSpec(d *schema.ResourceData, family string) types.CustomizationSpec {
-	obj := types.CustomizationSpec{
-		Identity:         expandBaseCustomizationIdentitySettings(d, family),
-		GlobalIPSettings: expandCustomizationGlobalIPSettings(d),
-		NicSettingMap:    expandSliceOfCustomizationAdapterMapping(d),
+	return expandCustomizationSpec(d, cKeyPrefix, family)
+}
+
+// ExpandGuestOSCustomizationSpec reads the customization settings at the
+// supplied prefix and returns a CustomizationSpec. It is used by resources
+// that manage the customization specs stored in vCenter.
+func ExpandGuestOSCustomizationSpec(d *schema.ResourceData, prefix string, family string) types.CustomizationSpec {
+	return expandCustomizationSpec(d, prefix, family)
+}
+
+// MergeCustomizationSpec returns a copy of the supplied customization spec,
+// usually one that is stored in vCenter, with the settings in the customize
+// block applied over it:
+//
+// * The identity settings are replaced if any of linux_options,
+// windows_options, or windows_sysprep_text are set.
+// * The global DNS settings are replaced if they are set.
+// * The settings of each network_interface block are applied to the network
+// adapter settings at the same index, and any additional network_interface
+// blocks are added to the end of the adapter settings.
+func MergeCustomizationSpec(d *schema.ResourceData, family string, base types.CustomizationSpec) types.CustomizationSpec {
+	obj := base
+	if identityExists(d.Get, cKeyPrefix) {
+		obj.Identity = expandBaseCustomizationIdentitySettings(d, cKeyPrefix, family)
+	}
+	global := expandCustomizationGlobalIPSettings(d, cKeyPrefix)
+	if len(global.DnsServerList) > 0 {
+		obj.GlobalIPSettings.DnsServerList = global.DnsServerList
+	}
+	if len(global.DnsSuffixList) > 0 {
+		obj.GlobalIPSettings.DnsSuffixList = global.DnsSuffixList
+	}
+	obj.NicSettingMap = append([]types.CustomizationAdapterMapping{}, base.NicSettingMap...)
+	for i, mapping := range expandSliceOfCustomizationAdapterMapping(d, cKeyPrefix) {
+		if i >= len(obj.NicSettingMap) {
+			obj.NicSettingMap = append(obj.NicSettingMap, mapping)
+			continue
+		}
+		current := &obj.NicSettingMap[i].Adapter
+		adapter := mapping.Adapter
+		if _, ok := d.GetOk(netifKey(cKeyPrefix, "ipv4_address", i)); ok {
+			current.Ip = adapter.Ip
+			current.SubnetMask = adapter.SubnetMask
+			if len(adapter.Gateway) > 0 {
+				current.Gateway = adapter.Gateway
+			}
+		}
+		if adapter.IpV6Spec != nil {
+			if current.IpV6Spec != nil && len(adapter.IpV6Spec.Gateway) < 1 {
+				adapter.IpV6Spec.Gateway = current.IpV6Spec.Gateway
+			}
+			current.IpV6Spec = adapter.IpV6Spec
+		}
+		if len(adapter.DnsServerList) > 0 {
+			current.DnsServerList = adapter.DnsServerList
+		}
+		if adapter.DnsDomain != "" {
+			current.DnsDomain = adapter.DnsDomain
+		}
 	}
 	return obj
 }
 
-// ValidateCustomizationSpec checks the validity of the supplied customization
-// spec. It should be called during diff customization to veto invalid configs.
-func ValidateCustomizationSpec(d *schema.ResourceDiff, family string) error {
+// identityExists returns true if any of the identity settings are set in the
+// customization settings at the supplied prefix. get is the Get function of
+// either a ResourceData or a ResourceDiff.
+func identityExists(get func(string) interface{}, prefix string) bool {
+	return len(get(prefix+"."+"linux_options").([]interface{})) > 0 ||
+		len(get(prefix+"."+"windows_options").([]interface{})) > 0 ||
+		get(prefix+"."+"windows_sysprep_text").(string) != ""
+}
+
+// validateCustomizationSpec checks the validity of the customization settings
+// at the supplied prefix.
+func validateCustomizationSpec(d *schema.ResourceDiff, c *govmomi.Client, prefix string, family string) error {
 	// Validate that the proper section exists for OS family suboptions.
-	linuxExists := len(d.Get(cKeyPrefix+"."+"linux_options").([]interface{})) > 0
-	windowsExists := len(d.Get(cKeyPrefix+"."+"windows_options").([]interface{})) > 0
-	sysprepExists := d.Get(cKeyPrefix+"."+"windows_sysprep_text").(string) != ""
+	linuxExists := len(d.Get(prefix+"."+"linux_options").([]interface{})) > 0
+	windowsExists := len(d.Get(prefix+"."+"windows_options").([]interface{})) > 0
+	sysprepExists := d.Get(prefix+"."+"windows_sysprep_text").(string) != ""
 	switch {
 	case family == string(types.VirtualMachineGuestOsFamilyLinuxGuest) && !linuxExists:
 		return errors.New("linux_options must exist in VM customization options for Linux operating systems")
 	case family == string(types.VirtualMachineGuestOsFamilyWindowsGuest) && !windowsExists && !sysprepExists:
 		return errors.New("one of windows_options or windows_sysprep_text must exist in VM customization options for Windows operating systems")
 	}
+	return validateCustomizationOptions(d, c, prefix)
+}
+
+// validateCustomizationOptions checks the settings in linux_options and
+// windows_options at the supplied prefix, including the settings that
+// require a newer version of vCenter.
+func validateCustomizationOptions(d *schema.ResourceDiff, c *govmomi.Client, prefix string) error {
+	version := viapi.ParseVersionFromClient(c)
+	minVersion := func(v viapi.VSphereVersion) viapi.VSphereVersion {
+		v.Product = version.Product
+		return v
+	}
+	if d.Get(linuxKeyPrefix(prefix)+"."+"script_text").(string) != "" && version.Older(minVersion(customizationScriptTextMinVersion)) {
+		return fmt.Errorf("linux_options.script_text requires vCenter 7.0 or higher")
+	}
+	if d.Get(windowsKeyPrefix(prefix)+"."+"domain_ou").(string) != "" {
+		if version.Older(minVersion(customizationDomainOUMinVersion)) {
+			return fmt.Errorf("windows_options.domain_ou requires vCenter 8.0 Update 2 or higher")
+		}
+		if d.Get(windowsKeyPrefix(prefix)+"."+"join_domain").(string) == "" {
+			return errors.New("windows_options.domain_ou requires join_domain")
+		}
+	}
+	for i, v := range d.Get(windowsKeyPrefix(prefix) + "." + "run_once_command_list").([]interface{}) {
+		command, _ := v.(string)
+		if _, err := parseRunOnceCommand(command); err != nil {
+			return fmt.Errorf("windows_options.run_once_command_list.%d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// ValidateCustomizationSpec checks the validity of the supplied customization
+// spec. It should be called during diff customization to veto invalid configs.
+//
+// The identity settings are optional when the customization is based on a
+// stored spec, as they are then taken from the stored spec.
+func ValidateCustomizationSpec(d *schema.ResourceDiff, c *govmomi.Client, family string) error {
+	if d.Get(cKeyPrefix+"."+"spec_name").(string) != "" && !identityExists(d.Get, cKeyPrefix) {
+		return nil
+	}
+	return validateCustomizationSpec(d, c, cKeyPrefix, family)
+}
+
+// ValidateGuestOSCustomizationSpec checks the validity of the customization
+// settings at the supplied prefix.
+func ValidateGuestOSCustomizationSpec(d *schema.ResourceDiff, c *govmomi.Client, prefix string, family string) error {
+	return validateCustomizationSpec(d, c, prefix, family)
+}
+
+// runOnceCommandData is the data that the commands in run_once_command_list
+// are rendered with when a virtual machine is customized. This allows the
+// same commands, such as the ones in a stored customization spec, to be used
+// for many virtual machines.
+type runOnceCommandData struct {
+	// The name of the virtual machine.
+	Name string
+
+	// The computer name of the virtual machine.
+	ComputerName string
+
+	// The static IPv4 and IPv6 addresses of the network interfaces of the
+	// virtual machine, in the order of the network interfaces.
+	IPv4Addresses []string
+	IPv6Addresses []string
+}
+
+// parseRunOnceCommand parses a run once command as a template. Only the
+// syntax of the template can be checked before the virtual machine is
+// customized, as the data depends on the virtual machine.
+func parseRunOnceCommand(command string) (*template.Template, error) {
+	return template.New("command").Option("missingkey=error").Parse(command)
+}
+
+// renderRunOnceCommand renders a run once command as a template with the
+// supplied data.
+func renderRunOnceCommand(command string, data runOnceCommandData) (string, error) {
+	t, err := parseRunOnceCommand(command)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// RenderRunOnceCommands renders the GuiRunOnce commands of a Windows
+// customization spec with the data of the virtual machine being customized.
+// Commands are Go templates, so {{.Name}} is replaced with the name of the
+// virtual machine, {{.ComputerName}} with its computer name, and
+// {{index .IPv4Addresses 0}} with the IPv4 address of its first network
+// interface.
+func RenderRunOnceCommands(d *schema.ResourceData, spec *types.CustomizationSpec) error {
+	sysprep, ok := spec.Identity.(*customizationspec.CustomizationSysprep)
+	if !ok || sysprep.GuiRunOnce == nil {
+		return nil
+	}
+	data := runOnceCommandData{
+		Name:         d.Get("name").(string),
+		ComputerName: customizationFixedName(sysprep.UserData.ComputerName),
+	}
+	if _, ok := sysprep.UserData.ComputerName.(*types.CustomizationVirtualMachineName); ok {
+		data.ComputerName = data.Name
+	}
+	for _, mapping := range spec.NicSettingMap {
+		if ip, ok := mapping.Adapter.Ip.(*types.CustomizationFixedIp); ok {
+			data.IPv4Addresses = append(data.IPv4Addresses, ip.IpAddress)
+		}
+		if mapping.Adapter.IpV6Spec != nil {
+			for _, gen := range mapping.Adapter.IpV6Spec.Ip {
+				if ip, ok := gen.(*types.CustomizationFixedIpV6); ok {
+					data.IPv6Addresses = append(data.IPv6Addresses, ip.IpAddress)
+					break
+				}
+			}
+		}
+	}
+	commands := make([]string, len(sysprep.GuiRunOnce.CommandList))
+	for i, command := range sysprep.GuiRunOnce.CommandList {
+		rendered, err := renderRunOnceCommand(command, data)
+		if err != nil {
+			return fmt.Errorf("could not render run once command %d: %s", i, err)
+		}
+		commands[i] = rendered
+	}
+	// Copy the commands, so a stored spec that is shared is left alone.
+	sysprep.GuiRunOnce = &types.CustomizationGuiRunOnce{CommandList: commands}
 	return nil
 }
+
+// CustomizationSpecChanged returns true if any of the settings that make up
+// the customization spec have changed. reapply_on_change and timeout only
+// control how the customization is run, so changes to them are ignored.
+func CustomizationSpecChanged(d *schema.ResourceData) bool {
+	for k := range VirtualMachineCustomizeSchema() {
+		if k == "reapply_on_change" || k == "timeout" || k == "diagnostics" {
+			continue
+		}
+		if d.HasChange(cKeyPrefix + "." + k) {
+			return true
+		}
+	}
+	return false
+}
+
+// v4DottedMaskToCIDR returns the prefix length of a dotted IPv4 netmask.
+func v4DottedMaskToCIDR(mask string) int {
+	ip := net.ParseIP(mask).To4()
+	if ip == nil {
+		return 0
+	}
+	ones, _ := net.IPMask(ip).Size()
+	return ones
+}
+
+// customizationFixedName returns the name of a fixed name generator. Other
+// name generators, such as ones that use the name of the virtual machine,
+// return an empty name.
+func customizationFixedName(name types.BaseCustomizationName) string {
+	if n, ok := name.(*types.CustomizationFixedName); ok {
+		return n.Name
+	}
+	return ""
+}
+
+// customizationPasswordValue returns the value of a customization password if
+// it is in plain text. Encrypted passwords cannot be read back, so the value
+// in state at key is returned for those.
+func customizationPasswordValue(d *schema.ResourceData, key string, password *types.CustomizationPassword) string {
+	switch {
+	case password == nil:
+		return ""
+	case password.PlainText:
+		return password.Value
+	}
+	return d.Get(key).(string)
+}
+
+// flattenCustomizationLinuxPrep returns the linux_options data for a
+// CustomizationLinuxPrep.
+func flattenCustomizationLinuxPrep(obj *customizationspec.CustomizationLinuxPrep) []interface{} {
+	hwClockUTC := true
+	if obj.HwClockUTC != nil {
+		hwClockUTC = *obj.HwClockUTC
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"host_name":    customizationFixedName(obj.HostName),
+			"domain":       obj.Domain,
+			"hw_clock_utc": hwClockUTC,
+			"time_zone":    obj.TimeZone,
+			"script_text":  obj.ScriptText,
+		},
+	}
+}
+
+// flattenCustomizationSysprep returns the windows_options data for a
+// CustomizationSysprep at the supplied prefix.
+func flattenCustomizationSysprep(d *schema.ResourceData, prefix string, obj *customizationspec.CustomizationSysprep) []interface{} {
+	m := map[string]interface{}{
+		"auto_logon":            obj.GuiUnattended.AutoLogon,
+		"auto_logon_count":      int(obj.GuiUnattended.AutoLogonCount),
+		"admin_password":        customizationPasswordValue(d, windowsKeyPrefix(prefix)+"."+"admin_password", obj.GuiUnattended.Password),
+		"time_zone":             int(obj.GuiUnattended.TimeZone),
+		"domain_admin_user":     obj.Identification.DomainAdmin,
+		"domain_admin_password": customizationPasswordValue(d, windowsKeyPrefix(prefix)+"."+"domain_admin_password", obj.Identification.DomainAdminPassword),
+		"join_domain":           obj.Identification.JoinDomain,
+		"domain_ou":             obj.Identification.DomainOU,
+		"workgroup":             obj.Identification.JoinWorkgroup,
+		"computer_name":         customizationFixedName(obj.UserData.ComputerName),
+		"full_name":             obj.UserData.FullName,
+		"organization_name":     obj.UserData.OrgName,
+		"product_key":           obj.UserData.ProductId,
+	}
+	var commands []string
+	if obj.GuiRunOnce != nil {
+		commands = obj.GuiRunOnce.CommandList
+	}
+	m["run_once_command_list"] = structure.SliceStringsToInterfaces(commands)
+	return []interface{}{m}
+}
+
+// flattenSliceOfCustomizationAdapterMapping returns the network_interface data
+// for a CustomizationAdapterMapping slice, along with the first IPv4 and IPv6
+// gateways in the adapter settings.
+func flattenSliceOfCustomizationAdapterMapping(mappings []types.CustomizationAdapterMapping) ([]interface{}, string, string) {
+	var v4gw, v6gw string
+	var result []interface{}
+	for _, mapping := range mappings {
+		adapter := mapping.Adapter
+		m := map[string]interface{}{
+			"dns_server_list": structure.SliceStringsToInterfaces(adapter.DnsServerList),
+			"dns_domain":      adapter.DnsDomain,
+			"ipv4_address":    "",
+			"ipv4_netmask":    0,
+			"ipv6_address":    "",
+			"ipv6_netmask":    0,
+		}
+		if ip, ok := adapter.Ip.(*types.CustomizationFixedIp); ok {
+			m["ipv4_address"] = ip.IpAddress
+			m["ipv4_netmask"] = v4DottedMaskToCIDR(adapter.SubnetMask)
+		}
+		if v4gw == "" && len(adapter.Gateway) > 0 {
+			v4gw = adapter.Gateway[0]
+		}
+		if adapter.IpV6Spec != nil {
+			for _, gen := range adapter.IpV6Spec.Ip {
+				if ip, ok := gen.(*types.CustomizationFixedIpV6); ok {
+					m["ipv6_address"] = ip.IpAddress
+					m["ipv6_netmask"] = int(ip.SubnetMask)
+					break
+				}
+			}
+			if v6gw == "" && len(adapter.IpV6Spec.Gateway) > 0 {
+				v6gw = adapter.IpV6Spec.Gateway[0]
+			}
+		}
+		result = append(result, m)
+	}
+	return result, v4gw, v6gw
+}
+
+// FlattenGuestOSCustomizationSpec returns the data for the customization
+// settings at the supplied prefix from a CustomizationSpec. Encrypted
+// passwords cannot be read back, so the passwords in state are kept for
+// those.
+func FlattenGuestOSCustomizationSpec(d *schema.ResourceData, prefix string, obj types.CustomizationSpec) map[string]interface{} {
+	nics, v4gw, v6gw := flattenSliceOfCustomizationAdapterMapping(obj.NicSettingMap)
+	m := map[string]interface{}{
+		"dns_server_list":      structure.SliceStringsToInterfaces(obj.GlobalIPSettings.DnsServerList),
+		"dns_suffix_list":      structure.SliceStringsToInterfaces(obj.GlobalIPSettings.DnsSuffixList),
+		"linux_options":        []interface{}{},
+		"windows_options":      []interface{}{},
+		"windows_sysprep_text": "",
+		"network_interface":    nics,
+		"ipv4_gateway":         v4gw,
+		"ipv6_gateway":         v6gw,
+	}
+	switch identity := obj.Identity.(type) {
+	case *customizationspec.CustomizationLinuxPrep:
+		m["linux_options"] = flattenCustomizationLinuxPrep(identity)
+	case *customizationspec.CustomizationSysprep:
+		m["windows_options"] = flattenCustomizationSysprep(d, prefix, identity)
+	case *types.CustomizationSysprepText:
+		m["windows_sysprep_text"] = identity.Value
+	}
+	return m
+}
@@ -0,0 +1,57 @@
+package vmworkflow
+
+import (
+	"testing"
+)
+
+func TestRenderRunOnceCommand(t *testing.T) {
+	data := runOnceCommandData{
+		Name:          "vm-01",
+		ComputerName:  "VM01",
+		IPv4Addresses: []string{"10.0.0.10", "10.0.1.10"},
+	}
+	cases := []struct {
+		name     string
+		command  string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "plain",
+			command:  "cmd.exe /c echo done",
+			expected: "cmd.exe /c echo done",
+		},
+		{
+			name:     "templated",
+			command:  "register.exe {{.ComputerName}} {{index .IPv4Addresses 1}}",
+			expected: "register.exe VM01 10.0.1.10",
+		},
+		{
+			name:    "unknown field",
+			command: "register.exe {{.Hostname}}",
+			err:     true,
+		},
+		{
+			name:    "syntax error",
+			command: "register.exe {{.Name",
+			err:     true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := renderRunOnceCommand(tc.command, data)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bad: %s", err)
+			}
+			if actual != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
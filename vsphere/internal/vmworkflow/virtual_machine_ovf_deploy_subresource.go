@@ -0,0 +1,181 @@
+package vmworkflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/network"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/ovfdeploy"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+var ovfDeployDiskProvisioningAllowedValues = []string{
+	string(types.OvfCreateImportSpecParamsDiskProvisioningTypeThin),
+	string(types.OvfCreateImportSpecParamsDiskProvisioningTypeThick),
+	string(types.OvfCreateImportSpecParamsDiskProvisioningTypeEagerZeroedThick),
+	string(types.OvfCreateImportSpecParamsDiskProvisioningTypeFlat),
+}
+
+var ovfDeployIPAllocationPolicyAllowedValues = []string{
+	string(types.VAppIPAssignmentInfoIpAllocationPolicyDhcpPolicy),
+	string(types.VAppIPAssignmentInfoIpAllocationPolicyTransientPolicy),
+	string(types.VAppIPAssignmentInfoIpAllocationPolicyFixedPolicy),
+	string(types.VAppIPAssignmentInfoIpAllocationPolicyFixedAllocatedPolicy),
+}
+
+var ovfDeployIPProtocolAllowedValues = []string{
+	string(types.VAppIPAssignmentInfoProtocolsIPv4),
+	string(types.VAppIPAssignmentInfoProtocolsIPv6),
+}
+
+// VirtualMachineOvfDeploySchema represents the schema for the VM OVF deploy
+// sub-resource.
+//
+// This is a workflow for vsphere_virtual_machine that facilitates the creation
+// of a virtual machine by importing an OVF descriptor or OVA archive, either
+// from the local file system or from a remote URL.
+func VirtualMachineOvfDeploySchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"local_ovf_path": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"ovf_deploy.0.remote_ovf_url"},
+			Description:   "The absolute path to the OVF or OVA file on the local system running Terraform.",
+		},
+		"remote_ovf_url": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"ovf_deploy.0.local_ovf_path"},
+			Description:   "The URL of a remote OVF or OVA file. Files referenced by an OVF descriptor are fetched relative to this URL.",
+		},
+		"allow_unverified_ssl_cert": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Description: "Allow unverified SSL certificates when fetching a remote OVF or OVA.",
+		},
+		"disk_provisioning": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Description:  "The disk provisioning type for the imported disks. Can be one of thin, thick, eagerZeroedThick, or flat. If not set, the provisioning type from the OVF descriptor is used.",
+			ValidateFunc: validation.StringInSlice(ovfDeployDiskProvisioningAllowedValues, false),
+		},
+		"deployment_option": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "The key of the deployment configuration to use, for OVFs that define more than one.",
+		},
+		"ip_allocation_policy": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Description:  "The IP allocation policy for the deployed virtual machine.",
+			ValidateFunc: validation.StringInSlice(ovfDeployIPAllocationPolicyAllowedValues, false),
+		},
+		"ip_protocol": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Description:  "The IP protocol for the deployed virtual machine.",
+			ValidateFunc: validation.StringInSlice(ovfDeployIPProtocolAllowedValues, false),
+		},
+		"ovf_network_map": {
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Description: "A mapping of network names in the OVF descriptor to the IDs of networks in vSphere.",
+			Elem:        &schema.Schema{Type: schema.TypeString},
+		},
+		"timeout": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      30,
+			Description:  "The timeout, in minutes, to wait for the OVF import and disk upload to complete.",
+			ValidateFunc: validation.IntAtLeast(10),
+		},
+	}
+}
+
+// ValidateVirtualMachineOvfDeploy does pre-creation validation of a virtual
+// machine's OVF deploy configuration. Only basic sanity checks are done here,
+// as the OVF descriptor itself is only validated by the OvfManager when the
+// import spec is created.
+func ValidateVirtualMachineOvfDeploy(d *schema.ResourceDiff) error {
+	log.Printf("[DEBUG] ValidateVirtualMachineOvfDeploy: Validating OVF deploy configuration")
+	localPath := d.Get("ovf_deploy.0.local_ovf_path").(string)
+	remoteURL := d.Get("ovf_deploy.0.remote_ovf_url").(string)
+	if localPath == "" && remoteURL == "" {
+		return errors.New("one of local_ovf_path or remote_ovf_url must be set in ovf_deploy")
+	}
+	if localPath != "" {
+		if _, err := os.Stat(localPath); err != nil {
+			return fmt.Errorf("cannot read OVF file at local_ovf_path: %s", err)
+		}
+	}
+	if _, ok := d.GetOk("datastore_cluster_id"); ok {
+		return errors.New("datastore_cluster_id is not supported with ovf_deploy, use datastore_id instead")
+	}
+	if d.NewValueKnown("datastore_id") && d.Get("datastore_id").(string) == "" {
+		return errors.New("datastore_id is required when using ovf_deploy")
+	}
+	return nil
+}
+
+// ExpandVirtualMachineOvfDeploy reads the ovf_deploy sub-resource and returns
+// the source of the OVF along with the import spec parameters that should be
+// sent to the OvfManager.
+//
+// vApp properties in vapp.0.properties are passed in as the property mapping
+// so that appliances have their configuration on first boot.
+func ExpandVirtualMachineOvfDeploy(d *schema.ResourceData, c *govmomi.Client) (*ovfdeploy.Source, types.OvfCreateImportSpecParams, error) {
+	log.Printf("[DEBUG] ExpandVirtualMachineOvfDeploy: Preparing OVF import spec parameters for VM")
+	src := &ovfdeploy.Source{
+		AllowUnverifiedSSL: d.Get("ovf_deploy.0.allow_unverified_ssl_cert").(bool),
+	}
+	if v, ok := d.GetOk("ovf_deploy.0.remote_ovf_url"); ok {
+		src.Path = v.(string)
+		src.Remote = true
+	} else {
+		src.Path = d.Get("ovf_deploy.0.local_ovf_path").(string)
+	}
+
+	params := types.OvfCreateImportSpecParams{
+		OvfManagerCommonParams: types.OvfManagerCommonParams{
+			DeploymentOption: d.Get("ovf_deploy.0.deployment_option").(string),
+		},
+		EntityName:         d.Get("name").(string),
+		DiskProvisioning:   d.Get("ovf_deploy.0.disk_provisioning").(string),
+		IpAllocationPolicy: d.Get("ovf_deploy.0.ip_allocation_policy").(string),
+		IpProtocol:         d.Get("ovf_deploy.0.ip_protocol").(string),
+	}
+
+	for name, id := range d.Get("ovf_deploy.0.ovf_network_map").(map[string]interface{}) {
+		net, err := network.FromID(c, id.(string))
+		if err != nil {
+			return nil, params, fmt.Errorf("error locating network %q for OVF network %q: %s", id.(string), name, err)
+		}
+		params.NetworkMapping = append(params.NetworkMapping, types.OvfNetworkMapping{
+			Name:    name,
+			Network: net.Reference(),
+		})
+	}
+
+	if props, ok := d.Get("vapp.0.properties").(map[string]interface{}); ok {
+		for k, v := range props {
+			params.PropertyMapping = append(params.PropertyMapping, types.KeyValue{
+				Key:   k,
+				Value: v.(string),
+			})
+		}
+	}
+
+	if v, ok := d.GetOk("host_system_id"); ok {
+		params.HostSystem = &types.ManagedObjectReference{
+			Type:  "HostSystem",
+			Value: v.(string),
+		}
+	}
+	log.Printf("[DEBUG] ExpandVirtualMachineOvfDeploy: OVF import spec parameter prep complete")
+	return src, params, nil
+}
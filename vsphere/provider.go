@@ -89,6 +89,8 @@ func Provider() terraform.ResourceProvider {
 
 		ResourcesMap: map[string]*schema.Resource{
 			"vsphere_compute_cluster":            resourceVSphereComputeCluster(),
+			"vsphere_content_library":            resourceVSphereContentLibrary(),
+			"vsphere_content_library_item":       resourceVSphereContentLibraryItem(),
 			"vsphere_custom_attribute":           resourceVSphereCustomAttribute(),
 			"vsphere_datacenter":                 resourceVSphereDatacenter(),
 			"vsphere_datastore_cluster":          resourceVSphereDatastoreCluster(),
@@ -98,9 +100,13 @@ func Provider() terraform.ResourceProvider {
 			"vsphere_dpm_host_override":          resourceVSphereDPMHostOverride(),
 			"vsphere_file":                       resourceVSphereFile(),
 			"vsphere_folder":                     resourceVSphereFolder(),
+			"vsphere_guest_command":              resourceVSphereGuestCommand(),
+			"vsphere_guest_file":                 resourceVSphereGuestFile(),
+			"vsphere_guest_os_customization":     resourceVSphereGuestOSCustomization(),
 			"vsphere_ha_vm_override":             resourceVSphereHAVMOverride(),
 			"vsphere_host_port_group":            resourceVSphereHostPortGroup(),
 			"vsphere_host_virtual_switch":        resourceVSphereHostVirtualSwitch(),
+			"vsphere_key_provider":               resourceVSphereKeyProvider(),
 			"vsphere_license":                    resourceVSphereLicense(),
 			"vsphere_tag":                        resourceVSphereTag(),
 			"vsphere_tag_category":               resourceVSphereTagCategory(),
@@ -109,19 +115,24 @@ func Provider() terraform.ResourceProvider {
 			"vsphere_nas_datastore":              resourceVSphereNasDatastore(),
 			"vsphere_storage_drs_vm_override":    resourceVSphereStorageDrsVMOverride(),
 			"vsphere_vmfs_datastore":             resourceVSphereVmfsDatastore(),
+			"vsphere_vm_storage_policy":          resourceVSphereVMStoragePolicy(),
 			"vsphere_virtual_machine_snapshot":   resourceVSphereVirtualMachineSnapshot(),
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
 			"vsphere_compute_cluster":            dataSourceVSphereComputeCluster(),
+			"vsphere_content_library":            dataSourceVSphereContentLibrary(),
+			"vsphere_content_library_item":       dataSourceVSphereContentLibraryItem(),
 			"vsphere_custom_attribute":           dataSourceVSphereCustomAttribute(),
 			"vsphere_datacenter":                 dataSourceVSphereDatacenter(),
 			"vsphere_datastore":                  dataSourceVSphereDatastore(),
 			"vsphere_datastore_cluster":          dataSourceVSphereDatastoreCluster(),
 			"vsphere_distributed_virtual_switch": dataSourceVSphereDistributedVirtualSwitch(),
 			"vsphere_host":                       dataSourceVSphereHost(),
+			"vsphere_host_pci_device":            dataSourceVSphereHostPciDevice(),
 			"vsphere_network":                    dataSourceVSphereNetwork(),
 			"vsphere_resource_pool":              dataSourceVSphereResourcePool(),
+			"vsphere_storage_policy":             dataSourceVSphereStoragePolicy(),
 			"vsphere_tag":                        dataSourceVSphereTag(),
 			"vsphere_tag_category":               dataSourceVSphereTagCategory(),
 			"vsphere_virtual_machine":            dataSourceVSphereVirtualMachine(),
@@ -0,0 +1,344 @@
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
+)
+
+// contentLibraryStorageBackingTypeDatastore is the storage backing type for
+// datastore-backed content libraries.
+const contentLibraryStorageBackingTypeDatastore = "DATASTORE"
+
+var contentLibraryAuthenticationMethodAllowedValues = []string{
+	contentlibrary.AuthenticationMethodNone,
+	contentlibrary.AuthenticationMethodBasic,
+}
+
+func resourceVSphereContentLibrary() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceVSphereContentLibraryCreate,
+		Read:          resourceVSphereContentLibraryRead,
+		Update:        resourceVSphereContentLibraryUpdate,
+		Delete:        resourceVSphereContentLibraryDelete,
+		CustomizeDiff: resourceVSphereContentLibraryCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereContentLibraryImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the content library.",
+				Required:    true,
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Description: "The description of the content library.",
+				Optional:    true,
+			},
+			"storage_backing": {
+				Type:        schema.TypeSet,
+				Description: "The managed object IDs of the datastores that back the content library.",
+				Required:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"publication": {
+				Type:          schema.TypeList,
+				Description:   "Publication settings for a local content library.",
+				Optional:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"subscription"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"published": {
+							Type:        schema.TypeBool,
+							Description: "Publish the content library so that other libraries can subscribe to it.",
+							Optional:    true,
+							Default:     true,
+						},
+						"authentication_method": {
+							Type:         schema.TypeString,
+							Description:  "The method subscribers must use to authenticate against the published library. Can be one of NONE or BASIC.",
+							Optional:     true,
+							Default:      contentlibrary.AuthenticationMethodNone,
+							ValidateFunc: validation.StringInSlice(contentLibraryAuthenticationMethodAllowedValues, false),
+						},
+						"username": {
+							Type:        schema.TypeString,
+							Description: "The username subscribers must use when authentication_method is BASIC.",
+							Optional:    true,
+							Computed:    true,
+						},
+						"password": {
+							Type:        schema.TypeString,
+							Description: "The password subscribers must use when authentication_method is BASIC.",
+							Optional:    true,
+							Sensitive:   true,
+						},
+						"publish_url": {
+							Type:        schema.TypeString,
+							Description: "The URL that subscribers use to subscribe to the published library.",
+							Computed:    true,
+						},
+					},
+				},
+			},
+			"subscription": {
+				Type:          schema.TypeList,
+				Description:   "Subscription settings. When specified, the content library is a subscribed library.",
+				Optional:      true,
+				MaxItems:      1,
+				ConflictsWith: []string{"publication"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"subscription_url": {
+							Type:        schema.TypeString,
+							Description: "The URL of the published library to subscribe to.",
+							Required:    true,
+							ForceNew:    true,
+						},
+						"authentication_method": {
+							Type:         schema.TypeString,
+							Description:  "The method used to authenticate against the published library. Can be one of NONE or BASIC.",
+							Optional:     true,
+							Default:      contentlibrary.AuthenticationMethodNone,
+							ValidateFunc: validation.StringInSlice(contentLibraryAuthenticationMethodAllowedValues, false),
+						},
+						"username": {
+							Type:        schema.TypeString,
+							Description: "The username used when authentication_method is BASIC.",
+							Optional:    true,
+						},
+						"password": {
+							Type:        schema.TypeString,
+							Description: "The password used when authentication_method is BASIC.",
+							Optional:    true,
+							Sensitive:   true,
+						},
+						"automatic_sync": {
+							Type:        schema.TypeBool,
+							Description: "Synchronize the library with the published library automatically.",
+							Optional:    true,
+							Default:     false,
+						},
+						"on_demand": {
+							Type:        schema.TypeBool,
+							Description: "Download the content of library items only when they are used, versus when the library is synchronized.",
+							Optional:    true,
+							Default:     true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceVSphereContentLibraryCreate(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	lib := expandContentLibrary(d)
+	for _, v := range d.Get("storage_backing").(*schema.Set).List() {
+		lib.StorageBackings = append(lib.StorageBackings, contentlibrary.StorageBacking{
+			Type:        contentLibraryStorageBackingTypeDatastore,
+			DatastoreID: v.(string),
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	id, err := client.CreateLibrary(ctx, lib)
+	if err != nil {
+		return fmt.Errorf("could not create content library: %s", err)
+	}
+	d.SetId(id)
+	return resourceVSphereContentLibraryRead(d, meta)
+}
+
+func resourceVSphereContentLibraryRead(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	id := d.Id()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	lib, err := client.GetLibrary(ctx, id)
+	if err != nil {
+		if contentlibrary.IsNotFoundError(err) {
+			log.Printf("[DEBUG] Content library %q not found, removing from state", id)
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("could not locate content library with id %q: %s", id, err)
+	}
+	d.Set("name", lib.Name)
+	d.Set("description", lib.Description)
+
+	var backings []string
+	for _, b := range lib.StorageBackings {
+		if b.DatastoreID != "" {
+			backings = append(backings, b.DatastoreID)
+		}
+	}
+	if err := d.Set("storage_backing", backings); err != nil {
+		return fmt.Errorf("could not set storage backing data for content library: %s", err)
+	}
+	if err := flattenContentLibraryPublication(d, lib.PublishInfo); err != nil {
+		return err
+	}
+	return flattenContentLibrarySubscription(d, lib.SubscriptionInfo)
+}
+
+func resourceVSphereContentLibraryUpdate(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	id := d.Id()
+	lib := expandContentLibrary(d)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if err := client.UpdateLibrary(ctx, id, lib); err != nil {
+		return fmt.Errorf("could not update content library with id %q: %s", id, err)
+	}
+	// Synchronize the library right away if its subscription settings have
+	// changed, rather than waiting for the next automatic synchronization.
+	if lib.Type == contentlibrary.LibraryTypeSubscribed && d.HasChange("subscription") {
+		if err := client.SyncLibrary(ctx, id); err != nil {
+			return fmt.Errorf("could not synchronize content library with id %q: %s", id, err)
+		}
+	}
+	return resourceVSphereContentLibraryRead(d, meta)
+}
+
+func resourceVSphereContentLibraryDelete(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	id := d.Id()
+	libraryType := contentlibrary.LibraryTypeLocal
+	if len(d.Get("subscription").([]interface{})) > 0 {
+		libraryType = contentlibrary.LibraryTypeSubscribed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if err := client.DeleteLibrary(ctx, id, libraryType); err != nil {
+		return fmt.Errorf("could not delete content library with id %q: %s", id, err)
+	}
+	return nil
+}
+
+func resourceVSphereContentLibraryCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	// A library cannot be converted between a local and a subscribed library,
+	// so adding or removing the subscription block requires a new resource.
+	if d.Id() != "" && d.HasChange("subscription") {
+		o, n := d.GetChange("subscription")
+		if len(o.([]interface{})) != len(n.([]interface{})) {
+			if err := d.ForceNew("subscription"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resourceVSphereContentLibraryImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	id, err := client.FindLibrary(ctx, d.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	d.SetId(id)
+	return []*schema.ResourceData{d}, nil
+}
+
+// expandContentLibrary reads the settings of a content library from
+// ResourceData. Storage backings are not included as they cannot be updated.
+func expandContentLibrary(d *schema.ResourceData) *contentlibrary.Library {
+	lib := &contentlibrary.Library{
+		Name:        d.Get("name").(string),
+		Description: d.Get("description").(string),
+		Type:        contentlibrary.LibraryTypeLocal,
+	}
+	if v, ok := d.GetOk("publication.0"); ok {
+		m := v.(map[string]interface{})
+		lib.PublishInfo = &contentlibrary.PublishInfo{
+			Published:            m["published"].(bool),
+			AuthenticationMethod: m["authentication_method"].(string),
+			UserName:             m["username"].(string),
+			Password:             m["password"].(string),
+		}
+	}
+	if v, ok := d.GetOk("subscription.0"); ok {
+		m := v.(map[string]interface{})
+		lib.Type = contentlibrary.LibraryTypeSubscribed
+		lib.SubscriptionInfo = &contentlibrary.SubscriptionInfo{
+			SubscriptionURL:      m["subscription_url"].(string),
+			AutomaticSyncEnabled: m["automatic_sync"].(bool),
+			OnDemand:             m["on_demand"].(bool),
+			AuthenticationMethod: m["authentication_method"].(string),
+			UserName:             m["username"].(string),
+			Password:             m["password"].(string),
+		}
+	}
+	return lib
+}
+
+// flattenContentLibraryPublication saves the publication settings of a
+// content library to ResourceData. Local libraries always have publication
+// settings, so the block is only populated if the library is published or
+// the block is already present. The password is never returned by the API
+// and is left as-is.
+func flattenContentLibraryPublication(d *schema.ResourceData, info *contentlibrary.PublishInfo) error {
+	if info == nil || (!info.Published && len(d.Get("publication").([]interface{})) < 1) {
+		return d.Set("publication", nil)
+	}
+	m := map[string]interface{}{
+		"published":             info.Published,
+		"authentication_method": info.AuthenticationMethod,
+		"username":              info.UserName,
+		"password":              d.Get("publication.0.password").(string),
+		"publish_url":           info.PublishURL,
+	}
+	return d.Set("publication", []interface{}{m})
+}
+
+// flattenContentLibrarySubscription saves the subscription settings of a
+// subscribed content library to ResourceData. As with publication settings,
+// the password is left as-is.
+func flattenContentLibrarySubscription(d *schema.ResourceData, info *contentlibrary.SubscriptionInfo) error {
+	if info == nil {
+		return d.Set("subscription", nil)
+	}
+	m := map[string]interface{}{
+		"subscription_url":      info.SubscriptionURL,
+		"authentication_method": info.AuthenticationMethod,
+		"username":              info.UserName,
+		"password":              d.Get("subscription.0.password").(string),
+		"automatic_sync":        info.AutomaticSyncEnabled,
+		"on_demand":             info.OnDemand,
+	}
+	return d.Set("subscription", []interface{}{m})
+}
@@ -0,0 +1,305 @@
+package vsphere
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/ovfdeploy"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+)
+
+const (
+	// contentLibraryItemTypeIso is the item type for ISO images.
+	contentLibraryItemTypeIso = "iso"
+
+	// contentLibraryItemTypeFile is the item type for files that vSphere does
+	// not otherwise recognize.
+	contentLibraryItemTypeFile = "file"
+)
+
+func resourceVSphereContentLibraryItem() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceVSphereContentLibraryItemCreate,
+		Read:   resourceVSphereContentLibraryItemRead,
+		Update: resourceVSphereContentLibraryItemUpdate,
+		Delete: resourceVSphereContentLibraryItemDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereContentLibraryItemImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"library_id": {
+				Type:        schema.TypeString,
+				Description: "The ID of the content library to create the item in.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the item.",
+				Required:    true,
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Description: "The description of the item.",
+				Optional:    true,
+			},
+			"file_url": {
+				Type:          schema.TypeString,
+				Description:   "The local path or HTTP(S) URL of the OVF, OVA, or ISO file to upload to the item.",
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"source_uuid"},
+			},
+			"source_uuid": {
+				Type:          schema.TypeString,
+				Description:   "The UUID of a virtual machine to capture as an OVF template in the item.",
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"file_url"},
+			},
+			"type": {
+				Type:        schema.TypeString,
+				Description: "The type of the item, such as ovf or iso. Derived from file_url or source_uuid when not specified.",
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+			},
+		},
+	}
+}
+
+func resourceVSphereContentLibraryItemCreate(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	item := &contentlibrary.LibraryItem{
+		LibraryID:   d.Get("library_id").(string),
+		Name:        d.Get("name").(string),
+		Description: d.Get("description").(string),
+		Type:        d.Get("type").(string),
+	}
+
+	// Uploads and captures can take a long time, so these are not bound to
+	// the default API timeout. vSphere fails stalled update sessions on its
+	// own.
+	ctx := context.Background()
+	var id string
+	switch {
+	case d.Get("source_uuid").(string) != "":
+		vm, err := virtualmachine.FromUUID(meta.(*VSphereClient).vimClient, d.Get("source_uuid").(string))
+		if err != nil {
+			return fmt.Errorf("cannot locate virtual machine with UUID %q: %s", d.Get("source_uuid").(string), err)
+		}
+		if id, err = client.CaptureVirtualMachine(ctx, vm.Reference().Value, item.LibraryID, item); err != nil {
+			return fmt.Errorf("could not capture virtual machine to content library: %s", err)
+		}
+	case d.Get("file_url").(string) != "":
+		src := contentLibraryItemSource(d.Get("file_url").(string))
+		if item.Type == "" {
+			item.Type = contentLibraryItemTypeFromPath(src.Path)
+		}
+		files, err := contentLibraryItemFiles(src, item.Type)
+		if err != nil {
+			return err
+		}
+		if id, err = client.CreateLibraryItem(ctx, item); err != nil {
+			return fmt.Errorf("could not create content library item: %s", err)
+		}
+		// Save the ID now so that the item is cleaned up on the next run if the
+		// upload fails.
+		d.SetId(id)
+		if err := client.UploadLibraryItemFiles(ctx, id, files); err != nil {
+			return fmt.Errorf("could not upload files to content library item %q: %s", id, err)
+		}
+	default:
+		return errors.New("one of file_url or source_uuid must be specified")
+	}
+
+	d.SetId(id)
+	return resourceVSphereContentLibraryItemRead(d, meta)
+}
+
+func resourceVSphereContentLibraryItemRead(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	id := d.Id()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	item, err := client.GetLibraryItem(ctx, id)
+	if err != nil {
+		if contentlibrary.IsNotFoundError(err) {
+			log.Printf("[DEBUG] Content library item %q not found, removing from state", id)
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("could not locate content library item with id %q: %s", id, err)
+	}
+	d.Set("library_id", item.LibraryID)
+	d.Set("name", item.Name)
+	d.Set("description", item.Description)
+	d.Set("type", item.Type)
+	return nil
+}
+
+func resourceVSphereContentLibraryItemUpdate(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	id := d.Id()
+	item := &contentlibrary.LibraryItem{
+		Name:        d.Get("name").(string),
+		Description: d.Get("description").(string),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if err := client.UpdateLibraryItem(ctx, id, item); err != nil {
+		return fmt.Errorf("could not update content library item with id %q: %s", id, err)
+	}
+	return resourceVSphereContentLibraryItemRead(d, meta)
+}
+
+func resourceVSphereContentLibraryItemDelete(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return err
+	}
+
+	id := d.Id()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	if err := client.DeleteLibraryItem(ctx, id); err != nil {
+		return fmt.Errorf("could not delete content library item with id %q: %s", id, err)
+	}
+	return nil
+}
+
+func resourceVSphereContentLibraryItemImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	// As with tags, import takes the library and item names through JSON, as
+	// both can contain nearly any character.
+	var data map[string]string
+	if err := json.Unmarshal([]byte(d.Id()), &data); err != nil {
+		return nil, err
+	}
+	libraryName, ok := data["library_name"]
+	if !ok {
+		return nil, errors.New("missing library_name in input data")
+	}
+	itemName, ok := data["item_name"]
+	if !ok {
+		return nil, errors.New("missing item_name in input data")
+	}
+
+	client, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	libraryID, err := client.FindLibrary(ctx, libraryName)
+	if err != nil {
+		return nil, err
+	}
+	id, err := client.FindLibraryItem(ctx, libraryID, itemName)
+	if err != nil {
+		return nil, err
+	}
+
+	d.SetId(id)
+	return []*schema.ResourceData{d}, nil
+}
+
+// contentLibraryItemSource returns the source for the file at p, which can
+// either be a local path or an HTTP(S) URL.
+func contentLibraryItemSource(p string) *ovfdeploy.Source {
+	return &ovfdeploy.Source{
+		Path:   p,
+		Remote: strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://"),
+	}
+}
+
+// contentLibraryItemFileName returns the base name of the file at the
+// supplied source.
+func contentLibraryItemFileName(src *ovfdeploy.Source) string {
+	if src.Remote {
+		if u, err := url.Parse(src.Path); err == nil {
+			return path.Base(u.Path)
+		}
+	}
+	return filepath.Base(src.Path)
+}
+
+// contentLibraryItemTypeFromPath derives the content library item type from
+// the extension of the file at p.
+func contentLibraryItemTypeFromPath(p string) string {
+	src := contentLibraryItemSource(p)
+	switch strings.ToLower(path.Ext(contentLibraryItemFileName(src))) {
+	case ".ovf", ".ova":
+		return contentlibrary.ItemTypeOvf
+	case ".iso":
+		return contentLibraryItemTypeIso
+	}
+	return contentLibraryItemTypeFile
+}
+
+// contentLibraryItemFiles returns the files that need to be uploaded to a
+// content library item of the supplied type from src. OVA archives are
+// unpacked, as content libraries only accept OVF templates as a descriptor
+// plus the files it references.
+func contentLibraryItemFiles(src *ovfdeploy.Source, itemType string) ([]contentlibrary.LibraryItemFile, error) {
+	name := contentLibraryItemFileName(src)
+	if itemType != contentlibrary.ItemTypeOvf {
+		return []contentlibrary.LibraryItemFile{
+			{
+				Name: name,
+				Open: func() (io.ReadCloser, int64, error) { return src.Open("") },
+			},
+		}, nil
+	}
+
+	descriptor, err := src.Descriptor()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := ovfdeploy.FileReferences(descriptor)
+	if err != nil {
+		return nil, err
+	}
+	files := []contentlibrary.LibraryItemFile{
+		{
+			Name: strings.TrimSuffix(name, path.Ext(name)) + ".ovf",
+			Open: func() (io.ReadCloser, int64, error) {
+				return ioutil.NopCloser(strings.NewReader(descriptor)), int64(len(descriptor)), nil
+			},
+		},
+	}
+	for _, ref := range refs {
+		ref := ref
+		files = append(files, contentlibrary.LibraryItemFile{
+			Name: ref,
+			Open: func() (io.ReadCloser, int64, error) { return src.Open(ref) },
+		})
+	}
+	return files, nil
+}
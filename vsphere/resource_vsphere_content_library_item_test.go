@@ -0,0 +1,166 @@
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
+)
+
+func TestAccResourceVSphereContentLibraryItem_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryItemPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryItemExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryItemConfig("terraform-test-item"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryItemExists(true),
+					testAccResourceVSphereContentLibraryItemHasName("terraform-test-item"),
+					resource.TestCheckResourceAttrSet("vsphere_content_library_item.item", "type"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereContentLibraryItem_rename(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryItemPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryItemExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryItemConfig("terraform-test-item"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryItemExists(true),
+					testAccResourceVSphereContentLibraryItemHasName("terraform-test-item"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereContentLibraryItemConfig("terraform-test-item-renamed"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryItemExists(true),
+					testAccResourceVSphereContentLibraryItemHasName("terraform-test-item-renamed"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereContentLibraryItem_import(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryItemPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryItemExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryItemConfig("terraform-test-item"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryItemExists(true),
+				),
+			},
+			{
+				ResourceName:            "vsphere_content_library_item.item",
+				ImportState:             true,
+				ImportStateId:           `{"library_name": "terraform-test-library", "item_name": "terraform-test-item"}`,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"file_url"},
+			},
+		},
+	})
+}
+
+func testAccResourceVSphereContentLibraryItemPreCheck(t *testing.T) {
+	testAccResourceVSphereContentLibraryPreCheck(t)
+	if os.Getenv("VSPHERE_CONTENT_LIBRARY_FILE_URL") == "" {
+		t.Skip("set VSPHERE_CONTENT_LIBRARY_FILE_URL to run vsphere_content_library_item acceptance tests")
+	}
+}
+
+func testAccResourceVSphereContentLibraryItemExists(expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		_, err := testGetContentLibraryItem(s, "item")
+		if err != nil {
+			if contentlibrary.IsNotFoundError(err) && !expected {
+				// Expected missing
+				return nil
+			}
+			return err
+		}
+		if !expected {
+			return errors.New("expected content library item to be missing")
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereContentLibraryItemHasName(expected string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		item, err := testGetContentLibraryItem(s, "item")
+		if err != nil {
+			return err
+		}
+		actual := item.Name
+		if expected != actual {
+			return fmt.Errorf("expected name to be %q, got %q", expected, actual)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereContentLibraryItemConfig(name string) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "file_url" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_content_library" "library" {
+  name            = "terraform-test-library"
+  storage_backing = ["${data.vsphere_datastore.datastore.id}"]
+}
+
+resource "vsphere_content_library_item" "item" {
+  name        = "%s"
+  description = "Terraform test content library item"
+  library_id  = "${vsphere_content_library.library.id}"
+  file_url    = "${var.file_url}"
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_CONTENT_LIBRARY_FILE_URL"),
+		name,
+	)
+}
@@ -0,0 +1,310 @@
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
+)
+
+func TestAccResourceVSphereContentLibrary_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryConfigBasic("terraform-test-library"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryExists(true),
+					testAccResourceVSphereContentLibraryHasName("terraform-test-library"),
+					resource.TestCheckResourceAttr("vsphere_content_library.library", "storage_backing.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereContentLibrary_rename(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryConfigBasic("terraform-test-library"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryExists(true),
+					testAccResourceVSphereContentLibraryHasName("terraform-test-library"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereContentLibraryConfigBasic("terraform-test-library-renamed"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryExists(true),
+					testAccResourceVSphereContentLibraryHasName("terraform-test-library-renamed"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereContentLibrary_published(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryConfigPublished(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryExists(true),
+					testAccResourceVSphereContentLibraryIsPublished(true),
+					resource.TestCheckResourceAttrSet("vsphere_content_library.library", "publication.0.publish_url"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereContentLibraryConfigBasic("terraform-test-library"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryExists(true),
+					testAccResourceVSphereContentLibraryIsPublished(false),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereContentLibrary_subscribed(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryConfigSubscribed(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryExists(true),
+					testAccResourceVSphereContentLibraryHasType(contentlibrary.LibraryTypeSubscribed),
+					resource.TestCheckResourceAttr("vsphere_content_library.subscriber", "subscription.0.on_demand", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereContentLibrary_import(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereContentLibraryPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereContentLibraryExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereContentLibraryConfigBasic("terraform-test-library"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereContentLibraryExists(true),
+				),
+			},
+			{
+				ResourceName:      "vsphere_content_library.library",
+				ImportState:       true,
+				ImportStateId:     "terraform-test-library",
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccResourceVSphereContentLibraryPreCheck(t *testing.T) {
+	if os.Getenv("VSPHERE_DATACENTER") == "" {
+		t.Skip("set VSPHERE_DATACENTER to run vsphere_content_library acceptance tests")
+	}
+	if os.Getenv("VSPHERE_DATASTORE") == "" {
+		t.Skip("set VSPHERE_DATASTORE to run vsphere_content_library acceptance tests")
+	}
+}
+
+func testAccResourceVSphereContentLibraryExists(expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		_, err := testGetContentLibrary(s, "library")
+		if err != nil {
+			if contentlibrary.IsNotFoundError(err) && !expected {
+				// Expected missing
+				return nil
+			}
+			return err
+		}
+		if !expected {
+			return errors.New("expected content library to be missing")
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereContentLibraryHasName(expected string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		lib, err := testGetContentLibrary(s, "library")
+		if err != nil {
+			return err
+		}
+		actual := lib.Name
+		if expected != actual {
+			return fmt.Errorf("expected name to be %q, got %q", expected, actual)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereContentLibraryIsPublished(expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		lib, err := testGetContentLibrary(s, "library")
+		if err != nil {
+			return err
+		}
+		actual := lib.PublishInfo != nil && lib.PublishInfo.Published
+		if expected != actual {
+			return fmt.Errorf("expected published to be %t, got %t", expected, actual)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereContentLibraryHasType(expected string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		lib, err := testGetContentLibrary(s, "subscriber")
+		if err != nil {
+			return err
+		}
+		actual := lib.Type
+		if expected != actual {
+			return fmt.Errorf("expected type to be %q, got %q", expected, actual)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereContentLibraryConfigBasic(name string) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_content_library" "library" {
+  name            = "%s"
+  description     = "Terraform test content library"
+  storage_backing = ["${data.vsphere_datastore.datastore.id}"]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		name,
+	)
+}
+
+func testAccResourceVSphereContentLibraryConfigPublished() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_content_library" "library" {
+  name            = "terraform-test-library"
+  description     = "Terraform test content library"
+  storage_backing = ["${data.vsphere_datastore.datastore.id}"]
+
+  publication {
+    published = true
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_DATASTORE"),
+	)
+}
+
+func testAccResourceVSphereContentLibraryConfigSubscribed() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_content_library" "library" {
+  name            = "terraform-test-library"
+  storage_backing = ["${data.vsphere_datastore.datastore.id}"]
+
+  publication {
+    published = true
+  }
+}
+
+resource "vsphere_content_library" "subscriber" {
+  name            = "terraform-test-library-subscriber"
+  storage_backing = ["${data.vsphere_datastore.datastore.id}"]
+
+  subscription {
+    subscription_url = "${vsphere_content_library.library.publication.0.publish_url}"
+    on_demand        = true
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_DATASTORE"),
+	)
+}
@@ -0,0 +1,144 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+)
+
+func resourceVSphereGuestCommand() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"command": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "The absolute path of the program to run in the guest.",
+		},
+		"arguments": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+			Description: "The arguments to pass to the program.",
+		},
+		"working_directory": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			ForceNew:    true,
+			Description: "The absolute path of the directory to run the program in.",
+		},
+		"environment": {
+			Type:        schema.TypeMap,
+			Optional:    true,
+			ForceNew:    true,
+			Description: "Environment variables to set for the program.",
+		},
+		"timeout": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      5,
+			Description:  "The amount of time, in minutes, to wait for the program to exit.",
+			ValidateFunc: validation.IntAtLeast(1),
+		},
+		"ignore_exit_code": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Description: "Do not fail when the program exits with a non-zero exit code.",
+		},
+		"exit_code": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "The exit code of the program.",
+		},
+		"stdout": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The standard output of the program.",
+		},
+		"stderr": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The standard error of the program.",
+		},
+	}
+	structure.MergeSchema(s, schemaGuestOperations())
+
+	return &schema.Resource{
+		Create: resourceVSphereGuestCommandCreate,
+		Read:   resourceVSphereGuestCommandRead,
+		Update: resourceVSphereGuestCommandUpdate,
+		Delete: resourceVSphereGuestCommandDelete,
+		Schema: s,
+	}
+}
+
+func resourceVSphereGuestCommandCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	gc, err := guestOperationsClient(d, client, true)
+	if err != nil {
+		return err
+	}
+
+	command := d.Get("command").(string)
+	var env []string
+	for k, v := range d.Get("environment").(map[string]interface{}) {
+		env = append(env, fmt.Sprintf("%s=%s", k, v.(string)))
+	}
+	sort.Strings(env)
+	result, err := gc.Run(
+		command,
+		d.Get("arguments").(string),
+		d.Get("working_directory").(string),
+		env,
+		d.Get("timeout").(int),
+	)
+	if err != nil {
+		return fmt.Errorf("error running %q in guest: %s", command, err)
+	}
+	if result.ExitCode != 0 && !d.Get("ignore_exit_code").(bool) {
+		return fmt.Errorf("%q exited with code %d\n\nstdout:\n%s\n\nstderr:\n%s", command, result.ExitCode, result.Stdout, result.Stderr)
+	}
+
+	d.SetId(fmt.Sprintf("%s:%d", d.Get("virtual_machine_uuid").(string), result.PID))
+	d.Set("exit_code", result.ExitCode)
+	d.Set("stdout", result.Stdout)
+	d.Set("stderr", result.Stderr)
+	return resourceVSphereGuestCommandRead(d, meta)
+}
+
+func resourceVSphereGuestCommandRead(d *schema.ResourceData, meta interface{}) error {
+	// The command has already run, so the only thing to check is that the
+	// virtual machine it ran in still exists.
+	client := meta.(*VSphereClient).vimClient
+	uuid := d.Get("virtual_machine_uuid").(string)
+	if _, err := virtualmachine.FromUUID(client, uuid); err != nil {
+		if virtualmachine.IsUUIDNotFoundError(err) {
+			log.Printf("[DEBUG] %s: Virtual machine %q not found, removing from state", resourceVSphereGuestCommandIDString(d), uuid)
+			d.SetId("")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func resourceVSphereGuestCommandUpdate(d *schema.ResourceData, meta interface{}) error {
+	// Everything that affects how the command runs forces a new resource, so
+	// there is nothing to do here other than save the new values.
+	return resourceVSphereGuestCommandRead(d, meta)
+}
+
+func resourceVSphereGuestCommandDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
+
+// resourceVSphereGuestCommandIDString prints a friendly string for the
+// vsphere_guest_command resource.
+func resourceVSphereGuestCommandIDString(d structure.ResourceIDStringer) string {
+	return structure.ResourceIDString(d, "vsphere_guest_command")
+}
@@ -0,0 +1,87 @@
+package vsphere
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccResourceVSphereGuestCommand_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereGuestOperationsPreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereGuestCommandConfig("/bin/echo", "terraform-test"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("vsphere_guest_command.command", "exit_code", "0"),
+					resource.TestCheckResourceAttr("vsphere_guest_command.command", "stdout", "terraform-test\n"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereGuestCommand_nonZeroExit(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereGuestOperationsPreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceVSphereGuestCommandConfig("/bin/false", ""),
+				ExpectError: regexp.MustCompile("exited with code 1"),
+			},
+		},
+	})
+}
+
+func testAccResourceVSphereGuestOperationsPreCheck(t *testing.T) {
+	if os.Getenv("VSPHERE_GUEST_VM_UUID") == "" {
+		t.Skip("set VSPHERE_GUEST_VM_UUID to run guest operations acceptance tests")
+	}
+	if os.Getenv("VSPHERE_GUEST_USERNAME") == "" {
+		t.Skip("set VSPHERE_GUEST_USERNAME to run guest operations acceptance tests")
+	}
+	if os.Getenv("VSPHERE_GUEST_PASSWORD") == "" {
+		t.Skip("set VSPHERE_GUEST_PASSWORD to run guest operations acceptance tests")
+	}
+}
+
+func testAccResourceVSphereGuestCommandConfig(command, args string) string {
+	return fmt.Sprintf(`
+variable "vm_uuid" {
+  default = "%s"
+}
+
+variable "guest_username" {
+  default = "%s"
+}
+
+variable "guest_password" {
+  default = "%s"
+}
+
+resource "vsphere_guest_command" "command" {
+  virtual_machine_uuid = "${var.vm_uuid}"
+  guest_username       = "${var.guest_username}"
+  guest_password       = "${var.guest_password}"
+  command              = "%s"
+  arguments            = "%s"
+}
+`,
+		os.Getenv("VSPHERE_GUEST_VM_UUID"),
+		os.Getenv("VSPHERE_GUEST_USERNAME"),
+		os.Getenv("VSPHERE_GUEST_PASSWORD"),
+		command,
+		args,
+	)
+}
@@ -0,0 +1,237 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/guestoperations"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+)
+
+// The directions that vsphere_guest_file can transfer files in.
+const (
+	guestFileDirectionUpload   = "upload"
+	guestFileDirectionDownload = "download"
+)
+
+var guestFileDirectionAllowedValues = []string{
+	guestFileDirectionUpload,
+	guestFileDirectionDownload,
+}
+
+func resourceVSphereGuestFile() *schema.Resource {
+	s := map[string]*schema.Schema{
+		"local_path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "The path of the file on the system running Terraform.",
+		},
+		"guest_path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "The absolute path of the file in the guest.",
+		},
+		"direction": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ForceNew:     true,
+			Default:      guestFileDirectionUpload,
+			Description:  "The direction to transfer the file in. Can be one of upload or download.",
+			ValidateFunc: validation.StringInSlice(guestFileDirectionAllowedValues, false),
+		},
+		"checksum": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The SHA-256 checksum of the file in the guest.",
+		},
+		"size": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "The size of the file in the guest, in bytes.",
+		},
+		"modification_time": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The time that the file in the guest was last modified, in RFC 3339 format.",
+		},
+	}
+	structure.MergeSchema(s, schemaGuestOperations())
+
+	return &schema.Resource{
+		Create:        resourceVSphereGuestFileCreate,
+		Read:          resourceVSphereGuestFileRead,
+		Update:        resourceVSphereGuestFileUpdate,
+		Delete:        resourceVSphereGuestFileDelete,
+		CustomizeDiff: resourceVSphereGuestFileCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+func resourceVSphereGuestFileCreate(d *schema.ResourceData, meta interface{}) error {
+	if err := resourceVSphereGuestFileTransfer(d, meta); err != nil {
+		return err
+	}
+	d.SetId(fmt.Sprintf("%s:%s", d.Get("virtual_machine_uuid").(string), d.Get("guest_path").(string)))
+	return resourceVSphereGuestFileRead(d, meta)
+}
+
+func resourceVSphereGuestFileRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	gc, err := guestOperationsClient(d, client, false)
+	switch {
+	case err == errGuestOperationsNotReady:
+		// The checksum can only be read while the guest is running. Keep what
+		// is in state until it is.
+		return nil
+	case virtualmachine.IsUUIDNotFoundError(err):
+		log.Printf("[DEBUG] %s: Virtual machine not found, removing from state", resourceVSphereGuestFileIDString(d))
+		d.SetId("")
+		return nil
+	case err != nil:
+		return err
+	}
+
+	guestPath := d.Get("guest_path").(string)
+	info, err := gc.Stat(guestPath)
+	if err != nil {
+		if guestoperations.IsFileNotFoundError(err) {
+			log.Printf("[DEBUG] %s: Guest file %q not found, removing from state", resourceVSphereGuestFileIDString(d), guestPath)
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("error reading guest file %q: %s", guestPath, err)
+	}
+	// The file is only downloaded to compute its checksum if its size or
+	// modification time have changed since the last read.
+	var mtime string
+	if attrs := info.Attributes.GetGuestFileAttributes(); attrs.ModificationTime != nil {
+		mtime = attrs.ModificationTime.UTC().Format(time.RFC3339)
+	}
+	if d.Get("checksum").(string) != "" && mtime != "" && mtime == d.Get("modification_time").(string) && int(info.Size) == d.Get("size").(int) {
+		log.Printf("[DEBUG] %s: Guest file %q is unchanged, keeping checksum", resourceVSphereGuestFileIDString(d), guestPath)
+		return nil
+	}
+	sum, err := gc.Checksum(guestPath)
+	if err != nil {
+		return fmt.Errorf("error reading guest file %q: %s", guestPath, err)
+	}
+	d.Set("checksum", sum)
+	d.Set("size", info.Size)
+	d.Set("modification_time", mtime)
+	return nil
+}
+
+func resourceVSphereGuestFileUpdate(d *schema.ResourceData, meta interface{}) error {
+	if d.HasChange("checksum") {
+		if err := resourceVSphereGuestFileTransfer(d, meta); err != nil {
+			return err
+		}
+		// Make sure the checksum is computed again, as the transfer may not
+		// have changed the size or modification time of the guest file.
+		d.Set("checksum", "")
+	}
+	return resourceVSphereGuestFileRead(d, meta)
+}
+
+func resourceVSphereGuestFileDelete(d *schema.ResourceData, meta interface{}) error {
+	// Downloaded files are left in place on the system running Terraform.
+	if d.Get("direction").(string) != guestFileDirectionUpload {
+		d.SetId("")
+		return nil
+	}
+	client := meta.(*VSphereClient).vimClient
+	gc, err := guestOperationsClient(d, client, true)
+	if err != nil {
+		return err
+	}
+	guestPath := d.Get("guest_path").(string)
+	if err := gc.DeleteFile(guestPath); err != nil && !guestoperations.IsFileNotFoundError(err) {
+		return fmt.Errorf("error deleting guest file %q: %s", guestPath, err)
+	}
+	d.SetId("")
+	return nil
+}
+
+// resourceVSphereGuestFileCustomizeDiff compares the checksum of the local
+// file against the checksum of the guest file that was read into state. If
+// they differ, the file is transferred again on the next apply.
+func resourceVSphereGuestFileCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	localPath := d.Get("local_path").(string)
+	f, err := os.Open(localPath)
+	if err != nil {
+		if os.IsNotExist(err) && d.Get("direction").(string) == guestFileDirectionDownload {
+			log.Printf("[DEBUG] %s: Local file %q not found, flagging for download", resourceVSphereGuestFileIDString(d), localPath)
+			return d.SetNewComputed("checksum")
+		}
+		return fmt.Errorf("error opening %q: %s", localPath, err)
+	}
+	defer f.Close()
+	sum, err := guestoperations.Checksum(f)
+	if err != nil {
+		return fmt.Errorf("error reading %q: %s", localPath, err)
+	}
+	if sum == d.Get("checksum").(string) {
+		return nil
+	}
+	log.Printf("[DEBUG] %s: Local and guest file checksums differ", resourceVSphereGuestFileIDString(d))
+	if d.Get("direction").(string) == guestFileDirectionUpload {
+		return d.SetNew("checksum", sum)
+	}
+	return d.SetNewComputed("checksum")
+}
+
+// resourceVSphereGuestFileTransfer transfers the file in the configured
+// direction.
+func resourceVSphereGuestFileTransfer(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	gc, err := guestOperationsClient(d, client, true)
+	if err != nil {
+		return err
+	}
+	localPath := d.Get("local_path").(string)
+	guestPath := d.Get("guest_path").(string)
+
+	if d.Get("direction").(string) == guestFileDirectionUpload {
+		f, err := os.Open(localPath)
+		if err != nil {
+			return fmt.Errorf("error opening %q: %s", localPath, err)
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("error reading %q: %s", localPath, err)
+		}
+		log.Printf("[DEBUG] %s: Uploading %q to guest file %q", resourceVSphereGuestFileIDString(d), localPath, guestPath)
+		return gc.Upload(guestPath, f, fi.Size())
+	}
+
+	log.Printf("[DEBUG] %s: Downloading guest file %q to %q", resourceVSphereGuestFileIDString(d), guestPath, localPath)
+	f, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("error creating %q: %s", localPath, err)
+	}
+	if err := gc.Download(guestPath, f); err != nil {
+		f.Close()
+		return fmt.Errorf("error downloading guest file %q: %s", guestPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing %q: %s", localPath, err)
+	}
+	return nil
+}
+
+// resourceVSphereGuestFileIDString prints a friendly string for the
+// vsphere_guest_file resource.
+func resourceVSphereGuestFileIDString(d structure.ResourceIDStringer) string {
+	return structure.ResourceIDString(d, "vsphere_guest_file")
+}
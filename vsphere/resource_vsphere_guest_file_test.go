@@ -0,0 +1,91 @@
+package vsphere
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccResourceVSphereGuestFile_upload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terraform-test")
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	localPath := filepath.Join(dir, "upload.txt")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereGuestOperationsPreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					if err := ioutil.WriteFile(localPath, []byte("foo\n"), 0644); err != nil {
+						t.Fatalf("bad: %s", err)
+					}
+				},
+				Config: testAccResourceVSphereGuestFileConfig(localPath, "upload"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"vsphere_guest_file.file",
+						"checksum",
+						"b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c",
+					),
+				),
+			},
+			{
+				PreConfig: func() {
+					if err := ioutil.WriteFile(localPath, []byte("bar\n"), 0644); err != nil {
+						t.Fatalf("bad: %s", err)
+					}
+				},
+				Config: testAccResourceVSphereGuestFileConfig(localPath, "upload"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"vsphere_guest_file.file",
+						"checksum",
+						"7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730",
+					),
+				),
+			},
+		},
+	})
+}
+
+func testAccResourceVSphereGuestFileConfig(localPath, direction string) string {
+	return fmt.Sprintf(`
+variable "vm_uuid" {
+  default = "%s"
+}
+
+variable "guest_username" {
+  default = "%s"
+}
+
+variable "guest_password" {
+  default = "%s"
+}
+
+resource "vsphere_guest_file" "file" {
+  virtual_machine_uuid = "${var.vm_uuid}"
+  guest_username       = "${var.guest_username}"
+  guest_password       = "${var.guest_password}"
+  local_path           = "%s"
+  guest_path           = "/tmp/terraform-test.txt"
+  direction            = "%s"
+}
+`,
+		os.Getenv("VSPHERE_GUEST_VM_UUID"),
+		os.Getenv("VSPHERE_GUEST_USERNAME"),
+		os.Getenv("VSPHERE_GUEST_PASSWORD"),
+		localPath,
+		direction,
+	)
+}
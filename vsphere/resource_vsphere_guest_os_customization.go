@@ -0,0 +1,193 @@
+package vsphere
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customizationspec"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/vmworkflow"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// guestOSCustomizationSpecKeyPrefix is the prefix of the spec block of the
+// vsphere_guest_os_customization resource.
+const guestOSCustomizationSpecKeyPrefix = "spec.0"
+
+var guestOSCustomizationTypeAllowedValues = []string{
+	customizationspec.TypeLinux,
+	customizationspec.TypeWindows,
+}
+
+func resourceVSphereGuestOSCustomization() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceVSphereGuestOSCustomizationCreate,
+		Read:          resourceVSphereGuestOSCustomizationRead,
+		Update:        resourceVSphereGuestOSCustomizationUpdate,
+		Delete:        resourceVSphereGuestOSCustomizationDelete,
+		CustomizeDiff: resourceVSphereGuestOSCustomizationCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereGuestOSCustomizationImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the customization spec. This is also the ID of the customization spec.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Description:  "The type of the customization spec. Can be one of Linux or Windows.",
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice(guestOSCustomizationTypeAllowedValues, false),
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Description: "The description of the customization spec.",
+				Optional:    true,
+			},
+			"spec": {
+				Type:        schema.TypeList,
+				Description: "The settings of the customization spec.",
+				Required:    true,
+				MaxItems:    1,
+				Elem:        &schema.Resource{Schema: vmworkflow.CustomizationSpecSchema(guestOSCustomizationSpecKeyPrefix)},
+			},
+		},
+	}
+}
+
+func resourceVSphereGuestOSCustomizationCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	if err := viapi.ValidateVirtualCenter(client); err != nil {
+		return fmt.Errorf("vsphere_guest_os_customization requires vCenter: %s", err)
+	}
+
+	item, err := expandGuestOSCustomizationSpecItem(d, client)
+	if err != nil {
+		return err
+	}
+	if err := customizationspec.Create(client, item); err != nil {
+		return fmt.Errorf("could not create customization spec %q: %s", item.Info.Name, err)
+	}
+	d.SetId(item.Info.Name)
+	return resourceVSphereGuestOSCustomizationRead(d, meta)
+}
+
+func resourceVSphereGuestOSCustomizationRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+
+	item, err := customizationspec.FromName(client, id)
+	if err != nil {
+		if customizationspec.IsNotFoundError(err) {
+			log.Printf("[DEBUG] Customization spec %q not found, removing from state", id)
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("could not locate customization spec %q: %s", id, err)
+	}
+	d.Set("name", item.Info.Name)
+	d.Set("type", item.Info.Type)
+	d.Set("description", item.Info.Description)
+	spec := vmworkflow.FlattenGuestOSCustomizationSpec(d, guestOSCustomizationSpecKeyPrefix, item.Spec)
+	if err := d.Set("spec", []interface{}{spec}); err != nil {
+		return fmt.Errorf("could not set spec data for customization spec: %s", err)
+	}
+	return nil
+}
+
+func resourceVSphereGuestOSCustomizationUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+
+	item, err := expandGuestOSCustomizationSpecItem(d, client)
+	if err != nil {
+		return err
+	}
+	if err := customizationspec.Update(client, item); err != nil {
+		return fmt.Errorf("could not update customization spec %q: %s", item.Info.Name, err)
+	}
+	return resourceVSphereGuestOSCustomizationRead(d, meta)
+}
+
+func resourceVSphereGuestOSCustomizationDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+
+	if err := customizationspec.Delete(client, id); err != nil {
+		return fmt.Errorf("could not delete customization spec %q: %s", id, err)
+	}
+	return nil
+}
+
+func resourceVSphereGuestOSCustomizationCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	family := guestOSCustomizationFamily(d.Get("type").(string))
+	return vmworkflow.ValidateGuestOSCustomizationSpec(d, client, guestOSCustomizationSpecKeyPrefix, family)
+}
+
+func resourceVSphereGuestOSCustomizationImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*VSphereClient).vimClient
+	if err := viapi.ValidateVirtualCenter(client); err != nil {
+		return nil, fmt.Errorf("vsphere_guest_os_customization requires vCenter: %s", err)
+	}
+	if _, err := customizationspec.FromName(client, d.Id()); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// guestOSCustomizationFamily returns the guest OS family for the type of a
+// customization spec.
+func guestOSCustomizationFamily(specType string) string {
+	if specType == customizationspec.TypeWindows {
+		return string(types.VirtualMachineGuestOsFamilyWindowsGuest)
+	}
+	return string(types.VirtualMachineGuestOsFamilyLinuxGuest)
+}
+
+// expandGuestOSCustomizationSpecItem reads the customization spec from
+// ResourceData. The passwords in the spec are encrypted with the encryption
+// key of the customization spec manager, so they are not stored in plain text
+// in vCenter.
+func expandGuestOSCustomizationSpecItem(d *schema.ResourceData, client *govmomi.Client) (types.CustomizationSpecItem, error) {
+	name := d.Get("name").(string)
+	specType := d.Get("type").(string)
+	spec := vmworkflow.ExpandGuestOSCustomizationSpec(d, guestOSCustomizationSpecKeyPrefix, guestOSCustomizationFamily(specType))
+
+	if sysprep, ok := spec.Identity.(*customizationspec.CustomizationSysprep); ok {
+		key, err := customizationspec.EncryptionKey(client)
+		if err != nil {
+			return types.CustomizationSpecItem{}, fmt.Errorf("could not get encryption key for customization spec %q: %s", name, err)
+		}
+		for _, password := range []**types.CustomizationPassword{
+			&sysprep.GuiUnattended.Password,
+			&sysprep.Identification.DomainAdminPassword,
+		} {
+			if *password == nil {
+				continue
+			}
+			encrypted, err := customizationspec.EncryptPassword(key, (*password).Value)
+			if err != nil {
+				return types.CustomizationSpecItem{}, fmt.Errorf("could not encrypt password for customization spec %q: %s", name, err)
+			}
+			*password = encrypted
+		}
+		spec.EncryptionKey = key
+	}
+
+	return types.CustomizationSpecItem{
+		Info: types.CustomizationSpecInfo{
+			Name:        name,
+			Description: d.Get("description").(string),
+			Type:        specType,
+		},
+		Spec: spec,
+	}, nil
+}
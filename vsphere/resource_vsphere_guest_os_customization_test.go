@@ -0,0 +1,164 @@
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customizationspec"
+)
+
+func TestAccResourceVSphereGuestOSCustomization_linux(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereGuestOSCustomizationExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereGuestOSCustomizationConfigLinux("test.internal"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereGuestOSCustomizationExists(true),
+					testAccResourceVSphereGuestOSCustomizationHasLinuxDomain("test.internal"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereGuestOSCustomizationConfigLinux("example.internal"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereGuestOSCustomizationExists(true),
+					testAccResourceVSphereGuestOSCustomizationHasLinuxDomain("example.internal"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereGuestOSCustomization_windows(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereGuestOSCustomizationExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereGuestOSCustomizationConfigWindows(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereGuestOSCustomizationExists(true),
+					testAccResourceVSphereGuestOSCustomizationHasEncryptedPassword(),
+				),
+			},
+			{
+				ResourceName:      "vsphere_guest_os_customization.spec",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{
+					"spec.0.windows_options.0.admin_password",
+				},
+			},
+		},
+	})
+}
+
+func testAccResourceVSphereGuestOSCustomizationExists(expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		_, err := testGetGuestOSCustomization(s, "spec")
+		if err != nil {
+			if customizationspec.IsNotFoundError(err) && !expected {
+				// Expected missing
+				return nil
+			}
+			return err
+		}
+		if !expected {
+			return errors.New("expected customization spec to be missing")
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereGuestOSCustomizationHasLinuxDomain(expected string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		item, err := testGetGuestOSCustomization(s, "spec")
+		if err != nil {
+			return err
+		}
+		identity, ok := item.Spec.Identity.(*customizationspec.CustomizationLinuxPrep)
+		if !ok {
+			return fmt.Errorf("expected Linux identity settings, got %T", item.Spec.Identity)
+		}
+		if identity.Domain != expected {
+			return fmt.Errorf("expected domain to be %q, got %q", expected, identity.Domain)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereGuestOSCustomizationHasEncryptedPassword() resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		item, err := testGetGuestOSCustomization(s, "spec")
+		if err != nil {
+			return err
+		}
+		identity, ok := item.Spec.Identity.(*customizationspec.CustomizationSysprep)
+		if !ok {
+			return fmt.Errorf("expected Windows identity settings, got %T", item.Spec.Identity)
+		}
+		if identity.GuiUnattended.Password == nil || identity.GuiUnattended.Password.PlainText {
+			return errors.New("expected admin password to be encrypted")
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereGuestOSCustomizationConfigLinux(domain string) string {
+	return fmt.Sprintf(`
+resource "vsphere_guest_os_customization" "spec" {
+  name        = "terraform-test-linux"
+  type        = "Linux"
+  description = "Managed by Terraform"
+
+  spec {
+    linux_options {
+      host_name = "terraform-test"
+      domain    = "%s"
+    }
+
+    network_interface {
+      ipv4_address = "10.0.0.10"
+      ipv4_netmask = 24
+    }
+
+    ipv4_gateway    = "10.0.0.1"
+    dns_server_list = ["10.0.0.2"]
+    dns_suffix_list = ["%s"]
+  }
+}
+`,
+		domain,
+		domain,
+	)
+}
+
+func testAccResourceVSphereGuestOSCustomizationConfigWindows() string {
+	return fmt.Sprintf(`
+resource "vsphere_guest_os_customization" "spec" {
+  name = "terraform-test-windows"
+  type = "Windows"
+
+  spec {
+    windows_options {
+      computer_name  = "terraform-test"
+      workgroup      = "test"
+      admin_password = "VMw4re!"
+    }
+
+    network_interface {}
+  }
+}
+`,
+	)
+}
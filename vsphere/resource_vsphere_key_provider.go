@@ -0,0 +1,291 @@
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/cryptomanager"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// keyProviderDefaultKmipPort is the default port of KMIP servers.
+const keyProviderDefaultKmipPort = 5696
+
+func resourceVSphereKeyProvider() *schema.Resource {
+	return &schema.Resource{
+		Create:        resourceVSphereKeyProviderCreate,
+		Read:          resourceVSphereKeyProviderRead,
+		Update:        resourceVSphereKeyProviderUpdate,
+		Delete:        resourceVSphereKeyProviderDelete,
+		CustomizeDiff: resourceVSphereKeyProviderCustomizeDiff,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereKeyProviderImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the key provider. This is also the ID of the key provider.",
+				Required:    true,
+				ForceNew:    true,
+			},
+			"use_as_default": {
+				Type:        schema.TypeBool,
+				Description: "Make this the default key provider. The first key provider that is added to vCenter becomes the default.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"server": {
+				Type:        schema.TypeList,
+				Description: "The KMIP servers of the key provider.",
+				Required:    true,
+				MinItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Description: "The name of the KMIP server.",
+							Required:    true,
+						},
+						"address": {
+							Type:        schema.TypeString,
+							Description: "The address of the KMIP server.",
+							Required:    true,
+						},
+						"port": {
+							Type:         schema.TypeInt,
+							Description:  "The port of the KMIP server.",
+							Optional:     true,
+							Default:      keyProviderDefaultKmipPort,
+							ValidateFunc: validation.IntBetween(1, 65535),
+						},
+						"proxy_address": {
+							Type:        schema.TypeString,
+							Description: "The address of a proxy server to connect to the KMIP server through.",
+							Optional:    true,
+						},
+						"proxy_port": {
+							Type:         schema.TypeInt,
+							Description:  "The port of the proxy server.",
+							Optional:     true,
+							ValidateFunc: validation.IntBetween(0, 65535),
+						},
+						"username": {
+							Type:        schema.TypeString,
+							Description: "The username to authenticate to the KMIP server with.",
+							Optional:    true,
+						},
+						"password": {
+							Type:        schema.TypeString,
+							Description: "The password to authenticate to the KMIP server with.",
+							Optional:    true,
+							Sensitive:   true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceVSphereKeyProviderCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	if err := viapi.ValidateVirtualCenter(client); err != nil {
+		return fmt.Errorf("vsphere_key_provider requires vCenter: %s", err)
+	}
+
+	id := d.Get("name").(string)
+	for _, v := range d.Get("server").([]interface{}) {
+		spec := expandKmipServerSpec(id, v.(map[string]interface{}))
+		if err := cryptomanager.RegisterKmipServer(client, spec); err != nil {
+			return fmt.Errorf("could not register KMIP server %q with key provider %q: %s", spec.Info.Name, id, err)
+		}
+		// Save the ID once the key provider exists, so that a failure to register
+		// any of the other servers does not leave it behind.
+		d.SetId(id)
+	}
+	if d.Get("use_as_default").(bool) {
+		if err := cryptomanager.MarkDefault(client, id); err != nil {
+			return fmt.Errorf("could not mark key provider %q as default: %s", id, err)
+		}
+	}
+	return resourceVSphereKeyProviderRead(d, meta)
+}
+
+func resourceVSphereKeyProviderRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+
+	info, err := cryptomanager.KeyProviderFromID(client, id)
+	if err != nil {
+		if cryptomanager.IsNotFoundError(err) {
+			log.Printf("[DEBUG] Key provider %q not found, removing from state", id)
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("could not locate key provider %q: %s", id, err)
+	}
+	d.Set("name", info.ClusterId.Id)
+	d.Set("use_as_default", info.UseAsDefault)
+	if err := d.Set("server", flattenKmipServerInfo(d, info.Servers)); err != nil {
+		return fmt.Errorf("could not set server data for key provider: %s", err)
+	}
+	return nil
+}
+
+func resourceVSphereKeyProviderUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+
+	if d.HasChange("server") {
+		o, n := d.GetChange("server")
+		oldServers := make(map[string]map[string]interface{})
+		for _, v := range o.([]interface{}) {
+			m := v.(map[string]interface{})
+			oldServers[m["name"].(string)] = m
+		}
+		// Add and update servers before removing any, as removing the last server
+		// of a key provider removes the key provider.
+		for _, v := range n.([]interface{}) {
+			m := v.(map[string]interface{})
+			spec := expandKmipServerSpec(id, m)
+			old, ok := oldServers[spec.Info.Name]
+			delete(oldServers, spec.Info.Name)
+			switch {
+			case !ok:
+				if err := cryptomanager.RegisterKmipServer(client, spec); err != nil {
+					return fmt.Errorf("could not register KMIP server %q with key provider %q: %s", spec.Info.Name, id, err)
+				}
+			case !kmipServerEqual(old, m):
+				if err := cryptomanager.UpdateKmipServer(client, spec); err != nil {
+					return fmt.Errorf("could not update KMIP server %q in key provider %q: %s", spec.Info.Name, id, err)
+				}
+			}
+		}
+		for name := range oldServers {
+			if err := cryptomanager.RemoveKmipServer(client, id, name); err != nil {
+				return fmt.Errorf("could not remove KMIP server %q from key provider %q: %s", name, id, err)
+			}
+		}
+	}
+	if d.HasChange("use_as_default") && d.Get("use_as_default").(bool) {
+		if err := cryptomanager.MarkDefault(client, id); err != nil {
+			return fmt.Errorf("could not mark key provider %q as default: %s", id, err)
+		}
+	}
+	return resourceVSphereKeyProviderRead(d, meta)
+}
+
+func resourceVSphereKeyProviderDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*VSphereClient).vimClient
+	id := d.Id()
+
+	info, err := cryptomanager.KeyProviderFromID(client, id)
+	if err != nil {
+		return fmt.Errorf("could not locate key provider %q: %s", id, err)
+	}
+	// The key provider is removed along with its last server.
+	for _, server := range info.Servers {
+		if err := cryptomanager.RemoveKmipServer(client, id, server.Name); err != nil {
+			return fmt.Errorf("could not remove KMIP server %q from key provider %q: %s", server.Name, id, err)
+		}
+	}
+	return nil
+}
+
+func resourceVSphereKeyProviderCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	// vCenter always has a default key provider, so a key provider stops being
+	// the default only when another one is marked as default.
+	if o, n := d.GetChange("use_as_default"); o.(bool) && !n.(bool) {
+		return errors.New("use_as_default cannot be changed from true to false - mark another key provider as default instead")
+	}
+	names := make(map[string]struct{})
+	for _, v := range d.Get("server").([]interface{}) {
+		name := v.(map[string]interface{})["name"].(string)
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("duplicate KMIP server name %q", name)
+		}
+		names[name] = struct{}{}
+	}
+	return nil
+}
+
+func resourceVSphereKeyProviderImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*VSphereClient).vimClient
+	if err := viapi.ValidateVirtualCenter(client); err != nil {
+		return nil, fmt.Errorf("vsphere_key_provider requires vCenter: %s", err)
+	}
+	if _, err := cryptomanager.KeyProviderFromID(client, d.Id()); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// expandKmipServerSpec reads a KMIP server of the key provider with the
+// supplied ID from server data.
+func expandKmipServerSpec(id string, m map[string]interface{}) types.KmipServerSpec {
+	return types.KmipServerSpec{
+		ClusterId: types.KeyProviderId{Id: id},
+		Info: types.KmipServerInfo{
+			Name:         m["name"].(string),
+			Address:      m["address"].(string),
+			Port:         int32(m["port"].(int)),
+			ProxyAddress: m["proxy_address"].(string),
+			ProxyPort:    int32(m["proxy_port"].(int)),
+			UserName:     m["username"].(string),
+		},
+		Password: m["password"].(string),
+	}
+}
+
+// flattenKmipServerInfo returns the server data for the KMIP servers of a key
+// provider. Servers that are in state keep their order and password, which
+// is not returned by vCenter, and any other servers are added to the end of
+// the list.
+func flattenKmipServerInfo(d *schema.ResourceData, servers []types.KmipServerInfo) []interface{} {
+	byName := make(map[string]types.KmipServerInfo)
+	for _, server := range servers {
+		byName[server.Name] = server
+	}
+	flatten := func(server types.KmipServerInfo, password string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":          server.Name,
+			"address":       server.Address,
+			"port":          int(server.Port),
+			"proxy_address": server.ProxyAddress,
+			"proxy_port":    int(server.ProxyPort),
+			"username":      server.UserName,
+			"password":      password,
+		}
+	}
+
+	var result []interface{}
+	for _, v := range d.Get("server").([]interface{}) {
+		m := v.(map[string]interface{})
+		if server, ok := byName[m["name"].(string)]; ok {
+			result = append(result, flatten(server, m["password"].(string)))
+			delete(byName, server.Name)
+		}
+	}
+	for _, server := range servers {
+		if _, ok := byName[server.Name]; ok {
+			result = append(result, flatten(server, ""))
+		}
+	}
+	return result
+}
+
+// kmipServerEqual returns true if the supplied server data describe the same
+// KMIP server settings.
+func kmipServerEqual(a, b map[string]interface{}) bool {
+	for _, k := range []string{"address", "port", "proxy_address", "proxy_port", "username", "password"} {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
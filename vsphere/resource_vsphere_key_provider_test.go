@@ -0,0 +1,146 @@
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/cryptomanager"
+)
+
+func TestAccResourceVSphereKeyProvider_basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereKeyProviderPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereKeyProviderExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereKeyProviderConfig(5696),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereKeyProviderExists(true),
+					testAccResourceVSphereKeyProviderHasServerPort(5696),
+					resource.TestCheckResourceAttr("vsphere_key_provider.provider", "use_as_default", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereKeyProvider_updatePort(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereKeyProviderPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereKeyProviderExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereKeyProviderConfig(5696),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereKeyProviderExists(true),
+					testAccResourceVSphereKeyProviderHasServerPort(5696),
+				),
+			},
+			{
+				Config: testAccResourceVSphereKeyProviderConfig(5697),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereKeyProviderExists(true),
+					testAccResourceVSphereKeyProviderHasServerPort(5697),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereKeyProvider_import(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereKeyProviderPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereKeyProviderExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereKeyProviderConfig(5696),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereKeyProviderExists(true),
+				),
+			},
+			{
+				ResourceName:            "vsphere_key_provider.provider",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"server.0.password"},
+			},
+		},
+	})
+}
+
+func testAccResourceVSphereKeyProviderPreCheck(t *testing.T) {
+	if os.Getenv("VSPHERE_KMS_ADDRESS") == "" {
+		t.Skip("set VSPHERE_KMS_ADDRESS to run vsphere_key_provider acceptance tests")
+	}
+}
+
+func testAccResourceVSphereKeyProviderExists(expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		_, err := testGetKeyProvider(s, "provider")
+		if err != nil {
+			if cryptomanager.IsNotFoundError(err) && !expected {
+				// Expected missing
+				return nil
+			}
+			return err
+		}
+		if !expected {
+			return errors.New("expected key provider to be missing")
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereKeyProviderHasServerPort(port int32) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		info, err := testGetKeyProvider(s, "provider")
+		if err != nil {
+			return err
+		}
+		if len(info.Servers) != 1 {
+			return fmt.Errorf("expected 1 KMIP server, got %d", len(info.Servers))
+		}
+		if info.Servers[0].Port != port {
+			return fmt.Errorf("expected KMIP server port to be %d, got %d", port, info.Servers[0].Port)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereKeyProviderConfig(port int) string {
+	return fmt.Sprintf(`
+variable "kms_address" {
+  default = "%s"
+}
+
+resource "vsphere_key_provider" "provider" {
+  name           = "terraform-test-kms"
+  use_as_default = true
+
+  server {
+    name    = "terraform-test-kms-server"
+    address = "${var.kms_address}"
+    port    = %d
+  }
+}
+`,
+		os.Getenv("VSPHERE_KMS_ADDRESS"),
+		port,
+	)
+}
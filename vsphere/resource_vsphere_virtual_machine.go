@@ -1,18 +1,31 @@
 package vsphere
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/cloudinit"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/contentlibrary"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customattribute"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/customizationspec"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/datastore"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/folder"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/hostsystem"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/ovfdeploy"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/resourcepool"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/storagepod"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
@@ -21,9 +34,23 @@ import (
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/vmworkflow"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
+// The values for the power_state attribute.
+const (
+	virtualMachinePowerStateOn        = "on"
+	virtualMachinePowerStateOff       = "off"
+	virtualMachinePowerStateSuspended = "suspended"
+)
+
+var virtualMachinePowerStateAllowedValues = []string{
+	virtualMachinePowerStateOn,
+	virtualMachinePowerStateOff,
+	virtualMachinePowerStateSuspended,
+}
+
 // formatVirtualMachinePostCloneRollbackError defines the verbose error when
 // rollback fails on a post-clone virtual machine operation.
 const formatVirtualMachinePostCloneRollbackError = `
@@ -58,6 +85,18 @@ again. For more information on how to do this, see the following page:
 https://www.terraform.io/docs/commands/taint.html
 `
 
+// formatVirtualMachineCustomizationReapplyWaitError defines the error that is
+// sent when the customization waiter returns an error while customization is
+// re-applied to an existing virtual machine.
+const formatVirtualMachineCustomizationReapplyWaitError = `
+Virtual machine customization failed on %q:
+
+%s
+
+The previous customization settings have been kept in state, so the
+customization will be attempted again on the next apply.
+`
+
 func resourceVSphereVirtualMachine() *schema.Resource {
 	s := map[string]*schema.Schema{
 		"resource_pool_id": {
@@ -78,6 +117,19 @@ func resourceVSphereVirtualMachine() *schema.Resource {
 			ConflictsWith: []string{"datastore_id"},
 			Description:   "The ID of a datastore cluster to put the virtual machine in.",
 		},
+		"storage_policy_id": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Computed:    true,
+			Description: "The ID of the storage policy to assign to the virtual machine home directory.",
+		},
+		"encryption": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Description: "Encrypt the virtual machine home directory with a key from a key provider.",
+			Elem:        &schema.Resource{Schema: virtualdevice.EncryptionSchema()},
+		},
 		"folder": {
 			Type:        schema.TypeString,
 			Optional:    true,
@@ -122,6 +174,13 @@ func resourceVSphereVirtualMachine() *schema.Resource {
 			Default:     true,
 			Description: "Set to true to force power-off a virtual machine if a graceful guest shutdown failed for a necessary operation.",
 		},
+		"power_state": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      virtualMachinePowerStateOn,
+			Description:  "The power state of the virtual machine. Can be one of on, off, or suspended.",
+			ValidateFunc: validation.StringInSlice(virtualMachinePowerStateAllowedValues, false),
+		},
 		"scsi_controller_count": {
 			Type:         schema.TypeInt,
 			Optional:     true,
@@ -136,6 +195,13 @@ func resourceVSphereVirtualMachine() *schema.Resource {
 			Description:  "The type of SCSI bus this virtual machine will have. Can be one of lsilogic, lsilogic-sas or pvscsi.",
 			ValidateFunc: validation.StringInSlice(virtualdevice.SCSIBusTypeAllowedValues, false),
 		},
+		"scsi_controller": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    4,
+			Description: "The type and bus sharing mode of individual SCSI controllers, overriding scsi_type.",
+			Elem:        &schema.Resource{Schema: virtualdevice.SCSIControllerSchema()},
+		},
 		// NOTE: disk is only optional so that we can flag it as computed and use
 		// it in ResourceDiff. We validate this field in ResourceDiff to enforce it
 		// having a minimum count of 1 for now - but may support diskless VMs
@@ -159,15 +225,78 @@ func resourceVSphereVirtualMachine() *schema.Resource {
 			Type:        schema.TypeList,
 			Optional:    true,
 			Description: "A specification for a CDROM device on this virtual machine.",
-			MaxItems:    1,
+			MaxItems:    4,
 			Elem:        &schema.Resource{Schema: virtualdevice.CdromSubresourceSchema()},
 		},
+		"serial_port": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "A specification for a serial port on this virtual machine.",
+			MaxItems:    32,
+			Elem:        &schema.Resource{Schema: virtualdevice.SerialPortSubresourceSchema()},
+		},
+		"parallel_port": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "A specification for a parallel port on this virtual machine.",
+			MaxItems:    3,
+			Elem:        &schema.Resource{Schema: virtualdevice.ParallelPortSubresourceSchema()},
+		},
+		"usb_controller": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "A specification for a USB controller on this virtual machine.",
+			MaxItems:    2,
+			Elem:        &schema.Resource{Schema: virtualdevice.USBControllerSchema()},
+		},
+		"pci_device": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "A specification for a PCI passthrough device on this virtual machine.",
+			MaxItems:    16,
+			Elem:        &schema.Resource{Schema: virtualdevice.PCIDeviceSchema()},
+		},
+		"video_card": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Computed:    true,
+			Description: "The settings of the video card of this virtual machine.",
+			MaxItems:    1,
+			Elem:        &schema.Resource{Schema: virtualdevice.VideoCardSchema()},
+		},
+		"vtpm": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Add a virtual TPM 2.0 device to the virtual machine. Requires EFI firmware and encryption.",
+		},
 		"clone": {
+			Type:          schema.TypeList,
+			Optional:      true,
+			Description:   "A specification for cloning a virtual machine from template.",
+			MaxItems:      1,
+			ConflictsWith: []string{"ovf_deploy"},
+			Elem:          &schema.Resource{Schema: vmworkflow.VirtualMachineCloneSchema()},
+		},
+		"clone_snapshot_id": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "The managed object ID of the snapshot of the source that this virtual machine was cloned from, if any.",
+		},
+		"ovf_deploy": {
+			Type:          schema.TypeList,
+			Optional:      true,
+			Description:   "A specification for deploying a virtual machine from an OVF or OVA file.",
+			MaxItems:      1,
+			ConflictsWith: []string{"clone"},
+			Elem:          &schema.Resource{Schema: vmworkflow.VirtualMachineOvfDeploySchema()},
+		},
+		"cloud_init": {
 			Type:        schema.TypeList,
 			Optional:    true,
-			Description: "A specification for cloning a virtual machine from template.",
+			Description: "A specification for delivering cloud-init data to the virtual machine.",
 			MaxItems:    1,
-			Elem:        &schema.Resource{Schema: vmworkflow.VirtualMachineCloneSchema()},
+			Elem:        &schema.Resource{Schema: vmworkflow.VirtualMachineCloudInitSchema()},
 		},
 		"reboot_required": {
 			Type:        schema.TypeBool,
@@ -217,7 +346,7 @@ func resourceVSphereVirtualMachine() *schema.Resource {
 
 func resourceVSphereVirtualMachineCreate(d *schema.ResourceData, meta interface{}) error {
 	log.Printf("[DEBUG] %s: Beginning create", resourceVSphereVirtualMachineIDString(d))
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	tagsClient, err := tagsClientIfDefined(d, meta)
 	if err != nil {
 		return err
@@ -232,10 +361,12 @@ func resourceVSphereVirtualMachineCreate(d *schema.ResourceData, meta interface{
 	// This is where we process our various VM deploy workflows. We expect the ID
 	// of the resource to be set in the workflow to ensure that any post-create
 	// operations that fail during this process don't create a dangling resource.
-	// The VM should also be returned powered on.
+	// The VM should also be returned powered on, unless power_state is off.
 	switch {
 	case len(d.Get("clone").([]interface{})) > 0:
 		vm, err = resourceVSphereVirtualMachineCreateClone(d, meta)
+	case len(d.Get("ovf_deploy").([]interface{})) > 0:
+		vm, err = resourceVSphereVirtualMachineCreateOvf(d, meta)
 	default:
 		vm, err = resourceVSphereVirtualMachineCreateBare(d, meta)
 	}
@@ -258,17 +389,20 @@ func resourceVSphereVirtualMachineCreate(d *schema.ResourceData, meta interface{
 		}
 	}
 
-	// Wait for a routable address if we have been set to wait for one
-	err = virtualmachine.WaitForGuestNet(
-		client,
-		vm,
-		d.Get("wait_for_guest_net_routable").(bool),
-		d.Get("wait_for_guest_net_timeout").(int),
-	)
-	if err != nil {
+	// Bring the VM to its configured power state. The deploy workflows leave it
+	// powered on unless it's meant to be powered off.
+	if err := resourceVSphereVirtualMachineApplyPowerState(d, meta, vm); err != nil {
 		return err
 	}
 
+	// Wait for a routable address and any other guest conditions if we have
+	// been set to wait for them
+	if d.Get("power_state").(string) == virtualMachinePowerStateOn {
+		if err := resourceVSphereVirtualMachineWaitForGuest(d, client, vm); err != nil {
+			return err
+		}
+	}
+
 	// All done!
 	log.Printf("[DEBUG] %s: Create complete", resourceVSphereVirtualMachineIDString(d))
 	return resourceVSphereVirtualMachineRead(d, meta)
@@ -276,7 +410,7 @@ func resourceVSphereVirtualMachineCreate(d *schema.ResourceData, meta interface{
 
 func resourceVSphereVirtualMachineRead(d *schema.ResourceData, meta interface{}) error {
 	log.Printf("[DEBUG] %s: Reading state of virtual machine", resourceVSphereVirtualMachineIDString(d))
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	id := d.Id()
 	vm, err := virtualmachine.FromUUID(client, id)
 	if err != nil {
@@ -301,6 +435,8 @@ func resourceVSphereVirtualMachineRead(d *schema.ResourceData, meta interface{})
 	// Reset reboot_required. This is an update only variable and should not be
 	// set across TF runs.
 	d.Set("reboot_required", false)
+	// Read the power state.
+	d.Set("power_state", flattenVirtualMachinePowerState(vprops.Runtime.PowerState))
 	// Check to see if VMware tools is running.
 	if vprops.Guest != nil {
 		d.Set("vmware_tools_status", vprops.Guest.ToolsRunningStatus)
@@ -355,11 +491,25 @@ func resourceVSphereVirtualMachineRead(d *schema.ResourceData, meta interface{})
 	if err := flattenVirtualMachineConfigInfo(d, vprops.Config); err != nil {
 		return fmt.Errorf("error reading virtual machine configuration: %s", err)
 	}
+	// The IOMMU and virtualization based security flags are missing from the
+	// config info of the vSphere API bindings, and are read separately on
+	// vSphere 6.7 and higher.
+	if version := viapi.ParseVersionFromClient(client); !version.Older(viapi.VSphereVersion{Product: version.Product, Major: 6, Minor: 7}) {
+		flags, err := virtualmachine.Flags(client, vm)
+		if err != nil {
+			return fmt.Errorf("error reading virtual machine flags: %s", err)
+		}
+		if err := flattenVirtualMachineSecurityFlags(d, flags); err != nil {
+			return fmt.Errorf("error reading virtual machine flags: %s", err)
+		}
+	}
 
 	// Perform pending device read operations.
 	devices := object.VirtualDeviceList(vprops.Config.Hardware.Device)
 	// Read the state of the SCSI bus.
-	d.Set("scsi_type", virtualdevice.ReadSCSIBusState(devices, d.Get("scsi_controller_count").(int)))
+	if err := virtualdevice.SCSIBusRefreshOperation(d, devices); err != nil {
+		return err
+	}
 	// Disks first
 	if err := virtualdevice.DiskRefreshOperation(d, client, devices); err != nil {
 		return err
@@ -372,6 +522,41 @@ func resourceVSphereVirtualMachineRead(d *schema.ResourceData, meta interface{})
 	if err := virtualdevice.CdromRefreshOperation(d, client, devices); err != nil {
 		return err
 	}
+	// Serial ports
+	if err := virtualdevice.SerialPortRefreshOperation(d, client, devices); err != nil {
+		return err
+	}
+	// Parallel ports
+	if err := virtualdevice.ParallelPortRefreshOperation(d, client, devices); err != nil {
+		return err
+	}
+	// USB controllers
+	if err := virtualdevice.USBControllerRefreshOperation(d, devices); err != nil {
+		return err
+	}
+	// PCI passthrough devices
+	if err := virtualdevice.PCIDeviceRefreshOperation(d, devices); err != nil {
+		return err
+	}
+	// Virtual TPM
+	if err := virtualdevice.VTPMRefreshOperation(d, devices); err != nil {
+		return err
+	}
+	// Video card
+	if err := virtualdevice.VideoCardRefreshOperation(d, devices); err != nil {
+		return err
+	}
+	// Boot order, which references the disks and network interfaces read above
+	if vprops.Config.BootOptions != nil {
+		if err := virtualdevice.FlattenBootOrder(d, vprops.Config.BootOptions.BootOrder); err != nil {
+			return err
+		}
+	}
+
+	// Read storage policies, which are only available on vCenter
+	if err := viapi.ValidateVirtualCenter(client); err == nil {
+		readVirtualMachineStoragePolicies(d, meta, vm)
+	}
 
 	// Read tags if we have the ability to do so
 	if tagsClient, _ := meta.(*VSphereClient).TagsClient(); tagsClient != nil {
@@ -400,7 +585,7 @@ func resourceVSphereVirtualMachineRead(d *schema.ResourceData, meta interface{})
 
 func resourceVSphereVirtualMachineUpdate(d *schema.ResourceData, meta interface{}) error {
 	log.Printf("[DEBUG] %s: Performing update", resourceVSphereVirtualMachineIDString(d))
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	tagsClient, err := tagsClientIfDefined(d, meta)
 	if err != nil {
 		return err
@@ -452,13 +637,25 @@ func resourceVSphereVirtualMachineUpdate(d *schema.ResourceData, meta interface{
 	if err != nil {
 		return fmt.Errorf("error in virtual machine configuration: %s", err)
 	}
+	if spec.Crypto, err = expandVirtualMachineCryptoSpec(d, client, vprops.Config.KeyId); err != nil {
+		return fmt.Errorf("error in virtual machine encryption settings: %s", err)
+	}
+	flags := expandVirtualMachineSecurityFlags(d)
 
 	devices := object.VirtualDeviceList(vprops.Config.Hardware.Device)
-	if spec.DeviceChange, err = applyVirtualDevices(d, client, devices); err != nil {
+	lc, err := cdromContentLibraryClient(d.Get("cdrom").([]interface{}), meta)
+	if err != nil {
+		return err
+	}
+	if spec.DeviceChange, err = applyVirtualDevices(d, client, lc, devices); err != nil {
 		return err
 	}
+	// The boot order references disks and network interfaces by device key, so
+	// it's applied again when those change.
+	bootOrderChanged := d.HasChange("boot_order") || (len(d.Get("boot_order").([]interface{})) > 0 && (d.HasChange("disk") || d.HasChange("network_interface")))
 	// Only carry out the reconfigure if we actually have a change to process.
-	if changed || len(spec.DeviceChange) > 0 {
+	specChanged := changed || spec.Crypto != nil || len(spec.DeviceChange) > 0
+	if specChanged || flags != nil {
 		//Check to see if we need to shutdown the VM for this process.
 		if d.Get("reboot_required").(bool) && vprops.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOff {
 			// Attempt a graceful shutdown of this process. We wrap this in a VM helper.
@@ -469,13 +666,28 @@ func resourceVSphereVirtualMachineUpdate(d *schema.ResourceData, meta interface{
 			}
 		}
 		// Perform updates.
-		if _, ok := d.GetOk("datastore_cluster_id"); ok {
-			err = resourceVSphereVirtualMachineUpdateReconfigureWithSDRS(d, meta, vm, spec)
-		} else {
-			err = virtualmachine.Reconfigure(vm, spec)
+		if specChanged {
+			if _, ok := d.GetOk("datastore_cluster_id"); ok {
+				err = resourceVSphereVirtualMachineUpdateReconfigureWithSDRS(d, meta, vm, spec)
+			} else {
+				err = virtualmachine.Reconfigure(vm, spec)
+			}
+			if err != nil {
+				return fmt.Errorf("error reconfiguring virtual machine: %s", err)
+			}
 		}
-		if err != nil {
-			return fmt.Errorf("error reconfiguring virtual machine: %s", err)
+		if flags != nil {
+			if err := virtualmachine.ReconfigureFlags(client, vm, *flags); err != nil {
+				return fmt.Errorf("error reconfiguring virtual machine flags: %s", err)
+			}
+		}
+		// Apply the boot order before the VM is powered back on, as the device
+		// changes may have replaced devices that it references.
+		if bootOrderChanged {
+			if err := resourceVSphereVirtualMachineApplyBootOrder(d, meta); err != nil {
+				return err
+			}
+			bootOrderChanged = false
 		}
 		// Re-fetch properties
 		vprops, err = virtualmachine.Properties(vm)
@@ -483,25 +695,48 @@ func resourceVSphereVirtualMachineUpdate(d *schema.ResourceData, meta interface{
 			return fmt.Errorf("error re-fetching VM properties after update: %s", err)
 		}
 		// Power back on the VM, and wait for network if necessary.
-		if vprops.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+		if vprops.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn && d.Get("power_state").(string) == virtualMachinePowerStateOn {
 			if err := virtualmachine.PowerOn(vm); err != nil {
 				return fmt.Errorf("error powering on virtual machine: %s", err)
 			}
-			err = virtualmachine.WaitForGuestNet(
-				client,
-				vm,
-				d.Get("wait_for_guest_net_routable").(bool),
-				d.Get("wait_for_guest_net_timeout").(int),
-			)
-			if err != nil {
+			if err := resourceVSphereVirtualMachineWaitForGuest(d, client, vm); err != nil {
 				return err
 			}
 		}
 	}
+	if bootOrderChanged {
+		if err := resourceVSphereVirtualMachineApplyBootOrder(d, meta); err != nil {
+			return err
+		}
+	}
+	// Customize the virtual machine again if the customization settings have
+	// changed and reapply_on_change is set. This is done in partial mode so
+	// that the old settings stay in state if the customization fails.
+	if d.Get("clone.0.customize.0.reapply_on_change").(bool) && vmworkflow.CustomizationSpecChanged(d) {
+		if err := resourceVSphereVirtualMachineReapplyCustomization(d, meta, vm); err != nil {
+			return err
+		}
+	}
 	// Now safe to turn off partial mode.
 	d.Partial(false)
 	d.Set("reboot_required", false)
 
+	// Reconcile the power state. This corrects any out-of-band power operations,
+	// which show up as a diff on power_state, and also brings the VM back to a
+	// suspended state after a reconfigure that required a shutdown.
+	if err := resourceVSphereVirtualMachineApplyPowerState(d, meta, vm); err != nil {
+		return err
+	}
+
+	// cloud-init data is applied separately, as it depends on the state of the
+	// network interfaces after the reconfigure. A change to the cdrom
+	// sub-resources can also replace the seed ISO with a client device.
+	if d.HasChange("cloud_init") || d.HasChange("cdrom") || (d.Get("cloud_init.0.generate_network_config").(bool) && d.HasChange("network_interface")) {
+		if err := resourceVSphereVirtualMachineApplyCloudInit(d, meta); err != nil {
+			return err
+		}
+	}
+
 	// Now that any pending changes have been done (namely, any disks that don't
 	// need to be migrated have been deleted), proceed with vMotion if we have
 	// one pending.
@@ -531,7 +766,7 @@ func resourceVSphereVirtualMachineUpdateReconfigureWithSDRS(
 		return virtualmachine.Reconfigure(vm, spec)
 	}
 
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	if err := viapi.ValidateVirtualCenter(client); err != nil {
 		return fmt.Errorf("connection ineligible to use datastore_cluster_id: %s", err)
 	}
@@ -551,7 +786,7 @@ func resourceVSphereVirtualMachineUpdateReconfigureWithSDRS(
 
 func resourceVSphereVirtualMachineDelete(d *schema.ResourceData, meta interface{}) error {
 	log.Printf("[DEBUG] %s: Performing delete", resourceVSphereVirtualMachineIDString(d))
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	id := d.Id()
 	vm, err := virtualmachine.FromUUID(client, id)
 	if err != nil {
@@ -604,9 +839,63 @@ func resourceVSphereVirtualMachineCustomizeDiff(d *schema.ResourceDiff, meta int
 			return fmt.Errorf("efi_secure_boot_enabled is only supported on vSphere 6.5 and higher")
 		}
 	}
+	// Storage policies are managed through vCenter.
+	if d.HasChange("storage_policy_id") && d.Get("storage_policy_id").(string) != "" {
+		if err := viapi.ValidateVirtualCenter(client); err != nil {
+			return errors.New("storage_policy_id requires vCenter")
+		}
+	}
+	// Encryption keys are managed through the vCenter crypto manager, and a
+	// virtual TPM stores its secrets in the encrypted virtual machine home.
+	encrypted := len(d.Get("encryption").([]interface{})) > 0
+	if encrypted {
+		if err := viapi.ValidateVirtualCenter(client); err != nil {
+			return errors.New("encryption requires vCenter")
+		}
+		if version.Older(viapi.VSphereVersion{Product: version.Product, Major: 6, Minor: 5}) {
+			return fmt.Errorf("encryption is only supported on vSphere 6.5 and higher")
+		}
+	}
+	if d.Get("vtpm").(bool) {
+		switch {
+		case version.Older(viapi.VSphereVersion{Product: version.Product, Major: 6, Minor: 7}):
+			return fmt.Errorf("vtpm is only supported on vSphere 6.7 and higher")
+		case d.Get("firmware").(string) != string(types.GuestOsDescriptorFirmwareTypeEfi):
+			return fmt.Errorf("vtpm requires firmware to be set to efi")
+		case !encrypted:
+			return fmt.Errorf("vtpm requires encryption to be set")
+		}
+	}
+
+	// Validate SCSI controller sub-resources
+	if err := virtualdevice.SCSIControllerDiffOperation(d); err != nil {
+		return err
+	}
 
 	// Validate cdrom sub-resources
-	if err := virtualdevice.CdromDiffOperation(d, client); err != nil {
+	lc, err := cdromContentLibraryClient(d.Get("cdrom").([]interface{}), meta)
+	if err != nil {
+		return err
+	}
+	if err := virtualdevice.CdromDiffOperation(d, client, lc); err != nil {
+		return err
+	}
+
+	// Validate serial port sub-resources
+	if err := virtualdevice.SerialPortDiffOperation(d, client); err != nil {
+		return err
+	}
+
+	// Validate parallel port sub-resources
+	if err := virtualdevice.ParallelPortDiffOperation(d, client); err != nil {
+		return err
+	}
+
+	// Validate USB controller and PCI passthrough sub-resources
+	if err := virtualdevice.USBControllerDiffOperation(d); err != nil {
+		return err
+	}
+	if err := virtualdevice.PCIDeviceDiffOperation(d, client); err != nil {
 		return err
 	}
 
@@ -615,6 +904,24 @@ func resourceVSphereVirtualMachineCustomizeDiff(d *schema.ResourceDiff, meta int
 		return err
 	}
 
+	// Validate the boot order against the device sub-resources
+	if err := virtualdevice.BootOrderDiffOperation(d); err != nil {
+		return err
+	}
+	if d.HasChange("network_boot_protocol") && d.Get("network_boot_protocol").(string) != "" && d.Get("firmware").(string) != string(types.GuestOsDescriptorFirmwareTypeEfi) {
+		return errors.New("network_boot_protocol requires firmware to be set to efi")
+	}
+
+	// Validate cloud-init data delivery
+	if err := resourceVSphereVirtualMachineCustomizeDiffCloudInitOperation(d); err != nil {
+		return err
+	}
+
+	// Validate security, CPUID mask, and placement settings
+	if err := resourceVSphereVirtualMachineCustomizeDiffHardwareFeatureOperation(d, client); err != nil {
+		return err
+	}
+
 	// Process changes to resource pool
 	if err := resourceVSphereVirtualMachineCustomizeDiffResourcePoolOperation(d); err != nil {
 		return err
@@ -644,14 +951,46 @@ func resourceVSphereVirtualMachineCustomizeDiff(d *schema.ResourceDiff, meta int
 			// flagging the imported flag to off.
 			d.SetNew("imported", false)
 		case d.Id() == "":
-			if err := vmworkflow.ValidateVirtualMachineClone(d, client); err != nil {
+			if _, ok := d.GetOk("clone.0.content_library_item_id"); ok {
+				lc, err := meta.(*VSphereClient).ContentLibraryClient()
+				if err != nil {
+					return err
+				}
+				if err := vmworkflow.ValidateVirtualMachineLibraryClone(d, client, lc); err != nil {
+					return err
+				}
+			} else if err := vmworkflow.ValidateVirtualMachineClone(d, client); err != nil {
 				return err
 			}
 			fallthrough
 		default:
+			// Show changes to the snapshot the virtual machine was cloned from.
+			// These are informational only and do not force a new resource.
+			if d.Id() != "" {
+				if err := vmworkflow.DiffCloneSnapshot(d, client); err != nil {
+					return err
+				}
+			}
+			// Changes to the customization settings of an existing virtual
+			// machine are applied in place when reapply_on_change is set, so
+			// validate them the same way as on creation.
+			reapply := d.Id() != "" && d.Get("clone.0.customize.0.reapply_on_change").(bool)
+			if reapply && d.HasChange("clone.0.customize") {
+				if err := vmworkflow.ValidateCloneCustomization(d, client); err != nil {
+					return err
+				}
+			}
 			// For most cases (all non-imported workflows), any changed attribute in
-			// the clone configuration namespace is a ForceNew. Flag those now.
+			// the clone configuration namespace is a ForceNew. Flag those now. The
+			// diagnostics settings are only used by the provider, so they never
+			// force a new resource.
 			for _, k := range d.GetChangedKeysPrefix("clone.0") {
+				if reapply && strings.HasPrefix(k, "clone.0.customize") {
+					continue
+				}
+				if strings.HasPrefix(k, "clone.0.customize.0.diagnostics") {
+					continue
+				}
 				if strings.HasSuffix(k, ".#") {
 					k = strings.TrimSuffix(k, ".#")
 				}
@@ -659,6 +998,25 @@ func resourceVSphereVirtualMachineCustomizeDiff(d *schema.ResourceDiff, meta int
 			}
 		}
 	}
+	// OVF deploy validation follows the same pattern as clone validation above.
+	if len(d.Get("ovf_deploy").([]interface{})) > 0 {
+		switch {
+		case d.Get("imported").(bool):
+			d.SetNew("imported", false)
+		case d.Id() == "":
+			if err := vmworkflow.ValidateVirtualMachineOvfDeploy(d); err != nil {
+				return err
+			}
+			fallthrough
+		default:
+			for _, k := range d.GetChangedKeysPrefix("ovf_deploy.0") {
+				for _, suffix := range []string{".#", ".%"} {
+					k = strings.TrimSuffix(k, suffix)
+				}
+				d.ForceNew(k)
+			}
+		}
+	}
 	// Validate that the config has the necessary components for vApp support.
 	// Note that for clones the data is prepopulated in
 	// ValidateVirtualMachineClone.
@@ -670,6 +1028,194 @@ func resourceVSphereVirtualMachineCustomizeDiff(d *schema.ResourceDiff, meta int
 	return nil
 }
 
+// resourceVSphereVirtualMachineCustomizeDiffCloudInitOperation validates the
+// cloud_init sub-resource against the rest of the configuration.
+func resourceVSphereVirtualMachineCustomizeDiffCloudInitOperation(d *schema.ResourceDiff) error {
+	if len(d.Get("cloud_init").([]interface{})) < 1 {
+		return nil
+	}
+	ec := d.Get("extra_config").(map[string]interface{})
+	for _, k := range vmworkflow.CloudInitGuestInfoKeys {
+		if _, ok := ec[k]; ok {
+			return fmt.Errorf("extra_config key %q is managed by cloud_init and cannot be set when cloud_init is defined", k)
+		}
+	}
+	return virtualdevice.VerifyCloudInitTransport(d)
+}
+
+// resourceVSphereVirtualMachineCustomizeDiffHardwareFeatureOperation validates
+// the virtualization based security, IOMMU, CPUID mask, affinity, and virtual
+// NUMA settings of the virtual machine.
+//
+// Changes to settings that depend on the hardware are also validated against
+// the config option of the resource pool, or of the host in host_system_id if
+// it's set. Note that this is the config option of the default hardware
+// version, which is the version that new virtual machines are created with.
+func resourceVSphereVirtualMachineCustomizeDiffHardwareFeatureOperation(d *schema.ResourceDiff, client *govmomi.Client) error {
+	ec := d.Get("extra_config").(map[string]interface{})
+	for k, key := range virtualMachineAdvancedSettingKeys {
+		if _, ok := ec[key]; !ok {
+			continue
+		}
+		if _, ok := d.GetOk(k); ok {
+			return fmt.Errorf("%s cannot be set together with extra_config key %q", k, key)
+		}
+		log.Printf("[WARN] %s: extra_config key %q takes precedence over %s, consider using %s instead", resourceVSphereVirtualMachineIDString(d), key, k, k)
+	}
+
+	vbs := d.Get("vbs_enabled").(bool)
+	vvtd := d.Get("vvtd_enabled").(bool)
+	if vbs || vvtd {
+		version := viapi.ParseVersionFromClient(client)
+		if version.Older(viapi.VSphereVersion{Product: version.Product, Major: 6, Minor: 7}) {
+			return fmt.Errorf("vbs_enabled and vvtd_enabled are only supported on vSphere 6.7 and higher")
+		}
+	}
+	if vbs {
+		switch {
+		case d.Get("firmware").(string) != string(types.GuestOsDescriptorFirmwareTypeEfi):
+			return fmt.Errorf("vbs_enabled requires firmware to be set to efi")
+		case !d.Get("efi_secure_boot_enabled").(bool):
+			return fmt.Errorf("vbs_enabled requires efi_secure_boot_enabled to be set")
+		case !d.Get("nested_hv_enabled").(bool):
+			return fmt.Errorf("vbs_enabled requires nested_hv_enabled to be set")
+		case !vvtd:
+			return fmt.Errorf("vbs_enabled requires vvtd_enabled to be set")
+		}
+	}
+
+	masks := d.Get("cpuid_mask").(*schema.Set).List()
+	levels := make(map[int]struct{})
+	for _, v := range masks {
+		level := v.(map[string]interface{})["level"].(int)
+		if _, ok := levels[level]; ok {
+			return fmt.Errorf("duplicate cpuid_mask level %d", level)
+		}
+		levels[level] = struct{}{}
+	}
+
+	if n := d.Get("vnuma_max_vcpus_per_node").(int); n > 0 && d.NewValueKnown("num_cpus") {
+		if d.Get("num_cpus").(int)%n != 0 {
+			return fmt.Errorf("num_cpus must be a multiple of vnuma_max_vcpus_per_node (%d)", n)
+		}
+	}
+
+	checkVersion := (vbs && d.HasChange("vbs_enabled")) || (vvtd && d.HasChange("vvtd_enabled"))
+	checkMask := len(masks) > 0 && d.HasChange("cpuid_mask")
+	checkAffinity := d.HasChange("cpu_affinity") || d.HasChange("memory_affinity")
+	if !checkVersion && !checkMask && !checkAffinity {
+		return nil
+	}
+	poolID := d.Get("resource_pool_id").(string)
+	if !d.NewValueKnown("resource_pool_id") || poolID == "" {
+		log.Printf("[DEBUG] %s: Resource pool not known yet, skipping hardware feature validation", resourceVSphereVirtualMachineIDString(d))
+		return nil
+	}
+	pool, err := resourcepool.FromID(client, poolID)
+	if err != nil {
+		return fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	var host *object.HostSystem
+	if hsID := d.Get("host_system_id").(string); d.NewValueKnown("host_system_id") && hsID != "" {
+		if host, err = hostsystem.FromID(client, hsID); err != nil {
+			return fmt.Errorf("could not find host system ID %q: %s", hsID, err)
+		}
+	}
+
+	if checkAffinity && host != nil {
+		if err := validateVirtualMachineAffinity(d, host); err != nil {
+			return err
+		}
+	}
+	if !checkVersion && !checkMask {
+		return nil
+	}
+	descriptors, err := resourcepool.ConfigOptionDescriptors(client, pool)
+	if err != nil {
+		return fmt.Errorf("error fetching config option descriptors: %s", err)
+	}
+	key := defaultConfigOptionKey(descriptors, host)
+	if key == "" {
+		return fmt.Errorf("could not determine the default hardware version of resource pool %q", poolID)
+	}
+	if checkVersion {
+		if v := virtualMachineHardwareVersion(key); v < virtualMachineVBSMinHardwareVersion {
+			return fmt.Errorf("vbs_enabled and vvtd_enabled require hardware version %d or higher, the default hardware version is %d", virtualMachineVBSMinHardwareVersion, v)
+		}
+	}
+	opt, err := resourcepool.ConfigOption(client, pool, key, host)
+	if err != nil {
+		return fmt.Errorf("error fetching config option %q: %s", key, err)
+	}
+	switch {
+	case vbs && checkVersion && (opt.Capabilities.NestedHVSupported == nil || !*opt.Capabilities.NestedHVSupported):
+		return fmt.Errorf("vbs_enabled requires nested hardware virtualization, which is not supported by config option %q", key)
+	case checkMask && !opt.Capabilities.CpuFeatureMaskSupported:
+		return fmt.Errorf("cpuid_mask is not supported by config option %q", key)
+	}
+	return nil
+}
+
+// virtualMachineVBSMinHardwareVersion is the minimum hardware version that
+// supports virtualization based security and a virtual IOMMU.
+const virtualMachineVBSMinHardwareVersion = 14
+
+// defaultConfigOptionKey returns the key of the default config option
+// descriptor, of the supplied host if it's not nil. An empty string is
+// returned if there is no default descriptor.
+func defaultConfigOptionKey(descriptors []types.VirtualMachineConfigOptionDescriptor, host *object.HostSystem) string {
+	for _, desc := range descriptors {
+		if desc.DefaultConfigOption == nil || !*desc.DefaultConfigOption {
+			continue
+		}
+		if host == nil {
+			return desc.Key
+		}
+		for _, ref := range desc.Host {
+			if ref.Value == host.Reference().Value {
+				return desc.Key
+			}
+		}
+	}
+	return ""
+}
+
+// virtualMachineHardwareVersion returns the hardware version of the supplied
+// config option key, such as 14 for vmx-14. 0 is returned if the key is not a
+// hardware version.
+func virtualMachineHardwareVersion(key string) int {
+	v, _ := strconv.Atoi(strings.TrimPrefix(key, "vmx-"))
+	return v
+}
+
+// validateVirtualMachineAffinity validates cpu_affinity and memory_affinity
+// against the logical processors and NUMA nodes of the supplied host.
+func validateVirtualMachineAffinity(d *schema.ResourceDiff, host *object.HostSystem) error {
+	hprops, err := hostsystem.Properties(host)
+	if err != nil {
+		return fmt.Errorf("error fetching host properties: %s", err)
+	}
+	if hprops.Hardware == nil {
+		return nil
+	}
+	threads := int(hprops.Hardware.CpuInfo.NumCpuThreads)
+	for _, v := range d.Get("cpu_affinity").(*schema.Set).List() {
+		if v.(int) >= threads {
+			return fmt.Errorf("cpu_affinity: host %q has %d logical processors, %d is out of range", host.Reference().Value, threads, v.(int))
+		}
+	}
+	if hprops.Hardware.NumaInfo == nil {
+		return nil
+	}
+	nodes := int(hprops.Hardware.NumaInfo.NumNodes)
+	for _, v := range d.Get("memory_affinity").(*schema.Set).List() {
+		if v.(int) >= nodes {
+			return fmt.Errorf("memory_affinity: host %q has %d NUMA nodes, %d is out of range", host.Reference().Value, nodes, v.(int))
+		}
+	}
+	return nil
+}
+
 func resourceVSphereVirtualMachineCustomizeDiffResourcePoolOperation(d *schema.ResourceDiff) error {
 	if d.HasChange("resource_pool_id") && !d.HasChange("host_system_id") {
 		log.Printf(
@@ -746,7 +1292,7 @@ func datastoreClusterDiffOperationCheckMembership(d *schema.ResourceDiff, client
 }
 
 func resourceVSphereVirtualMachineImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 
 	name := d.Id()
 	if name == "" {
@@ -788,13 +1334,18 @@ func resourceVSphereVirtualMachineImport(d *schema.ResourceData, meta interface{
 		ctlrCnt++
 	}
 	if ctlrCnt < 1 {
-		return nil, fmt.Errorf("VM %q has no SCSI controllers", name)
+		// The resource always manages at least one SCSI controller. VMs with
+		// disks only on SATA, NVMe, or IDE controllers get one added on the
+		// first apply.
+		log.Printf("[DEBUG] VM %q has no SCSI controllers, one will be added on the next apply", name)
+		ctlrCnt = 1
 	}
 	d.Set("scsi_controller_count", ctlrCnt)
 
 	// Validate the disks in the VM to make sure that they will work with the
-	// resource. This is mainly ensuring that all disks are SCSI disks, but a
-	// Read operation is attempted as well to make sure it will survive that.
+	// resource. This is mainly ensuring that all disks are on supported
+	// controllers, but a Read operation is attempted as well to make sure it
+	// will survive that.
 	if err := virtualdevice.DiskImportOperation(d, client, object.VirtualDeviceList(props.Config.Hardware.Device)); err != nil {
 		return nil, err
 	}
@@ -820,7 +1371,7 @@ func resourceVSphereVirtualMachineImport(d *schema.ResourceData, meta interface{
 // deploy path. The VM is returned.
 func resourceVSphereVirtualMachineCreateBare(d *schema.ResourceData, meta interface{}) (*object.VirtualMachine, error) {
 	log.Printf("[DEBUG] %s: VM being created from scratch", resourceVSphereVirtualMachineIDString(d))
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	poolID := d.Get("resource_pool_id").(string)
 	pool, err := resourcepool.FromID(client, poolID)
 	if err != nil {
@@ -854,6 +1405,9 @@ func resourceVSphereVirtualMachineCreateBare(d *schema.ResourceData, meta interf
 	if err != nil {
 		return nil, fmt.Errorf("error in virtual machine configuration: %s", err)
 	}
+	if spec.Crypto, err = expandVirtualMachineCryptoSpec(d, client, nil); err != nil {
+		return nil, fmt.Errorf("error in virtual machine encryption settings: %s", err)
+	}
 
 	// Now we need to get the default device set - this is available in the
 	// environment info in the resource pool, which we can then filter through
@@ -865,7 +1419,11 @@ func resourceVSphereVirtualMachineCreateBare(d *schema.ResourceData, meta interf
 	}
 	log.Printf("[DEBUG] Default devices: %s", virtualdevice.DeviceListString(devices))
 
-	if spec.DeviceChange, err = applyVirtualDevices(d, client, devices); err != nil {
+	lc, err := cdromContentLibraryClient(d.Get("cdrom").([]interface{}), meta)
+	if err != nil {
+		return nil, err
+	}
+	if spec.DeviceChange, err = applyVirtualDevices(d, client, lc, devices); err != nil {
 		return nil, err
 	}
 
@@ -890,9 +1448,29 @@ func resourceVSphereVirtualMachineCreateBare(d *schema.ResourceData, meta interf
 	log.Printf("[DEBUG] VM %q - UUID is %q", vm.InventoryPath, vprops.Config.Uuid)
 	d.SetId(vprops.Config.Uuid)
 
-	// Start the virtual machine
-	if err := virtualmachine.PowerOn(vm); err != nil {
-		return nil, fmt.Errorf("error powering on virtual machine: %s", err)
+	if flags := expandVirtualMachineSecurityFlags(d); flags != nil {
+		if err := virtualmachine.ReconfigureFlags(client, vm, *flags); err != nil {
+			return nil, fmt.Errorf("error reconfiguring virtual machine flags: %s", err)
+		}
+	}
+
+	// Set the boot order and deliver cloud-init data before the first boot.
+	if len(d.Get("boot_order").([]interface{})) > 0 {
+		if err := resourceVSphereVirtualMachineApplyBootOrder(d, meta); err != nil {
+			return nil, err
+		}
+	}
+	if len(d.Get("cloud_init").([]interface{})) > 0 {
+		if err := resourceVSphereVirtualMachineApplyCloudInit(d, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	// Start the virtual machine, unless it's meant to stay off
+	if d.Get("power_state").(string) != virtualMachinePowerStateOff {
+		if err := virtualmachine.PowerOn(vm); err != nil {
+			return nil, fmt.Errorf("error powering on virtual machine: %s", err)
+		}
 	}
 	return vm, nil
 }
@@ -909,7 +1487,7 @@ func resourceVSphereVirtualMachineCreateBareWithSDRS(
 	pool *object.ResourcePool,
 	hs *object.HostSystem,
 ) (*object.VirtualMachine, error) {
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	if err := viapi.ValidateVirtualCenter(client); err != nil {
 		return nil, fmt.Errorf("connection ineligible to use datastore_cluster_id: %s", err)
 	}
@@ -939,7 +1517,7 @@ func resourceVSphereVirtualMachineCreateBareStandard(
 	pool *object.ResourcePool,
 	hs *object.HostSystem,
 ) (*object.VirtualMachine, error) {
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 
 	// Set the datastore for the VM.
 	ds, err := datastore.FromID(client, d.Get("datastore_id").(string))
@@ -961,7 +1539,7 @@ func resourceVSphereVirtualMachineCreateBareStandard(
 // path. The VM is returned.
 func resourceVSphereVirtualMachineCreateClone(d *schema.ResourceData, meta interface{}) (*object.VirtualMachine, error) {
 	log.Printf("[DEBUG] %s: VM being created from clone", resourceVSphereVirtualMachineIDString(d))
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 
 	// Find the folder based off the path to the resource pool. Basically what we
 	// are saying here is that the VM folder that we are placing this VM in needs
@@ -977,23 +1555,37 @@ func resourceVSphereVirtualMachineCreateClone(d *schema.ResourceData, meta inter
 		return nil, err
 	}
 
-	// Expand the clone spec. We get the source VM here too.
-	cloneSpec, srcVM, err := vmworkflow.ExpandVirtualMachineCloneSpec(d, client)
-	if err != nil {
-		return nil, err
-	}
-
-	// Start the clone
-	name := d.Get("name").(string)
-	timeout := d.Get("clone.0.timeout").(int)
 	var vm *object.VirtualMachine
-	if _, ok := d.GetOk("datastore_cluster_id"); ok {
-		vm, err = resourceVSphereVirtualMachineCreateCloneWithSDRS(d, meta, srcVM, fo, name, cloneSpec, timeout)
-	} else {
-		vm, err = virtualmachine.Clone(client, srcVM, fo, name, cloneSpec, timeout)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("error cloning virtual machine: %s", err)
+	instant := d.Get("clone.0.instant_clone").(bool)
+	switch {
+	case instant:
+		vm, err = resourceVSphereVirtualMachineCreateInstantClone(d, meta, fo)
+		if err != nil {
+			return nil, err
+		}
+	case d.Get("clone.0.content_library_item_id").(string) != "":
+		vm, err = resourceVSphereVirtualMachineCreateCloneFromLibrary(d, meta, fo)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		// Expand the clone spec. We get the source VM here too.
+		cloneSpec, srcVM, err := vmworkflow.ExpandVirtualMachineCloneSpec(d, client)
+		if err != nil {
+			return nil, err
+		}
+
+		// Start the clone
+		name := d.Get("name").(string)
+		timeout := d.Get("clone.0.timeout").(int)
+		if _, ok := d.GetOk("datastore_cluster_id"); ok {
+			vm, err = resourceVSphereVirtualMachineCreateCloneWithSDRS(d, meta, srcVM, fo, name, cloneSpec, timeout)
+		} else {
+			vm, err = virtualmachine.Clone(client, srcVM, fo, name, cloneSpec, timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error cloning virtual machine: %s", err)
+		}
 	}
 
 	// The VM has been created. We still need to do post-clone configuration, and
@@ -1003,97 +1595,18 @@ func resourceVSphereVirtualMachineCreateClone(d *schema.ResourceData, meta inter
 	//
 	// It's generally safe to not rollback after the initial re-configuration is
 	// fully complete and we move on to sending the customization spec.
-	vprops, err := virtualmachine.Properties(vm)
-	if err != nil {
-		return nil, resourceVSphereVirtualMachineRollbackCreate(
-			d,
-			meta,
-			vm,
-			fmt.Errorf("cannot fetch properties of created virtual machine: %s", err),
-		)
-	}
-	log.Printf("[DEBUG] VM %q - UUID is %q", vm.InventoryPath, vprops.Config.Uuid)
-	d.SetId(vprops.Config.Uuid)
-
-	// Before starting or proceeding any further, we need to normalize the
-	// configuration of the newly cloned VM. This is basically a subset of update
-	// with the stipulation that there is currently no state to help move this
-	// along.
-	cfgSpec, err := expandVirtualMachineConfigSpec(d, client)
-	if err != nil {
-		return nil, resourceVSphereVirtualMachineRollbackCreate(
-			d,
-			meta,
-			vm,
-			fmt.Errorf("error in virtual machine configuration: %s", err),
-		)
-	}
-
-	// To apply device changes, we need the current devicecfgSpec from the config
-	// info. We then filter this list through the same apply process we did for
-	// create, which will apply the changes in an incremental fashion.
-	devices := object.VirtualDeviceList(vprops.Config.Hardware.Device)
-	var delta []types.BaseVirtualDeviceConfigSpec
-	// First check the state of our SCSI bus. Normalize it if we need to.
-	devices, delta, err = virtualdevice.NormalizeSCSIBus(devices, d.Get("scsi_type").(string), d.Get("scsi_controller_count").(int))
-	if err != nil {
-		return nil, resourceVSphereVirtualMachineRollbackCreate(
-			d,
-			meta,
-			vm,
-			fmt.Errorf("error normalizing SCSI bus post-clone: %s", err),
-		)
-	}
-	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
-	// Disks
-	devices, delta, err = virtualdevice.DiskPostCloneOperation(d, client, devices)
-	if err != nil {
-		return nil, resourceVSphereVirtualMachineRollbackCreate(
-			d,
-			meta,
-			vm,
-			fmt.Errorf("error processing disk changes post-clone: %s", err),
-		)
-	}
-	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
-	// Network devices
-	devices, delta, err = virtualdevice.NetworkInterfacePostCloneOperation(d, client, devices)
-	if err != nil {
-		return nil, resourceVSphereVirtualMachineRollbackCreate(
-			d,
-			meta,
-			vm,
-			fmt.Errorf("error processing network device changes post-clone: %s", err),
-		)
-	}
-	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
-	// CDROM
-	devices, delta, err = virtualdevice.CdromPostCloneOperation(d, client, devices)
-	if err != nil {
-		return nil, resourceVSphereVirtualMachineRollbackCreate(
-			d,
-			meta,
-			vm,
-			fmt.Errorf("error processing CDROM device changes post-clone: %s", err),
-		)
+	if err := resourceVSphereVirtualMachinePostDeployChanges(d, meta, vm); err != nil {
+		return nil, err
 	}
-	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
-	log.Printf("[DEBUG] %s: Final device list: %s", resourceVSphereVirtualMachineIDString(d), virtualdevice.DeviceListString(devices))
-	log.Printf("[DEBUG] %s: Final device change cfgSpec: %s", resourceVSphereVirtualMachineIDString(d), virtualdevice.DeviceChangeString(cfgSpec.DeviceChange))
-
-	// Perform updates
-	if _, ok := d.GetOk("datastore_cluster_id"); ok {
-		err = resourceVSphereVirtualMachineUpdateReconfigureWithSDRS(d, meta, vm, cfgSpec)
-	} else {
-		err = virtualmachine.Reconfigure(vm, cfgSpec)
+	if len(d.Get("boot_order").([]interface{})) > 0 {
+		if err := resourceVSphereVirtualMachineApplyBootOrder(d, meta); err != nil {
+			return nil, err
+		}
 	}
-	if err != nil {
-		return nil, resourceVSphereVirtualMachineRollbackCreate(
-			d,
-			meta,
-			vm,
-			fmt.Errorf("error reconfiguring virtual machine: %s", err),
-		)
+	if len(d.Get("cloud_init").([]interface{})) > 0 {
+		if err := resourceVSphereVirtualMachineApplyCloudInit(d, meta); err != nil {
+			return nil, err
+		}
 	}
 
 	var cw *virtualMachineCustomizationWaiter
@@ -1103,9 +1616,12 @@ func resourceVSphereVirtualMachineCreateClone(d *schema.ResourceData, meta inter
 		if err != nil {
 			return nil, fmt.Errorf("cannot find OS family for guest ID %q: %s", d.Get("guest_id").(string), err)
 		}
-		custSpec := vmworkflow.ExpandCustomizationSpec(d, family)
+		custSpec, err := resourceVSphereVirtualMachineExpandCustomizationSpec(d, client, family)
+		if err != nil {
+			return nil, err
+		}
 		cw = newVirtualMachineCustomizationWaiter(client, vm, d.Get("clone.0.customize.0.timeout").(int))
-		if err := virtualmachine.Customize(vm, custSpec); err != nil {
+		if err := virtualmachine.Customize(client, vm, custSpec); err != nil {
 			// Roll back the VMs as per the error handling in reconfigure.
 			if derr := resourceVSphereVirtualMachineDelete(d, meta); derr != nil {
 				return nil, fmt.Errorf(formatVirtualMachinePostCloneRollbackError, vm.InventoryPath, err, derr)
@@ -1114,16 +1630,20 @@ func resourceVSphereVirtualMachineCreateClone(d *schema.ResourceData, meta inter
 			return nil, fmt.Errorf("error sending customization spec: %s", err)
 		}
 	}
-	// Finally time to power on the virtual machine!
-	if err := virtualmachine.PowerOn(vm); err != nil {
-		return nil, fmt.Errorf("error powering on virtual machine: %s", err)
+	// Finally time to power on the virtual machine! Customization happens on
+	// first boot, so the VM is powered on for it regardless of power_state.
+	// Instant clones are already running at this point.
+	if !instant && (cw != nil || d.Get("power_state").(string) != virtualMachinePowerStateOff) {
+		if err := virtualmachine.PowerOn(vm); err != nil {
+			return nil, fmt.Errorf("error powering on virtual machine: %s", err)
+		}
 	}
 	// If we customized, wait on customization.
 	if cw != nil {
 		log.Printf("[DEBUG] %s: Waiting for VM customization to complete", resourceVSphereVirtualMachineIDString(d))
 		<-cw.Done()
 		if err := cw.Err(); err != nil {
-			return nil, fmt.Errorf(formatVirtualMachineCustomizationWaitError, vm.InventoryPath, err)
+			return nil, fmt.Errorf(formatVirtualMachineCustomizationWaitError, vm.InventoryPath, customizationDiagnosticsError(d, client, vm, err))
 		}
 	}
 	// Clone is complete and ready to return
@@ -1143,7 +1663,7 @@ func resourceVSphereVirtualMachineCreateCloneWithSDRS(
 	spec types.VirtualMachineCloneSpec,
 	timeout int,
 ) (*object.VirtualMachine, error) {
-	client := meta.(*VSphereClient).vimClient
+	client := resourceVSphereVirtualMachineClient(meta)
 	if err := viapi.ValidateVirtualCenter(client); err != nil {
 		return nil, fmt.Errorf("connection ineligible to use datastore_cluster_id: %s", err)
 	}
@@ -1162,127 +1682,460 @@ func resourceVSphereVirtualMachineCreateCloneWithSDRS(
 	return vm, nil
 }
 
-// resourceVSphereVirtualMachineRollbackCreate attempts to "roll back" a
-// resource due to an error that happened post-create that will put the VM in a
-// state where it cannot be worked with. This should only be done early on in
-// the process, namely on clone operations between when the clone actually
-// happens, and no later than after the initial post-clone update is complete.
-//
-// If the rollback fails, an error is displayed prompting the user to manually
-// delete the virtual machine before trying again.
-func resourceVSphereVirtualMachineRollbackCreate(
+// resourceVSphereVirtualMachineCreateInstantClone runs the clone part of
+// resourceVSphereVirtualMachineCreateClone through InstantClone_Task. The
+// virtual machine returned is powered on.
+func resourceVSphereVirtualMachineCreateInstantClone(
 	d *schema.ResourceData,
 	meta interface{},
-	vm *object.VirtualMachine,
-	origErr error,
-) error {
-	defer d.SetId("")
-	// Updates are largely atomic, so more than likely no disks with
-	// keep_on_remove were attached, but just in case, we run this through delete
-	// to make sure to safely remove any disk that may have been attached as part
-	// of this process if it was flagged as such.
-	if err := resourceVSphereVirtualMachineDelete(d, meta); err != nil {
-		return fmt.Errorf(formatVirtualMachinePostCloneRollbackError, vm.InventoryPath, origErr, err)
+	fo *object.Folder,
+) (*object.VirtualMachine, error) {
+	client := resourceVSphereVirtualMachineClient(meta)
+	log.Printf("[DEBUG] %s: Instant cloning virtual machine", resourceVSphereVirtualMachineIDString(d))
+	spec, srcVM, err := vmworkflow.ExpandVirtualMachineInstantCloneSpec(d, client, fo)
+	if err != nil {
+		return nil, err
 	}
-	return fmt.Errorf("error reconfiguring virtual machine: %s", origErr)
+	vm, err := virtualmachine.InstantClone(client, srcVM, spec, d.Get("clone.0.timeout").(int))
+	if err != nil {
+		return nil, fmt.Errorf("error instant cloning virtual machine: %s", err)
+	}
+	return vm, nil
 }
 
-// resourceVSphereVirtualMachineUpdateLocation manages vMotion. This includes
-// the migration of a VM from one host to another, or from one datastore to
-// another (storage vMotion).
-//
-// This function is responsible for building the top-level relocate spec. For
-// disks, we call out to relocate functionality in the disk sub-resource.
-func resourceVSphereVirtualMachineUpdateLocation(d *schema.ResourceData, meta interface{}) error {
-	log.Printf("[DEBUG] %s: Checking for pending migration operations", resourceVSphereVirtualMachineIDString(d))
-	client := meta.(*VSphereClient).vimClient
+// resourceVSphereVirtualMachineCreateCloneFromLibrary runs the clone part of
+// resourceVSphereVirtualMachineCreateClone through the content library API.
+// It's designed to be run when a content library item is specified as the
+// clone source, versus a template UUID.
+func resourceVSphereVirtualMachineCreateCloneFromLibrary(
+	d *schema.ResourceData,
+	meta interface{},
+	fo *object.Folder,
+) (*object.VirtualMachine, error) {
+	client := resourceVSphereVirtualMachineClient(meta)
+	lc, err := meta.(*VSphereClient).ContentLibraryClient()
+	if err != nil {
+		return nil, err
+	}
 
-	// A little bit of duplication of VM object data is done here to keep the
-	// method signature lean.
-	id := d.Id()
-	vm, err := virtualmachine.FromUUID(client, id)
+	id := d.Get("clone.0.content_library_item_id").(string)
+	log.Printf("[DEBUG] %s: Cloning virtual machine from content library item %q", resourceVSphereVirtualMachineIDString(d), id)
+	target, err := vmworkflow.ExpandLibraryDeploymentTarget(d, client, fo)
 	if err != nil {
-		return fmt.Errorf("cannot locate virtual machine with UUID %q: %s", id, err)
+		return nil, err
 	}
 
-	// Determine if we are performing any storage vMotion tasks. This will generate the relocators if there are any.
-	vprops, err := virtualmachine.Properties(vm)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*time.Duration(d.Get("clone.0.timeout").(int)))
+	defer cancel()
+	item, err := lc.GetLibraryItem(ctx, id)
 	if err != nil {
-		return fmt.Errorf("error fetching VM properties: %s", err)
+		return nil, fmt.Errorf("cannot locate content library item %q: %s", id, err)
+	}
+	var vmID string
+	name := d.Get("name").(string)
+	switch item.Type {
+	case contentlibrary.ItemTypeVMTemplate:
+		vmID, err = lc.DeployVMTemplateLibraryItem(ctx, id, name, target)
+	default:
+		vmID, err = lc.DeployOvfLibraryItem(ctx, id, name, target)
 	}
-	devices := object.VirtualDeviceList(vprops.Config.Hardware.Device)
-	relocators, diskRelocateOK, err := virtualdevice.DiskMigrateRelocateOperation(d, client, devices)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error deploying content library item %q: %s", id, err)
 	}
-	// If we don't have any changes, stop here.
-	if !d.HasChange("resource_pool_id") && !d.HasChange("host_system_id") && !d.HasChange("datastore_id") && !diskRelocateOK {
-		log.Printf("[DEBUG] %s: No migration operations found", resourceVSphereVirtualMachineIDString(d))
-		return nil
+
+	vm, err := virtualmachine.FromMOID(client, vmID)
+	if err != nil {
+		return nil, fmt.Errorf("error locating deployed virtual machine %q: %s", vmID, err)
 	}
-	log.Printf("[DEBUG] %s: Migration operations found, proceeding with migration", resourceVSphereVirtualMachineIDString(d))
+	return vm, nil
+}
 
-	// Fetch and validate pool and host
+// resourceVSphereVirtualMachineCreateOvf contains the OVF deploy path. The
+// VM is returned.
+func resourceVSphereVirtualMachineCreateOvf(d *schema.ResourceData, meta interface{}) (*object.VirtualMachine, error) {
+	log.Printf("[DEBUG] %s: VM being created from OVF", resourceVSphereVirtualMachineIDString(d))
+	client := resourceVSphereVirtualMachineClient(meta)
+
+	// Find the folder based off the path to the resource pool, the same as we do
+	// for clones.
 	poolID := d.Get("resource_pool_id").(string)
 	pool, err := resourcepool.FromID(client, poolID)
 	if err != nil {
-		return fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+		return nil, fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	fo, err := folder.VirtualMachineFolderFromObject(client, pool, d.Get("folder").(string))
+	if err != nil {
+		return nil, err
 	}
 	var hs *object.HostSystem
 	if v, ok := d.GetOk("host_system_id"); ok {
 		hsID := v.(string)
 		var err error
 		if hs, err = hostsystem.FromID(client, hsID); err != nil {
-			return fmt.Errorf("error locating host system at ID %q: %s", hsID, err)
+			return nil, fmt.Errorf("error locating host system at ID %q: %s", hsID, err)
 		}
 	}
 	if err := resourcepool.ValidateHost(client, pool, hs); err != nil {
-		return err
+		return nil, err
+	}
+	ds, err := datastore.FromID(client, d.Get("datastore_id").(string))
+	if err != nil {
+		return nil, fmt.Errorf("error locating datastore for VM: %s", err)
 	}
 
-	// Start building the spec
-	spec := types.VirtualMachineRelocateSpec{
-		Pool: types.NewReference(pool.Reference()),
+	src, params, err := vmworkflow.ExpandVirtualMachineOvfDeploy(d, client)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := ovfdeploy.Deploy(client, src, pool, fo, hs, ds, params, d.Get("ovf_deploy.0.timeout").(int))
+	if err != nil {
+		return nil, fmt.Errorf("error deploying OVF: %s", err)
 	}
 
-	// Fetch the datastore
-	if dsID, ok := d.GetOk("datastore_id"); ok {
-		ds, err := datastore.FromID(client, dsID.(string))
-		if err != nil {
-			return fmt.Errorf("error locating datastore for VM: %s", err)
+	// From here on out, the workflow is the same as a clone: the VM exists with
+	// whatever devices the OVF defined, and the configuration needs to be
+	// reconciled against it.
+	if err := resourceVSphereVirtualMachinePostDeployChanges(d, meta, vm); err != nil {
+		return nil, err
+	}
+	if len(d.Get("boot_order").([]interface{})) > 0 {
+		if err := resourceVSphereVirtualMachineApplyBootOrder(d, meta); err != nil {
+			return nil, err
 		}
-		spec.Datastore = types.NewReference(ds.Reference())
 	}
-
-	if hs != nil {
-		hsRef := hs.Reference()
-		spec.Host = &hsRef
+	if len(d.Get("cloud_init").([]interface{})) > 0 {
+		if err := resourceVSphereVirtualMachineApplyCloudInit(d, meta); err != nil {
+			return nil, err
+		}
 	}
-
-	spec.Disk = relocators
-
-	// Ready to perform migration
-	timeout := d.Get("migrate_wait_timeout").(int)
-	if _, ok := d.GetOk("datastore_cluster_id"); ok {
-		err = resourceVSphereVirtualMachineUpdateLocationRelocateWithSDRS(d, meta, vm, spec, timeout)
-	} else {
-		err = virtualmachine.Relocate(vm, spec, timeout)
+	if d.Get("power_state").(string) != virtualMachinePowerStateOff {
+		if err := virtualmachine.PowerOn(vm); err != nil {
+			return nil, fmt.Errorf("error powering on virtual machine: %s", err)
+		}
 	}
-	return err
+	return vm, nil
 }
 
-// resourceVSphereVirtualMachineUpdateLocationRelocateWithSDRS runs the storage vMotion
-// part of resourceVSphereVirtualMachineUpdateLocation through storage DRS.
-// It's designed to be run when a storage cluster is specified, versus simply
-// specifying datastores.
-func resourceVSphereVirtualMachineUpdateLocationRelocateWithSDRS(
-	d *schema.ResourceData,
-	meta interface{},
-	vm *object.VirtualMachine,
-	spec types.VirtualMachineRelocateSpec,
-	timeout int,
-) error {
-	client := meta.(*VSphereClient).vimClient
+// resourceVSphereVirtualMachinePostDeployChanges performs the initial
+// reconfiguration of a virtual machine that was created from an existing
+// source, such as a template or an OVF. The resource ID is set here, and the
+// device configuration of the new VM is normalized against the configuration
+// through the post-clone device operations.
+//
+// Any error here will roll back the creation of the virtual machine.
+func resourceVSphereVirtualMachinePostDeployChanges(d *schema.ResourceData, meta interface{}, vm *object.VirtualMachine) error {
+	client := resourceVSphereVirtualMachineClient(meta)
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("cannot fetch properties of created virtual machine: %s", err),
+		)
+	}
+	log.Printf("[DEBUG] VM %q - UUID is %q", vm.InventoryPath, vprops.Config.Uuid)
+	d.SetId(vprops.Config.Uuid)
+
+	// Before starting or proceeding any further, we need to normalize the
+	// configuration of the newly cloned VM. This is basically a subset of update
+	// with the stipulation that there is currently no state to help move this
+	// along.
+	cfgSpec, err := expandVirtualMachineConfigSpec(d, client)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error in virtual machine configuration: %s", err),
+		)
+	}
+	// The source of a clone may already be encrypted, so the crypto spec starts
+	// from the current key of the new virtual machine.
+	if cfgSpec.Crypto, err = expandVirtualMachineCryptoSpec(d, client, vprops.Config.KeyId); err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error in virtual machine encryption settings: %s", err),
+		)
+	}
+
+	// To apply device changes, we need the current devicecfgSpec from the config
+	// info. We then filter this list through the same apply process we did for
+	// create, which will apply the changes in an incremental fashion.
+	devices := object.VirtualDeviceList(vprops.Config.Hardware.Device)
+	var delta []types.BaseVirtualDeviceConfigSpec
+	// First check the state of our SCSI bus. Normalize it if we need to.
+	devices, delta, err = virtualdevice.NormalizeSCSIBus(devices, d)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error normalizing SCSI bus post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// Disks
+	devices, delta, err = virtualdevice.DiskPostCloneOperation(d, client, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing disk changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// Network devices
+	devices, delta, err = virtualdevice.NetworkInterfacePostCloneOperation(d, client, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing network device changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// CDROM
+	lc, err := cdromContentLibraryClient(d.Get("cdrom").([]interface{}), meta)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(d, meta, vm, err)
+	}
+	devices, delta, err = virtualdevice.CdromPostCloneOperation(d, client, lc, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing CDROM device changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// Serial ports
+	devices, delta, err = virtualdevice.SerialPortPostCloneOperation(d, client, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing serial port changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// Parallel ports
+	devices, delta, err = virtualdevice.ParallelPortPostCloneOperation(d, client, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing parallel port changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// USB controllers
+	devices, delta, err = virtualdevice.USBControllerApplyOperation(d, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing USB controller changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// PCI passthrough devices
+	devices, delta, err = virtualdevice.PCIDeviceApplyOperation(d, client, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing PCI passthrough device changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// Virtual TPM
+	devices, delta, err = virtualdevice.VTPMApplyOperation(d, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing virtual TPM changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	// Video card
+	devices, delta, err = virtualdevice.VideoCardApplyOperation(d, devices)
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error processing video card changes post-clone: %s", err),
+		)
+	}
+	cfgSpec.DeviceChange = virtualdevice.AppendDeviceChangeSpec(cfgSpec.DeviceChange, delta...)
+	log.Printf("[DEBUG] %s: Final device list: %s", resourceVSphereVirtualMachineIDString(d), virtualdevice.DeviceListString(devices))
+	log.Printf("[DEBUG] %s: Final device change cfgSpec: %s", resourceVSphereVirtualMachineIDString(d), virtualdevice.DeviceChangeString(cfgSpec.DeviceChange))
+
+	// Perform updates
+	if _, ok := d.GetOk("datastore_cluster_id"); ok {
+		err = resourceVSphereVirtualMachineUpdateReconfigureWithSDRS(d, meta, vm, cfgSpec)
+	} else {
+		err = virtualmachine.Reconfigure(vm, cfgSpec)
+	}
+	if err != nil {
+		return resourceVSphereVirtualMachineRollbackCreate(
+			d,
+			meta,
+			vm,
+			fmt.Errorf("error reconfiguring virtual machine: %s", err),
+		)
+	}
+	if flags := expandVirtualMachineSecurityFlags(d); flags != nil {
+		if err := virtualmachine.ReconfigureFlags(client, vm, *flags); err != nil {
+			return resourceVSphereVirtualMachineRollbackCreate(
+				d,
+				meta,
+				vm,
+				fmt.Errorf("error reconfiguring virtual machine flags: %s", err),
+			)
+		}
+	}
+	return nil
+}
+
+// resourceVSphereVirtualMachineRollbackCreate attempts to "roll back" a
+// resource due to an error that happened post-create that will put the VM in a
+// state where it cannot be worked with. This should only be done early on in
+// the process, namely on clone operations between when the clone actually
+// happens, and no later than after the initial post-clone update is complete.
+//
+// If the rollback fails, an error is displayed prompting the user to manually
+// delete the virtual machine before trying again.
+func resourceVSphereVirtualMachineRollbackCreate(
+	d *schema.ResourceData,
+	meta interface{},
+	vm *object.VirtualMachine,
+	origErr error,
+) error {
+	defer d.SetId("")
+	// Updates are largely atomic, so more than likely no disks with
+	// keep_on_remove were attached, but just in case, we run this through delete
+	// to make sure to safely remove any disk that may have been attached as part
+	// of this process if it was flagged as such.
+	if err := resourceVSphereVirtualMachineDelete(d, meta); err != nil {
+		return fmt.Errorf(formatVirtualMachinePostCloneRollbackError, vm.InventoryPath, origErr, err)
+	}
+	return fmt.Errorf("error reconfiguring virtual machine: %s", origErr)
+}
+
+// resourceVSphereVirtualMachineUpdateLocation manages vMotion. This includes
+// the migration of a VM from one host to another, or from one datastore to
+// another (storage vMotion).
+//
+// This function is responsible for building the top-level relocate spec. For
+// disks, we call out to relocate functionality in the disk sub-resource.
+func resourceVSphereVirtualMachineUpdateLocation(d *schema.ResourceData, meta interface{}) error {
+	log.Printf("[DEBUG] %s: Checking for pending migration operations", resourceVSphereVirtualMachineIDString(d))
+	client := resourceVSphereVirtualMachineClient(meta)
+
+	// A little bit of duplication of VM object data is done here to keep the
+	// method signature lean.
+	id := d.Id()
+	vm, err := virtualmachine.FromUUID(client, id)
+	if err != nil {
+		return fmt.Errorf("cannot locate virtual machine with UUID %q: %s", id, err)
+	}
+
+	// Determine if we are performing any storage vMotion tasks. This will generate the relocators if there are any.
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return fmt.Errorf("error fetching VM properties: %s", err)
+	}
+	devices := object.VirtualDeviceList(vprops.Config.Hardware.Device)
+	relocators, diskRelocateOK, err := virtualdevice.DiskMigrateRelocateOperation(d, client, devices)
+	if err != nil {
+		return err
+	}
+	// If we don't have any changes, stop here.
+	if !d.HasChange("resource_pool_id") && !d.HasChange("host_system_id") && !d.HasChange("datastore_id") && !diskRelocateOK {
+		log.Printf("[DEBUG] %s: No migration operations found", resourceVSphereVirtualMachineIDString(d))
+		return nil
+	}
+	log.Printf("[DEBUG] %s: Migration operations found, proceeding with migration", resourceVSphereVirtualMachineIDString(d))
+
+	// Fetch and validate pool and host
+	poolID := d.Get("resource_pool_id").(string)
+	pool, err := resourcepool.FromID(client, poolID)
+	if err != nil {
+		return fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	var hs *object.HostSystem
+	if v, ok := d.GetOk("host_system_id"); ok {
+		hsID := v.(string)
+		var err error
+		if hs, err = hostsystem.FromID(client, hsID); err != nil {
+			return fmt.Errorf("error locating host system at ID %q: %s", hsID, err)
+		}
+	}
+	if err := resourcepool.ValidateHost(client, pool, hs); err != nil {
+		return err
+	}
+
+	// Start building the spec
+	spec := types.VirtualMachineRelocateSpec{
+		Pool: types.NewReference(pool.Reference()),
+	}
+
+	// Fetch the datastore
+	if dsID, ok := d.GetOk("datastore_id"); ok {
+		ds, err := datastore.FromID(client, dsID.(string))
+		if err != nil {
+			return fmt.Errorf("error locating datastore for VM: %s", err)
+		}
+		spec.Datastore = types.NewReference(ds.Reference())
+	}
+
+	if hs != nil {
+		hsRef := hs.Reference()
+		spec.Host = &hsRef
+	}
+
+	spec.Disk = relocators
+
+	// Keep the virtual machine home on its storage policy while it moves.
+	if policyID := d.Get("storage_policy_id").(string); policyID != "" {
+		spec.Profile = spbm.PolicySpecByID(policyID)
+	}
+
+	// Ready to perform migration
+	timeout := d.Get("migrate_wait_timeout").(int)
+	if _, ok := d.GetOk("datastore_cluster_id"); ok {
+		err = resourceVSphereVirtualMachineUpdateLocationRelocateWithSDRS(d, meta, vm, spec, timeout)
+	} else {
+		err = virtualmachine.Relocate(vm, spec, timeout)
+	}
+	return err
+}
+
+// resourceVSphereVirtualMachineUpdateLocationRelocateWithSDRS runs the storage vMotion
+// part of resourceVSphereVirtualMachineUpdateLocation through storage DRS.
+// It's designed to be run when a storage cluster is specified, versus simply
+// specifying datastores.
+func resourceVSphereVirtualMachineUpdateLocationRelocateWithSDRS(
+	d *schema.ResourceData,
+	meta interface{},
+	vm *object.VirtualMachine,
+	spec types.VirtualMachineRelocateSpec,
+	timeout int,
+) error {
+	client := resourceVSphereVirtualMachineClient(meta)
 	if err := viapi.ValidateVirtualCenter(client); err != nil {
 		return fmt.Errorf("connection ineligible to use datastore_cluster_id: %s", err)
 	}
@@ -1300,16 +2153,440 @@ func resourceVSphereVirtualMachineUpdateLocationRelocateWithSDRS(
 	return nil
 }
 
+// readVirtualMachineStoragePolicies reads the IDs of the storage policies
+// assigned to the virtual machine home and to each of its virtual disks. The
+// disk sub-resources need to have been refreshed before this is called, so
+// that their device keys are current.
+//
+// Only the policies that are set in state are read, so that virtual machines
+// that do not use storage policies do not make any calls to the storage policy
+// service. Errors are logged rather than returned, leaving the policy in state
+// as is, as the storage policy service being unavailable should not stop the
+// virtual machine from being refreshed.
+func readVirtualMachineStoragePolicies(d *schema.ResourceData, meta interface{}, vm *object.VirtualMachine) {
+	disks := d.Get("disk").([]interface{})
+	var keys []int
+	for _, v := range disks {
+		m := v.(map[string]interface{})
+		if key, ok := m["key"].(int); ok && key > 0 && m["storage_policy_id"].(string) != "" {
+			keys = append(keys, key)
+		}
+	}
+	if d.Get("storage_policy_id").(string) == "" && len(keys) < 1 {
+		return
+	}
+	client, err := meta.(*VSphereClient).PbmClient()
+	if err != nil {
+		log.Printf("[WARN] %s: Could not read storage policies: %s", resourceVSphereVirtualMachineIDString(d), err)
+		return
+	}
+
+	moid := vm.Reference().Value
+	if d.Get("storage_policy_id").(string) != "" {
+		policyID, err := spbm.PolicyIDByVirtualMachine(client, moid)
+		if err != nil {
+			log.Printf("[WARN] %s: Could not read storage policy: %s", resourceVSphereVirtualMachineIDString(d), err)
+		} else {
+			d.Set("storage_policy_id", policyID)
+		}
+	}
+	if len(keys) < 1 {
+		return
+	}
+	for _, v := range disks {
+		m := v.(map[string]interface{})
+		key, ok := m["key"].(int)
+		if !ok || key < 1 || m["storage_policy_id"].(string) == "" {
+			continue
+		}
+		policyID, err := spbm.PolicyIDByVirtualDisk(client, moid, key)
+		if err != nil {
+			log.Printf("[WARN] %s: Could not read storage policy of disk with key %d: %s", resourceVSphereVirtualMachineIDString(d), key, err)
+			continue
+		}
+		m["storage_policy_id"] = policyID
+	}
+	d.Set("disk", disks)
+}
+
+// resourceVSphereVirtualMachineApplyPowerState brings the virtual machine to
+// the state in power_state. Powering off attempts a guest shutdown first, and
+// falls back to a hard power-off if force_power_off is set.
+func resourceVSphereVirtualMachineApplyPowerState(d *schema.ResourceData, meta interface{}, vm *object.VirtualMachine) error {
+	client := resourceVSphereVirtualMachineClient(meta)
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return fmt.Errorf("error fetching VM properties: %s", err)
+	}
+	current := vprops.Runtime.PowerState
+	desired := d.Get("power_state").(string)
+	log.Printf("[DEBUG] %s: Power state is %q, want %q", resourceVSphereVirtualMachineIDString(d), current, desired)
+
+	switch desired {
+	case virtualMachinePowerStateOn:
+		// Powering on also resumes a suspended VM.
+		if current != types.VirtualMachinePowerStatePoweredOn {
+			if err := virtualmachine.PowerOn(vm); err != nil {
+				return fmt.Errorf("error powering on virtual machine: %s", err)
+			}
+		}
+	case virtualMachinePowerStateOff:
+		switch current {
+		case types.VirtualMachinePowerStateSuspended:
+			// A suspended VM has no running guest to shut down, so it's always
+			// powered off directly, regardless of force_power_off.
+			if err := virtualmachine.PowerOff(vm); err != nil {
+				return fmt.Errorf("error powering off virtual machine: %s", err)
+			}
+		case types.VirtualMachinePowerStatePoweredOn:
+			timeout := d.Get("shutdown_wait_timeout").(int)
+			force := d.Get("force_power_off").(bool)
+			if err := virtualmachine.GracefulPowerOff(client, vm, timeout, force); err != nil {
+				return fmt.Errorf("error shutting down virtual machine: %s", err)
+			}
+		}
+	case virtualMachinePowerStateSuspended:
+		// Only running VMs can be suspended.
+		if current == types.VirtualMachinePowerStatePoweredOff {
+			if err := virtualmachine.PowerOn(vm); err != nil {
+				return fmt.Errorf("error powering on virtual machine: %s", err)
+			}
+		}
+		if current != types.VirtualMachinePowerStateSuspended {
+			if err := virtualmachine.Suspend(vm); err != nil {
+				return fmt.Errorf("error suspending virtual machine: %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// flattenVirtualMachinePowerState converts a VM power state to its
+// power_state value.
+func flattenVirtualMachinePowerState(state types.VirtualMachinePowerState) string {
+	switch state {
+	case types.VirtualMachinePowerStatePoweredOff:
+		return virtualMachinePowerStateOff
+	case types.VirtualMachinePowerStateSuspended:
+		return virtualMachinePowerStateSuspended
+	}
+	return virtualMachinePowerStateOn
+}
+
+// resourceVSphereVirtualMachineApplyCloudInit delivers the data in the
+// cloud_init sub-resource to the virtual machine, either through guestinfo
+// keys or a NoCloud seed ISO in a client CDROM device. This happens after the
+// virtual machine has been created, as the default metadata and generated
+// network configuration depend on its UUID and MAC addresses.
+//
+// None of these changes require a reboot. Removing cloud_init, or switching
+// datasources, clears the guestinfo keys and ejects the seed ISO.
+func resourceVSphereVirtualMachineApplyCloudInit(d *schema.ResourceData, meta interface{}) error {
+	log.Printf("[DEBUG] %s: Applying cloud-init data", resourceVSphereVirtualMachineIDString(d))
+	client := resourceVSphereVirtualMachineClient(meta)
+	vm, err := virtualmachine.FromUUID(client, d.Id())
+	if err != nil {
+		return fmt.Errorf("cannot locate virtual machine with UUID %q: %s", d.Id(), err)
+	}
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return fmt.Errorf("error fetching VM properties: %s", err)
+	}
+	devices := object.VirtualDeviceList(vprops.Config.Hardware.Device)
+
+	var data *vmworkflow.CloudInitData
+	if len(d.Get("cloud_init").([]interface{})) > 0 {
+		data, err = vmworkflow.ExpandCloudInitData(d, vprops.Config.Uuid, vprops.Name, virtualMachineMacAddresses(devices))
+		if err != nil {
+			return err
+		}
+	}
+	datasource := d.Get("cloud_init.0.datasource").(string)
+
+	spec := types.VirtualMachineConfigSpec{}
+	guestInfo := data
+	if datasource != cloudinit.DatasourceGuestInfo {
+		guestInfo = nil
+	}
+	if spec.ExtraConfig, err = guestInfo.GuestInfo(); err != nil {
+		return err
+	}
+
+	cdrom, oldSeed := virtualdevice.CloudInitSeedCdrom(devices)
+	var newSeed string
+	if data != nil && datasource == cloudinit.DatasourceNoCloud {
+		if cdrom == nil {
+			return errors.New("cannot find a client CDROM device to insert the cloud-init seed ISO into")
+		}
+		if newSeed, err = uploadCloudInitSeedIso(client, vm, vprops.Config.Files.VmPathName, data); err != nil {
+			return err
+		}
+		if newSeed != oldSeed {
+			cdrom = devices.InsertIso(cdrom, newSeed)
+			devices.Connect(cdrom)
+			cs, err := object.VirtualDeviceList{cdrom}.ConfigSpec(types.VirtualDeviceConfigSpecOperationEdit)
+			if err != nil {
+				return err
+			}
+			spec.DeviceChange = cs
+		}
+	} else if oldSeed != "" {
+		cdrom.Backing = &types.VirtualCdromRemoteAtapiBackingInfo{
+			VirtualDeviceRemoteDeviceBackingInfo: types.VirtualDeviceRemoteDeviceBackingInfo{},
+		}
+		cs, err := object.VirtualDeviceList{cdrom}.ConfigSpec(types.VirtualDeviceConfigSpecOperationEdit)
+		if err != nil {
+			return err
+		}
+		spec.DeviceChange = cs
+	}
+
+	if err := virtualmachine.Reconfigure(vm, spec); err != nil {
+		return fmt.Errorf("error applying cloud-init data: %s", err)
+	}
+	if oldSeed != "" && oldSeed != newSeed {
+		if err := deleteCloudInitSeedIso(client, vm, oldSeed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resourceVSphereVirtualMachineWaitForGuest waits for the guest of a powered
+// on virtual machine to be ready. This waits for an available IP address as
+// set by the wait_for_guest_net settings, and then for the conditions in the
+// wait_for_guest block, if any.
+func resourceVSphereVirtualMachineWaitForGuest(d *schema.ResourceData, client *govmomi.Client, vm *object.VirtualMachine) error {
+	err := virtualmachine.WaitForGuestNet(
+		client,
+		vm,
+		d.Get("wait_for_guest_net_routable").(bool),
+		d.Get("wait_for_guest_net_timeout").(int),
+	)
+	if err != nil {
+		return err
+	}
+	if len(d.Get("wait_for_guest").([]interface{})) < 1 {
+		return nil
+	}
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return fmt.Errorf("error fetching VM properties: %s", err)
+	}
+	conditions, err := expandGuestWaitConditions(d, object.VirtualDeviceList(vprops.Config.Hardware.Device))
+	if err != nil {
+		return err
+	}
+	return virtualmachine.WaitForGuestConditions(client, vm, conditions, d.Get("wait_for_guest.0.timeout").(int))
+}
+
+// resourceVSphereVirtualMachineReapplyCustomization customizes an existing
+// virtual machine with the settings in the customize block of the clone
+// sub-resource. Customization can only be run on a powered off virtual
+// machine and happens on the next boot, so the virtual machine is shut down,
+// customized, and powered on to wait for the customization to complete. The
+// power state is reconciled with power_state afterwards.
+func resourceVSphereVirtualMachineReapplyCustomization(d *schema.ResourceData, meta interface{}, vm *object.VirtualMachine) error {
+	log.Printf("[DEBUG] %s: Re-applying guest OS customization", resourceVSphereVirtualMachineIDString(d))
+	client := resourceVSphereVirtualMachineClient(meta)
+	poolID := d.Get("resource_pool_id").(string)
+	pool, err := resourcepool.FromID(client, poolID)
+	if err != nil {
+		return fmt.Errorf("could not find resource pool ID %q: %s", poolID, err)
+	}
+	family, err := resourcepool.OSFamily(client, pool, d.Get("guest_id").(string))
+	if err != nil {
+		return fmt.Errorf("cannot find OS family for guest ID %q: %s", d.Get("guest_id").(string), err)
+	}
+	custSpec, err := resourceVSphereVirtualMachineExpandCustomizationSpec(d, client, family)
+	if err != nil {
+		return err
+	}
+
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return fmt.Errorf("error fetching VM properties: %s", err)
+	}
+	if vprops.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOff {
+		timeout := d.Get("shutdown_wait_timeout").(int)
+		force := d.Get("force_power_off").(bool)
+		if err := virtualmachine.GracefulPowerOff(client, vm, timeout, force); err != nil {
+			return fmt.Errorf("error shutting down virtual machine: %s", err)
+		}
+	}
+	cw := newVirtualMachineCustomizationWaiter(client, vm, d.Get("clone.0.customize.0.timeout").(int))
+	if err := virtualmachine.Customize(client, vm, custSpec); err != nil {
+		return fmt.Errorf("error sending customization spec: %s", err)
+	}
+	if err := virtualmachine.PowerOn(vm); err != nil {
+		return fmt.Errorf("error powering on virtual machine: %s", err)
+	}
+	log.Printf("[DEBUG] %s: Waiting for VM customization to complete", resourceVSphereVirtualMachineIDString(d))
+	<-cw.Done()
+	if err := cw.Err(); err != nil {
+		return fmt.Errorf(formatVirtualMachineCustomizationReapplyWaitError, vm.InventoryPath, customizationDiagnosticsError(d, client, vm, err))
+	}
+	log.Printf("[DEBUG] %s: Guest OS customization complete", resourceVSphereVirtualMachineIDString(d))
+	return nil
+}
+
+// resourceVSphereVirtualMachineExpandCustomizationSpec returns the
+// customization spec for the customize block of the clone sub-resource. If
+// spec_name is set, the settings in the block are merged over the stored
+// customization spec with that name. The run once commands of the spec are
+// rendered with the data of the virtual machine.
+func resourceVSphereVirtualMachineExpandCustomizationSpec(d *schema.ResourceData, client *govmomi.Client, family string) (types.CustomizationSpec, error) {
+	var spec types.CustomizationSpec
+	if name := d.Get("clone.0.customize.0.spec_name").(string); name != "" {
+		item, err := customizationspec.FromName(client, name)
+		if err != nil {
+			return types.CustomizationSpec{}, fmt.Errorf("cannot locate customization spec %q: %s", name, err)
+		}
+		spec = vmworkflow.MergeCustomizationSpec(d, family, item.Spec)
+	} else {
+		spec = vmworkflow.ExpandCustomizationSpec(d, family)
+	}
+	if err := vmworkflow.RenderRunOnceCommands(d, &spec); err != nil {
+		return types.CustomizationSpec{}, err
+	}
+	return spec, nil
+}
+
+// resourceVSphereVirtualMachineApplyBootOrder sets the boot order of the
+// virtual machine to boot_order. This happens after the devices of the virtual
+// machine have been created or reconfigured, as the boot order references the
+// disks and network interfaces by their device keys.
+//
+// Changes to the boot order do not require a reboot, and take effect on the
+// next boot of the virtual machine.
+func resourceVSphereVirtualMachineApplyBootOrder(d *schema.ResourceData, meta interface{}) error {
+	log.Printf("[DEBUG] %s: Applying boot order", resourceVSphereVirtualMachineIDString(d))
+	client := resourceVSphereVirtualMachineClient(meta)
+	vm, err := virtualmachine.FromUUID(client, d.Id())
+	if err != nil {
+		return fmt.Errorf("cannot locate virtual machine with UUID %q: %s", d.Id(), err)
+	}
+	vprops, err := virtualmachine.Properties(vm)
+	if err != nil {
+		return fmt.Errorf("error fetching VM properties: %s", err)
+	}
+	order, err := virtualdevice.ExpandBootOrder(d, object.VirtualDeviceList(vprops.Config.Hardware.Device))
+	if err != nil {
+		return fmt.Errorf("error in boot order: %s", err)
+	}
+	spec := types.VirtualMachineConfigSpec{
+		BootOptions: &types.VirtualMachineBootOptions{
+			BootOrder: order,
+		},
+	}
+	if err := virtualmachine.Reconfigure(vm, spec); err != nil {
+		return fmt.Errorf("error applying boot order: %s", err)
+	}
+	return nil
+}
+
+// virtualMachineMacAddresses returns the MAC addresses of the network
+// interfaces in the device list, in the order of their device keys, which
+// matches the order of the network_interface sub-resources.
+func virtualMachineMacAddresses(l object.VirtualDeviceList) []string {
+	nics := l.SelectByType((*types.VirtualEthernetCard)(nil))
+	sort.Slice(nics, func(i, j int) bool { return nics[i].GetVirtualDevice().Key < nics[j].GetVirtualDevice().Key })
+	var macs []string
+	for _, nic := range nics {
+		macs = append(macs, nic.(types.BaseVirtualEthernetCard).GetVirtualEthernetCard().MacAddress)
+	}
+	return macs
+}
+
+// uploadCloudInitSeedIso uploads the NoCloud seed ISO for the cloud-init data
+// to the directory of the virtual machine, and returns its datastore path. The
+// file name includes a checksum of the image, so that an unchanged seed ISO is
+// not uploaded again.
+func uploadCloudInitSeedIso(client *govmomi.Client, vm *object.VirtualMachine, vmxPath string, data *vmworkflow.CloudInitData) (string, error) {
+	img, err := data.SeedISO()
+	if err != nil {
+		return "", err
+	}
+	vmx := &object.DatastorePath{}
+	if ok := vmx.FromString(vmxPath); !ok {
+		return "", fmt.Errorf("could not read datastore path %q", vmxPath)
+	}
+	sum := sha256.Sum256(img)
+	seed := &object.DatastorePath{
+		Datastore: vmx.Datastore,
+		Path:      path.Join(path.Dir(vmx.Path), virtualdevice.CloudInitSeedIsoFileName(hex.EncodeToString(sum[:4]))),
+	}
+	ds, err := virtualMachineDatastoreByName(client, vm, seed.Datastore)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[DEBUG] Uploading cloud-init seed ISO to %q", seed.String())
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	p := soap.DefaultUpload
+	p.ContentLength = int64(len(img))
+	if err := ds.Upload(ctx, bytes.NewReader(img), seed.Path, &p); err != nil {
+		return "", fmt.Errorf("error uploading cloud-init seed ISO: %s", err)
+	}
+	return seed.String(), nil
+}
+
+// deleteCloudInitSeedIso deletes a NoCloud seed ISO that is no longer in use.
+func deleteCloudInitSeedIso(client *govmomi.Client, vm *object.VirtualMachine, seedPath string) error {
+	seed := &object.DatastorePath{}
+	if ok := seed.FromString(seedPath); !ok {
+		return fmt.Errorf("could not read datastore path %q", seedPath)
+	}
+	ds, err := virtualMachineDatastoreByName(client, vm, seed.Datastore)
+	if err != nil {
+		return err
+	}
+	dc, err := getDatacenter(client, ds.DatacenterPath)
+	if err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Deleting old cloud-init seed ISO %q", seedPath)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+	defer cancel()
+	task, err := object.NewFileManager(client.Client).DeleteDatastoreFile(ctx, seedPath, dc)
+	if err != nil {
+		return err
+	}
+	if err := task.Wait(ctx); err != nil {
+		return fmt.Errorf("error deleting cloud-init seed ISO %q: %s", seedPath, err)
+	}
+	return nil
+}
+
+// virtualMachineDatastoreByName locates a datastore by name in the datacenter
+// of the supplied virtual machine. Datastores looked up through the finder
+// have their datacenter path set, which is needed for file transfers.
+func virtualMachineDatastoreByName(client *govmomi.Client, vm *object.VirtualMachine, name string) (*object.Datastore, error) {
+	dcp, err := folder.RootPathParticleVM.SplitDatacenter(vm.InventoryPath)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := getDatacenter(client, dcp)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := datastore.FromPath(client, name, dc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate datastore %q: %s", name, err)
+	}
+	return ds, nil
+}
+
 // applyVirtualDevices is used by Create and Update to build a list of virtual
 // device changes.
-func applyVirtualDevices(d *schema.ResourceData, c *govmomi.Client, l object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
+func applyVirtualDevices(d *schema.ResourceData, c *govmomi.Client, lc *contentlibrary.Client, l object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
 	// We filter this device list through each major device class' apply
 	// operation. This will give us a final set of changes that will be our
 	// deviceChange attribute.
 	var spec, delta []types.BaseVirtualDeviceConfigSpec
 	var err error
 	// First check the state of our SCSI bus. Normalize it if we need to.
-	l, delta, err = virtualdevice.NormalizeSCSIBus(l, d.Get("scsi_type").(string), d.Get("scsi_controller_count").(int))
+	l, delta, err = virtualdevice.NormalizeSCSIBus(l, d)
 	if err != nil {
 		return nil, err
 	}
@@ -1331,7 +2608,43 @@ func applyVirtualDevices(d *schema.ResourceData, c *govmomi.Client, l object.Vir
 	}
 	spec = virtualdevice.AppendDeviceChangeSpec(spec, delta...)
 	// CDROM
-	l, delta, err = virtualdevice.CdromApplyOperation(d, c, l)
+	l, delta, err = virtualdevice.CdromApplyOperation(d, c, lc, l)
+	if err != nil {
+		return nil, err
+	}
+	spec = virtualdevice.AppendDeviceChangeSpec(spec, delta...)
+	// Serial ports
+	l, delta, err = virtualdevice.SerialPortApplyOperation(d, c, l)
+	if err != nil {
+		return nil, err
+	}
+	spec = virtualdevice.AppendDeviceChangeSpec(spec, delta...)
+	// Parallel ports
+	l, delta, err = virtualdevice.ParallelPortApplyOperation(d, c, l)
+	if err != nil {
+		return nil, err
+	}
+	spec = virtualdevice.AppendDeviceChangeSpec(spec, delta...)
+	// USB controllers
+	l, delta, err = virtualdevice.USBControllerApplyOperation(d, l)
+	if err != nil {
+		return nil, err
+	}
+	spec = virtualdevice.AppendDeviceChangeSpec(spec, delta...)
+	// PCI passthrough devices
+	l, delta, err = virtualdevice.PCIDeviceApplyOperation(d, c, l)
+	if err != nil {
+		return nil, err
+	}
+	spec = virtualdevice.AppendDeviceChangeSpec(spec, delta...)
+	// Virtual TPM
+	l, delta, err = virtualdevice.VTPMApplyOperation(d, l)
+	if err != nil {
+		return nil, err
+	}
+	spec = virtualdevice.AppendDeviceChangeSpec(spec, delta...)
+	// Video card
+	l, delta, err = virtualdevice.VideoCardApplyOperation(d, l)
 	if err != nil {
 		return nil, err
 	}
@@ -1341,6 +2654,29 @@ func applyVirtualDevices(d *schema.ResourceData, c *govmomi.Client, l object.Vir
 	return spec, nil
 }
 
+// cdromContentLibraryClient returns the content library client if any of the
+// supplied cdrom sub-resources use a content library item, or nil otherwise.
+// This keeps the content library API, which requires vCenter, out of the
+// workflow of virtual machines that don't use it.
+func cdromContentLibraryClient(cdroms []interface{}, meta interface{}) (*contentlibrary.Client, error) {
+	for _, c := range cdroms {
+		if id, _ := c.(map[string]interface{})["content_library_item_id"].(string); id != "" {
+			return meta.(*VSphereClient).ContentLibraryClient()
+		}
+	}
+	return nil, nil
+}
+
+// resourceVSphereVirtualMachineClient returns the client to manage the
+// virtual machine with. Virtual machines are managed with a client that uses
+// the newest API version that the devices of the resource were added in, or
+// the version of the server if it is older, so that devices that were added
+// after the version of the vendored API bindings are sent and read back.
+func resourceVSphereVirtualMachineClient(meta interface{}) *govmomi.Client {
+	client := meta.(*VSphereClient).vimClient
+	return viapi.VersionedClient(client, virtualdevice.APIVersion(client))
+}
+
 // resourceVSphereVirtualMachineIDString prints a friendly string for the
 // vsphere_virtual_machine resource.
 func resourceVSphereVirtualMachineIDString(d structure.ResourceIDStringer) string {
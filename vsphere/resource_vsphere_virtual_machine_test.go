@@ -1,9 +1,14 @@
 package vsphere
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path"
 	"reflect"
@@ -21,8 +26,12 @@ import (
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/storagepod"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualdisk"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/virtualdevice"
+	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -265,6 +274,322 @@ func TestAccResourceVSphereVirtualMachine_multiDevice(t *testing.T) {
 	})
 }
 
+func TestAccResourceVSphereVirtualMachine_diskControllerTypes(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigDiskControllerTypes(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "disk.0.controller_type", "scsi"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "disk.1.controller_type", "sata"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "disk.2.controller_type", "nvme"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "disk.2.unit_number", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_scsiControllerSharing(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigSCSIControllerSharing(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "scsi_type", "pvscsi"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "scsi_controller.0.type", "lsilogic-sas"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "scsi_controller.0.sharing", "virtualSharing"),
+					testAccResourceVSphereVirtualMachineCheckDiskBus("terraform-test_1.vmdk", 1, 0),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_rawDeviceMapping(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+			if os.Getenv("VSPHERE_RDM_LUN_NAME") == "" {
+				t.Skip("set VSPHERE_RDM_LUN_NAME to run vsphere_virtual_machine raw device mapping acceptance tests")
+			}
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigRawDeviceMapping(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "disk.1.rdm.0.lun_name", os.Getenv("VSPHERE_RDM_LUN_NAME")),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "disk.1.rdm.0.compatibility_mode", "physicalMode"),
+					resource.TestCheckResourceAttrSet("vsphere_virtual_machine.vm", "disk.1.rdm.0.lun_uuid"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_serialPort(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigSerialPort(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "serial_port.#", "2"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "serial_port.0.backing_type", "network"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "serial_port.0.service_uri", "telnet://:9001"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "serial_port.1.backing_type", "file"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "serial_port.1.path", "terraform-test/serial.log"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_parallelPort(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigParallelPort(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "parallel_port.#", "1"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "parallel_port.0.backing_type", "file"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "parallel_port.0.path", "terraform-test/parallel.log"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_usbController(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigUSBController("xhci"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "usb_controller.#", "1"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "usb_controller.0.type", "xhci"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigUSBController("ehci"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "usb_controller.#", "1"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "usb_controller.0.type", "ehci"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_vtpmEncryption(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+			testAccResourceVSphereKeyProviderPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigVTPMEncryption(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "vtpm", "true"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "encryption.0.key_provider_id", "terraform-test-kms"),
+					resource.TestCheckResourceAttrSet("vsphere_virtual_machine.vm", "encryption.0.key_id"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "disk.0.encryption.0.key_provider_id", "terraform-test-kms"),
+					resource.TestCheckResourceAttrSet("vsphere_virtual_machine.vm", "disk.0.encryption.0.key_id"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_vbsAndCPUIDMask(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigVBSAndCPUIDMask(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "vbs_enabled", "true"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "vvtd_enabled", "true"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "vnuma_max_vcpus_per_node", "2"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "cpuid_mask.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_advancedSettingInExtraConfig(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigAdvancedSettingInExtraConfig(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "vnuma_max_vcpus_per_node", "0"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "extra_config.numa.vcpu.maxPerVirtualNode", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_bootOrder(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigBootOrder(`"network_interface:0", "disk:disk0"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "boot_order.#", "2"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "boot_order.0", "network_interface:0"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "network_boot_protocol", "ipv4"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigBootOrder(`"disk:disk1", "disk:disk0", "network_interface:0"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "boot_order.#", "3"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "boot_order.0", "disk:disk1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_videoCard(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigBasic(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "video_card.#", "1"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigVideoCard(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "video_card.0.video_ram_size_kb", "16384"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "video_card.0.num_displays", "2"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "video_card.0.enable_3d", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_cloneWaitForGuest(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneWaitForGuest(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "default_ip_address", os.Getenv("VSPHERE_IPV4_ADDRESS")),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_multipleCdroms(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigMultipleCdroms(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "cdrom.#", "2"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "cdrom.0.path", os.Getenv("VSPHERE_ISO_FILE")),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "cdrom.1.client_device", "true"),
+					resource.TestCheckResourceAttrSet("vsphere_virtual_machine.vm", "cdrom.1.device_address"),
+				),
+			},
+		},
+	})
+}
+
 func TestAccResourceVSphereVirtualMachine_addDevices(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck: func() {
@@ -1304,7 +1629,7 @@ func TestAccResourceVSphereVirtualMachine_cloneCustomizeForceNew(t *testing.T) {
 	})
 }
 
-func TestAccResourceVSphereVirtualMachine_cloneCustomizeForceNewWithDatastore(t *testing.T) {
+func TestAccResourceVSphereVirtualMachine_cloneCustomizeReapply(t *testing.T) {
 	var state *terraform.State
 
 	resource.Test(t, resource.TestCase{
@@ -1316,39 +1641,29 @@ func TestAccResourceVSphereVirtualMachine_cloneCustomizeForceNewWithDatastore(t
 		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
 		Steps: []resource.TestStep{
 			{
-				Config: testAccResourceVSphereVirtualMachineConfigCloneParameterized(
-					os.Getenv("VSPHERE_DATASTORE"),
-					"terraform-test",
-				),
+				Config: testAccResourceVSphereVirtualMachineConfigCloneCustomizeReapply("terraform-test"),
 				Check: resource.ComposeTestCheckFunc(
 					copyState(&state),
 					testAccResourceVSphereVirtualMachineCheckExists(true),
 					testAccResourceVSphereVirtualMachineCheckHostname("terraform-test"),
-					testAccResourceVSphereVirtualMachineCheckVmxDatastore(os.Getenv("VSPHERE_DATASTORE")),
-					testAccResourceVSphereVirtualMachineCheckVmdkDatastore("terraform-test.vmdk", os.Getenv("VSPHERE_DATASTORE")),
 				),
 			},
 			{
-				Config: testAccResourceVSphereVirtualMachineConfigCloneParameterized(
-					os.Getenv("VSPHERE_DATASTORE2"),
-					"terraform-test-renamed",
-				),
+				Config: testAccResourceVSphereVirtualMachineConfigCloneCustomizeReapply("terraform-test-reapply"),
 				Check: resource.ComposeTestCheckFunc(
 					testAccResourceVSphereVirtualMachineCheckExists(true),
-					testAccResourceVSphereVirtualMachineCheckHostname("terraform-test-renamed"),
-					testAccResourceVSphereVirtualMachineCheckVmxDatastore(os.Getenv("VSPHERE_DATASTORE2")),
-					testAccResourceVSphereVirtualMachineCheckVmdkDatastore("terraform-test.vmdk", os.Getenv("VSPHERE_DATASTORE2")),
 					func(s *terraform.State) error {
 						oldID := state.RootModule().Resources["vsphere_virtual_machine.vm"].Primary.ID
-						return testCheckResourceNotAttr("vsphere_virtual_machine.vm", "id", oldID)(s)
+						return resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "id", oldID)(s)
 					},
+					testAccResourceVSphereVirtualMachineCheckHostname("terraform-test-reapply"),
 				),
 			},
 		},
 	})
 }
 
-func TestAccResourceVSphereVirtualMachine_datastoreClusterClone(t *testing.T) {
+func TestAccResourceVSphereVirtualMachine_cloneCustomizeSpecName(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck: func() {
 			testAccPreCheck(t)
@@ -1358,7 +1673,72 @@ func TestAccResourceVSphereVirtualMachine_datastoreClusterClone(t *testing.T) {
 		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
 		Steps: []resource.TestStep{
 			{
-				Config: testAccResourceVSphereVirtualMachineConfigCloneDatastoreCluster(),
+				Config: testAccResourceVSphereVirtualMachineConfigCloneCustomizeSpecName(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckHostname("terraform-test-stored"),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "default_ip_address", os.Getenv("VSPHERE_IPV4_ADDRESS")),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_cloneCustomizeForceNewWithDatastore(t *testing.T) {
+	var state *terraform.State
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneParameterized(
+					os.Getenv("VSPHERE_DATASTORE"),
+					"terraform-test",
+				),
+				Check: resource.ComposeTestCheckFunc(
+					copyState(&state),
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckHostname("terraform-test"),
+					testAccResourceVSphereVirtualMachineCheckVmxDatastore(os.Getenv("VSPHERE_DATASTORE")),
+					testAccResourceVSphereVirtualMachineCheckVmdkDatastore("terraform-test.vmdk", os.Getenv("VSPHERE_DATASTORE")),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneParameterized(
+					os.Getenv("VSPHERE_DATASTORE2"),
+					"terraform-test-renamed",
+				),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckHostname("terraform-test-renamed"),
+					testAccResourceVSphereVirtualMachineCheckVmxDatastore(os.Getenv("VSPHERE_DATASTORE2")),
+					testAccResourceVSphereVirtualMachineCheckVmdkDatastore("terraform-test.vmdk", os.Getenv("VSPHERE_DATASTORE2")),
+					func(s *terraform.State) error {
+						oldID := state.RootModule().Resources["vsphere_virtual_machine.vm"].Primary.ID
+						return testCheckResourceNotAttr("vsphere_virtual_machine.vm", "id", oldID)(s)
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_datastoreClusterClone(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneDatastoreCluster(),
 				Check: resource.ComposeTestCheckFunc(
 					testAccResourceVSphereVirtualMachineCheckExists(true),
 					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "default_ip_address", os.Getenv("VSPHERE_IPV4_ADDRESS")),
@@ -2638,6 +3018,224 @@ func TestAccResourceVSphereVirtualMachine_importClone(t *testing.T) {
 	})
 }
 
+func TestAccResourceVSphereVirtualMachine_cloneFromContentLibrary(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+			if os.Getenv("VSPHERE_CONTENT_LIBRARY_ITEM_ID") == "" {
+				t.Skip("set VSPHERE_CONTENT_LIBRARY_ITEM_ID to run vsphere_virtual_machine content library clone acceptance tests")
+			}
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneFromContentLibrary(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestMatchResourceAttr("vsphere_virtual_machine.vm", "moid", regexp.MustCompile("^vm-")),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_ovfDeploy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+			if os.Getenv("VSPHERE_OVF_URL") == "" {
+				t.Skip("set VSPHERE_OVF_URL to run vsphere_virtual_machine OVF deploy acceptance tests")
+			}
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigOvfDeploy(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestMatchResourceAttr("vsphere_virtual_machine.vm", "moid", regexp.MustCompile("^vm-")),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_storagePolicy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+			testAccDataSourceVSphereStoragePolicyPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigStoragePolicy(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestCheckResourceAttrPair(
+						"vsphere_virtual_machine.vm", "storage_policy_id",
+						"data.vsphere_storage_policy.policy", "id",
+					),
+					resource.TestCheckResourceAttrPair(
+						"vsphere_virtual_machine.vm", "disk.0.storage_policy_id",
+						"data.vsphere_storage_policy.policy", "id",
+					),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_cloudInitGuestInfo(t *testing.T) {
+	userData := "#cloud-config\nhostname: terraform-test\n"
+	updated := "#cloud-config\nhostname: terraform-test-updated\n"
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloudInit(userData),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckExtraConfig("guestinfo.userdata", base64.StdEncoding.EncodeToString([]byte(userData))),
+					testAccResourceVSphereVirtualMachineCheckExtraConfig("guestinfo.userdata.encoding", "base64"),
+					testAccResourceVSphereVirtualMachineCheckExtraConfig("guestinfo.metadata.encoding", "base64"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloudInit(updated),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckPowerOffEvent(false),
+					testAccResourceVSphereVirtualMachineCheckExtraConfig("guestinfo.userdata", base64.StdEncoding.EncodeToString([]byte(updated))),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloudInit(""),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckExtraConfigKeyMissing("guestinfo.userdata"),
+					testAccResourceVSphereVirtualMachineCheckExtraConfigKeyMissing("guestinfo.metadata"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_powerState(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigPowerState("off"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckPowerState(types.VirtualMachinePowerStatePoweredOff),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "power_state", "off"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigPowerState("suspended"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckPowerState(types.VirtualMachinePowerStateSuspended),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "power_state", "suspended"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigPowerState("off"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckPowerState(types.VirtualMachinePowerStatePoweredOff),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "power_state", "off"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigPowerState("on"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckPowerState(types.VirtualMachinePowerStatePoweredOn),
+					resource.TestCheckResourceAttr("vsphere_virtual_machine.vm", "power_state", "on"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_cloneInstant(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+			if os.Getenv("VSPHERE_INSTANT_CLONE_SOURCE") == "" {
+				t.Skip("set VSPHERE_INSTANT_CLONE_SOURCE to run vsphere_virtual_machine instant clone acceptance tests")
+			}
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		// The source virtual machine is expected to have 2 vCPUs and 2048 MB of
+		// memory, without CPU or memory hot add.
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceVSphereVirtualMachineConfigCloneInstant(4, 4096),
+				ExpectError: regexp.MustCompile("num_cpus cannot be increased from 2 to 4 on an instant clone"),
+				PlanOnly:    true,
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneInstant(2, 2048),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckPowerState(types.VirtualMachinePowerStatePoweredOn),
+					testAccResourceVSphereVirtualMachineCheckExtraConfig("guestinfo.terraform.clone", "instant"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneInstant(4, 4096),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					testAccResourceVSphereVirtualMachineCheckPowerState(types.VirtualMachinePowerStatePoweredOn),
+					testAccResourceVSphereVirtualMachineCheckCPUMem(4, 4096),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVirtualMachine_cloneFromSnapshotName(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccResourceVSphereVirtualMachinePreCheck(t)
+			if os.Getenv("VSPHERE_TEMPLATE_SNAPSHOT_NAME") == "" {
+				t.Skip("set VSPHERE_TEMPLATE_SNAPSHOT_NAME to run vsphere_virtual_machine clone from snapshot acceptance tests")
+			}
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVirtualMachineCheckExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVirtualMachineConfigCloneSnapshotName(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVirtualMachineCheckExists(true),
+					resource.TestMatchResourceAttr("vsphere_virtual_machine.vm", "clone_snapshot_id", regexp.MustCompile("^snapshot-")),
+				),
+			},
+		},
+	})
+}
+
 func testAccResourceVSphereVirtualMachinePreCheck(t *testing.T) {
 	// Note that VSPHERE_USE_LINKED_CLONE is also a variable and its presence
 	// speeds up tests greatly, but it's not a necessary variable, so we don't
@@ -4018,7 +4616,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigMultiHighBusInsufficientBus() string {
+func testAccResourceVSphereVirtualMachineConfigDiskControllerTypes() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4060,25 +4658,12 @@ resource "vsphere_virtual_machine" "vm" {
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  scsi_controller_count = 1
-
   num_cpus = 2
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "normal"
-  }
-
-  network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "high"
-  }
-
-  network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "low"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
@@ -4087,15 +4672,16 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label       = "disk1"
-    unit_number = 15
-    size        = 10
+    label           = "disk1"
+    controller_type = "sata"
+    size            = 10
   }
 
   disk {
-    label       = "disk2"
-    unit_number = 31
-    size        = 5
+    label           = "disk2"
+    controller_type = "nvme"
+    unit_number     = 1
+    size            = 5
   }
 }
 `,
@@ -4106,7 +4692,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigMultiHighBus() string {
+func testAccResourceVSphereVirtualMachineConfigSCSIControllerSharing() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4148,25 +4734,20 @@ resource "vsphere_virtual_machine" "vm" {
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  scsi_controller_count = 3
-
   num_cpus = 2
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
-  network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "normal"
+  scsi_controller_count = 2
+
+  scsi_controller {
+    bus_number = 1
+    type       = "lsilogic-sas"
+    sharing    = "virtualSharing"
   }
 
   network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "high"
-  }
-
-  network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "low"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
@@ -4175,15 +4756,11 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label       = "disk1"
-    unit_number = 15
-    size        = 10
-  }
-
-  disk {
-    label       = "disk2"
-    unit_number = 31
-    size        = 5
+    label            = "disk1"
+    unit_number      = 15
+    size             = 10
+    eagerly_scrub    = true
+    thin_provisioned = false
   }
 }
 `,
@@ -4194,7 +4771,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigRemoveMiddle() string {
+func testAccResourceVSphereVirtualMachineConfigRawDeviceMapping() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4212,6 +4789,10 @@ variable "datastore" {
   default = "%s"
 }
 
+variable "lun_name" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -4241,13 +4822,7 @@ resource "vsphere_virtual_machine" "vm" {
   guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "normal"
-  }
-
-  network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "low"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
@@ -4256,9 +4831,12 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label       = "disk2"
-    unit_number = 2
-    size        = 5
+    label       = "disk1"
+    unit_number = 1
+
+    rdm {
+      lun_name = "${var.lun_name}"
+    }
   }
 }
 `,
@@ -4266,10 +4844,11 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_RDM_LUN_NAME"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigRemoveMiddleChangeUnit() string {
+func testAccResourceVSphereVirtualMachineConfigSerialPort() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4316,13 +4895,7 @@ resource "vsphere_virtual_machine" "vm" {
   guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "normal"
-  }
-
-  network_interface {
-    network_id            = "${data.vsphere_network.network.id}"
-    bandwidth_share_level = "low"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
@@ -4330,10 +4903,15 @@ resource "vsphere_virtual_machine" "vm" {
     size  = 20
   }
 
-  disk {
-    label       = "disk2"
-    unit_number = 1
-    size        = 5
+  serial_port {
+    backing_type = "network"
+    service_uri  = "telnet://:9001"
+  }
+
+  serial_port {
+    backing_type = "file"
+    datastore_id = "${data.vsphere_datastore.datastore.id}"
+    path         = "terraform-test/serial.log"
   }
 }
 `,
@@ -4344,7 +4922,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigIsoCdrom() string {
+func testAccResourceVSphereVirtualMachineConfigParallelPort() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4362,14 +4940,6 @@ variable "datastore" {
   default = "%s"
 }
 
-variable "iso_datastore" {
-  default = "%s"
-}
-
-variable "iso_path" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -4379,11 +4949,6 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_datastore" "iso_datastore" {
-  name          = "${var.iso_datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -4403,8 +4968,6 @@ resource "vsphere_virtual_machine" "vm" {
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
-  wait_for_guest_net_timeout = -1
-
   network_interface {
     network_id = "${data.vsphere_network.network.id}"
   }
@@ -4414,9 +4977,10 @@ resource "vsphere_virtual_machine" "vm" {
     size  = 20
   }
 
-  cdrom {
-    datastore_id = "${data.vsphere_datastore.iso_datastore.id}"
-    path         = "${var.iso_path}"
+  parallel_port {
+    backing_type = "file"
+    datastore_id = "${data.vsphere_datastore.datastore.id}"
+    path         = "terraform-test/parallel.log"
   }
 }
 `,
@@ -4424,12 +4988,10 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_ISO_DATASTORE"),
-		os.Getenv("VSPHERE_ISO_FILE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigIsoCdromCloneIsoVApp() string {
+func testAccResourceVSphereVirtualMachineConfigMultipleCdroms() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4443,39 +5005,15 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "cdrom_datastore" {
-  default = "%s"
-}
-
-variable "cdrom_iso" {
-  default = "%s"
-}
-
-variable "template" {
+variable "iso_datastore" {
   default = "%s"
 }
 
-variable "linked_clone" {
+variable "iso_path" {
   default = "%s"
 }
 
@@ -4488,6 +5026,11 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_datastore" "iso_datastore" {
+  name          = "${var.iso_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -4498,33 +5041,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "vsphere_datastore" "cdrom_datastore" {
-  name = "${var.cdrom_datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "template_file" "config_data" {
-	template = <<EOS
-#!/bin/sh
-cat > /etc/network/interfaces << EOT
-auto lo
-iface lo inet loopback
-
-auto ens192
-iface ens192 inet static
-    address ${var.ipv4_address}
-    netmask ${cidrnetmask("${var.ipv4_address}/${var.ipv4_netmask}")}
-    gateway ${var.ipv4_gateway}
-EOT
-reboot
-EOS
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -4532,56 +5048,39 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
 
-  wait_for_guest_net_timeout = 10
+  wait_for_guest_net_timeout = -1
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    label = "disk0"
+    size  = 20
   }
 
   cdrom {
-    datastore_id = "${data.vsphere_datastore.cdrom_datastore.id}"
-    path         = "${var.cdrom_iso}"
-  }
-
-  vapp {
-    properties {
-      "user-data" = "${base64encode(data.template_file.config_data.rendered)}"
-      "hostname"  = "custom-hostname"
-    }
+    datastore_id = "${data.vsphere_datastore.iso_datastore.id}"
+    path         = "${var.iso_path}"
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+  cdrom {
+    client_device = true
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_ISO_DATASTORE"),
 		os.Getenv("VSPHERE_ISO_FILE"),
-		os.Getenv("VSPHERE_TEMPLATE_ISO_TRANSPORT"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigNoCdromCloneIsoVApp() string {
+func testAccResourceVSphereVirtualMachineConfigUSBController(usbType string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4595,34 +5094,10 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -4642,28 +5117,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "template_file" "config_data" {
-	template = <<EOS
-#!/bin/sh
-cat > /etc/network/interfaces << EOT
-auto lo
-iface lo inet loopback
-
-auto ens192
-iface ens192 inet static
-    address ${var.ipv4_address}
-    netmask ${cidrnetmask("${var.ipv4_address}/${var.ipv4_netmask}")}
-    gateway ${var.ipv4_gateway}
-EOT
-reboot
-EOS
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -4671,49 +5124,31 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
-
-  wait_for_guest_net_timeout = 10
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  vapp {
-    properties {
-      "user-data" = "${base64encode(data.template_file.config_data.rendered)}"
-      "hostname"  = "custom-hostname"
-    }
+    label = "disk0"
+    size  = 20
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+  usb_controller {
+    type = "%s"
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE_ISO_TRANSPORT"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		usbType,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigClientCdromCloneIsoVApp() string {
+func testAccResourceVSphereVirtualMachineConfigVTPMEncryption() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4727,36 +5162,16 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "ipv4_netmask" {
+variable "kms_address" {
   default = "%s"
 }
 
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
-variable "datastore" {
-  default = "%s"
-}
-
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
-data "vsphere_datacenter" "dc" {
-  name = "${var.datacenter}"
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
 }
 
 data "vsphere_datastore" "datastore" {
@@ -4774,26 +5189,13 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "template_file" "config_data" {
-	template = <<EOS
-#!/bin/sh
-cat > /etc/network/interfaces << EOT
-auto lo
-iface lo inet loopback
+resource "vsphere_key_provider" "provider" {
+  name = "terraform-test-kms"
 
-auto ens192
-iface ens192 inet static
-    address ${var.ipv4_address}
-    netmask ${cidrnetmask("${var.ipv4_address}/${var.ipv4_netmask}")}
-    gateway ${var.ipv4_gateway}
-EOT
-reboot
-EOS
+  server {
+    name    = "terraform-test-kms-server"
+    address = "${var.kms_address}"
+  }
 }
 
 resource "vsphere_virtual_machine" "vm" {
@@ -4803,53 +5205,37 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "windows9_64Guest"
+  firmware = "efi"
+  vtpm     = true
 
-  wait_for_guest_net_timeout = 10
+  encryption {
+    key_provider_id = "${vsphere_key_provider.provider.id}"
+  }
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  cdrom {
-    client_device = true
-  }
+    label = "disk0"
+    size  = 20
 
-  vapp {
-    properties {
-      "user-data" = "${base64encode(data.template_file.config_data.rendered)}"
-      "hostname"  = "custom-hostname"
+    encryption {
+      key_provider_id = "${vsphere_key_provider.provider.id}"
     }
   }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE_ISO_TRANSPORT"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		os.Getenv("VSPHERE_KMS_ADDRESS"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigClientCdromClone() string {
+func testAccResourceVSphereVirtualMachineConfigVBSAndCPUIDMask() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -4863,34 +5249,10 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -4910,72 +5272,44 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
-
-  network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
-  }
+  num_cpus                 = 4
+  memory                   = 2048
+  guest_id                 = "windows9_64Guest"
+  firmware                 = "efi"
+  efi_secure_boot_enabled  = true
+  nested_hv_enabled        = true
+  vbs_enabled              = true
+  vvtd_enabled             = true
+  vnuma_max_vcpus_per_node = 2
 
-  disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  cpuid_mask {
+    level = 1
+    ecx   = "----:----:----:----:----:----:----:---0"
   }
 
-  cdrom {
-    client_device = true
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+  disk {
+    label = "disk0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigClientCdrom() string {
+func testAccResourceVSphereVirtualMachineConfigAdvancedSettingInExtraConfig() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5017,11 +5351,13 @@ resource "vsphere_virtual_machine" "vm" {
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus = 2
+  num_cpus = 4
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
-  wait_for_guest_net_timeout = -1
+  extra_config {
+    "numa.vcpu.maxPerVirtualNode" = "2"
+  }
 
   network_interface {
     network_id = "${data.vsphere_network.network.id}"
@@ -5031,10 +5367,6 @@ resource "vsphere_virtual_machine" "vm" {
     label = "disk0"
     size  = 20
   }
-
-  cdrom {
-    client_device = true
-  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
@@ -5044,7 +5376,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigNoCdromParameters() string {
+func testAccResourceVSphereVirtualMachineConfigBootOrder(order string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5089,9 +5421,13 @@ resource "vsphere_virtual_machine" "vm" {
   num_cpus = 2
   memory   = 2048
   guest_id = "other3xLinux64Guest"
+  firmware = "efi"
 
   wait_for_guest_net_timeout = -1
 
+  boot_order            = [%s]
+  network_boot_protocol = "ipv4"
+
   network_interface {
     network_id = "${data.vsphere_network.network.id}"
   }
@@ -5101,17 +5437,22 @@ resource "vsphere_virtual_machine" "vm" {
     size  = 20
   }
 
-  cdrom {}
+  disk {
+    label       = "disk1"
+    size        = 10
+    unit_number = 1
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		order,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigConflictingCdromParameters() string {
+func testAccResourceVSphereVirtualMachineConfigVideoCard() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5129,14 +5470,6 @@ variable "datastore" {
   default = "%s"
 }
 
-variable "iso_datastore" {
-  default = "%s"
-}
-
-variable "iso_path" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -5146,11 +5479,6 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_datastore" "iso_datastore" {
-  name          = "${var.iso_datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -5172,6 +5500,12 @@ resource "vsphere_virtual_machine" "vm" {
 
   wait_for_guest_net_timeout = -1
 
+  video_card {
+    video_ram_size_kb = 16384
+    num_displays      = 2
+    enable_3d         = true
+  }
+
   network_interface {
     network_id = "${data.vsphere_network.network.id}"
   }
@@ -5180,24 +5514,16 @@ resource "vsphere_virtual_machine" "vm" {
     label = "disk0"
     size  = 20
   }
-
-  cdrom {
-    datastore_id  = "${data.vsphere_datastore.iso_datastore.id}"
-    path          = "${var.iso_path}"
-    client_device = true
-  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_ISO_DATASTORE"),
-		os.Getenv("VSPHERE_ISO_FILE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigBeefy() string {
+func testAccResourceVSphereVirtualMachineConfigCloneCustomizeReapply(hostname string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5211,70 +5537,35 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "datastore" {
+variable "ipv4_address" {
   default = "%s"
 }
 
-data "vsphere_datacenter" "dc" {
-  name = "${var.datacenter}"
-}
-
-data "vsphere_datastore" "datastore" {
-  name          = "${var.datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "vsphere_resource_pool" "pool" {
-  name          = "${var.resource_pool}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+variable "ipv4_netmask" {
+  default = "%s"
 }
 
-data "vsphere_network" "network" {
-  name          = "${var.network_label}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+variable "ipv4_gateway" {
+  default = "%s"
 }
 
-resource "vsphere_virtual_machine" "vm" {
-  name             = "terraform-test"
-  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
-  datastore_id     = "${data.vsphere_datastore.datastore.id}"
-
-  num_cpus = 4
-  memory   = 8192
-  guest_id = "other3xLinux64Guest"
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
-
-  disk {
-    label = "disk0"
-    size  = 20
-  }
-}
-`,
-		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
-		os.Getenv("VSPHERE_DATASTORE"),
-	)
+variable "dns_server" {
+  default = "%s"
 }
 
-func testAccResourceVSphereVirtualMachineConfigMaxNIC() string {
-	return fmt.Sprintf(`
-variable "datacenter" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "resource_pool" {
+variable "template" {
   default = "%s"
 }
 
-variable "network_label" {
+variable "linked_clone" {
   default = "%s"
 }
 
-variable "datastore" {
+variable "hostname" {
   default = "%s"
 }
 
@@ -5297,6 +5588,11 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -5304,62 +5600,59 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
+    customize {
+      reapply_on_change = true
 
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
+      linux_options {
+        host_name = "${var.hostname}"
+        domain    = "test.internal"
+      }
 
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
 
-  disk {
-    label = "disk0"
-    size  = 20
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		hostname,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigBasicAnnotation() string {
+func testAccResourceVSphereVirtualMachineConfigCloneCustomizeSpecName() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5373,11 +5666,31 @@ variable "network_label" {
   default = "%s"
 }
 
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
 variable "datastore" {
   default = "%s"
 }
 
-variable "annotation" {
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
   default = "%s"
 }
 
@@ -5400,35 +5713,80 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_guest_os_customization" "spec" {
+  name = "terraform-test-stored"
+  type = "Linux"
+
+  spec {
+    linux_options {
+      host_name = "terraform-test-stored"
+      domain    = "test.internal"
+    }
+
+    network_interface {}
+
+    dns_server_list = ["${var.dns_server}"]
+    dns_suffix_list = ["test.internal"]
+  }
+}
+
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus   = 2
-  memory     = 2048
-  guest_id   = "other3xLinux64Guest"
-  annotation = "${var.annotation}"
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      spec_name = "${vsphere_guest_os_customization.spec.name}"
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway = "${var.ipv4_gateway}"
+    }
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		testAccResourceVSphereVirtualMachineAnnotation,
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigGrowDisk(size int) string {
+func testAccResourceVSphereVirtualMachineConfigCloneWaitForGuest() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5442,16 +5800,40 @@ variable "network_label" {
   default = "%s"
 }
 
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
 variable "datastore" {
   default = "%s"
 }
 
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
 data "vsphere_datastore" "datastore" {
-  name = "${var.datastore}"
+  name          = "${var.datastore}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -5465,6 +5847,11 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -5472,27 +5859,67 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  default_ip_address_cidr = "${var.ipv4_address}/32"
+
+  wait_for_guest {
+    network_interface {
+      cidrs = ["${var.ipv4_address}/32"]
+    }
+
+    tools_running_status = "guestToolsRunning"
+    heartbeat_status     = "green"
+  }
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = %d
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		size,
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigLsiLogicSAS() string {
+func testAccResourceVSphereVirtualMachineConfigMultiHighBusInsufficientBus() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5534,20 +5961,43 @@ resource "vsphere_virtual_machine" "vm" {
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
+  scsi_controller_count = 1
+
   num_cpus = 2
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
-  scsi_type = "lsilogic-sas"
+  network_interface {
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "normal"
+  }
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "high"
+  }
+
+  network_interface {
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "low"
   }
 
   disk {
     label = "disk0"
     size  = 20
   }
+
+  disk {
+    label       = "disk1"
+    unit_number = 15
+    size        = 10
+  }
+
+  disk {
+    label       = "disk2"
+    unit_number = 31
+    size        = 5
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
@@ -5557,7 +6007,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigExtraConfig(k, v string) string {
+func testAccResourceVSphereVirtualMachineConfigMultiHighBus() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5599,33 +6049,53 @@ resource "vsphere_virtual_machine" "vm" {
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
+  scsi_controller_count = 3
+
   num_cpus = 2
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
-  extra_config {
-    "%s" = "%s"
+  network_interface {
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "normal"
   }
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "high"
+  }
+
+  network_interface {
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "low"
   }
 
   disk {
     label = "disk0"
     size  = 20
   }
+
+  disk {
+    label       = "disk1"
+    unit_number = 15
+    size        = 10
+  }
+
+  disk {
+    label       = "disk2"
+    unit_number = 31
+    size        = 5
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		k, v,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigExistingVmdk() string {
+func testAccResourceVSphereVirtualMachineConfigRemoveMiddle() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5643,10 +6113,6 @@ variable "datastore" {
   default = "%s"
 }
 
-variable "extra_vmdk_name" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -5666,15 +6132,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_virtual_disk" "disk" {
-  size         = 1
-  vmdk_path    = "${var.extra_vmdk_name}"
-  datacenter   = "${var.datacenter}"
-  datastore    = "${var.datastore}"
-  type         = "thin"
-  adapter_type = "lsiLogic"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -5685,7 +6142,13 @@ resource "vsphere_virtual_machine" "vm" {
   guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "normal"
+  }
+
+  network_interface {
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "low"
   }
 
   disk {
@@ -5694,12 +6157,9 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label        = "disk1"
-    datastore_id = "${data.vsphere_datastore.datastore.id}"
-    path         = "${vsphere_virtual_disk.disk.vmdk_path}"
-    disk_mode    = "independent_persistent"
-    attach       = true
-    unit_number  = 1
+    label       = "disk2"
+    unit_number = 2
+    size        = 5
   }
 }
 `,
@@ -5707,11 +6167,10 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigInFolder() string {
+func testAccResourceVSphereVirtualMachineConfigRemoveMiddleChangeUnit() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5748,30 +6207,35 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_folder" "folder" {
-  path          = "terraform-test-vms"
-  type          = "vm"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
-  folder           = "${vsphere_folder.folder.path}"
 
   num_cpus = 2
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "normal"
+  }
+
+  network_interface {
+    network_id            = "${data.vsphere_network.network.id}"
+    bandwidth_share_level = "low"
   }
 
   disk {
     label = "disk0"
     size  = 20
   }
+
+  disk {
+    label       = "disk2"
+    unit_number = 1
+    size        = 5
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
@@ -5781,7 +6245,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStaticMAC() string {
+func testAccResourceVSphereVirtualMachineConfigIsoCdrom() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5799,6 +6263,14 @@ variable "datastore" {
   default = "%s"
 }
 
+variable "iso_datastore" {
+  default = "%s"
+}
+
+variable "iso_path" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -5808,6 +6280,11 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_datastore" "iso_datastore" {
+  name          = "${var.iso_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -5827,27 +6304,33 @@ resource "vsphere_virtual_machine" "vm" {
   memory   = 2048
   guest_id = "other3xLinux64Guest"
 
+  wait_for_guest_net_timeout = -1
+
   network_interface {
-    network_id     = "${data.vsphere_network.network.id}"
-    use_static_mac = true
-    mac_address    = "%s"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
     label = "disk0"
     size  = 20
   }
+
+  cdrom {
+    datastore_id = "${data.vsphere_datastore.iso_datastore.id}"
+    path         = "${var.iso_path}"
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		testAccResourceVSphereVirtualMachineStaticMacAddr,
+		os.Getenv("VSPHERE_ISO_DATASTORE"),
+		os.Getenv("VSPHERE_ISO_FILE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigSingleTag() string {
+func testAccResourceVSphereVirtualMachineConfigIsoCdromCloneIsoVApp() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5861,10 +6344,42 @@ variable "network_label" {
   default = "%s"
 }
 
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
 variable "datastore" {
   default = "%s"
 }
 
+variable "cdrom_datastore" {
+  default = "%s"
+}
+
+variable "cdrom_iso" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -5884,18 +6399,31 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_tag_category" "terraform-test-category" {
-  name        = "terraform-test-tag-category"
-  cardinality = "MULTIPLE"
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
 
-  associable_types = [
-    "VirtualMachine",
-  ]
+data "vsphere_datastore" "cdrom_datastore" {
+  name = "${var.cdrom_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_tag" "terraform-test-tag" {
-  name        = "terraform-test-tag"
-  category_id = "${vsphere_tag_category.terraform-test-category.id}"
+data "template_file" "config_data" {
+	template = <<EOS
+#!/bin/sh
+cat > /etc/network/interfaces << EOT
+auto lo
+iface lo inet loopback
+
+auto ens192
+iface ens192 inet static
+    address ${var.ipv4_address}
+    netmask ${cidrnetmask("${var.ipv4_address}/${var.ipv4_netmask}")}
+    gateway ${var.ipv4_gateway}
+EOT
+reboot
+EOS
 }
 
 resource "vsphere_virtual_machine" "vm" {
@@ -5905,30 +6433,56 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  wait_for_guest_net_timeout = 10
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  tags = [
-    "${vsphere_tag.terraform-test-tag.id}",
-  ]
+  cdrom {
+    datastore_id = "${data.vsphere_datastore.cdrom_datastore.id}"
+    path         = "${var.cdrom_iso}"
+  }
+
+  vapp {
+    properties {
+      "user-data" = "${base64encode(data.template_file.config_data.rendered)}"
+      "hostname"  = "custom-hostname"
+    }
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_ISO_DATASTORE"),
+		os.Getenv("VSPHERE_ISO_FILE"),
+		os.Getenv("VSPHERE_TEMPLATE_ISO_TRANSPORT"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigMultiTag() string {
+func testAccResourceVSphereVirtualMachineConfigNoCdromCloneIsoVApp() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -5942,15 +6496,32 @@ variable "network_label" {
   default = "%s"
 }
 
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
 variable "datastore" {
   default = "%s"
 }
 
-variable "extra_tags" {
-  default = [
-    "terraform-test-thing1",
-    "terraform-test-thing2",
-  ]
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
 }
 
 data "vsphere_datacenter" "dc" {
@@ -5972,24 +6543,26 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_tag_category" "terraform-test-category" {
-  name        = "terraform-test-tag-category"
-  cardinality = "MULTIPLE"
-
-  associable_types = [
-    "VirtualMachine",
-  ]
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_tag" "terraform-test-tag" {
-  name        = "terraform-test-tag"
-  category_id = "${vsphere_tag_category.terraform-test-category.id}"
-}
+data "template_file" "config_data" {
+	template = <<EOS
+#!/bin/sh
+cat > /etc/network/interfaces << EOT
+auto lo
+iface lo inet loopback
 
-resource "vsphere_tag" "terraform-test-tags-alt" {
-  count       = "${length(var.extra_tags)}"
-  name        = "${var.extra_tags[count.index]}"
-  category_id = "${vsphere_tag_category.terraform-test-category.id}"
+auto ens192
+iface ens192 inet static
+    address ${var.ipv4_address}
+    netmask ${cidrnetmask("${var.ipv4_address}/${var.ipv4_netmask}")}
+    gateway ${var.ipv4_gateway}
+EOT
+reboot
+EOS
 }
 
 resource "vsphere_virtual_machine" "vm" {
@@ -5999,28 +6572,49 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  wait_for_guest_net_timeout = 10
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  tags = ["${vsphere_tag.terraform-test-tags-alt.*.id}"]
+  vapp {
+    properties {
+      "user-data" = "${base64encode(data.template_file.config_data.rendered)}"
+      "hostname"  = "custom-hostname"
+    }
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE_ISO_TRANSPORT"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigComputedDisk() string {
+func testAccResourceVSphereVirtualMachineConfigClientCdromCloneIsoVApp() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6034,72 +6628,31 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "datastore" {
+variable "ipv4_address" {
   default = "%s"
 }
 
-data "vsphere_datacenter" "dc" {
-  name = "${var.datacenter}"
-}
-
-data "vsphere_datastore" "datastore" {
-  name          = "${var.datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "vsphere_resource_pool" "pool" {
-  name          = "${var.resource_pool}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "vsphere_network" "network" {
-  name          = "${var.network_label}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+variable "ipv4_netmask" {
+  default = "%s"
 }
 
-resource "random_pet" "pet" {}
-
-resource "vsphere_virtual_machine" "vm" {
-  name             = "terraform-test-${random_pet.pet.id}"
-  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
-  datastore_id     = "${data.vsphere_datastore.datastore.id}"
-
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "other3xLinux64Guest"
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
-
-  disk {
-    label = "terraform-test-${random_pet.pet.id}.vmdk"
-    size  = 20
-  }
-}
-`,
-		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
-		os.Getenv("VSPHERE_DATASTORE"),
-	)
+variable "ipv4_gateway" {
+  default = "%s"
 }
 
-func testAccResourceVSphereVirtualMachineVAppPropertiesNonClone() string {
-	return fmt.Sprintf(`
-variable "datacenter" {
+variable "dns_server" {
   default = "%s"
 }
 
-variable "resource_pool" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "network_label" {
+variable "template" {
   default = "%s"
 }
 
-variable "datastore" {
+variable "linked_clone" {
   default = "%s"
 }
 
@@ -6122,6 +6675,28 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "template_file" "config_data" {
+	template = <<EOS
+#!/bin/sh
+cat > /etc/network/interfaces << EOT
+auto lo
+iface lo inet loopback
+
+auto ens192
+iface ens192 inet static
+    address ${var.ipv4_address}
+    netmask ${cidrnetmask("${var.ipv4_address}/${var.ipv4_netmask}")}
+    gateway ${var.ipv4_gateway}
+EOT
+reboot
+EOS
+}
+
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -6129,95 +6704,53 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  wait_for_guest_net_timeout = 10
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  cdrom {
+    client_device = true
   }
 
   vapp {
     properties {
-      "foo" = "bar"
+      "user-data" = "${base64encode(data.template_file.config_data.rendered)}"
+      "hostname"  = "custom-hostname"
     }
   }
-}
-`,
-		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
-		os.Getenv("VSPHERE_DATASTORE"),
-	)
-}
-
-func testAccResourceVSphereVirtualMachineConfigBadOrphanedLabel() string {
-	return fmt.Sprintf(`
-variable "datacenter" {
-  default = "%s"
-}
-
-variable "resource_pool" {
-  default = "%s"
-}
-
-variable "network_label" {
-  default = "%s"
-}
-
-variable "datastore" {
-  default = "%s"
-}
-
-data "vsphere_datacenter" "dc" {
-  name = "${var.datacenter}"
-}
-
-data "vsphere_datastore" "datastore" {
-  name          = "${var.datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "vsphere_resource_pool" "pool" {
-  name          = "${var.resource_pool}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "vsphere_network" "network" {
-  name          = "${var.network_label}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-resource "vsphere_virtual_machine" "vm" {
-  name             = "terraform-test"
-  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
-  datastore_id     = "${data.vsphere_datastore.datastore.id}"
-
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "other3xLinux64Guest"
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
 
-  disk {
-    label = "orphaned_disk_0"
-    size  = 20
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE_ISO_TRANSPORT"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigClone() string {
+func testAccResourceVSphereVirtualMachineConfigClientCdromClone() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6304,6 +6837,10 @@ resource "vsphere_virtual_machine" "vm" {
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
+  cdrom {
+    client_device = true
+  }
+
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
     linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
@@ -6339,7 +6876,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneDHCP() string {
+func testAccResourceVSphereVirtualMachineConfigClientCdrom() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6357,14 +6894,6 @@ variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -6384,11 +6913,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -6396,47 +6920,32 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
 
   wait_for_guest_net_timeout = -1
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    label = "disk0"
+    size  = 20
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {}
-    }
+  cdrom {
+    client_device = true
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigBadEager() string {
+func testAccResourceVSphereVirtualMachineConfigNoCdromParameters() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6450,34 +6959,10 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -6497,11 +6982,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -6509,56 +6989,30 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
+
+  wait_for_guest_net_timeout = -1
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub == "true" ? "false" : "true"}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    label = "disk0"
+    size  = 20
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = true
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
-  }
+  cdrom {}
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigBadThin() string {
+func testAccResourceVSphereVirtualMachineConfigConflictingCdromParameters() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6572,31 +7026,15 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
+variable "iso_datastore" {
   default = "%s"
 }
 
-variable "linked_clone" {
+variable "iso_path" {
   default = "%s"
 }
 
@@ -6609,6 +7047,11 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_datastore" "iso_datastore" {
+  name          = "${var.iso_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -6619,11 +7062,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -6631,56 +7069,36 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
+
+  wait_for_guest_net_timeout = -1
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned == "true" ? "false" : "true"}"
+    label = "disk0"
+    size  = 20
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = true
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+  cdrom {
+    datastore_id  = "${data.vsphere_datastore.iso_datastore.id}"
+    path          = "${var.iso_path}"
+    client_device = true
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		os.Getenv("VSPHERE_ISO_DATASTORE"),
+		os.Getenv("VSPHERE_ISO_FILE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigBadSizeLinked() string {
+func testAccResourceVSphereVirtualMachineConfigBeefy() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6694,34 +7112,10 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -6741,68 +7135,33 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  num_cpus = 4
+  memory   = 8192
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = 999
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = true
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "disk0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigBadSizeUnlinked() string {
+func testAccResourceVSphereVirtualMachineConfigMaxNIC() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6816,31 +7175,7 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
-variable "datastore" {
-  default = "%s"
-}
-
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
+variable "datastore" {
   default = "%s"
 }
 
@@ -6863,11 +7198,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -6875,55 +7205,62 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
-  disk {
-    label            = "disk0"
-    size             = 1
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
 
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
 
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
 
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneMultiNIC() string {
+func testAccResourceVSphereVirtualMachineConfigBasicAnnotation() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -6937,31 +7274,11 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
+variable "annotation" {
   default = "%s"
 }
 
@@ -6984,73 +7301,35 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
-
-  network_interface {
-    network_id = "${data.vsphere_network.network.id}"
-  }
+  num_cpus   = 2
+  memory     = 2048
+  guest_id   = "other3xLinux64Guest"
+  annotation = "${var.annotation}"
 
   network_interface {
     network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      network_interface {}
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "disk0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		testAccResourceVSphereVirtualMachineAnnotation,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneTimeZone(zone string) string {
+func testAccResourceVSphereVirtualMachineConfigGrowDisk(size int) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7064,44 +7343,16 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
-variable "time_zone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
 data "vsphere_datastore" "datastore" {
-  name          = "${var.datastore}"
+  name = "${var.datastore}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -7115,11 +7366,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -7127,58 +7373,27 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-        time_zone = "${var.time_zone}"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "disk0"
+    size  = %d
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
-		zone,
+		size,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneHostname() string {
+func testAccResourceVSphereVirtualMachineConfigLsiLogicSAS() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7192,31 +7407,7 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
-variable "datastore" {
-  default = "%s"
-}
-
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
+variable "datastore" {
   default = "%s"
 }
 
@@ -7239,11 +7430,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -7251,56 +7437,28 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
+
+  scsi_type = "lsilogic-sas"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test-renamed"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "disk0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneWithCdrom() string {
+func testAccResourceVSphereVirtualMachineConfigExtraConfig(k, v string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7314,39 +7472,79 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "ipv4_netmask" {
-  default = "%s"
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
 }
 
-variable "ipv4_gateway" {
-  default = "%s"
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-variable "dns_server" {
-  default = "%s"
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-variable "datastore" {
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  extra_config {
+    "%s" = "%s"
+  }
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		k, v,
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigExistingVmdk() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
   default = "%s"
 }
 
-variable "iso_datastore" {
+variable "resource_pool" {
   default = "%s"
 }
 
-variable "iso_path" {
+variable "network_label" {
   default = "%s"
 }
 
-variable "template" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "linked_clone" {
+variable "extra_vmdk_name" {
   default = "%s"
 }
 
@@ -7359,11 +7557,6 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_datastore" "iso_datastore" {
-  name          = "${var.iso_datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -7374,9 +7567,13 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+resource "vsphere_virtual_disk" "disk" {
+  size         = 1
+  vmdk_path    = "${var.extra_vmdk_name}"
+  datacenter   = "${var.datacenter}"
+  datastore    = "${var.datastore}"
+  type         = "thin"
+  adapter_type = "lsiLogic"
 }
 
 resource "vsphere_virtual_machine" "vm" {
@@ -7386,63 +7583,36 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  cdrom {
-    datastore_id = "${data.vsphere_datastore.iso_datastore.id}"
-    path         = "${var.iso_path}"
+    label = "disk0"
+    size  = 20
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+  disk {
+    label        = "disk1"
+    datastore_id = "${data.vsphere_datastore.datastore.id}"
+    path         = "${vsphere_virtual_disk.disk.vmdk_path}"
+    disk_mode    = "independent_persistent"
+    attach       = true
+    unit_number  = 1
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_ISO_DATASTORE"),
-		os.Getenv("VSPHERE_ISO_FILE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigWithHotAdd(nc, nm int, cha, chr, mha bool) string {
+func testAccResourceVSphereVirtualMachineConfigInFolder() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7456,34 +7626,10 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -7503,8 +7649,9 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
+resource "vsphere_folder" "folder" {
+  path          = "terraform-test-vms"
+  type          = "vm"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -7512,67 +7659,30 @@ resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
+  folder           = "${vsphere_folder.folder.path}"
 
-  num_cpus                  = %d
-  memory                    = %d
-  cpu_hot_add_enabled       = %t
-  cpu_hot_remove_enabled    = %t
-  memory_hot_add_enabled    = %t
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "disk0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
-		nc,
-		nm,
-		cha,
-		chr,
-		mha,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigDualStack() string {
+func testAccResourceVSphereVirtualMachineConfigStaticMAC() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7586,34 +7696,10 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -7633,11 +7719,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -7645,59 +7726,29 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id     = "${data.vsphere_network.network.id}"
+    use_static_mac = true
+    mac_address    = "%s"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-        ipv6_address = "fd00::2"
-        ipv6_netmask = "32"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      ipv6_gateway    = "fd00::1"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "disk0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		testAccResourceVSphereVirtualMachineStaticMacAddr,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigIPv6Only() string {
+func testAccResourceVSphereVirtualMachineConfigSingleTag() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7715,14 +7766,6 @@ variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -7742,9 +7785,18 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+resource "vsphere_tag_category" "terraform-test-category" {
+  name        = "terraform-test-tag-category"
+  cardinality = "MULTIPLE"
+
+  associable_types = [
+    "VirtualMachine",
+  ]
+}
+
+resource "vsphere_tag" "terraform-test-tag" {
+  name        = "terraform-test-tag"
+  category_id = "${vsphere_tag_category.terraform-test-category.id}"
 }
 
 resource "vsphere_virtual_machine" "vm" {
@@ -7754,52 +7806,30 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
-
-  wait_for_guest_net_timeout = 10
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    label = "disk0"
+    size  = 20
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv6_address = "fd00::2"
-        ipv6_netmask = "32"
-      }
-
-      ipv6_gateway = "fd00::1"
-    }
-  }
+  tags = [
+    "${vsphere_tag.terraform-test-tag.id}",
+  ]
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigHostVMotion(host string) string {
+func testAccResourceVSphereVirtualMachineConfigMultiTag() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7813,36 +7843,15 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
-variable "host" {
-  default = "%s"
+variable "extra_tags" {
+  default = [
+    "terraform-test-thing1",
+    "terraform-test-thing2",
+  ]
 }
 
 data "vsphere_datacenter" "dc" {
@@ -7859,80 +7868,125 @@ data "vsphere_resource_pool" "pool" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_host" "host" {
-  name          = "${var.host}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 data "vsphere_network" "network" {
   name          = "${var.network_label}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+resource "vsphere_tag_category" "terraform-test-category" {
+  name        = "terraform-test-tag-category"
+  cardinality = "MULTIPLE"
+
+  associable_types = [
+    "VirtualMachine",
+  ]
+}
+
+resource "vsphere_tag" "terraform-test-tag" {
+  name        = "terraform-test-tag"
+  category_id = "${vsphere_tag_category.terraform-test-category.id}"
+}
+
+resource "vsphere_tag" "terraform-test-tags-alt" {
+  count       = "${length(var.extra_tags)}"
+  name        = "${var.extra_tags[count.index]}"
+  category_id = "${vsphere_tag_category.terraform-test-category.id}"
 }
 
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
-  host_system_id   = "${data.vsphere_host.host.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    label = "disk0"
+    size  = 20
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+  tags = ["${vsphere_tag.terraform-test-tags-alt.*.id}"]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+	)
+}
 
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
+func testAccResourceVSphereVirtualMachineConfigComputedDisk() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
 
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
+variable "resource_pool" {
+  default = "%s"
+}
 
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "random_pet" "pet" {}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test-${random_pet.pet.id}"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "terraform-test-${random_pet.pet.id}.vmdk"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
-		host,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigResourcePoolVMotion(pool string) string {
+func testAccResourceVSphereVirtualMachineVAppPropertiesNonClone() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -7946,31 +8000,76 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "ipv4_netmask" {
-  default = "%s"
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
 }
 
-variable "ipv4_gateway" {
-  default = "%s"
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-variable "dns_server" {
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+
+  vapp {
+    properties {
+      "foo" = "bar"
+    }
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigBadOrphanedLabel() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
   default = "%s"
 }
 
-variable "datastore" {
+variable "resource_pool" {
   default = "%s"
 }
 
-variable "template" {
+variable "network_label" {
   default = "%s"
 }
 
-variable "linked_clone" {
+variable "datastore" {
   default = "%s"
 }
 
@@ -7993,11 +8092,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -8005,56 +8099,26 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "other3xLinux64Guest"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "orphaned_disk_0"
+    size  = 20
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		pool,
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionGlobal(datastore string) string {
+func testAccResourceVSphereVirtualMachineConfigClone() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -8127,7 +8191,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -8143,6 +8207,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
     customize {
       linux_options {
@@ -8169,13 +8234,13 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		datastore,
+		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionSingleDisk(datastore string) string {
+func testAccResourceVSphereVirtualMachineConfigCloneDHCP() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -8189,22 +8254,6 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
@@ -8217,10 +8266,6 @@ variable "linked_clone" {
   default = "%s"
 }
 
-variable "disk_datastore" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -8230,11 +8275,6 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_datastore" "disk_datastore" {
-  name          = "${var.disk_datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -8257,7 +8297,9 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  wait_for_guest_net_timeout = -1
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -8271,15 +8313,9 @@ resource "vsphere_virtual_machine" "vm" {
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  disk {
-    label        = "disk1"
-    datastore_id = "${data.vsphere_datastore.disk_datastore.id}"
-    size         = 1
-    unit_number  = 1
-  }
-
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
     customize {
       linux_options {
@@ -8287,14 +8323,7 @@ resource "vsphere_virtual_machine" "vm" {
         domain    = "test.internal"
       }
 
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
+      network_interface {}
     }
   }
 }
@@ -8302,18 +8331,13 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
-		datastore,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionPinDatastore(datastore string) string {
+func testAccResourceVSphereVirtualMachineConfigBadEager() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -8355,10 +8379,6 @@ variable "linked_clone" {
   default = "%s"
 }
 
-variable "disk_datastore" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -8368,13 +8388,8 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_datastore" "disk_datastore" {
-  name          = "${var.disk_datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-data "vsphere_resource_pool" "pool" {
-  name          = "${var.resource_pool}"
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -8395,7 +8410,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -8405,19 +8420,13 @@ resource "vsphere_virtual_machine" "vm" {
   disk {
     label            = "disk0"
     size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub == "true" ? "false" : "true"}"
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  disk {
-    label        = "disk1"
-    datastore_id = "${data.vsphere_datastore.disk_datastore.id}"
-    size         = 1
-    unit_number  = 1
-  }
-
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = true
 
     customize {
       linux_options {
@@ -8444,14 +8453,13 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		datastore,
+		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
-		os.Getenv("VSPHERE_DATASTORE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionRename(name, datastore string) string {
+func testAccResourceVSphereVirtualMachineConfigBadThin() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -8481,10 +8489,6 @@ variable "dns_server" {
   default = "%s"
 }
 
-variable "vm_name" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
@@ -8522,13 +8526,13 @@ data "vsphere_virtual_machine" "template" {
 }
 
 resource "vsphere_virtual_machine" "vm" {
-  name             = "${var.vm_name}"
+  name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -8539,11 +8543,12 @@ resource "vsphere_virtual_machine" "vm" {
     label            = "disk0"
     size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
     eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned == "true" ? "false" : "true"}"
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = true
 
     customize {
       linux_options {
@@ -8570,14 +8575,13 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		name,
-		datastore,
+		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionLinkedClone(datastore string) string {
+func testAccResourceVSphereVirtualMachineConfigBadSizeLinked() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -8650,7 +8654,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -8659,7 +8663,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   disk {
     label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    size             = 999
     eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
@@ -8693,13 +8697,13 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		datastore,
+		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionAttachedDisk(datastore string) string {
+func testAccResourceVSphereVirtualMachineConfigBadSizeUnlinked() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -8741,10 +8745,6 @@ variable "linked_clone" {
   default = "%s"
 }
 
-variable "extra_vmdk_name" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -8769,15 +8769,6 @@ data "vsphere_virtual_machine" "template" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_virtual_disk" "disk" {
-  size         = 1
-  vmdk_path    = "${var.extra_vmdk_name}"
-  datacenter   = "${var.datacenter}"
-  datastore    = "${var.datastore}"
-  type         = "thin"
-  adapter_type = "lsiLogic"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -8785,7 +8776,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "ubuntu64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -8794,20 +8785,11 @@ resource "vsphere_virtual_machine" "vm" {
 
   disk {
     label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    size             = 1
     eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  disk {
-    label        = "disk1"
-    datastore_id = "${data.vsphere_datastore.datastore.id}"
-    path         = "${vsphere_virtual_disk.disk.vmdk_path}"
-    disk_mode    = "independent_persistent"
-    attach       = true
-    unit_number  = 1
-  }
-
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
 
@@ -8836,14 +8818,13 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		datastore,
+		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
-		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneExtraDisks() string {
+func testAccResourceVSphereVirtualMachineConfigCloneMultiNIC() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -8881,23 +8862,7 @@ variable "template" {
   default = "%s"
 }
 
-variable "disk0" {
-  type    = "string"
-  default = "%s"
-}
-
-variable "disk1" {
-  type    = "string"
-  default = "%s"
-}
-
-variable "disk2" {
-  type    = "string"
-  default = "%s"
-}
-
-variable "host" {
-  type    = "string"
+variable "linked_clone" {
   default = "%s"
 }
 
@@ -8905,8 +8870,8 @@ data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
-data "vsphere_host" "esxi_host" {
-  name          = "${var.host}"
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -8925,29 +8890,21 @@ data "vsphere_virtual_machine" "template" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_vmfs_datastore" "datastore" {
-  name           = "terraform-test"
-  host_system_id = "${data.vsphere_host.esxi_host.id}"
-
-  disks = [
-    "${var.disk0}",
-    "${var.disk1}",
-    "${var.disk2}",
-  ]
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
-  datastore_id     = "${vsphere_vmfs_datastore.datastore.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
   num_cpus = 2
   memory   = 2048
   guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
@@ -8957,31 +8914,9 @@ resource "vsphere_virtual_machine" "vm" {
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  disk {
-    label            = "disk1"
-    size             = 1
-    unit_number      = 1
-    thin_provisioned = false
-    eagerly_scrub    = true
-  }
-
-  disk {
-    label            = "disk2"
-    size             = 1
-    unit_number      = 2
-    thin_provisioned = false
-    eagerly_scrub    = false
-  }
-
-  disk {
-    label       = "disk3"
-    size        = 1
-    unit_number = 3
-  }
-
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = false
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
     customize {
       linux_options {
@@ -8994,6 +8929,8 @@ resource "vsphere_virtual_machine" "vm" {
         ipv4_netmask = "${var.ipv4_netmask}"
       }
 
+      network_interface {}
+
       ipv4_gateway    = "${var.ipv4_gateway}"
       dns_server_list = ["${var.dns_server}"]
       dns_suffix_list = ["test.internal"]
@@ -9010,14 +8947,11 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_DS_VMFS_DISK0"),
-		os.Getenv("VSPHERE_DS_VMFS_DISK1"),
-		os.Getenv("VSPHERE_DS_VMFS_DISK2"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigWindows() string {
+func testAccResourceVSphereVirtualMachineConfigCloneTimeZone(zone string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9059,6 +8993,10 @@ variable "linked_clone" {
   default = "%s"
 }
 
+variable "time_zone" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -9088,12 +9026,10 @@ resource "vsphere_virtual_machine" "vm" {
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus = 4
-  memory   = 4096
+  num_cpus = 2
+  memory   = 2048
   guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
-  scsi_type = "${data.vsphere_virtual_machine.template.scsi_type}"
-
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
     adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
@@ -9111,20 +9047,20 @@ resource "vsphere_virtual_machine" "vm" {
     linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
     customize {
-      windows_options {
-        computer_name  = "terraform-test"
-        workgroup      = "test"
-        admin_password = "VMw4re"
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+        time_zone = "${var.time_zone}"
       }
 
       network_interface {
-        ipv4_address    = "${var.ipv4_address}"
-        ipv4_netmask    = "${var.ipv4_netmask}"
-        dns_server_list = ["${var.dns_server}"]
-        dns_domain      = "test.internal"
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
       }
 
-      ipv4_gateway = "${var.ipv4_gateway}"
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
     }
   }
 }
@@ -9137,12 +9073,13 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE_WINDOWS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		zone,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigWithCustomAttribute() string {
+func testAccResourceVSphereVirtualMachineConfigCloneHostname() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9208,25 +9145,14 @@ data "vsphere_virtual_machine" "template" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_custom_attribute" "terraform-test-attribute" {
-  name                = "terraform-test-attribute"
-  managed_object_type = "VirtualMachine"
-}
-
-locals {
-  vm_attrs = {
-    "${vsphere_custom_attribute.terraform-test-attribute.id}" = "value"
-  }
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
-  num_cpus         = 2
-  memory           = 2048
-  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
-  scsi_type        = "${data.vsphere_virtual_machine.template.scsi_type}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -9234,8 +9160,10 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label = "disk0"
-    size  = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
   clone {
@@ -9244,7 +9172,7 @@ resource "vsphere_virtual_machine" "vm" {
 
     customize {
       linux_options {
-        host_name = "terraform-test"
+        host_name = "terraform-test-renamed"
         domain    = "test.internal"
       }
 
@@ -9258,8 +9186,6 @@ resource "vsphere_virtual_machine" "vm" {
       dns_suffix_list = ["test.internal"]
     }
   }
-
-  custom_attributes = "${local.vm_attrs}"
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
@@ -9275,7 +9201,7 @@ resource "vsphere_virtual_machine" "vm" {
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigWithMultiCustomAttribute() string {
+func testAccResourceVSphereVirtualMachineConfigCloneWithCdrom() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9309,19 +9235,20 @@ variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
+variable "iso_datastore" {
   default = "%s"
 }
 
-variable "linked_clone" {
+variable "iso_path" {
   default = "%s"
 }
 
-variable "custom_attrs" {
-  default = [
-    "terraform-test-attribute-1",
-    "terraform-test-attriubute-2",
-  ]
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
 }
 
 data "vsphere_datacenter" "dc" {
@@ -9333,6 +9260,11 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_datastore" "iso_datastore" {
+  name          = "${var.iso_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -9348,32 +9280,14 @@ data "vsphere_virtual_machine" "template" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_custom_attribute" "terraform-test-attribute" {
-  name                = "terraform-test-attribute"
-  managed_object_type = "VirtualMachine"
-}
-
-resource "vsphere_custom_attribute" "terraform-test-attribute-alt" {
-  count               = "${length(var.custom_attrs)}"
-  name                = "${var.custom_attrs[count.index]}"
-  managed_object_type = "VirtualMachine"
-}
-
-locals {
-  vm_attrs = {
-    "${vsphere_custom_attribute.terraform-test-attribute-alt.0.id}" = "value"
-    "${vsphere_custom_attribute.terraform-test-attribute-alt.1.id}" = "value-2"
-  }
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
-  num_cpus         = 2
-  memory           = 2048
-  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
-  scsi_type        = "${data.vsphere_virtual_machine.template.scsi_type}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -9381,8 +9295,15 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label = "disk0"
-    size  = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  cdrom {
+    datastore_id = "${data.vsphere_datastore.iso_datastore.id}"
+    path         = "${var.iso_path}"
   }
 
   clone {
@@ -9405,8 +9326,6 @@ resource "vsphere_virtual_machine" "vm" {
       dns_suffix_list = ["test.internal"]
     }
   }
-
-  custom_attributes = "${local.vm_attrs}"
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
@@ -9417,12 +9336,14 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_ISO_DATASTORE"),
+		os.Getenv("VSPHERE_ISO_FILE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneWithVAppProperties() string {
+func testAccResourceVSphereVirtualMachineConfigWithHotAdd(nc, nm int, cha, chr, mha bool) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9492,9 +9413,13 @@ resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
-  num_cpus         = 2
-  memory           = 2048
-  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  num_cpus                  = %d
+  memory                    = %d
+  cpu_hot_add_enabled       = %t
+  cpu_hot_remove_enabled    = %t
+  memory_hot_add_enabled    = %t
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -9504,28 +9429,32 @@ resource "vsphere_virtual_machine" "vm" {
   disk {
     label            = "disk0"
     size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
     eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-  }
-
-  vapp {
-    properties {
-      "guestinfo.hostname"                        = "terraform-test.test.internal"
-      "guestinfo.interface.0.name"                = "ens192"
-      "guestinfo.interface.0.ip.0.address"        = "${var.ipv4_address}/${var.ipv4_netmask}"
-      "guestinfo.interface.0.route.0.gateway"     = "${var.ipv4_gateway}"
-      "guestinfo.interface.0.route.0.destination" = "0.0.0.0/0"
-      "guestinfo.dns.server.0"                    = "${var.dns_server}"
-    }
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
   }
 }
 `,
-
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL"),
@@ -9534,12 +9463,17 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE_COREOS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		nc,
+		nm,
+		cha,
+		chr,
+		mha,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneUpdatingVAppProperties() string {
+func testAccResourceVSphereVirtualMachineConfigDualStack() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9609,9 +9543,10 @@ resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
-  num_cpus         = 2
-  memory           = 2048
-  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -9621,29 +9556,35 @@ resource "vsphere_virtual_machine" "vm" {
   disk {
     label            = "disk0"
     size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
     eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-  }
-
-  vapp {
-    properties {
-      "guestinfo.hostname"                        = "terraform-test.test.internal"
-      "guestinfo.interface.0.name"                = "ens192"
-      "guestinfo.interface.0.ip.0.address"        = "${var.ipv4_address}/${var.ipv4_netmask}"
-      "guestinfo.interface.0.route.0.gateway"     = "${var.ipv4_gateway}"
-      "guestinfo.interface.0.route.0.destination" = "0.0.0.0/0"
-      "guestinfo.dns.server.0"                    = "${var.dns_server}"
-      "guestinfo.dns.server.1"                    = "8.8.8.8"
-    }
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+        ipv6_address = "fd00::2"
+        ipv6_netmask = "32"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      ipv6_gateway    = "fd00::1"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
   }
 }
 `,
-
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL"),
@@ -9652,12 +9593,12 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE_COREOS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneVAppPropertiesBadKey() string {
+func testAccResourceVSphereVirtualMachineConfigIPv6Only() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9671,22 +9612,6 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "ipv4_gateway" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
@@ -9727,9 +9652,12 @@ resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
-  num_cpus         = 2
-  memory           = 2048
-  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  wait_for_guest_net_timeout = 10
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -9739,43 +9667,40 @@ resource "vsphere_virtual_machine" "vm" {
   disk {
     label            = "disk0"
     size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
     eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-  }
-
-  vapp {
-    properties {
-      "guestinfo.hostname"                        = "terraform-test.test.internal"
-      "guestinfo.interface.0.name"                = "ens192"
-      "guestinfo.interface.0.ip.0.address"        = "${var.ipv4_address}/${var.ipv4_netmask}"
-      "guestinfo.interface.0.route.0.gateway"     = "${var.ipv4_gateway}"
-      "guestinfo.interface.0.route.0.destination" = "0.0.0.0/0"
-      "guestinfo.dns.server.0"                    = "${var.dns_server}"
-      "foo"                                       = "bar"
-    }
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv6_address = "fd00::2"
+        ipv6_netmask = "32"
+      }
+
+      ipv6_gateway = "fd00::1"
+    }
   }
 }
 `,
-
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE_COREOS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneBadVAppSettings() string {
+func testAccResourceVSphereVirtualMachineConfigHostVMotion(host string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9817,6 +9742,10 @@ variable "linked_clone" {
   default = "%s"
 }
 
+variable "host" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -9831,6 +9760,11 @@ data "vsphere_resource_pool" "pool" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_host" "host" {
+  name          = "${var.host}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_network" "network" {
   name          = "${var.network_label}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -9844,11 +9778,12 @@ data "vsphere_virtual_machine" "template" {
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  host_system_id   = "${data.vsphere_host.host.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -9882,12 +9817,6 @@ resource "vsphere_virtual_machine" "vm" {
       dns_suffix_list = ["test.internal"]
     }
   }
-
-  vapp {
-    properties {
-      "foo" = "bar"
-    }
-  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
@@ -9900,11 +9829,11 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		host,
 	)
 }
 
-// TODO: Remove this fixture in 2.0
-func testAccResourceVSphereVirtualMachineConfigBasicDiskNameOrLabel(nameKey string) string {
+func testAccResourceVSphereVirtualMachineConfigResourcePoolVMotion(pool string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9918,10 +9847,34 @@ variable "network_label" {
   default = "%s"
 }
 
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
 variable "datastore" {
   default = "%s"
 }
 
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -9941,6 +9894,11 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -9948,28 +9906,56 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    %s    = "terraform-test.vmdk"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
-}
-`,
-		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
-		os.Getenv("VSPHERE_DATASTORE"),
-		nameKey,
-	)
-}
 
-// TODO: Remove this fixture in 2.0
-func testAccResourceVSphereVirtualMachineConfigExistingVmdkWithName() string {
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		pool,
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionGlobal(datastore string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -9983,11 +9969,31 @@ variable "network_label" {
   default = "%s"
 }
 
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
 variable "datastore" {
   default = "%s"
 }
 
-variable "extra_vmdk_name" {
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
   default = "%s"
 }
 
@@ -10010,13 +10016,9 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_virtual_disk" "disk" {
-  size         = 1
-  vmdk_path    = "${var.extra_vmdk_name}"
-  datacenter   = "${var.datacenter}"
-  datastore    = "${var.datastore}"
-  type         = "thin"
-  adapter_type = "lsiLogic"
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
 resource "vsphere_virtual_machine" "vm" {
@@ -10026,36 +10028,55 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    name  = "terraform-test.vmdk"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  disk {
-    name         = "${vsphere_virtual_disk.disk.vmdk_path}"
-    datastore_id = "${data.vsphere_datastore.datastore.id}"
-    disk_mode    = "independent_persistent"
-    attach       = true
-    unit_number  = 1
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
-		os.Getenv("VSPHERE_DATASTORE"),
-		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		datastore,
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-// TODO: Remove this fixture in 2.0
-func testAccResourceVSphereVirtualMachineConfigExistingVmdkWithLabel() string {
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionSingleDisk(datastore string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -10069,11 +10090,35 @@ variable "network_label" {
   default = "%s"
 }
 
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
 variable "datastore" {
   default = "%s"
 }
 
-variable "extra_vmdk_name" {
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+variable "disk_datastore" {
   default = "%s"
 }
 
@@ -10086,6 +10131,11 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_datastore" "disk_datastore" {
+  name          = "${var.disk_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -10096,13 +10146,9 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_virtual_disk" "disk" {
-  size         = 1
-  vmdk_path    = "${var.extra_vmdk_name}"
-  datacenter   = "${var.datacenter}"
-  datastore    = "${var.datastore}"
-  type         = "thin"
-  adapter_type = "lsiLogic"
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
 resource "vsphere_virtual_machine" "vm" {
@@ -10112,36 +10158,63 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "terraform-test.vmdk"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
   disk {
-    label        = "${vsphere_virtual_disk.disk.vmdk_path}"
-    path         = "${vsphere_virtual_disk.disk.vmdk_path}"
-    datastore_id = "${data.vsphere_datastore.datastore.id}"
-    disk_mode    = "independent_persistent"
-    attach       = true
+    label        = "disk1"
+    datastore_id = "${data.vsphere_datastore.disk_datastore.id}"
+    size         = 1
     unit_number  = 1
   }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		datastore,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneChangeDiskAndSCSI() string {
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionPinDatastore(datastore string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -10179,6 +10252,14 @@ variable "template" {
   default = "%s"
 }
 
+variable "linked_clone" {
+  default = "%s"
+}
+
+variable "disk_datastore" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -10188,6 +10269,11 @@ data "vsphere_datastore" "datastore" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_datastore" "disk_datastore" {
+  name          = "${var.disk_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -10210,9 +10296,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
-
-  scsi_type = "${data.vsphere_virtual_machine.template.scsi_type == "pvscsi" ? "lsilogic-sas" : "pvscsi"}"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -10220,8 +10304,17 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label = "disk0"
-    size  = "${data.vsphere_virtual_machine.template.disks.0.size * 2}"
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  disk {
+    label        = "disk1"
+    datastore_id = "${data.vsphere_datastore.disk_datastore.id}"
+    size         = 1
+    unit_number  = 1
   }
 
   clone {
@@ -10252,34 +10345,64 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		os.Getenv("VSPHERE_DATASTORE"),
+		datastore,
 		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		os.Getenv("VSPHERE_DATASTORE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigBasicEmptyCluster() string {
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionRename(name, datastore string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
 variable "resource_pool" {
-  default = "%s/Resources"
+  default = "%s"
 }
 
 variable "network_label" {
   default = "%s"
 }
 
-variable "datastore" {
+variable "ipv4_address" {
   default = "%s"
 }
 
-data "vsphere_datacenter" "dc" {
-  name = "${var.datacenter}"
+variable "ipv4_netmask" {
+  default = "%s"
 }
 
-data "vsphere_datastore" "datastore" {
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "vm_name" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
   name          = "${var.datastore}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
@@ -10294,40 +10417,75 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 resource "vsphere_virtual_machine" "vm" {
-  name             = "terraform-test"
+  name             = "${var.vm_name}"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_EMPTY_CLUSTER"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
-		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		name,
+		datastore,
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneEmptyCluster() string {
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionLinkedClone(datastore string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
 variable "resource_pool" {
-  default = "%s/Resources"
+  default = "%s"
 }
 
 variable "network_label" {
@@ -10393,7 +10551,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -10409,7 +10567,7 @@ resource "vsphere_virtual_machine" "vm" {
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+    linked_clone  = true
 
     customize {
       linux_options {
@@ -10430,45 +10588,61 @@ resource "vsphere_virtual_machine" "vm" {
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_EMPTY_CLUSTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL"),
 		os.Getenv("VSPHERE_IPV4_ADDRESS"),
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		os.Getenv("VSPHERE_DATASTORE"),
+		datastore,
 		os.Getenv("VSPHERE_TEMPLATE"),
 		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigDatastoreCluster() string {
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionAttachedDisk(datastore string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
-variable "nfs_host" {
+variable "resource_pool" {
   default = "%s"
 }
 
-variable "nfs_path" {
+variable "network_label" {
   default = "%s"
 }
 
-variable "esxi_hosts" {
-  default = [
-    "%s",
-    "%s",
-    "%s",
-  ]
+variable "ipv4_address" {
+  default = "%s"
 }
 
-variable "resource_pool" {
+variable "ipv4_netmask" {
   default = "%s"
 }
 
-variable "network_label" {
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+variable "extra_vmdk_name" {
   default = "%s"
 }
 
@@ -10476,6 +10650,11 @@ data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -10486,186 +10665,265 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_host" "esxi_hosts" {
-  count         = "${length(var.esxi_hosts)}"
-  name          = "${var.esxi_hosts[count.index]}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-resource "vsphere_datastore_cluster" "datastore_cluster" {
-  name          = "terraform-datastore-cluster-test"
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
 }
 
-resource "vsphere_nas_datastore" "datastore" {
-  name                 = "terraform-test-nas"
-  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
-
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path}"
+resource "vsphere_virtual_disk" "disk" {
+  size         = 1
+  vmdk_path    = "${var.extra_vmdk_name}"
+  datacenter   = "${var.datacenter}"
+  datastore    = "${var.datastore}"
+  type         = "thin"
+  adapter_type = "lsiLogic"
 }
 
 resource "vsphere_virtual_machine" "vm" {
-  name                 = "terraform-test"
-  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "ubuntu64Guest"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
-  depends_on = ["vsphere_nas_datastore.datastore"]
+  disk {
+    label        = "disk1"
+    datastore_id = "${data.vsphere_datastore.datastore.id}"
+    path         = "${vsphere_virtual_disk.disk.vmdk_path}"
+    disk_mode    = "independent_persistent"
+    attach       = true
+    unit_number  = 1
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_NAS_HOST"),
-		os.Getenv("VSPHERE_NFS_PATH"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
-		os.Getenv("VSPHERE_ESXI_HOST2"),
-		os.Getenv("VSPHERE_ESXI_HOST3"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		datastore,
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigDatastoreClusterMultiDisk() string {
+func testAccResourceVSphereVirtualMachineConfigCloneExtraDisks() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
-variable "nfs_host" {
+variable "resource_pool" {
   default = "%s"
 }
 
-variable "nfs_path" {
+variable "network_label" {
   default = "%s"
 }
 
-variable "esxi_hosts" {
-  default = [
-    "%s",
-    "%s",
-    "%s",
-  ]
+variable "ipv4_address" {
+  default = "%s"
 }
 
-variable "resource_pool" {
+variable "ipv4_netmask" {
   default = "%s"
 }
 
-variable "network_label" {
+variable "ipv4_gateway" {
   default = "%s"
 }
 
-data "vsphere_datacenter" "dc" {
-  name = "${var.datacenter}"
+variable "dns_server" {
+  default = "%s"
 }
 
-data "vsphere_resource_pool" "pool" {
-  name          = "${var.resource_pool}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+variable "datastore" {
+  default = "%s"
 }
 
-data "vsphere_network" "network" {
-  name          = "${var.network_label}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+variable "template" {
+  default = "%s"
 }
 
-data "vsphere_host" "esxi_hosts" {
-  count         = "${length(var.esxi_hosts)}"
-  name          = "${var.esxi_hosts[count.index]}"
+variable "disk0" {
+  type    = "string"
+  default = "%s"
+}
+
+variable "disk1" {
+  type    = "string"
+  default = "%s"
+}
+
+variable "disk2" {
+  type    = "string"
+  default = "%s"
+}
+
+variable "host" {
+  type    = "string"
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_host" "esxi_host" {
+  name          = "${var.host}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_datastore_cluster" "datastore_cluster" {
-  name          = "terraform-datastore-cluster-test"
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
 }
 
-resource "vsphere_nas_datastore" "datastore" {
-  name                 = "terraform-test-nas"
-  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
 
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path}"
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_vmfs_datastore" "datastore" {
+  name           = "terraform-test"
+  host_system_id = "${data.vsphere_host.esxi_host.id}"
+
+  disks = [
+    "${var.disk0}",
+    "${var.disk1}",
+    "${var.disk2}",
+  ]
 }
 
 resource "vsphere_virtual_machine" "vm" {
-  name                 = "terraform-test"
-  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${vsphere_vmfs_datastore.datastore.id}"
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "other3xLinux64Guest"
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
   }
 
   disk {
-    label       = "disk1"
-    size        = 20
-    unit_number = 1
+    label            = "disk1"
+    size             = 1
+    unit_number      = 1
+    thin_provisioned = false
+    eagerly_scrub    = true
   }
 
-  depends_on = ["vsphere_nas_datastore.datastore"]
+  disk {
+    label            = "disk2"
+    size             = 1
+    unit_number      = 2
+    thin_provisioned = false
+    eagerly_scrub    = false
+  }
+
+  disk {
+    label       = "disk3"
+    size        = 1
+    unit_number = 3
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = false
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_NAS_HOST"),
-		os.Getenv("VSPHERE_NFS_PATH"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
-		os.Getenv("VSPHERE_ESXI_HOST2"),
-		os.Getenv("VSPHERE_ESXI_HOST3"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_DS_VMFS_DISK0"),
+		os.Getenv("VSPHERE_DS_VMFS_DISK1"),
+		os.Getenv("VSPHERE_DS_VMFS_DISK2"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneDatastoreCluster() string {
+func testAccResourceVSphereVirtualMachineConfigWindows() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
-variable "nfs_host" {
-  default = "%s"
-}
-
-variable "nfs_path" {
-  default = "%s"
-}
-
-variable "esxi_hosts" {
-  default = [
-    "%s",
-    "%s",
-    "%s",
-  ]
-}
-
 variable "resource_pool" {
   default = "%s"
 }
@@ -10690,14 +10948,27 @@ variable "dns_server" {
   default = "%s"
 }
 
+variable "datastore" {
+  default = "%s"
+}
+
 variable "template" {
   default = "%s"
 }
 
+variable "linked_clone" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -10708,42 +10979,22 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_host" "esxi_hosts" {
-  count         = "${length(var.esxi_hosts)}"
-  name          = "${var.esxi_hosts[count.index]}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-resource "vsphere_datastore_cluster" "datastore_cluster" {
-  name          = "terraform-datastore-cluster-test"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
-}
-
-resource "vsphere_nas_datastore" "datastore" {
-  name                 = "terraform-test-nas"
-  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
-
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path}"
-}
-
 data "vsphere_virtual_machine" "template" {
   name          = "${var.template}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
 resource "vsphere_virtual_machine" "vm" {
-  name                 = "terraform-test"
-  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus = 2
-  memory   = 2048
+  num_cpus = 4
+  memory   = 4096
   guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
 
+  scsi_type = "${data.vsphere_virtual_machine.template.scsi_type}"
+
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
     adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
@@ -10758,69 +11009,46 @@ resource "vsphere_virtual_machine" "vm" {
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
     customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
+      windows_options {
+        computer_name  = "terraform-test"
+        workgroup      = "test"
+        admin_password = "VMw4re"
       }
 
       network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
+        ipv4_address    = "${var.ipv4_address}"
+        ipv4_netmask    = "${var.ipv4_netmask}"
+        dns_server_list = ["${var.dns_server}"]
+        dns_domain      = "test.internal"
       }
 
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
+      ipv4_gateway = "${var.ipv4_gateway}"
     }
   }
-
-  depends_on = ["vsphere_nas_datastore.datastore"]
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_NAS_HOST"),
-		os.Getenv("VSPHERE_NFS_PATH"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
-		os.Getenv("VSPHERE_ESXI_HOST2"),
-		os.Getenv("VSPHERE_ESXI_HOST3"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
 		os.Getenv("VSPHERE_IPV4_ADDRESS"),
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE_WINDOWS"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionDatastoreCluster(clusterName string) string {
+func testAccResourceVSphereVirtualMachineConfigWithCustomAttribute() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
-variable "nfs_host" {
-  default = "%s"
-}
-
-variable "nfs_path" {
-  default = "%s"
-}
-
-variable "nfs_path2" {
-  default = "%s"
-}
-
-variable "esxi_hosts" {
-  default = [
-    "%s",
-    "%s",
-    "%s",
-  ]
-}
-
 variable "resource_pool" {
   default = "%s"
 }
@@ -10845,14 +11073,27 @@ variable "dns_server" {
   default = "%s"
 }
 
+variable "datastore" {
+  default = "%s"
+}
+
 variable "template" {
   default = "%s"
 }
 
+variable "linked_clone" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -10863,57 +11104,30 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_host" "esxi_hosts" {
-  count         = "${length(var.esxi_hosts)}"
-  name          = "${var.esxi_hosts[count.index]}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
-resource "vsphere_datastore_cluster" "%s" {
-  name          = "%s"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
-}
-
-resource "vsphere_nas_datastore" "datastore" {
-  name                 = "terraform-test-nas"
-  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.%s.id}"
-
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path}"
-}
-
-resource "vsphere_datastore_cluster" "%s" {
-  name          = "%s"
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
 }
 
-resource "vsphere_nas_datastore" "datastore2" {
-  name                 = "terraform-test-nas2"
-  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.%s.id}"
-
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path2}"
+resource "vsphere_custom_attribute" "terraform-test-attribute" {
+  name                = "terraform-test-attribute"
+  managed_object_type = "VirtualMachine"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+locals {
+  vm_attrs = {
+    "${vsphere_custom_attribute.terraform-test-attribute.id}" = "value"
+  }
 }
 
 resource "vsphere_virtual_machine" "vm" {
-  name                 = "terraform-test"
-  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
-  datastore_cluster_id = "${vsphere_datastore_cluster.%s.id}"
-
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+  num_cpus         = 2
+  memory           = 2048
+  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
+  scsi_type        = "${data.vsphere_virtual_machine.template.scsi_type}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -10921,14 +11135,13 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    label = "disk0"
+    size  = "${data.vsphere_virtual_machine.template.disks.0.size}"
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
     customize {
       linux_options {
@@ -10947,58 +11160,28 @@ resource "vsphere_virtual_machine" "vm" {
     }
   }
 
-  depends_on = [
-	"vsphere_nas_datastore.datastore",
-	"vsphere_nas_datastore.datastore2",
-	]
+  custom_attributes = "${local.vm_attrs}"
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_NAS_HOST"),
-		os.Getenv("VSPHERE_NFS_PATH"),
-		os.Getenv("VSPHERE_NFS_PATH2"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
-		os.Getenv("VSPHERE_ESXI_HOST2"),
-		os.Getenv("VSPHERE_ESXI_HOST3"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
 		os.Getenv("VSPHERE_IPV4_ADDRESS"),
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
-		testAccResourceVSphereVirtualMachineDatastoreCluster,
-		testAccResourceVSphereVirtualMachineDatastoreCluster,
-		testAccResourceVSphereVirtualMachineDatastoreCluster,
-		testAccResourceVSphereVirtualMachineDatastoreClusterAlt,
-		testAccResourceVSphereVirtualMachineDatastoreClusterAlt,
-		testAccResourceVSphereVirtualMachineDatastoreClusterAlt,
-		clusterName,
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigHostVMotionDatastoreCluster(host string) string {
+func testAccResourceVSphereVirtualMachineConfigWithMultiCustomAttribute() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
-variable "nfs_host" {
-  default = "%s"
-}
-
-variable "nfs_path" {
-  default = "%s"
-}
-
-variable "mount_hosts" {
-  default = [
-    "%s",
-    "%s",
-    "%s",
-  ]
-}
-
 variable "resource_pool" {
   default = "%s"
 }
@@ -11023,31 +11206,36 @@ variable "dns_server" {
   default = "%s"
 }
 
+variable "datastore" {
+  default = "%s"
+}
+
 variable "template" {
   default = "%s"
 }
 
-variable "host" {
+variable "linked_clone" {
   default = "%s"
 }
 
-data "vsphere_datacenter" "dc" {
-  name = "${var.datacenter}"
+variable "custom_attrs" {
+  default = [
+    "terraform-test-attribute-1",
+    "terraform-test-attriubute-2",
+  ]
 }
 
-data "vsphere_resource_pool" "pool" {
-  name          = "${var.resource_pool}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
 }
 
-data "vsphere_host" "host" {
-  name          = "${var.host}"
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_host" "mount_hosts" {
-  count         = "${length(var.mount_hosts)}"
-  name          = "${var.mount_hosts[count.index]}"
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -11061,31 +11249,32 @@ data "vsphere_virtual_machine" "template" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_datastore_cluster" "datastore_cluster" {
-  name          = "terraform-datastore-cluster-test"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
+resource "vsphere_custom_attribute" "terraform-test-attribute" {
+  name                = "terraform-test-attribute"
+  managed_object_type = "VirtualMachine"
 }
 
-resource "vsphere_nas_datastore" "datastore" {
-  name                 = "terraform-test-nas"
-  host_system_ids      = ["${data.vsphere_host.mount_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+resource "vsphere_custom_attribute" "terraform-test-attribute-alt" {
+  count               = "${length(var.custom_attrs)}"
+  name                = "${var.custom_attrs[count.index]}"
+  managed_object_type = "VirtualMachine"
+}
 
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path}"
+locals {
+  vm_attrs = {
+    "${vsphere_custom_attribute.terraform-test-attribute-alt.0.id}" = "value"
+    "${vsphere_custom_attribute.terraform-test-attribute-alt.1.id}" = "value-2"
+  }
 }
 
 resource "vsphere_virtual_machine" "vm" {
-  name                 = "terraform-test"
-  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
-  host_system_id       = "${data.vsphere_host.host.id}"
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
-
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "ubuntu64Guest"
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+  num_cpus         = 2
+  memory           = 2048
+  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
+  scsi_type        = "${data.vsphere_virtual_machine.template.scsi_type}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -11093,14 +11282,13 @@ resource "vsphere_virtual_machine" "vm" {
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    label = "disk0"
+    size  = "${data.vsphere_virtual_machine.template.disks.0.size}"
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
 
     customize {
       linux_options {
@@ -11119,50 +11307,28 @@ resource "vsphere_virtual_machine" "vm" {
     }
   }
 
-  depends_on = [
-    "vsphere_nas_datastore.datastore",
-  ]
+  custom_attributes = "${local.vm_attrs}"
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_NAS_HOST"),
-		os.Getenv("VSPHERE_NFS_PATH"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
-		os.Getenv("VSPHERE_ESXI_HOST2"),
-		os.Getenv("VSPHERE_ESXI_HOST3"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL"),
 		os.Getenv("VSPHERE_IPV4_ADDRESS"),
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
 		os.Getenv("VSPHERE_TEMPLATE"),
-		host,
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionDatastoreClusterSingleDiskStep0() string {
+func testAccResourceVSphereVirtualMachineConfigCloneWithVAppProperties() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
-variable "nfs_host" {
-  default = "%s"
-}
-
-variable "nfs_path" {
-  default = "%s"
-}
-
-variable "esxi_hosts" {
-  default = [
-    "%s",
-    "%s",
-    "%s",
-  ]
-}
-
 variable "resource_pool" {
   default = "%s"
 }
@@ -11187,17 +11353,24 @@ variable "dns_server" {
   default = "%s"
 }
 
+variable "datastore" {
+  default = "%s"
+}
+
 variable "template" {
   default = "%s"
 }
 
+variable "linked_clone" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
-data "vsphere_host" "esxi_hosts" {
-  count         = "${length(var.esxi_hosts)}"
-  name          = "${var.esxi_hosts[count.index]}"
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -11216,30 +11389,13 @@ data "vsphere_virtual_machine" "template" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_datastore_cluster" "datastore_cluster" {
-  name          = "terraform-datastore-cluster-test"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
-}
-
-resource "vsphere_nas_datastore" "datastore" {
-  name                 = "terraform-test-nas"
-  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
-
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
-  name                 = "terraform-test"
-  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
-
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "ubuntu64Guest"
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+  num_cpus         = 2
+  memory           = 2048
+  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -11249,77 +11405,163 @@ resource "vsphere_virtual_machine" "vm" {
   disk {
     label            = "disk0"
     size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
   }
 
-  disk {
-    label       = "disk1"
-    size        = 1
-    unit_number = 1
+  vapp {
+    properties {
+      "guestinfo.hostname"                        = "terraform-test.test.internal"
+      "guestinfo.interface.0.name"                = "ens192"
+      "guestinfo.interface.0.ip.0.address"        = "${var.ipv4_address}/${var.ipv4_netmask}"
+      "guestinfo.interface.0.route.0.gateway"     = "${var.ipv4_gateway}"
+      "guestinfo.interface.0.route.0.destination" = "0.0.0.0/0"
+      "guestinfo.dns.server.0"                    = "${var.dns_server}"
+    }
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    linked_clone  = "${var.linked_clone}"
   }
-
-  depends_on = [
-    "vsphere_nas_datastore.datastore",
-  ]
 }
 `,
+
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_NAS_HOST"),
-		os.Getenv("VSPHERE_NFS_PATH"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
-		os.Getenv("VSPHERE_ESXI_HOST2"),
-		os.Getenv("VSPHERE_ESXI_HOST3"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL"),
 		os.Getenv("VSPHERE_IPV4_ADDRESS"),
 		os.Getenv("VSPHERE_IPV4_PREFIX"),
 		os.Getenv("VSPHERE_IPV4_GATEWAY"),
 		os.Getenv("VSPHERE_DNS"),
-		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE_COREOS"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigStorageVMotionDatastoreClusterSingleDiskStep1(datastore string) string {
+func testAccResourceVSphereVirtualMachineConfigCloneUpdatingVAppProperties() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
 }
 
-variable "nfs_host" {
+variable "resource_pool" {
   default = "%s"
 }
 
-variable "nfs_path" {
+variable "network_label" {
   default = "%s"
 }
 
-variable "esxi_hosts" {
-  default = [
-    "%s",
-    "%s",
-    "%s",
-  ]
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+  num_cpus         = 2
+  memory           = 2048
+  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+  }
+
+  vapp {
+    properties {
+      "guestinfo.hostname"                        = "terraform-test.test.internal"
+      "guestinfo.interface.0.name"                = "ens192"
+      "guestinfo.interface.0.ip.0.address"        = "${var.ipv4_address}/${var.ipv4_netmask}"
+      "guestinfo.interface.0.route.0.gateway"     = "${var.ipv4_gateway}"
+      "guestinfo.interface.0.route.0.destination" = "0.0.0.0/0"
+      "guestinfo.dns.server.0"                    = "${var.dns_server}"
+      "guestinfo.dns.server.1"                    = "8.8.8.8"
+    }
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone}"
+  }
+}
+`,
+
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE_COREOS"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneVAppPropertiesBadKey() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
 }
 
 variable "resource_pool" {
@@ -11354,7 +11596,7 @@ variable "template" {
   default = "%s"
 }
 
-variable "disk_datastore" {
+variable "linked_clone" {
   default = "%s"
 }
 
@@ -11362,22 +11604,11 @@ data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
 
-data "vsphere_host" "esxi_hosts" {
-  count         = "${length(var.esxi_hosts)}"
-  name          = "${var.esxi_hosts[count.index]}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 data "vsphere_datastore" "datastore" {
   name          = "${var.datastore}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_datastore" "disk_datastore" {
-  name          = "${var.disk_datastore}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 data "vsphere_resource_pool" "pool" {
   name          = "${var.resource_pool}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
@@ -11393,30 +11624,13 @@ data "vsphere_virtual_machine" "template" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-resource "vsphere_datastore_cluster" "datastore_cluster" {
-  name          = "terraform-datastore-cluster-test"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-  sdrs_enabled  = true
-}
-
-resource "vsphere_nas_datastore" "datastore" {
-  name                 = "terraform-test-nas"
-  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
-  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
-
-  type         = "NFS"
-  remote_hosts = ["${var.nfs_host}"]
-  remote_path  = "${var.nfs_path}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
-  datastore_id     = "${vsphere_nas_datastore.datastore.id}"
-
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "ubuntu64Guest"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+  num_cpus         = 2
+  memory           = 2048
+  guest_id         = "${data.vsphere_virtual_machine.template.guest_id}"
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
@@ -11426,61 +11640,2292 @@ resource "vsphere_virtual_machine" "vm" {
   disk {
     label            = "disk0"
     size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
     thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
   }
 
-  disk {
-    label        = "disk1"
-    datastore_id = "${data.vsphere_datastore.disk_datastore.id}"
-    size         = 1
-    unit_number  = 1
+  vapp {
+    properties {
+      "guestinfo.hostname"                        = "terraform-test.test.internal"
+      "guestinfo.interface.0.name"                = "ens192"
+      "guestinfo.interface.0.ip.0.address"        = "${var.ipv4_address}/${var.ipv4_netmask}"
+      "guestinfo.interface.0.route.0.gateway"     = "${var.ipv4_gateway}"
+      "guestinfo.interface.0.route.0.destination" = "0.0.0.0/0"
+      "guestinfo.dns.server.0"                    = "${var.dns_server}"
+      "foo"                                       = "bar"
+    }
   }
 
   clone {
     template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone}"
+  }
+}
+`,
 
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE_COREOS"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+	)
+}
 
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
+func testAccResourceVSphereVirtualMachineConfigCloneBadVAppSettings() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
 
-      ipv4_gateway    = "${var.ipv4_gateway}"
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
       dns_server_list = ["${var.dns_server}"]
       dns_suffix_list = ["test.internal"]
     }
   }
 
-  depends_on = [
-    "vsphere_nas_datastore.datastore",
-  ]
+  vapp {
+    properties {
+      "foo" = "bar"
+    }
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+	)
+}
+
+// TODO: Remove this fixture in 2.0
+func testAccResourceVSphereVirtualMachineConfigBasicDiskNameOrLabel(nameKey string) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    %s    = "terraform-test.vmdk"
+    size  = 20
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		nameKey,
+	)
+}
+
+// TODO: Remove this fixture in 2.0
+func testAccResourceVSphereVirtualMachineConfigExistingVmdkWithName() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "extra_vmdk_name" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_disk" "disk" {
+  size         = 1
+  vmdk_path    = "${var.extra_vmdk_name}"
+  datacenter   = "${var.datacenter}"
+  datastore    = "${var.datastore}"
+  type         = "thin"
+  adapter_type = "lsiLogic"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    name  = "terraform-test.vmdk"
+    size  = 20
+  }
+
+  disk {
+    name         = "${vsphere_virtual_disk.disk.vmdk_path}"
+    datastore_id = "${data.vsphere_datastore.datastore.id}"
+    disk_mode    = "independent_persistent"
+    attach       = true
+    unit_number  = 1
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
+	)
+}
+
+// TODO: Remove this fixture in 2.0
+func testAccResourceVSphereVirtualMachineConfigExistingVmdkWithLabel() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "extra_vmdk_name" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_disk" "disk" {
+  size         = 1
+  vmdk_path    = "${var.extra_vmdk_name}"
+  datacenter   = "${var.datacenter}"
+  datastore    = "${var.datastore}"
+  type         = "thin"
+  adapter_type = "lsiLogic"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "terraform-test.vmdk"
+    size  = 20
+  }
+
+  disk {
+    label        = "${vsphere_virtual_disk.disk.vmdk_path}"
+    path         = "${vsphere_virtual_disk.disk.vmdk_path}"
+    datastore_id = "${data.vsphere_datastore.datastore.id}"
+    disk_mode    = "independent_persistent"
+    attach       = true
+    unit_number  = 1
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		testAccResourceVSphereVirtualMachineDiskNameExtraVmdk,
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneChangeDiskAndSCSI() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  scsi_type = "${data.vsphere_virtual_machine.template.scsi_type == "pvscsi" ? "lsilogic-sas" : "pvscsi"}"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = "${data.vsphere_virtual_machine.template.disks.0.size * 2}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigBasicEmptyCluster() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s/Resources"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_EMPTY_CLUSTER"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneEmptyCluster() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s/Resources"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_EMPTY_CLUSTER"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigDatastoreCluster() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "nfs_host" {
+  default = "%s"
+}
+
+variable "nfs_path" {
+  default = "%s"
+}
+
+variable "esxi_hosts" {
+  default = [
+    "%s",
+    "%s",
+    "%s",
+  ]
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_host" "esxi_hosts" {
+  count         = "${length(var.esxi_hosts)}"
+  name          = "${var.esxi_hosts[count.index]}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_datastore_cluster" "datastore_cluster" {
+  name          = "terraform-datastore-cluster-test"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore" {
+  name                 = "terraform-test-nas"
+  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name                 = "terraform-test"
+  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+
+  depends_on = ["vsphere_nas_datastore.datastore"]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_NAS_HOST"),
+		os.Getenv("VSPHERE_NFS_PATH"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_ESXI_HOST2"),
+		os.Getenv("VSPHERE_ESXI_HOST3"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigDatastoreClusterMultiDisk() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "nfs_host" {
+  default = "%s"
+}
+
+variable "nfs_path" {
+  default = "%s"
+}
+
+variable "esxi_hosts" {
+  default = [
+    "%s",
+    "%s",
+    "%s",
+  ]
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_host" "esxi_hosts" {
+  count         = "${length(var.esxi_hosts)}"
+  name          = "${var.esxi_hosts[count.index]}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_datastore_cluster" "datastore_cluster" {
+  name          = "terraform-datastore-cluster-test"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore" {
+  name                 = "terraform-test-nas"
+  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name                 = "terraform-test"
+  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+
+  disk {
+    label       = "disk1"
+    size        = 20
+    unit_number = 1
+  }
+
+  depends_on = ["vsphere_nas_datastore.datastore"]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_NAS_HOST"),
+		os.Getenv("VSPHERE_NFS_PATH"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_ESXI_HOST2"),
+		os.Getenv("VSPHERE_ESXI_HOST3"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneDatastoreCluster() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "nfs_host" {
+  default = "%s"
+}
+
+variable "nfs_path" {
+  default = "%s"
+}
+
+variable "esxi_hosts" {
+  default = [
+    "%s",
+    "%s",
+    "%s",
+  ]
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_host" "esxi_hosts" {
+  count         = "${length(var.esxi_hosts)}"
+  name          = "${var.esxi_hosts[count.index]}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_datastore_cluster" "datastore_cluster" {
+  name          = "terraform-datastore-cluster-test"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore" {
+  name                 = "terraform-test-nas"
+  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name                 = "terraform-test"
+  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+
+  depends_on = ["vsphere_nas_datastore.datastore"]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_NAS_HOST"),
+		os.Getenv("VSPHERE_NFS_PATH"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_ESXI_HOST2"),
+		os.Getenv("VSPHERE_ESXI_HOST3"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionDatastoreCluster(clusterName string) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "nfs_host" {
+  default = "%s"
+}
+
+variable "nfs_path" {
+  default = "%s"
+}
+
+variable "nfs_path2" {
+  default = "%s"
+}
+
+variable "esxi_hosts" {
+  default = [
+    "%s",
+    "%s",
+    "%s",
+  ]
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_host" "esxi_hosts" {
+  count         = "${length(var.esxi_hosts)}"
+  name          = "${var.esxi_hosts[count.index]}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_datastore_cluster" "%s" {
+  name          = "%s"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore" {
+  name                 = "terraform-test-nas"
+  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.%s.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path}"
+}
+
+resource "vsphere_datastore_cluster" "%s" {
+  name          = "%s"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore2" {
+  name                 = "terraform-test-nas2"
+  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.%s.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path2}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name                 = "terraform-test"
+  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
+  datastore_cluster_id = "${vsphere_datastore_cluster.%s.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+
+  depends_on = [
+	"vsphere_nas_datastore.datastore",
+	"vsphere_nas_datastore.datastore2",
+	]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_NAS_HOST"),
+		os.Getenv("VSPHERE_NFS_PATH"),
+		os.Getenv("VSPHERE_NFS_PATH2"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_ESXI_HOST2"),
+		os.Getenv("VSPHERE_ESXI_HOST3"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		testAccResourceVSphereVirtualMachineDatastoreCluster,
+		testAccResourceVSphereVirtualMachineDatastoreCluster,
+		testAccResourceVSphereVirtualMachineDatastoreCluster,
+		testAccResourceVSphereVirtualMachineDatastoreClusterAlt,
+		testAccResourceVSphereVirtualMachineDatastoreClusterAlt,
+		testAccResourceVSphereVirtualMachineDatastoreClusterAlt,
+		clusterName,
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigHostVMotionDatastoreCluster(host string) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "nfs_host" {
+  default = "%s"
+}
+
+variable "nfs_path" {
+  default = "%s"
+}
+
+variable "mount_hosts" {
+  default = [
+    "%s",
+    "%s",
+    "%s",
+  ]
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "host" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_host" "host" {
+  name          = "${var.host}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_host" "mount_hosts" {
+  count         = "${length(var.mount_hosts)}"
+  name          = "${var.mount_hosts[count.index]}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_datastore_cluster" "datastore_cluster" {
+  name          = "terraform-datastore-cluster-test"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore" {
+  name                 = "terraform-test-nas"
+  host_system_ids      = ["${data.vsphere_host.mount_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name                 = "terraform-test"
+  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
+  host_system_id       = "${data.vsphere_host.host.id}"
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "ubuntu64Guest"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+
+  depends_on = [
+    "vsphere_nas_datastore.datastore",
+  ]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_NAS_HOST"),
+		os.Getenv("VSPHERE_NFS_PATH"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_ESXI_HOST2"),
+		os.Getenv("VSPHERE_ESXI_HOST3"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		host,
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionDatastoreClusterSingleDiskStep0() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "nfs_host" {
+  default = "%s"
+}
+
+variable "nfs_path" {
+  default = "%s"
+}
+
+variable "esxi_hosts" {
+  default = [
+    "%s",
+    "%s",
+    "%s",
+  ]
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_host" "esxi_hosts" {
+  count         = "${length(var.esxi_hosts)}"
+  name          = "${var.esxi_hosts[count.index]}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_datastore_cluster" "datastore_cluster" {
+  name          = "terraform-datastore-cluster-test"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore" {
+  name                 = "terraform-test-nas"
+  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name                 = "terraform-test"
+  resource_pool_id     = "${data.vsphere_resource_pool.pool.id}"
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "ubuntu64Guest"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  disk {
+    label       = "disk1"
+    size        = 1
+    unit_number = 1
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+
+  depends_on = [
+    "vsphere_nas_datastore.datastore",
+  ]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_NAS_HOST"),
+		os.Getenv("VSPHERE_NFS_PATH"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_ESXI_HOST2"),
+		os.Getenv("VSPHERE_ESXI_HOST3"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigStorageVMotionDatastoreClusterSingleDiskStep1(datastore string) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "nfs_host" {
+  default = "%s"
+}
+
+variable "nfs_path" {
+  default = "%s"
+}
+
+variable "esxi_hosts" {
+  default = [
+    "%s",
+    "%s",
+    "%s",
+  ]
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "disk_datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_host" "esxi_hosts" {
+  count         = "${length(var.esxi_hosts)}"
+  name          = "${var.esxi_hosts[count.index]}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_datastore" "disk_datastore" {
+  name          = "${var.disk_datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_datastore_cluster" "datastore_cluster" {
+  name          = "terraform-datastore-cluster-test"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+  sdrs_enabled  = true
+}
+
+resource "vsphere_nas_datastore" "datastore" {
+  name                 = "terraform-test-nas"
+  host_system_ids      = ["${data.vsphere_host.esxi_hosts.*.id}"]
+  datastore_cluster_id = "${vsphere_datastore_cluster.datastore_cluster.id}"
+
+  type         = "NFS"
+  remote_hosts = ["${var.nfs_host}"]
+  remote_path  = "${var.nfs_path}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${vsphere_nas_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "ubuntu64Guest"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  disk {
+    label        = "disk1"
+    datastore_id = "${data.vsphere_datastore.disk_datastore.id}"
+    size         = 1
+    unit_number  = 1
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+
+  depends_on = [
+    "vsphere_nas_datastore.datastore",
+  ]
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_NAS_HOST"),
+		os.Getenv("VSPHERE_NFS_PATH"),
+		os.Getenv("VSPHERE_ESXI_HOST"),
+		os.Getenv("VSPHERE_ESXI_HOST2"),
+		os.Getenv("VSPHERE_ESXI_HOST3"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		datastore,
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneNoGateway() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+	wait_for_guest_net_routable = false
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "terraform-test"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneParameterized(datastore, hostname string) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "ipv4_address" {
+  default = "%s"
+}
+
+variable "ipv4_netmask" {
+  default = "%s"
+}
+
+variable "ipv4_gateway" {
+  default = "%s"
+}
+
+variable "dns_server" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "template" {
+  default = "%s"
+}
+
+variable "linked_clone" {
+  default = "%s"
+}
+
+variable "hostname" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  network_interface {
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+  }
+
+  disk {
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
+
+    customize {
+      linux_options {
+        host_name = "${var.hostname}"
+        domain    = "test.internal"
+      }
+
+      network_interface {
+        ipv4_address = "${var.ipv4_address}"
+        ipv4_netmask = "${var.ipv4_netmask}"
+      }
+
+      ipv4_gateway    = "${var.ipv4_gateway}"
+      dns_server_list = ["${var.dns_server}"]
+      dns_suffix_list = ["test.internal"]
+    }
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL"),
+		os.Getenv("VSPHERE_IPV4_ADDRESS"),
+		os.Getenv("VSPHERE_IPV4_PREFIX"),
+		os.Getenv("VSPHERE_IPV4_GATEWAY"),
+		os.Getenv("VSPHERE_DNS"),
+		datastore,
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		hostname,
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigHighSensitivity() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus            = 2
+  memory              = 2048
+  memory_reservation  = 2048
+  latency_sensitivity = "high"
+  guest_id            = "other3xLinux64Guest"
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigOvfDeploy() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "ovf_url" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  wait_for_guest_net_timeout = -1
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+
+  ovf_deploy {
+    remote_ovf_url    = "${var.ovf_url}"
+    disk_provisioning = "thin"
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_OVF_URL"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneFromContentLibrary() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "library_item_id" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  wait_for_guest_net_timeout = -1
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+
+  clone {
+    content_library_item_id = "${var.library_item_id}"
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_CONTENT_LIBRARY_ITEM_ID"),
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigStoragePolicy() string {
+	return fmt.Sprintf(`
+variable "datacenter" {
+  default = "%s"
+}
+
+variable "resource_pool" {
+  default = "%s"
+}
+
+variable "network_label" {
+  default = "%s"
+}
+
+variable "datastore" {
+  default = "%s"
+}
+
+variable "storage_policy" {
+  default = "%s"
+}
+
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
+}
+
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_storage_policy" "policy" {
+  name = "${var.storage_policy}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name              = "terraform-test"
+  resource_pool_id  = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id      = "${data.vsphere_datastore.datastore.id}"
+  storage_policy_id = "${data.vsphere_storage_policy.policy.id}"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  wait_for_guest_net_timeout = -1
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label             = "disk0"
+    size              = 20
+    storage_policy_id = "${data.vsphere_storage_policy.policy.id}"
+  }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
-		os.Getenv("VSPHERE_NAS_HOST"),
-		os.Getenv("VSPHERE_NFS_PATH"),
-		os.Getenv("VSPHERE_ESXI_HOST"),
-		os.Getenv("VSPHERE_ESXI_HOST2"),
-		os.Getenv("VSPHERE_ESXI_HOST3"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		datastore,
+		os.Getenv("VSPHERE_STORAGE_POLICY"),
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneNoGateway() string {
+func testAccResourceVSphereVirtualMachineConfigCloudInit(userData string) string {
+	var cloudInit string
+	if userData != "" {
+		cloudInit = fmt.Sprintf(`
+  cloud_init {
+    user_data               = %q
+    generate_network_config = true
+  }
+`, userData)
+	}
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -11494,30 +13939,10 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
-  default = "%s"
-}
-
-variable "ipv4_netmask" {
-  default = "%s"
-}
-
-variable "dns_server" {
-  default = "%s"
-}
-
 variable "datastore" {
   default = "%s"
 }
 
-variable "template" {
-  default = "%s"
-}
-
-variable "linked_clone" {
-  default = "%s"
-}
-
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -11537,11 +13962,6 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
-  datacenter_id = "${data.vsphere_datacenter.dc.id}"
-}
-
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
@@ -11549,56 +13969,29 @@ resource "vsphere_virtual_machine" "vm" {
 
   num_cpus = 2
   memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  guest_id = "other3xLinux64Guest"
 
-	wait_for_guest_net_routable = false
+  wait_for_guest_net_timeout = -1
 
   network_interface {
-    network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    network_id = "${data.vsphere_network.network.id}"
   }
 
   disk {
-    label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
-  }
-
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "terraform-test"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
-
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+    label = "disk0"
+    size  = 20
   }
-}
+%s}
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_DNS"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
+		cloudInit,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigCloneParameterized(datastore, hostname string) string {
+func testAccResourceVSphereVirtualMachineConfigPowerState(powerState string) string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -11612,35 +14005,78 @@ variable "network_label" {
   default = "%s"
 }
 
-variable "ipv4_address" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "ipv4_netmask" {
-  default = "%s"
+data "vsphere_datacenter" "dc" {
+  name = "${var.datacenter}"
 }
 
-variable "ipv4_gateway" {
-  default = "%s"
+data "vsphere_datastore" "datastore" {
+  name          = "${var.datastore}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-variable "dns_server" {
+data "vsphere_resource_pool" "pool" {
+  name          = "${var.resource_pool}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+data "vsphere_network" "network" {
+  name          = "${var.network_label}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
+resource "vsphere_virtual_machine" "vm" {
+  name             = "terraform-test"
+  resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
+  datastore_id     = "${data.vsphere_datastore.datastore.id}"
+  power_state      = "%s"
+
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "other3xLinux64Guest"
+
+  wait_for_guest_net_timeout = -1
+
+  network_interface {
+    network_id = "${data.vsphere_network.network.id}"
+  }
+
+  disk {
+    label = "disk0"
+    size  = 20
+  }
+}
+`,
+		os.Getenv("VSPHERE_DATACENTER"),
+		os.Getenv("VSPHERE_RESOURCE_POOL"),
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		powerState,
+	)
+}
+
+func testAccResourceVSphereVirtualMachineConfigCloneInstant(cpus, memory int) string {
+	return fmt.Sprintf(`
+variable "datacenter" {
   default = "%s"
 }
 
-variable "datastore" {
+variable "resource_pool" {
   default = "%s"
 }
 
-variable "template" {
+variable "network_label" {
   default = "%s"
 }
 
-variable "linked_clone" {
+variable "datastore" {
   default = "%s"
 }
 
-variable "hostname" {
+variable "source" {
   default = "%s"
 }
 
@@ -11663,8 +14099,8 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
-data "vsphere_virtual_machine" "template" {
-  name          = "${var.template}"
+data "vsphere_virtual_machine" "source" {
+  name          = "${var.source}"
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
@@ -11673,59 +14109,45 @@ resource "vsphere_virtual_machine" "vm" {
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus = 2
-  memory   = 2048
-  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+  num_cpus = %d
+  memory   = %d
+  guest_id = "${data.vsphere_virtual_machine.source.guest_id}"
+
+  wait_for_guest_net_timeout = -1
 
   network_interface {
     network_id   = "${data.vsphere_network.network.id}"
-    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
+    adapter_type = "${data.vsphere_virtual_machine.source.network_interface_types[0]}"
   }
 
   disk {
     label            = "disk0"
-    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
-    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
-    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+    size             = "${data.vsphere_virtual_machine.source.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.source.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.source.disks.0.thin_provisioned}"
   }
 
-  clone {
-    template_uuid = "${data.vsphere_virtual_machine.template.id}"
-    linked_clone  = "${var.linked_clone != "" ? "true" : "false" }"
-
-    customize {
-      linux_options {
-        host_name = "${var.hostname}"
-        domain    = "test.internal"
-      }
-
-      network_interface {
-        ipv4_address = "${var.ipv4_address}"
-        ipv4_netmask = "${var.ipv4_netmask}"
-      }
+  extra_config {
+    "guestinfo.terraform.clone" = "instant"
+  }
 
-      ipv4_gateway    = "${var.ipv4_gateway}"
-      dns_server_list = ["${var.dns_server}"]
-      dns_suffix_list = ["test.internal"]
-    }
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.source.id}"
+    instant_clone = true
   }
 }
 `,
 		os.Getenv("VSPHERE_DATACENTER"),
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
-		os.Getenv("VSPHERE_NETWORK_LABEL"),
-		os.Getenv("VSPHERE_IPV4_ADDRESS"),
-		os.Getenv("VSPHERE_IPV4_PREFIX"),
-		os.Getenv("VSPHERE_IPV4_GATEWAY"),
-		os.Getenv("VSPHERE_DNS"),
-		datastore,
-		os.Getenv("VSPHERE_TEMPLATE"),
-		os.Getenv("VSPHERE_USE_LINKED_CLONE"),
-		hostname,
+		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
+		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_INSTANT_CLONE_SOURCE"),
+		cpus,
+		memory,
 	)
 }
 
-func testAccResourceVSphereVirtualMachineConfigHighSensitivity() string {
+func testAccResourceVSphereVirtualMachineConfigCloneSnapshotName() string {
 	return fmt.Sprintf(`
 variable "datacenter" {
   default = "%s"
@@ -11743,6 +14165,14 @@ variable "datastore" {
   default = "%s"
 }
 
+variable "template" {
+  default = "%s"
+}
+
+variable "snapshot_name" {
+  default = "%s"
+}
+
 data "vsphere_datacenter" "dc" {
   name = "${var.datacenter}"
 }
@@ -11762,24 +14192,38 @@ data "vsphere_network" "network" {
   datacenter_id = "${data.vsphere_datacenter.dc.id}"
 }
 
+data "vsphere_virtual_machine" "template" {
+  name          = "${var.template}"
+  datacenter_id = "${data.vsphere_datacenter.dc.id}"
+}
+
 resource "vsphere_virtual_machine" "vm" {
   name             = "terraform-test"
   resource_pool_id = "${data.vsphere_resource_pool.pool.id}"
   datastore_id     = "${data.vsphere_datastore.datastore.id}"
 
-  num_cpus            = 2
-  memory              = 2048
-  memory_reservation  = 2048
-  latency_sensitivity = "high"
-  guest_id            = "other3xLinux64Guest"
+  num_cpus = 2
+  memory   = 2048
+  guest_id = "${data.vsphere_virtual_machine.template.guest_id}"
+
+  wait_for_guest_net_timeout = -1
 
   network_interface {
-    network_id = "${data.vsphere_network.network.id}"
+    network_id   = "${data.vsphere_network.network.id}"
+    adapter_type = "${data.vsphere_virtual_machine.template.network_interface_types[0]}"
   }
 
   disk {
-    label = "disk0"
-    size  = 20
+    label            = "disk0"
+    size             = "${data.vsphere_virtual_machine.template.disks.0.size}"
+    eagerly_scrub    = "${data.vsphere_virtual_machine.template.disks.0.eagerly_scrub}"
+    thin_provisioned = "${data.vsphere_virtual_machine.template.disks.0.thin_provisioned}"
+  }
+
+  clone {
+    template_uuid = "${data.vsphere_virtual_machine.template.id}"
+    linked_clone  = true
+    snapshot_name = "${var.snapshot_name}"
   }
 }
 `,
@@ -11787,5 +14231,129 @@ resource "vsphere_virtual_machine" "vm" {
 		os.Getenv("VSPHERE_RESOURCE_POOL"),
 		os.Getenv("VSPHERE_NETWORK_LABEL_PXE"),
 		os.Getenv("VSPHERE_DATASTORE"),
+		os.Getenv("VSPHERE_TEMPLATE"),
+		os.Getenv("VSPHERE_TEMPLATE_SNAPSHOT_NAME"),
 	)
 }
+
+// testVirtualMachineDevice is a device of the virtual machine served by
+// testVirtualMachineSOAPServer, along with the API version it was added in.
+type testVirtualMachineDevice struct {
+	version string
+	xml     string
+}
+
+// testVirtualMachineSOAPServer returns a server that answers property
+// requests for a virtual machine with the supplied devices. Like vSphere,
+// the server drops devices that were added in an API version newer than the
+// version of the request. The versions in these tests only have single digit
+// components, so they can be compared as strings.
+func testVirtualMachineSOAPServer(devices ...testVirtualMachineDevice) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := strings.TrimPrefix(r.Header.Get("SOAPAction"), "urn:vim25/")
+		var b bytes.Buffer
+		for _, device := range devices {
+			if version >= device.version {
+				b.WriteString(device.xml)
+			}
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<soapenv:Body>
+<RetrievePropertiesResponse xmlns="urn:vim25">
+<returnval>
+<obj type="VirtualMachine">vm-1</obj>
+<propSet><name>config</name><val xsi:type="VirtualMachineConfigInfo"><uuid>42010b2b-3f8a-4b7a-9e5e-2b1d3c4e5f60</uuid><hardware><numCPU>1</numCPU><memoryMB>1024</memoryMB>%s</hardware></val></propSet>
+</returnval>
+</RetrievePropertiesResponse>
+</soapenv:Body>
+</soapenv:Envelope>`, b.String())
+	}))
+}
+
+// testVirtualMachineSOAPMeta returns provider metadata with a client for the
+// supplied server, which reports the supplied API version.
+func testVirtualMachineSOAPMeta(t *testing.T, serverURL, apiVersion string) *VSphereClient {
+	u, err := url.Parse(serverURL + "/sdk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := soap.NewClient(u, true)
+	client := &govmomi.Client{
+		Client: &vim25.Client{
+			Client:       sc,
+			RoundTripper: sc,
+		},
+	}
+	client.ServiceContent.PropertyCollector = types.ManagedObjectReference{Type: "PropertyCollector", Value: "propertyCollector"}
+	client.ServiceContent.About.ApiVersion = apiVersion
+	return &VSphereClient{vimClient: client}
+}
+
+// testVirtualMachineImportedDevices reads the devices of the virtual machine
+// from the supplied client, as the first read after an import does.
+func testVirtualMachineImportedDevices(t *testing.T, client *govmomi.Client) object.VirtualDeviceList {
+	vm := object.NewVirtualMachine(client.Client, types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"})
+	props, err := virtualmachine.Properties(vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return object.VirtualDeviceList(props.Config.Hardware.Device)
+}
+
+func TestResourceVSphereVirtualMachineImportVTPM(t *testing.T) {
+	s := testVirtualMachineSOAPServer(testVirtualMachineDevice{
+		version: "6.7",
+		xml:     `<device xsi:type="VirtualTPM"><key>11000</key></device>`,
+	})
+	defer s.Close()
+	meta := testVirtualMachineSOAPMeta(t, s.URL, "7.0.3.0")
+
+	// The state of an imported virtual machine has no vtpm, so the version of
+	// the client can't depend on it.
+	d := resourceVSphereVirtualMachine().Data(&terraform.InstanceState{
+		ID:         "42010b2b-3f8a-4b7a-9e5e-2b1d3c4e5f60",
+		Attributes: map[string]string{"imported": "true"},
+	})
+	if err := virtualdevice.VTPMRefreshOperation(d, testVirtualMachineImportedDevices(t, resourceVSphereVirtualMachineClient(meta))); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Get("vtpm").(bool) {
+		t.Fatal("expected vtpm to be read from the imported virtual machine")
+	}
+
+	// Reading with the version of the API bindings drops the TPM.
+	if devices := testVirtualMachineImportedDevices(t, meta.vimClient); len(devices) > 0 {
+		t.Fatalf("expected the TPM to be dropped by the server, got %d devices", len(devices))
+	}
+}
+
+func TestResourceVSphereVirtualMachineImportPCIDeviceDynamic(t *testing.T) {
+	s := testVirtualMachineSOAPServer(testVirtualMachineDevice{
+		version: "7.0",
+		xml: `<device xsi:type="VirtualPCIPassthrough"><key>13000</key>` +
+			`<backing xsi:type="VirtualPCIPassthroughDynamicBackingInfo"><deviceName></deviceName>` +
+			`<allowedDevice><vendorId>4318</vendorId><deviceId>7864</deviceId></allowedDevice></backing></device>`,
+	})
+	defer s.Close()
+	meta := testVirtualMachineSOAPMeta(t, s.URL, "7.0.3.0")
+
+	d := resourceVSphereVirtualMachine().Data(&terraform.InstanceState{
+		ID:         "42010b2b-3f8a-4b7a-9e5e-2b1d3c4e5f60",
+		Attributes: map[string]string{"imported": "true"},
+	})
+	if err := virtualdevice.PCIDeviceRefreshOperation(d, testVirtualMachineImportedDevices(t, resourceVSphereVirtualMachineClient(meta))); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{
+			"host_device_id": "",
+			"vendor_id":      "10de",
+			"device_id":      "1eb8",
+		},
+	}
+	if actual := d.Get("pci_device"); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,309 @@
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
+	"github.com/vmware/vic/pkg/vsphere/tags"
+)
+
+func resourceVSphereVMStoragePolicy() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceVSphereVMStoragePolicyCreate,
+		Read:   resourceVSphereVMStoragePolicyRead,
+		Update: resourceVSphereVMStoragePolicyUpdate,
+		Delete: resourceVSphereVMStoragePolicyDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceVSphereVMStoragePolicyImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Description: "The name of the storage policy.",
+				Required:    true,
+			},
+			"description": {
+				Type:        schema.TypeString,
+				Description: "The description of the storage policy.",
+				Optional:    true,
+			},
+			"tag_rules": {
+				Type:        schema.TypeList,
+				Description: "Tag-based placement rules. Datastores must match all rules to be compatible with the policy.",
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"tag_category": {
+							Type:        schema.TypeString,
+							Description: "The ID of the tag category the rule applies to.",
+							Required:    true,
+						},
+						"tags": {
+							Type:        schema.TypeSet,
+							Description: "The IDs of the tags in the category that the rule matches.",
+							Required:    true,
+							MinItems:    1,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+						"include_datastores_with_tags": {
+							Type:        schema.TypeBool,
+							Description: "Match datastores that have any of the tags. When false, match datastores that have none of them.",
+							Optional:    true,
+							Default:     true,
+						},
+					},
+				},
+			},
+			"vsan_rules": {
+				Type:        schema.TypeList,
+				Description: "vSAN capability rules.",
+				Optional:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"failures_to_tolerate": {
+							Type:         schema.TypeInt,
+							Description:  "The number of host, disk, or network failures that storage objects can tolerate.",
+							Optional:     true,
+							Default:      1,
+							ValidateFunc: validation.IntBetween(0, 3),
+						},
+						"stripe_width": {
+							Type:         schema.TypeInt,
+							Description:  "The number of capacity disks that each replica of a storage object is striped across.",
+							Optional:     true,
+							Default:      1,
+							ValidateFunc: validation.IntBetween(1, 12),
+						},
+						"object_space_reservation": {
+							Type:         schema.TypeInt,
+							Description:  "The percentage of the logical size of storage objects that is reserved on creation.",
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validation.IntBetween(0, 100),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceVSphereVMStoragePolicyCreate(d *schema.ResourceData, meta interface{}) error {
+	if err := viapi.ValidateVirtualCenter(meta.(*VSphereClient).vimClient); err != nil {
+		return fmt.Errorf("vsphere_vm_storage_policy requires vCenter: %s", err)
+	}
+	client, err := meta.(*VSphereClient).PbmClient()
+	if err != nil {
+		return err
+	}
+
+	policy, err := expandVMStoragePolicy(d, meta)
+	if err != nil {
+		return err
+	}
+	id, err := spbm.CreatePolicy(client, policy)
+	if err != nil {
+		return fmt.Errorf("could not create storage policy: %s", err)
+	}
+	d.SetId(id)
+	return resourceVSphereVMStoragePolicyRead(d, meta)
+}
+
+func resourceVSphereVMStoragePolicyRead(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).PbmClient()
+	if err != nil {
+		return err
+	}
+	id := d.Id()
+
+	policy, err := spbm.GetPolicy(client, id)
+	if err != nil {
+		if spbm.IsNotFoundError(err) {
+			log.Printf("[DEBUG] Storage policy %q not found, removing from state", id)
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("could not locate storage policy with id %q: %s", id, err)
+	}
+	d.Set("name", policy.Name)
+	d.Set("description", policy.Description)
+	return flattenVMStoragePolicyRules(d, meta, policy)
+}
+
+func resourceVSphereVMStoragePolicyUpdate(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).PbmClient()
+	if err != nil {
+		return err
+	}
+	id := d.Id()
+
+	policy, err := expandVMStoragePolicy(d, meta)
+	if err != nil {
+		return err
+	}
+	if err := spbm.UpdatePolicy(client, id, policy); err != nil {
+		return fmt.Errorf("could not update storage policy with id %q: %s", id, err)
+	}
+	return resourceVSphereVMStoragePolicyRead(d, meta)
+}
+
+func resourceVSphereVMStoragePolicyDelete(d *schema.ResourceData, meta interface{}) error {
+	client, err := meta.(*VSphereClient).PbmClient()
+	if err != nil {
+		return err
+	}
+	id := d.Id()
+
+	// vCenter removes the policy from any virtual machines and disks that use
+	// it, which would silently change their placement, so refuse to delete a
+	// policy that is still in use.
+	entities, err := spbm.AssociatedEntities(client, id)
+	if err != nil {
+		return err
+	}
+	if len(entities) > 0 {
+		return fmt.Errorf(
+			"storage policy %q is associated with %d virtual machine objects and cannot be deleted. Remove the policy from the virtual machines and disks that use it first",
+			d.Get("name").(string),
+			len(entities),
+		)
+	}
+	if err := spbm.DeletePolicy(client, id); err != nil {
+		return fmt.Errorf("could not delete storage policy with id %q: %s", id, err)
+	}
+	return nil
+}
+
+func resourceVSphereVMStoragePolicyImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if err := viapi.ValidateVirtualCenter(meta.(*VSphereClient).vimClient); err != nil {
+		return nil, fmt.Errorf("vsphere_vm_storage_policy requires vCenter: %s", err)
+	}
+	client, err := meta.(*VSphereClient).PbmClient()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := spbm.GetPolicy(client, d.Id()); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// expandVMStoragePolicy reads a storage policy from ResourceData. Storage
+// policies reference tag categories and tags by name, so the IDs in tag_rules
+// are translated to names here.
+func expandVMStoragePolicy(d *schema.ResourceData, meta interface{}) (*spbm.Policy, error) {
+	policy := &spbm.Policy{
+		Name:        d.Get("name").(string),
+		Description: d.Get("description").(string),
+	}
+
+	rules := d.Get("tag_rules").([]interface{})
+	if len(rules) > 0 {
+		client, err := meta.(*VSphereClient).TagsClient()
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultAPITimeout)
+		defer cancel()
+		for _, v := range rules {
+			m := v.(map[string]interface{})
+			category, err := client.GetCategory(ctx, m["tag_category"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("could not locate tag category with id %q: %s", m["tag_category"].(string), err)
+			}
+			rule := spbm.TagRule{
+				Category: category.Name,
+				Exclude:  !m["include_datastores_with_tags"].(bool),
+			}
+			for _, id := range m["tags"].(*schema.Set).List() {
+				tag, err := client.GetTag(ctx, id.(string))
+				if err != nil {
+					return nil, fmt.Errorf("could not locate tag with id %q: %s", id.(string), err)
+				}
+				if tag.CategoryID != category.ID {
+					return nil, fmt.Errorf("tag %q is not in tag category %q", tag.Name, category.Name)
+				}
+				rule.Tags = append(rule.Tags, tag.Name)
+			}
+			policy.TagRules = append(policy.TagRules, rule)
+		}
+	}
+
+	if v, ok := d.GetOk("vsan_rules.0"); ok {
+		m := v.(map[string]interface{})
+		policy.VsanRule = &spbm.VsanRule{
+			FailuresToTolerate:     m["failures_to_tolerate"].(int),
+			StripeWidth:            m["stripe_width"].(int),
+			ObjectSpaceReservation: m["object_space_reservation"].(int),
+		}
+	}
+
+	if len(policy.TagRules) < 1 && policy.VsanRule == nil {
+		return nil, fmt.Errorf("storage policy %q must have at least one of tag_rules or vsan_rules", policy.Name)
+	}
+	return policy, nil
+}
+
+// flattenVMStoragePolicyRules saves the rules of a storage policy to
+// ResourceData, translating tag category and tag names back to IDs.
+func flattenVMStoragePolicyRules(d *schema.ResourceData, meta interface{}, policy *spbm.Policy) error {
+	var tagRules []interface{}
+	if len(policy.TagRules) > 0 {
+		client, err := meta.(*VSphereClient).TagsClient()
+		if err != nil {
+			return err
+		}
+		for _, r := range policy.TagRules {
+			rule, err := flattenVMStoragePolicyTagRule(client, r)
+			if err != nil {
+				return err
+			}
+			tagRules = append(tagRules, rule)
+		}
+	}
+	if err := d.Set("tag_rules", tagRules); err != nil {
+		return fmt.Errorf("could not set tag rule data for storage policy: %s", err)
+	}
+
+	var vsanRules []interface{}
+	if r := policy.VsanRule; r != nil {
+		vsanRules = append(vsanRules, map[string]interface{}{
+			"failures_to_tolerate":     r.FailuresToTolerate,
+			"stripe_width":             r.StripeWidth,
+			"object_space_reservation": r.ObjectSpaceReservation,
+		})
+	}
+	if err := d.Set("vsan_rules", vsanRules); err != nil {
+		return fmt.Errorf("could not set vSAN rule data for storage policy: %s", err)
+	}
+	return nil
+}
+
+// flattenVMStoragePolicyTagRule returns the ResourceData representation of a
+// single tag rule.
+func flattenVMStoragePolicyTagRule(client *tags.RestClient, r spbm.TagRule) (map[string]interface{}, error) {
+	categoryID, err := tagCategoryByName(client, r.Category)
+	if err != nil {
+		return nil, err
+	}
+	var tagIDs []string
+	for _, name := range r.Tags {
+		id, err := tagByName(client, name, categoryID)
+		if err != nil {
+			return nil, err
+		}
+		tagIDs = append(tagIDs, id)
+	}
+	return map[string]interface{}{
+		"tag_category":                 categoryID,
+		"tags":                         tagIDs,
+		"include_datastores_with_tags": !r.Exclude,
+	}, nil
+}
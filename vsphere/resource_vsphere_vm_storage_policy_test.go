@@ -0,0 +1,202 @@
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
+)
+
+func TestAccResourceVSphereVMStoragePolicy_tagRules(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVMStoragePolicyExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVMStoragePolicyConfigTagRules(true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVMStoragePolicyExists(true),
+					testAccResourceVSphereVMStoragePolicyHasTagRule("terraform-test-category", []string{"terraform-test-tag"}, false),
+					resource.TestCheckResourceAttr("vsphere_vm_storage_policy.policy", "tag_rules.0.tags.#", "1"),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVMStoragePolicyConfigTagRules(false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVMStoragePolicyExists(true),
+					testAccResourceVSphereVMStoragePolicyHasTagRule("terraform-test-category", []string{"terraform-test-tag"}, true),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVMStoragePolicy_vsanRules(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVMStoragePolicyExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVMStoragePolicyConfigVsanRules(1, 1),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVMStoragePolicyExists(true),
+					testAccResourceVSphereVMStoragePolicyHasVsanRule(1, 1),
+				),
+			},
+			{
+				Config: testAccResourceVSphereVMStoragePolicyConfigVsanRules(0, 2),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVMStoragePolicyExists(true),
+					testAccResourceVSphereVMStoragePolicyHasVsanRule(0, 2),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVSphereVMStoragePolicy_import(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Providers:    testAccProviders,
+		CheckDestroy: testAccResourceVSphereVMStoragePolicyExists(false),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceVSphereVMStoragePolicyConfigTagRules(true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccResourceVSphereVMStoragePolicyExists(true),
+				),
+			},
+			{
+				ResourceName:      "vsphere_vm_storage_policy.policy",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccResourceVSphereVMStoragePolicyExists(expected bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		_, err := testGetVMStoragePolicy(s, "policy")
+		if err != nil {
+			if spbm.IsNotFoundError(err) && !expected {
+				// Expected missing
+				return nil
+			}
+			return err
+		}
+		if !expected {
+			return errors.New("expected storage policy to be missing")
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereVMStoragePolicyHasTagRule(category string, tags []string, exclude bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		policy, err := testGetVMStoragePolicy(s, "policy")
+		if err != nil {
+			return err
+		}
+		if len(policy.TagRules) != 1 {
+			return fmt.Errorf("expected 1 tag rule, got %d", len(policy.TagRules))
+		}
+		r := policy.TagRules[0]
+		if r.Category != category {
+			return fmt.Errorf("expected tag category to be %q, got %q", category, r.Category)
+		}
+		if fmt.Sprint(r.Tags) != fmt.Sprint(tags) {
+			return fmt.Errorf("expected tags to be %v, got %v", tags, r.Tags)
+		}
+		if r.Exclude != exclude {
+			return fmt.Errorf("expected exclude to be %t, got %t", exclude, r.Exclude)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereVMStoragePolicyHasVsanRule(ftt, stripeWidth int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		policy, err := testGetVMStoragePolicy(s, "policy")
+		if err != nil {
+			return err
+		}
+		if policy.VsanRule == nil {
+			return errors.New("expected storage policy to have vSAN rules")
+		}
+		if policy.VsanRule.FailuresToTolerate != ftt {
+			return fmt.Errorf("expected failures to tolerate to be %d, got %d", ftt, policy.VsanRule.FailuresToTolerate)
+		}
+		if policy.VsanRule.StripeWidth != stripeWidth {
+			return fmt.Errorf("expected stripe width to be %d, got %d", stripeWidth, policy.VsanRule.StripeWidth)
+		}
+		return nil
+	}
+}
+
+func testAccResourceVSphereVMStoragePolicyConfigTagRules(include bool) string {
+	return fmt.Sprintf(`
+resource "vsphere_tag_category" "category" {
+  name        = "terraform-test-category"
+  cardinality = "SINGLE"
+
+  associable_types = [
+    "Datastore",
+  ]
+}
+
+resource "vsphere_tag" "tag" {
+  name        = "terraform-test-tag"
+  category_id = "${vsphere_tag_category.category.id}"
+}
+
+resource "vsphere_vm_storage_policy" "policy" {
+  name        = "terraform-test-policy"
+  description = "Managed by Terraform"
+
+  tag_rules {
+    tag_category                 = "${vsphere_tag_category.category.id}"
+    tags                         = ["${vsphere_tag.tag.id}"]
+    include_datastores_with_tags = %t
+  }
+}
+`,
+		include,
+	)
+}
+
+func testAccResourceVSphereVMStoragePolicyConfigVsanRules(ftt, stripeWidth int) string {
+	return fmt.Sprintf(`
+variable "ftt" {
+  default = "%d"
+}
+
+variable "stripe_width" {
+  default = "%d"
+}
+
+resource "vsphere_vm_storage_policy" "policy" {
+  name = "terraform-test-policy"
+
+  vsan_rules {
+    failures_to_tolerate     = "${var.ftt}"
+    stripe_width             = "${var.stripe_width}"
+    object_space_reservation = 0
+  }
+}
+`,
+		ftt,
+		stripeWidth,
+	)
+}
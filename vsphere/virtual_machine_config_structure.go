@@ -5,14 +5,19 @@ import (
 	"io/ioutil"
 	"log"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 
 	"github.com/hashicorp/terraform/helper/logging"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/spbm"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/structure"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/viapi"
 	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/virtualdevice"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/vim25/types"
 )
@@ -42,6 +47,14 @@ var virtualMachineFirmwareAllowedValues = []string{
 	string(types.GuestOsDescriptorFirmwareTypeEfi),
 }
 
+var virtualMachineNetworkBootProtocolAllowedValues = []string{
+	string(types.VirtualMachineBootOptionsNetworkBootProtocolTypeIpv4),
+	string(types.VirtualMachineBootOptionsNetworkBootProtocolTypeIpv6),
+}
+
+// virtualMachineBootOrderEntryRegexp matches an entry in boot_order.
+var virtualMachineBootOrderEntryRegexp = regexp.MustCompile("^(disk:.+|network_interface:[0-9]+|cdrom|floppy)$")
+
 var virtualMachineLatencySensitivityAllowedValues = []string{
 	string(types.LatencySensitivitySensitivityLevelLow),
 	string(types.LatencySensitivitySensitivityLevelNormal),
@@ -49,6 +62,25 @@ var virtualMachineLatencySensitivityAllowedValues = []string{
 	string(types.LatencySensitivitySensitivityLevelHigh),
 }
 
+// virtualMachineCPUIDMaskRegisterDefault is the CPUID mask register value that
+// leaves all of the bits of the register at their default value.
+const virtualMachineCPUIDMaskRegisterDefault = "----:----:----:----:----:----:----:----"
+
+// virtualMachineCPUIDMaskRegisterRegexp matches a CPUID mask register value,
+// which is 32 mask characters in groups of 4 separated by colons.
+var virtualMachineCPUIDMaskRegisterRegexp = regexp.MustCompile("^([-01HhRrXx]{4}:){7}[-01HhRrXx]{4}$")
+
+var virtualMachineCPUIDMaskVendorAllowedValues = []string{"amd", "intel"}
+
+// virtualMachineAdvancedSettingKeys maps the integer virtual machine
+// arguments that are managed through advanced settings of the virtual machine
+// to their extraConfig keys. These settings have no counterpart in
+// VirtualMachineConfigSpec, and so they are written to extraConfig. If a key
+// is also set in extra_config, the setting is left to extra_config.
+var virtualMachineAdvancedSettingKeys = map[string]string{
+	"vnuma_max_vcpus_per_node": "numa.vcpu.maxPerVirtualNode",
+}
+
 // getWithRestart fetches the resoruce data specified at key. If the value has
 // changed, a reboot is flagged in the virtual machine by setting
 // reboot_required to true.
@@ -98,6 +130,22 @@ func schemaVirtualMachineConfigSpec() map[string]*schema.Schema {
 			Optional:    true,
 			Description: "If set to true, a virtual machine that fails to boot will try again after the delay defined in boot_retry_delay.",
 		},
+		"boot_order": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "The order of the devices to boot from. Disks are referenced as disk:<label>, network interfaces as network_interface:<index>, and the CDROM and floppy drives as cdrom and floppy.",
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringMatch(virtualMachineBootOrderEntryRegexp, "must be one of disk:<label>, network_interface:<index>, cdrom, or floppy"),
+			},
+		},
+		"network_boot_protocol": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Computed:     true,
+			Description:  "The protocol to use for network boot when firmware is efi. Can be one of ipv4 or ipv6.",
+			ValidateFunc: validation.StringInSlice(virtualMachineNetworkBootProtocolAllowedValues, false),
+		},
 
 		// VirtualMachineFlagInfo
 		"enable_disk_uuid": {
@@ -209,6 +257,64 @@ func schemaVirtualMachineConfigSpec() map[string]*schema.Schema {
 			Optional:    true,
 			Description: "Enable CPU performance counters on this virtual machine.",
 		},
+		"cpu_affinity": {
+			Type:        schema.TypeSet,
+			Optional:    true,
+			Description: "The logical processors of the host that the virtual processors of this virtual machine can be scheduled on.",
+			Elem: &schema.Schema{
+				Type:         schema.TypeInt,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+		},
+		"memory_affinity": {
+			Type:        schema.TypeSet,
+			Optional:    true,
+			Description: "The NUMA nodes of the host that the memory of this virtual machine can be allocated from.",
+			Elem: &schema.Schema{
+				Type:         schema.TypeInt,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+		},
+		"cpuid_mask": {
+			Type:        schema.TypeSet,
+			Optional:    true,
+			Description: "CPUID feature masks to apply to the virtual processors of this virtual machine.",
+			Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+				"level": {
+					Type:        schema.TypeInt,
+					Required:    true,
+					Description: "The CPUID level (the value of EAX when CPUID is called) to mask.",
+				},
+				"vendor": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Description:  "The CPU vendor to apply the mask to. Can be one of amd or intel. Applies to all vendors if not set.",
+					ValidateFunc: validation.StringInSlice(virtualMachineCPUIDMaskVendorAllowedValues, false),
+				},
+				"eax": schemaVirtualMachineCPUIDMaskRegister("eax"),
+				"ebx": schemaVirtualMachineCPUIDMaskRegister("ebx"),
+				"ecx": schemaVirtualMachineCPUIDMaskRegister("ecx"),
+				"edx": schemaVirtualMachineCPUIDMaskRegister("edx"),
+			}},
+		},
+
+		// Advanced settings
+		"vbs_enabled": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Description: "Enable Virtualization Based Security. Requires firmware to be efi, and efi_secure_boot_enabled, nested_hv_enabled, and vvtd_enabled to be set.",
+		},
+		"vvtd_enabled": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Description: "Expose an IOMMU (Intel Virtualization Technology for Directed I/O) to the guest of this virtual machine.",
+		},
+		"vnuma_max_vcpus_per_node": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Description:  "The maximum number of virtual processors in each virtual NUMA node of this virtual machine. The default sizing of vSphere is used when not set.",
+			ValidateFunc: validation.IntAtLeast(0),
+		},
 		"memory": {
 			Type:        schema.TypeInt,
 			Optional:    true,
@@ -280,6 +386,18 @@ func schemaVirtualMachineConfigSpec() map[string]*schema.Schema {
 		},
 	}
 	structure.MergeSchema(s, schemaVirtualMachineResourceAllocation())
+	// The memory reservation is locked to the size of the memory while the
+	// virtual machine has PCI passthrough devices, so the difference between
+	// the locked reservation and the configured reservation is ignored. Any
+	// other difference still shows, as does the change back to the configured
+	// reservation once the last device is removed.
+	s["memory_reservation"].DiffSuppressFunc = func(k, old, new string, d *schema.ResourceData) bool {
+		if len(d.Get("pci_device").([]interface{})) < 1 {
+			return false
+		}
+		oldMem, newMem := d.GetChange("memory")
+		return old == strconv.Itoa(oldMem.(int)) || old == strconv.Itoa(newMem.(int))
+	}
 	return s
 }
 
@@ -299,12 +417,15 @@ func vAppSubresourceSchema() map[string]*schema.Schema {
 }
 
 // expandVirtualMachineBootOptions reads certain ResourceData keys and
-// returns a VirtualMachineBootOptions.
+// returns a VirtualMachineBootOptions. The boot order is applied separately
+// once the devices of the virtual machine exist.
 func expandVirtualMachineBootOptions(d *schema.ResourceData, client *govmomi.Client) *types.VirtualMachineBootOptions {
 	obj := &types.VirtualMachineBootOptions{
 		BootDelay:        int64(d.Get("boot_delay").(int)),
 		BootRetryEnabled: structure.GetBool(d, "boot_retry_enabled"),
 		BootRetryDelay:   int64(d.Get("boot_retry_delay").(int)),
+
+		NetworkBootProtocol: d.Get("network_boot_protocol").(string),
 	}
 	// Only set EFI secure boot if we are on vSphere 6.5 and higher
 	version := viapi.ParseVersionFromClient(client)
@@ -321,6 +442,7 @@ func flattenVirtualMachineBootOptions(d *schema.ResourceData, obj *types.Virtual
 	structure.SetBoolPtr(d, "efi_secure_boot_enabled", obj.EfiSecureBootEnabled)
 	structure.SetBoolPtr(d, "boot_retry_enabled", obj.BootRetryEnabled)
 	d.Set("boot_retry_delay", obj.BootRetryDelay)
+	d.Set("network_boot_protocol", obj.NetworkBootProtocol)
 	return nil
 }
 
@@ -346,6 +468,30 @@ func flattenVirtualMachineFlagInfo(d *schema.ResourceData, obj *types.VirtualMac
 	return nil
 }
 
+// expandVirtualMachineSecurityFlags returns the IOMMU and virtualization based
+// security flags of the virtual machine if either of them has changed, or nil
+// otherwise. These flags are sent separately from the rest of the config spec
+// with virtualmachine.ReconfigureFlags.
+//
+// Changes to these flags require a restart of the virtual machine.
+func expandVirtualMachineSecurityFlags(d *schema.ResourceData) *virtualmachine.FlagInfo {
+	if !d.HasChange("vvtd_enabled") && !d.HasChange("vbs_enabled") {
+		return nil
+	}
+	return &virtualmachine.FlagInfo{
+		VvtdEnabled: getBoolWithRestart(d, "vvtd_enabled"),
+		VbsEnabled:  getBoolWithRestart(d, "vbs_enabled"),
+	}
+}
+
+// flattenVirtualMachineSecurityFlags reads the IOMMU and virtualization based
+// security flags from a FlagInfo into the passed in ResourceData.
+func flattenVirtualMachineSecurityFlags(d *schema.ResourceData, obj *virtualmachine.FlagInfo) error {
+	d.Set("vvtd_enabled", obj.VvtdEnabled)
+	d.Set("vbs_enabled", obj.VbsEnabled)
+	return nil
+}
+
 // expandToolsConfigInfo reads certain ResourceData keys and
 // returns a ToolsConfigInfo.
 func expandToolsConfigInfo(d *schema.ResourceData) *types.ToolsConfigInfo {
@@ -475,6 +621,18 @@ func flattenVirtualMachineResourceAllocation(d *schema.ResourceData, obj *types.
 	return nil
 }
 
+// schemaVirtualMachineCPUIDMaskRegister returns the schema for a register of
+// a cpuid_mask.
+func schemaVirtualMachineCPUIDMaskRegister(register string) *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      virtualMachineCPUIDMaskRegisterDefault,
+		Description:  fmt.Sprintf("The mask for the %s register, as 32 mask characters in groups of 4 separated by colons, most significant bit first.", register),
+		ValidateFunc: validation.StringMatch(virtualMachineCPUIDMaskRegisterRegexp, "must be 32 of the characters -, 0, 1, H, R, or X, in groups of 4 separated by colons"),
+	}
+}
+
 // expandExtraConfig reads in all the extra_config key/value pairs and returns
 // the appropriate OptionValue slice.
 //
@@ -568,6 +726,183 @@ func flattenExtraConfig(d *schema.ResourceData, opts []types.BaseOptionValue) er
 	return d.Set("extra_config", ec)
 }
 
+// expandVirtualMachineAdvancedSettings returns the extraConfig options for the
+// arguments in virtualMachineAdvancedSettingKeys that have changed. Unset
+// or zero values are removed from extraConfig. Keys that are set in
+// extra_config are skipped.
+//
+// All of these settings require a restart of the virtual machine.
+func expandVirtualMachineAdvancedSettings(d *schema.ResourceData) []types.BaseOptionValue {
+	var opts []types.BaseOptionValue
+	ec := d.Get("extra_config").(map[string]interface{})
+	for k, key := range virtualMachineAdvancedSettingKeys {
+		if _, ok := ec[key]; ok || !d.HasChange(k) {
+			continue
+		}
+		var value string
+		if v := getWithRestart(d, k).(int); v > 0 {
+			value = strconv.Itoa(v)
+		}
+		opts = append(opts, &types.OptionValue{
+			Key:   key,
+			Value: value,
+		})
+	}
+	// Sort the options so that the same changes always result in the same spec.
+	sort.Slice(opts, func(i, j int) bool {
+		return opts[i].GetOptionValue().Key < opts[j].GetOptionValue().Key
+	})
+	return opts
+}
+
+// flattenVirtualMachineAdvancedSettings reads the arguments in
+// virtualMachineAdvancedSettingKeys from the extraConfig of a virtual
+// machine. Settings that are missing from extraConfig are set to zero, and
+// settings whose key is set in extra_config are skipped.
+func flattenVirtualMachineAdvancedSettings(d *schema.ResourceData, opts []types.BaseOptionValue) error {
+	values := make(map[string]string)
+	for _, v := range opts {
+		ov := v.GetOptionValue()
+		if s, ok := ov.Value.(string); ok {
+			values[ov.Key] = s
+		}
+	}
+	ec := d.Get("extra_config").(map[string]interface{})
+	for k, key := range virtualMachineAdvancedSettingKeys {
+		if _, ok := ec[key]; ok {
+			continue
+		}
+		n, _ := strconv.Atoi(values[key])
+		if err := d.Set(k, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandVirtualMachineAffinityInfo reads the affinity set at key into a
+// VirtualMachineAffinityInfo. nil is returned if the set has not changed, and
+// an empty affinity set, which clears the affinity of the virtual machine, is
+// returned if the set has been removed.
+//
+// Changes to affinity require a restart of the virtual machine.
+func expandVirtualMachineAffinityInfo(d *schema.ResourceData, key string) *types.VirtualMachineAffinityInfo {
+	if !d.HasChange(key) {
+		return nil
+	}
+	log.Printf("[DEBUG] %s: Resource argument %q requires a VM restart", resourceVSphereVirtualMachineIDString(d), key)
+	d.Set("reboot_required", true)
+	obj := &types.VirtualMachineAffinityInfo{
+		AffinitySet: []int32{},
+	}
+	for _, v := range d.Get(key).(*schema.Set).List() {
+		obj.AffinitySet = append(obj.AffinitySet, int32(v.(int)))
+	}
+	sort.Slice(obj.AffinitySet, func(i, j int) bool { return obj.AffinitySet[i] < obj.AffinitySet[j] })
+	return obj
+}
+
+// flattenVirtualMachineAffinityInfo reads a VirtualMachineAffinityInfo into
+// the affinity set at key.
+func flattenVirtualMachineAffinityInfo(d *schema.ResourceData, key string, obj *types.VirtualMachineAffinityInfo) error {
+	var affinity []interface{}
+	if obj != nil {
+		for _, v := range obj.AffinitySet {
+			affinity = append(affinity, int(v))
+		}
+	}
+	return d.Set(key, affinity)
+}
+
+// expandVirtualMachineCPUFeatureMask reads the cpuid_mask set into a list of
+// VirtualMachineCpuIdInfoSpec. Masks are tracked by their level, and masks
+// that have been removed from configuration are removed from the virtual
+// machine. nil is returned if the set has not changed.
+//
+// Changes to the CPUID mask require a restart of the virtual machine.
+func expandVirtualMachineCPUFeatureMask(d *schema.ResourceData) []types.VirtualMachineCpuIdInfoSpec {
+	if !d.HasChange("cpuid_mask") {
+		return nil
+	}
+	log.Printf("[DEBUG] %s: Resource argument %q requires a VM restart", resourceVSphereVirtualMachineIDString(d), "cpuid_mask")
+	d.Set("reboot_required", true)
+	o, n := d.GetChange("cpuid_mask")
+	oldLevels := make(map[int32]struct{})
+	for _, v := range o.(*schema.Set).List() {
+		oldLevels[int32(v.(map[string]interface{})["level"].(int))] = struct{}{}
+	}
+	var specs []types.VirtualMachineCpuIdInfoSpec
+	for _, v := range n.(*schema.Set).List() {
+		info := expandHostCPUIDInfo(v.(map[string]interface{}))
+		spec := types.VirtualMachineCpuIdInfoSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{
+				Operation: types.ArrayUpdateOperationAdd,
+			},
+			Info: &info,
+		}
+		if _, ok := oldLevels[info.Level]; ok {
+			spec.Operation = types.ArrayUpdateOperationEdit
+			delete(oldLevels, info.Level)
+		}
+		specs = append(specs, spec)
+	}
+	for level := range oldLevels {
+		specs = append(specs, types.VirtualMachineCpuIdInfoSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{
+				Operation: types.ArrayUpdateOperationRemove,
+				RemoveKey: level,
+			},
+		})
+	}
+	sort.Slice(specs, func(i, j int) bool { return cpuIDInfoSpecLevel(specs[i]) < cpuIDInfoSpecLevel(specs[j]) })
+	return specs
+}
+
+// cpuIDInfoSpecLevel returns the CPUID level that a
+// VirtualMachineCpuIdInfoSpec operates on.
+func cpuIDInfoSpecLevel(spec types.VirtualMachineCpuIdInfoSpec) int32 {
+	if spec.Info != nil {
+		return spec.Info.Level
+	}
+	return spec.RemoveKey.(int32)
+}
+
+// expandHostCPUIDInfo reads a cpuid_mask entry into a HostCpuIdInfo.
+func expandHostCPUIDInfo(m map[string]interface{}) types.HostCpuIdInfo {
+	return types.HostCpuIdInfo{
+		Level:  int32(m["level"].(int)),
+		Vendor: m["vendor"].(string),
+		Eax:    m["eax"].(string),
+		Ebx:    m["ebx"].(string),
+		Ecx:    m["ecx"].(string),
+		Edx:    m["edx"].(string),
+	}
+}
+
+// flattenVirtualMachineCPUFeatureMask reads the CPUID mask of a virtual
+// machine into the cpuid_mask set. Registers that are not masked are set to
+// the default mask.
+func flattenVirtualMachineCPUFeatureMask(d *schema.ResourceData, obj []types.HostCpuIdInfo) error {
+	register := func(v string) string {
+		if v == "" {
+			return virtualMachineCPUIDMaskRegisterDefault
+		}
+		return v
+	}
+	var masks []interface{}
+	for _, info := range obj {
+		masks = append(masks, map[string]interface{}{
+			"level":  int(info.Level),
+			"vendor": info.Vendor,
+			"eax":    register(info.Eax),
+			"ebx":    register(info.Ebx),
+			"ecx":    register(info.Ecx),
+			"edx":    register(info.Edx),
+		})
+	}
+	return d.Set("cpuid_mask", masks)
+}
+
 // expandVAppConfig reads in all the vapp key/value pairs and returns
 // the appropriate VmConfigSpec.
 //
@@ -739,6 +1074,37 @@ func expandMemorySizeConfig(d *schema.ResourceData) int64 {
 	return newMem
 }
 
+// expandMemoryReservationLockedToMax locks the memory reservation of the
+// virtual machine to the size of its memory when PCI passthrough devices are
+// added, as the memory of virtual machines with passthrough devices must be
+// fully reserved. The lock is released when the last device is removed, and
+// left alone otherwise.
+func expandMemoryReservationLockedToMax(d *schema.ResourceData) *bool {
+	if !d.HasChange("pci_device") {
+		return nil
+	}
+	return structure.BoolPtr(len(d.Get("pci_device").([]interface{})) > 0)
+}
+
+// expandVirtualMachineCryptoSpec returns the crypto spec that brings the home
+// directory of a virtual machine encrypted with the key in current, or
+// unencrypted if current is nil, to the settings in encryption. The current
+// key is read from the virtual machine rather than from state, as clones can
+// inherit the encryption of their source.
+//
+// Encrypting, decrypting, and re-keying a virtual machine requires a restart.
+func expandVirtualMachineCryptoSpec(d *schema.ResourceData, client *govmomi.Client, current *types.CryptoKeyId) (types.BaseCryptoSpec, error) {
+	crypto, err := virtualdevice.ExpandCryptoSpec(client, current, d.Get("encryption").([]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	if crypto != nil {
+		log.Printf("[DEBUG] %s: Resource argument %q requires a VM restart", resourceVSphereVirtualMachineIDString(d), "encryption")
+		d.Set("reboot_required", true)
+	}
+	return crypto, nil
+}
+
 // expandVirtualMachineConfigSpec reads certain ResourceData keys and
 // returns a VirtualMachineConfigSpec.
 func expandVirtualMachineConfigSpec(d *schema.ResourceData, client *govmomi.Client) (types.VirtualMachineConfigSpec, error) {
@@ -763,7 +1129,7 @@ func expandVirtualMachineConfigSpec(d *schema.ResourceData, client *govmomi.Clie
 		CpuHotRemoveEnabled: getBoolWithRestart(d, "cpu_hot_remove_enabled"),
 		CpuAllocation:       expandVirtualMachineResourceAllocation(d, "cpu"),
 		MemoryAllocation:    expandVirtualMachineResourceAllocation(d, "memory"),
-		ExtraConfig:         expandExtraConfig(d),
+		ExtraConfig:         append(expandExtraConfig(d), expandVirtualMachineAdvancedSettings(d)...),
 		SwapPlacement:       getWithRestart(d, "swap_placement_policy").(string),
 		BootOptions:         expandVirtualMachineBootOptions(d, client),
 		VAppConfig:          vappConfig,
@@ -771,6 +1137,26 @@ func expandVirtualMachineConfigSpec(d *schema.ResourceData, client *govmomi.Clie
 		NestedHVEnabled:     getBoolWithRestart(d, "nested_hv_enabled"),
 		VPMCEnabled:         getBoolWithRestart(d, "cpu_performance_counters_enabled"),
 		LatencySensitivity:  expandLatencySensitivity(d),
+		CpuAffinity:         expandVirtualMachineAffinityInfo(d, "cpu_affinity"),
+		MemoryAffinity:      expandVirtualMachineAffinityInfo(d, "memory_affinity"),
+		CpuFeatureMask:      expandVirtualMachineCPUFeatureMask(d),
+
+		MemoryReservationLockedToMax: expandMemoryReservationLockedToMax(d),
+	}
+
+	// Releasing the lock leaves the memory reservation at the size of the
+	// memory, so the configured reservation is always sent along, even if it
+	// is not set.
+	if locked := obj.MemoryReservationLockedToMax; locked != nil && !*locked && obj.MemoryAllocation.Reservation == nil {
+		obj.MemoryAllocation.Reservation = structure.Int64Ptr(0)
+	}
+
+	// Only send the storage policy when it changes, as re-applying a policy can
+	// trigger a compliance check on the virtual machine's storage.
+	if d.HasChange("storage_policy_id") {
+		if policyID := d.Get("storage_policy_id").(string); policyID != "" {
+			obj.VmProfile = spbm.PolicySpecByID(policyID)
+		}
 	}
 
 	return obj, nil
@@ -797,6 +1183,7 @@ func flattenVirtualMachineConfigInfo(d *schema.ResourceData, obj *types.VirtualM
 	d.Set("cpu_performance_counters_enabled", obj.VPMCEnabled)
 	d.Set("change_version", obj.ChangeVersion)
 	d.Set("uuid", obj.Uuid)
+	d.Set("encryption", virtualdevice.FlattenCryptoKeyID(obj.KeyId))
 
 	if err := flattenToolsConfigInfo(d, obj.Tools); err != nil {
 		return err
@@ -813,9 +1200,21 @@ func flattenVirtualMachineConfigInfo(d *schema.ResourceData, obj *types.VirtualM
 	if err := flattenExtraConfig(d, obj.ExtraConfig); err != nil {
 		return err
 	}
+	if err := flattenVirtualMachineAdvancedSettings(d, obj.ExtraConfig); err != nil {
+		return err
+	}
 	if err := flattenVAppConfig(d, obj.VAppConfig); err != nil {
 		return err
 	}
+	if err := flattenVirtualMachineAffinityInfo(d, "cpu_affinity", obj.CpuAffinity); err != nil {
+		return err
+	}
+	if err := flattenVirtualMachineAffinityInfo(d, "memory_affinity", obj.MemoryAffinity); err != nil {
+		return err
+	}
+	if err := flattenVirtualMachineCPUFeatureMask(d, obj.CpuFeatureMask); err != nil {
+		return err
+	}
 	if err := flattenLatencySensitivity(d, obj.LatencySensitivity); err != nil {
 		return err
 	}
@@ -1,11 +1,16 @@
 package vsphere
 
 import (
+	"fmt"
 	"log"
 	"net"
 	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/helper/virtualmachine"
+	"github.com/terraform-providers/terraform-provider-vsphere/vsphere/internal/virtualdevice"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
@@ -18,21 +23,136 @@ func schemaVirtualMachineGuestInfo() map[string]*schema.Schema {
 			Computed:    true,
 			Description: "The IP address selected by Terraform to be used for the provisioner.",
 		},
+		"default_ip_address_cidr": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Description:  "A network in CIDR notation to select default_ip_address from. The first guest IP address in this network is used. If no address is in the network, the address is selected as if this was not set.",
+			ValidateFunc: validation.CIDRNetwork(0, 128),
+		},
 		"guest_ip_addresses": {
 			Type:        schema.TypeList,
 			Computed:    true,
 			Description: "The current list of IP addresses on this virtual machine.",
 			Elem:        &schema.Schema{Type: schema.TypeString},
 		},
+		"wait_for_guest": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Description: "Guest readiness conditions to wait for after the virtual machine is powered on. All of the conditions that are set need to be met.",
+			Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+				"timeout": {
+					Type:        schema.TypeInt,
+					Optional:    true,
+					Default:     5,
+					Description: "The amount of time, in minutes, to wait for the guest conditions. A value less than 1 disables the waiter.",
+				},
+				"network_interface": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Description: "Address conditions for the network interfaces of the virtual machine, in the same order as the network_interface blocks.",
+					Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+						"cidrs": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Description: "Networks in CIDR notation. The network interface needs to have an address in one of these networks.",
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validation.CIDRNetwork(0, 128),
+							},
+						},
+					}},
+				},
+				"guestinfo": {
+					Type:        schema.TypeMap,
+					Optional:    true,
+					Description: "guestinfo keys and the values that they need to have, such as a key that the guest sets when it is ready. The guestinfo. prefix of the keys is optional.",
+					Elem:        &schema.Schema{Type: schema.TypeString},
+				},
+				"tools_running_status": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Description:  "The running status that VMware tools needs to report. Can be one of guestToolsNotRunning, guestToolsRunning, or guestToolsExecutingScripts.",
+					ValidateFunc: validation.StringInSlice(virtualMachineToolsRunningStatusAllowedValues, false),
+				},
+				"heartbeat_status": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Description:  "The guest heartbeat status that needs to be reported. Can be one of gray, green, yellow, or red.",
+					ValidateFunc: validation.StringInSlice(virtualMachineHeartbeatStatusAllowedValues, false),
+				},
+			}},
+		},
+	}
+}
+
+// guestInfoKeyPrefix is the prefix of the guestinfo keys in the extra config
+// of a virtual machine.
+const guestInfoKeyPrefix = "guestinfo."
+
+var virtualMachineToolsRunningStatusAllowedValues = []string{
+	string(types.VirtualMachineToolsRunningStatusGuestToolsNotRunning),
+	string(types.VirtualMachineToolsRunningStatusGuestToolsRunning),
+	string(types.VirtualMachineToolsRunningStatusGuestToolsExecutingScripts),
+}
+
+var virtualMachineHeartbeatStatusAllowedValues = []string{
+	string(types.ManagedEntityStatusGray),
+	string(types.ManagedEntityStatusGreen),
+	string(types.ManagedEntityStatusYellow),
+	string(types.ManagedEntityStatusRed),
+}
+
+// expandGuestWaitConditions reads the wait_for_guest block and returns the
+// guest conditions to wait for. The network_interface conditions are matched
+// to the network interfaces in the supplied device list by their position.
+func expandGuestWaitConditions(d *schema.ResourceData, l object.VirtualDeviceList) (virtualmachine.GuestWaitConditions, error) {
+	var c virtualmachine.GuestWaitConditions
+	keys := virtualdevice.NetworkInterfaceKeys(l)
+	for n, item := range d.Get("wait_for_guest.0.network_interface").([]interface{}) {
+		var cidrs []interface{}
+		if item != nil {
+			cidrs = item.(map[string]interface{})["cidrs"].([]interface{})
+		}
+		if len(cidrs) < 1 {
+			continue
+		}
+		key, ok := keys[n]
+		if !ok {
+			return c, fmt.Errorf("wait_for_guest.0.network_interface.%d: virtual machine has no network interface at this position", n)
+		}
+		for _, v := range cidrs {
+			_, ipnet, err := net.ParseCIDR(v.(string))
+			if err != nil {
+				return c, fmt.Errorf("wait_for_guest.0.network_interface.%d: %s", n, err)
+			}
+			if c.NetworkInterfaceCIDRs == nil {
+				c.NetworkInterfaceCIDRs = make(map[int32][]*net.IPNet)
+			}
+			c.NetworkInterfaceCIDRs[key] = append(c.NetworkInterfaceCIDRs[key], ipnet)
+		}
+	}
+	for k, v := range d.Get("wait_for_guest.0.guestinfo").(map[string]interface{}) {
+		if !strings.HasPrefix(k, guestInfoKeyPrefix) {
+			k = guestInfoKeyPrefix + k
+		}
+		if c.GuestInfo == nil {
+			c.GuestInfo = make(map[string]string)
+		}
+		c.GuestInfo[k] = v.(string)
 	}
+	c.ToolsRunningStatus = d.Get("wait_for_guest.0.tools_running_status").(string)
+	c.HeartbeatStatus = d.Get("wait_for_guest.0.heartbeat_status").(string)
+	return c, nil
 }
 
 // buildAndSelectGuestIPs builds a list of IP addresses known to VMware tools.
 // From this list, it selects the first IP address it seems that's associated
 // with a default gateway - first IPv4, and then IPv6 if criteria can't be
 // satisfied - and sets that as the default_ip_address and also the IP address
-// used for provisioning. The full list of IP addresses is saved to
-// guest_ip_addresses.
+// used for provisioning. If default_ip_address_cidr is set, the first IP
+// address in that network is selected instead. The full list of IP addresses
+// is saved to guest_ip_addresses.
 func buildAndSelectGuestIPs(d *schema.ResourceData, guest types.GuestInfo) error {
 	log.Printf("[DEBUG] %s: Checking guest networking state", resourceVSphereVirtualMachineIDString(d))
 	var v4primary, v6primary, v4gw, v6gw net.IP
@@ -87,7 +207,11 @@ func buildAndSelectGuestIPs(d *schema.ResourceData, guest types.GuestInfo) error
 		return d.Set("guest_ip_addresses", addrs)
 	}
 	var primary string
+	if cidr := d.Get("default_ip_address_cidr").(string); cidr != "" {
+		primary = selectGuestIPInCIDR(addrs, cidr)
+	}
 	switch {
+	case primary != "":
 	case v4primary != nil:
 		primary = v4primary.String()
 	case v6primary != nil:
@@ -108,3 +232,18 @@ func buildAndSelectGuestIPs(d *schema.ResourceData, guest types.GuestInfo) error
 
 	return nil
 }
+
+// selectGuestIPInCIDR returns the first of the supplied IP addresses that is
+// in the network described by cidr, or an empty string if there is none.
+func selectGuestIPInCIDR(addrs []string, cidr string) string {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ipnet.Contains(ip) {
+			return addr
+		}
+	}
+	return ""
+}